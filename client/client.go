@@ -4,10 +4,13 @@ import (
 	"bufio"
 	"net"
 	"sync"
+	"time"
 )
 
 const (
 	CLIENT_MULTI = 1 << iota
+	CLIENT_CLOSE_AFTER_REPLY
+	CLIENT_MONITOR
 )
 
 type Client struct {
@@ -17,8 +20,20 @@ type Client struct {
 	Reader        *bufio.Reader
 	LastCommand   *Command
 	Authenticated bool
+	User          string
+	CertSubject   string
+	Name          string
 	Flag          int
 	State         []*Command
+	CreatedAt     time.Time
+	// Done receives a value when the database loop finished the last command
+	// of the client, so the next command is not read before.
+	Done chan struct{}
+
+	lastCommandName string
+
+	infoMu sync.Mutex
+	info   Info
 }
 
 var clientPool sync.Pool
@@ -30,8 +45,18 @@ func NewClient(conn net.Conn, id uint64) *Client {
 	cli.Reader = bufio.NewReader(conn)
 	cli.DB = 0
 	cli.Authenticated = false
+	cli.User = ""
+	cli.CertSubject = ""
+	cli.Name = ""
 	cli.Flag = 0
 	cli.State = make([]*Command, 0)
+	cli.CreatedAt = time.Now()
+	if cli.Done == nil {
+		cli.Done = make(chan struct{}, 1)
+	}
+	cli.lastCommandName = ""
+	cli.info = Info{}
+	cli.UpdateInfo()
 	return cli
 }
 
@@ -0,0 +1,76 @@
+package client
+
+import (
+	"net"
+	"strings"
+	"time"
+)
+
+// Info is a snapshot of the state of a client, which can be read by the other
+// goroutines while the client is running commands.
+type Info struct {
+	ID              uint64
+	Addr            string
+	LocalAddr       string
+	Name            string
+	User            string
+	DB              int
+	Flags           string
+	Multi           int
+	LastCommand     string
+	CreatedAt       time.Time
+	LastInteraction time.Time
+}
+
+// UpdateInfo updates the snapshot of the client state. It must be called by the
+// goroutine of the client.
+func (cli *Client) UpdateInfo() {
+	info := Info{
+		ID:              cli.ID,
+		Addr:            connAddr(cli.Conn.RemoteAddr(), cli.Conn.LocalAddr()),
+		LocalAddr:       connAddr(cli.Conn.LocalAddr(), cli.Conn.LocalAddr()),
+		Name:            cli.Name,
+		User:            cli.User,
+		DB:              cli.DB,
+		Flags:           "N",
+		Multi:           -1,
+		CreatedAt:       cli.CreatedAt,
+		LastInteraction: time.Now(),
+	}
+	if cli.Flag&CLIENT_MULTI != 0 {
+		info.Flags = "x"
+		info.Multi = len(cli.State)
+	}
+	if cli.Flag&CLIENT_MONITOR != 0 {
+		info.Flags = "O"
+	}
+	if cli.lastCommandName != "" {
+		info.LastCommand = strings.ToLower(cli.lastCommandName)
+	} else {
+		info.LastCommand = "NULL"
+	}
+
+	cli.infoMu.Lock()
+	cli.info = info
+	cli.infoMu.Unlock()
+}
+
+func (cli *Client) Info() Info {
+	cli.infoMu.Lock()
+	defer cli.infoMu.Unlock()
+
+	return cli.info
+}
+
+// connAddr returns the address as ip:port, or as path:0 for the unix socket
+// where the peer address is unnamed.
+func connAddr(addr net.Addr, local net.Addr) string {
+	if addr, ok := addr.(*net.UnixAddr); ok {
+		name := addr.Name
+		if name == "" || name == "@" {
+			name = local.String()
+		}
+		return name + ":0"
+	}
+	return addr.String()
+}
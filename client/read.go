@@ -39,6 +39,7 @@ func (cli *Client) ReadCommand() error {
 		cmd.Args = fields[1:]
 	}
 	cli.LastCommand = cmd
+	cli.lastCommandName = cmd.Command
 
 	return nil
 }
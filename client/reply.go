@@ -34,6 +34,12 @@ func (cli *Client) ReplyBulkString(s string) (int, error) {
 	return cli.rely(buf.Bytes())
 }
 
+// ReplyEmptyBulk replies an empty bulk string, which ReplyBulkString replies as
+// a nil bulk string.
+func (cli *Client) ReplyEmptyBulk() (int, error) {
+	return cli.rely([]byte("$0\r\n\r\n"))
+}
+
 func (cli *Client) ReplyNilBulk() (int, error) {
 	return cli.rely([]byte("$-1\r\n"))
 }
@@ -1,8 +1,11 @@
 package main
 
 import (
-	"log"
+	"context"
+	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/ghosind/antdb/config"
 	"github.com/ghosind/antdb/server"
@@ -11,14 +14,31 @@ import (
 func main() {
 	cfg, err := config.ParseArgs(os.Args[1:])
 	if err != nil {
-		log.Fatalf("Failed to parse config: %v", err)
+		slog.Warn("Failed to parse config", "error", err)
+		os.Exit(1)
 	}
 
-	options := config.BuildOptionsByConfig(cfg)
+	options, err := config.BuildOptionsByConfig(cfg)
+	if err != nil {
+		slog.Warn("Failed to parse config", "error", err)
+		os.Exit(1)
+	}
 	s := server.NewServer(options...)
+	logger := s.Logger()
+
+	handleReopenSignal(s)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		logger.Log(context.Background(), server.LevelNotice, "Shutting down", "signal", sig.String())
+		s.Close()
+	}()
 
 	err = s.Listen()
 	if err != nil {
-		log.Fatalf("Failed to start AntDB: %v", err)
+		logger.Warn("Failed to start AntDB", "error", err)
+		os.Exit(1)
 	}
 }
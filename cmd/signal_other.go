@@ -0,0 +1,8 @@
+//go:build !unix
+
+package main
+
+import "github.com/ghosind/antdb/server"
+
+// handleReopenSignal does nothing on the platforms without SIGUSR1.
+func handleReopenSignal(s *server.Server) {}
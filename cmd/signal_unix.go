@@ -0,0 +1,25 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ghosind/antdb/server"
+)
+
+// handleReopenSignal reopens the log file of the server on SIGUSR1, which lets
+// the log file be rotated without restarting the server.
+func handleReopenSignal(s *server.Server) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1)
+	go func() {
+		for range signals {
+			if err := s.ReopenLogFile(); err != nil {
+				s.Logger().Warn("Failed to reopen log file", "error", err)
+			}
+		}
+	}()
+}
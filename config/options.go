@@ -1,7 +1,9 @@
 package config
 
 import (
+	"errors"
 	"strconv"
+	"strings"
 
 	"github.com/ghosind/antdb/server"
 )
@@ -11,6 +13,10 @@ type ServerOptionParamType int
 const (
 	ServerOptionParamTypeInt ServerOptionParamType = iota
 	ServerOptionParamTypeString
+	ServerOptionParamTypeMemory
+	ServerOptionParamTypeOctal
+	ServerOptionParamTypeStringList
+	ServerOptionParamTypeBool
 )
 
 type ServerOptionParam struct {
@@ -19,45 +25,194 @@ type ServerOptionParam struct {
 	OptionBuilder any
 }
 
-func (p *ServerOptionParam) BuildOption(cfg *Config) server.ServerOption {
+// BuildOption returns the server option of the directive, or nil if the
+// directive is not set. It returns an error if the value cannot be parsed.
+func (p *ServerOptionParam) BuildOption(cfg *Config) (server.ServerOption, error) {
 	switch p.Type {
 	case ServerOptionParamTypeInt:
-		return buildIntOption(cfg, p.Name, p.OptionBuilder.(func(int) server.ServerOption))
+		return buildIntOption(cfg, p.Name, p.OptionBuilder.(func(int) server.ServerOption)), nil
 	case ServerOptionParamTypeString:
-		return buildStringOption(cfg, p.Name, p.OptionBuilder.(func(string) server.ServerOption))
+		return buildStringOption(cfg, p.Name, p.OptionBuilder.(func(string) server.ServerOption)), nil
+	case ServerOptionParamTypeMemory:
+		return buildMemoryOption(cfg, p.Name, p.OptionBuilder.(func(int64) server.ServerOption))
+	case ServerOptionParamTypeStringList:
+		return buildStringListOption(cfg, p.Name, p.OptionBuilder.(func(...string) server.ServerOption)), nil
+	case ServerOptionParamTypeBool:
+		return buildBoolOption(cfg, p.Name, p.OptionBuilder.(func(bool) server.ServerOption)), nil
+	case ServerOptionParamTypeOctal:
+		return buildOctalOption(cfg, p.Name, p.OptionBuilder.(func(int) server.ServerOption)), nil
 	default:
-		return nil
+		return nil, nil
 	}
 }
 
 var optionParams = map[string]ServerOptionParam{
 	"port":      {Name: "port", Type: ServerOptionParamTypeInt, OptionBuilder: server.WithPort},
-	"bind":      {Name: "bind", Type: ServerOptionParamTypeString, OptionBuilder: server.WithBind},
+	"bind":      {Name: "bind", Type: ServerOptionParamTypeStringList, OptionBuilder: server.WithBind},
 	"databases": {Name: "databases", Type: ServerOptionParamTypeInt, OptionBuilder: server.WithDatabases},
 	"hz":        {Name: "hz", Type: ServerOptionParamTypeInt, OptionBuilder: server.WithHZ},
+	"timeout":   {Name: "timeout", Type: ServerOptionParamTypeInt, OptionBuilder: server.WithTimeout},
 	"active-expire-samples": {
 		Name:          "active-expire-samples",
 		Type:          ServerOptionParamTypeInt,
 		OptionBuilder: server.WithActiveExpireSamples,
 	},
+	"unixsocket": {
+		Name:          "unixsocket",
+		Type:          ServerOptionParamTypeString,
+		OptionBuilder: server.WithUnixSocket,
+	},
+	"unixsocketperm": {
+		Name:          "unixsocketperm",
+		Type:          ServerOptionParamTypeOctal,
+		OptionBuilder: server.WithUnixSocketPerm,
+	},
+	"metrics-port": {
+		Name:          "metrics-port",
+		Type:          ServerOptionParamTypeInt,
+		OptionBuilder: server.WithMetricsPort,
+	},
+	"metrics-bind": {
+		Name:          "metrics-bind",
+		Type:          ServerOptionParamTypeString,
+		OptionBuilder: server.WithMetricsBind,
+	},
+	"loglevel": {
+		Name:          "loglevel",
+		Type:          ServerOptionParamTypeString,
+		OptionBuilder: server.WithLogLevel,
+	},
+	"logfile": {
+		Name:          "logfile",
+		Type:          ServerOptionParamTypeString,
+		OptionBuilder: server.WithLogFile,
+	},
+	"log-format": {
+		Name:          "log-format",
+		Type:          ServerOptionParamTypeString,
+		OptionBuilder: server.WithLogFormat,
+	},
+	"tcp-keepalive": {
+		Name:          "tcp-keepalive",
+		Type:          ServerOptionParamTypeInt,
+		OptionBuilder: server.WithTCPKeepAlive,
+	},
+	"maxclients": {
+		Name:          "maxclients",
+		Type:          ServerOptionParamTypeInt,
+		OptionBuilder: server.WithMaxClients,
+	},
+	"tls-port": {
+		Name:          "tls-port",
+		Type:          ServerOptionParamTypeInt,
+		OptionBuilder: server.WithTLSPort,
+	},
+	"tls-cert-file": {
+		Name:          "tls-cert-file",
+		Type:          ServerOptionParamTypeString,
+		OptionBuilder: server.WithTLSCertFile,
+	},
+	"tls-key-file": {
+		Name:          "tls-key-file",
+		Type:          ServerOptionParamTypeString,
+		OptionBuilder: server.WithTLSKeyFile,
+	},
+	"tls-ca-cert-file": {
+		Name:          "tls-ca-cert-file",
+		Type:          ServerOptionParamTypeString,
+		OptionBuilder: server.WithTLSCACertFile,
+	},
+	"tls-auth-clients": {
+		Name:          "tls-auth-clients",
+		Type:          ServerOptionParamTypeString,
+		OptionBuilder: server.WithTLSAuthClients,
+	},
+	"tls-protocols": {
+		Name:          "tls-protocols",
+		Type:          ServerOptionParamTypeString,
+		OptionBuilder: server.WithTLSProtocols,
+	},
+	"tls-ciphers": {
+		Name:          "tls-ciphers",
+		Type:          ServerOptionParamTypeString,
+		OptionBuilder: server.WithTLSCiphers,
+	},
 	"requirepass": {
 		Name:          "requirepass",
 		Type:          ServerOptionParamTypeString,
 		OptionBuilder: server.WithRequirePass,
 	},
+	"slowlog-log-slower-than": {
+		Name:          "slowlog-log-slower-than",
+		Type:          ServerOptionParamTypeInt,
+		OptionBuilder: server.WithSlowlogLogSlowerThan,
+	},
+	"slowlog-max-len": {
+		Name:          "slowlog-max-len",
+		Type:          ServerOptionParamTypeInt,
+		OptionBuilder: server.WithSlowlogMaxLen,
+	},
+	"latency-monitor-threshold": {
+		Name:          "latency-monitor-threshold",
+		Type:          ServerOptionParamTypeInt,
+		OptionBuilder: server.WithLatencyMonitorThreshold,
+	},
+	"proto-max-bulk-len": {
+		Name:          "proto-max-bulk-len",
+		Type:          ServerOptionParamTypeMemory,
+		OptionBuilder: server.WithProtoMaxBulkLen,
+	},
+	"list-max-listpack-size": {
+		Name:          "list-max-listpack-size",
+		Type:          ServerOptionParamTypeInt,
+		OptionBuilder: server.WithListMaxListpackSize,
+	},
+	"list-compress-depth": {
+		Name:          "list-compress-depth",
+		Type:          ServerOptionParamTypeInt,
+		OptionBuilder: server.WithListCompressDepth,
+	},
+	"maxmemory": {
+		Name:          "maxmemory",
+		Type:          ServerOptionParamTypeMemory,
+		OptionBuilder: server.WithMaxMemory,
+	},
+	"maxmemory-policy": {
+		Name:          "maxmemory-policy",
+		Type:          ServerOptionParamTypeString,
+		OptionBuilder: server.WithMaxMemoryPolicy,
+	},
+	"protected-mode": {
+		Name:          "protected-mode",
+		Type:          ServerOptionParamTypeBool,
+		OptionBuilder: server.WithProtectedMode,
+	},
+	"aclfile": {
+		Name:          "aclfile",
+		Type:          ServerOptionParamTypeString,
+		OptionBuilder: server.WithACLFile,
+	},
+	"maxmemory-samples": {
+		Name:          "maxmemory-samples",
+		Type:          ServerOptionParamTypeInt,
+		OptionBuilder: server.WithMaxMemorySamples,
+	},
 }
 
-func BuildOptionsByConfig(cfg *Config) []server.ServerOption {
+func BuildOptionsByConfig(cfg *Config) ([]server.ServerOption, error) {
 	var options []server.ServerOption
 
 	for _, param := range optionParams {
-		option := param.BuildOption(cfg)
+		option, err := param.BuildOption(cfg)
+		if err != nil {
+			return nil, err
+		}
 		if option != nil {
 			options = append(options, option)
 		}
 	}
 
-	return options
+	return options, nil
 }
 
 func buildIntOption(cfg *Config, name string, setter func(int) server.ServerOption) server.ServerOption {
@@ -72,6 +227,50 @@ func buildIntOption(cfg *Config, name string, setter func(int) server.ServerOpti
 	return setter(value)
 }
 
+// buildStringListOption reads the arguments of the directive as a list, where
+// an argument from the command line may have multiple space separated values.
+func buildStringListOption(cfg *Config, name string, setter func(...string) server.ServerOption) server.ServerOption {
+	directives := cfg.Get(name)
+	if len(directives) == 0 {
+		return nil
+	}
+	values := make([]string, 0, len(directives[0].Args))
+	for _, arg := range directives[0].Args {
+		values = append(values, strings.Fields(arg)...)
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return setter(values...)
+}
+
+func buildBoolOption(cfg *Config, name string, setter func(bool) server.ServerOption) server.ServerOption {
+	directives := cfg.Get(name)
+	if len(directives) == 0 || len(directives[0].Args) == 0 {
+		return nil
+	}
+	switch strings.ToLower(directives[0].Args[0]) {
+	case "yes":
+		return setter(true)
+	case "no":
+		return setter(false)
+	default:
+		return nil
+	}
+}
+
+func buildOctalOption(cfg *Config, name string, setter func(int) server.ServerOption) server.ServerOption {
+	directives := cfg.Get(name)
+	if len(directives) == 0 || len(directives[0].Args) == 0 {
+		return nil
+	}
+	value, err := strconv.ParseInt(directives[0].Args[0], 8, 32)
+	if err != nil {
+		return nil
+	}
+	return setter(int(value))
+}
+
 func buildStringOption(cfg *Config, name string, setter func(string) server.ServerOption) server.ServerOption {
 	directives := cfg.Get(name)
 	if len(directives) == 0 || len(directives[0].Args) == 0 {
@@ -79,3 +278,54 @@ func buildStringOption(cfg *Config, name string, setter func(string) server.Serv
 	}
 	return setter(directives[0].Args[0])
 }
+
+// buildMemoryOption returns an error for an invalid memory size, as a typo like
+// "1gbb" must not start a server without the limit.
+func buildMemoryOption(cfg *Config, name string, setter func(int64) server.ServerOption) (server.ServerOption, error) {
+	directives := cfg.Get(name)
+	if len(directives) == 0 || len(directives[0].Args) == 0 {
+		return nil, nil
+	}
+	value, err := parseMemory(directives[0].Args[0])
+	if err != nil || value < 0 {
+		return nil, newInvalidValueError(name, directives[0].Args[0])
+	}
+	return setter(value), nil
+}
+
+// parseMemory parses a memory size like "1gb", "512mb" or "100", with the same
+// units as Redis: k/m/g are powers of 1000 and kb/mb/gb are powers of 1024.
+func parseMemory(value string) (int64, error) {
+	value = strings.ToLower(value)
+	units := []struct {
+		suffix string
+		mul    int64
+	}{
+		{"kb", 1024},
+		{"mb", 1024 * 1024},
+		{"gb", 1024 * 1024 * 1024},
+		{"k", 1000},
+		{"m", 1000 * 1000},
+		{"g", 1000 * 1000 * 1000},
+		{"b", 1},
+	}
+
+	mul := int64(1)
+	for _, unit := range units {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSuffix(value, unit.suffix)
+			mul = unit.mul
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return n * mul, nil
+}
+
+func newInvalidValueError(name, value string) error {
+	return errors.New("invalid value '" + value + "' for '" + name + "'")
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseMemory(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected int64
+		ok       bool
+	}{
+		{"100", 100, true},
+		{"100b", 100, true},
+		{"1k", 1000, true},
+		{"1kb", 1024, true},
+		{"2m", 2000000, true},
+		{"2MB", 2 * 1024 * 1024, true},
+		{"1g", 1000000000, true},
+		{"1gb", 1024 * 1024 * 1024, true},
+		{"0", 0, true},
+		{"1gbb", 0, false},
+		{"gb", 0, false},
+		{"1.5gb", 0, false},
+		{"", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			value, err := parseMemory(tt.value)
+			if (err == nil) != tt.ok || (tt.ok && value != tt.expected) {
+				t.Errorf("parseMemory(%q) = (%d, %v), expected (%d, %v)", tt.value, value, err, tt.expected, tt.ok)
+			}
+		})
+	}
+}
+
+func TestBuildOptionsByConfig(t *testing.T) {
+	tests := []struct {
+		config string
+		err    string
+	}{
+		{"maxmemory 1gb", ""},
+		{"maxmemory 1gbb", "invalid value '1gbb' for 'maxmemory'"},
+		{"maxmemory -1", "invalid value '-1' for 'maxmemory'"},
+		{"proto-max-bulk-len 512m", ""},
+		{"proto-max-bulk-len large", "invalid value 'large' for 'proto-max-bulk-len'"},
+		{"maxmemory-policy allkeys-lru", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.config, func(t *testing.T) {
+			cfg, err := Parse(strings.NewReader(tt.config))
+			if err != nil {
+				t.Fatalf("Parse: unexpected error %v", err)
+			}
+
+			options, err := BuildOptionsByConfig(cfg)
+			if tt.err == "" {
+				if err != nil || len(options) != 1 {
+					t.Errorf("expected 1 option, got (%d, %v)", len(options), err)
+				}
+			} else if err == nil || err.Error() != tt.err {
+				t.Errorf("expected error %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package core
+
+type BitFieldOpKind int
+
+const (
+	BitFieldGet BitFieldOpKind = iota
+	BitFieldSet
+	BitFieldIncrBy
+)
+
+type BitFieldOverflow int
+
+const (
+	BitFieldOverflowWrap BitFieldOverflow = iota
+	BitFieldOverflowSat
+	BitFieldOverflowFail
+)
+
+// BitFieldOp is an operation of BITFIELD on the integer of the width in bits at
+// the bit offset. Value is the value of SET or the increment of INCRBY.
+type BitFieldOp struct {
+	Kind     BitFieldOpKind
+	Signed   bool
+	Bits     int
+	Offset   int64
+	Value    int64
+	Overflow BitFieldOverflow
+}
+
+// BitFieldResult is the result of a BITFIELD operation, where Failed is true if
+// the operation was not performed for the FAIL overflow behavior.
+type BitFieldResult struct {
+	Value  int64
+	Failed bool
+}
+
+// BitField performs the operations on the string of the key in order. The
+// string is created or grown with zero bytes only if there is any SET or INCRBY
+// operation, and the bits out of the string are read as 0.
+func (db *Database) BitField(key string, ops []BitFieldOp) ([]BitFieldResult, error) {
+	obj, err := db.lookupKey(key, TypeString, true)
+	if err != nil {
+		return nil, err
+	}
+
+	length := int64(0)
+	for _, op := range ops {
+		if op.Kind != BitFieldGet {
+			if end := (op.Offset+int64(op.Bits)-1)>>3 + 1; end > length {
+				length = end
+			}
+		}
+	}
+	if length > db.maxStringLength {
+		return nil, ErrStringTooLarge
+	}
+
+	var buf []byte
+	if length > 0 {
+		if obj == nil {
+			obj = db.newObject()
+			obj.Type = TypeString
+			obj.Value = []byte{}
+			obj.Encoding = EncodingBytes
+			db.setKey(key, obj)
+		}
+		buf = growBytes(obj.mutableBytes(), int(length))
+		obj.Value = buf
+	} else if obj != nil {
+		buf = obj.bytesValue()
+	}
+
+	results := make([]BitFieldResult, len(ops))
+	for i, op := range ops {
+		old := getBitField(buf, op.Offset, op.Bits, op.Signed)
+		switch op.Kind {
+		case BitFieldGet:
+			results[i].Value = old
+		case BitFieldSet, BitFieldIncrBy:
+			val, incr := op.Value, int64(0)
+			if op.Kind == BitFieldIncrBy {
+				val, incr = old, op.Value
+			}
+			val, ok := checkBitFieldOverflow(val, incr, op.Bits, op.Signed, op.Overflow)
+			if !ok {
+				results[i].Failed = true
+				continue
+			}
+			setBitField(buf, op.Offset, op.Bits, uint64(val))
+			if op.Kind == BitFieldSet {
+				results[i].Value = old
+			} else {
+				results[i].Value = val
+			}
+		}
+	}
+
+	if length > 0 {
+		db.trackMemory(key, obj)
+	}
+	return results, nil
+}
+
+// getBitField reads the integer of the width in bits at the bit offset, with the
+// most significant bit first.
+func getBitField(buf []byte, offset int64, bits int, signed bool) int64 {
+	val := uint64(0)
+	for i := int64(0); i < int64(bits); i++ {
+		pos := offset + i
+		bit := uint64(0)
+		if pos>>3 < int64(len(buf)) {
+			bit = uint64(buf[pos>>3]>>(7-pos&7)) & 1
+		}
+		val = val<<1 | bit
+	}
+
+	if signed && bits < 64 && val&(1<<(bits-1)) != 0 {
+		val |= ^uint64(0) << bits
+	}
+	return int64(val)
+}
+
+func setBitField(buf []byte, offset int64, bits int, val uint64) {
+	for i := int64(0); i < int64(bits); i++ {
+		pos := offset + i
+		mask := byte(1) << (7 - pos&7)
+		if val>>(int64(bits)-1-i)&1 != 0 {
+			buf[pos>>3] |= mask
+		} else {
+			buf[pos>>3] &^= mask
+		}
+	}
+}
+
+// checkBitFieldOverflow returns the sum of the value and the increment, handled
+// by the overflow behavior if it does not fit the width in bits. It returns
+// false if the sum does not fit for the FAIL behavior.
+func checkBitFieldOverflow(val, incr int64, bits int, signed bool, overflow BitFieldOverflow) (int64, bool) {
+	var lower, upper int64
+	if signed {
+		upper = int64(^uint64(0) >> (65 - bits))
+		lower = -upper - 1
+	} else {
+		upper = int64(uint64(1)<<bits - 1)
+	}
+
+	var limit int64
+	switch {
+	case !signed && uint64(val) > uint64(upper):
+		// The value of SET is out of the unsigned range, including negative
+		// values.
+		limit = upper
+	case val > upper || (incr > 0 && val > upper-incr):
+		limit = upper
+	case val < lower || (incr < 0 && val < lower-incr):
+		limit = lower
+	default:
+		return val + incr, true
+	}
+
+	switch overflow {
+	case BitFieldOverflowSat:
+		return limit, true
+	case BitFieldOverflowFail:
+		return 0, false
+	}
+
+	sum := uint64(val) + uint64(incr)
+	if bits < 64 {
+		mask := uint64(1)<<bits - 1
+		sum &= mask
+		if signed && sum&(1<<(bits-1)) != 0 {
+			sum |= ^mask
+		}
+	}
+	return int64(sum), true
+}
@@ -0,0 +1,297 @@
+package core
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+type BitOp int
+
+const (
+	BitOpAnd BitOp = iota
+	BitOpOr
+	BitOpXor
+	BitOpNot
+	// BitOpDiff sets the bits of the first key that are not set in any other key.
+	BitOpDiff
+	// BitOpAndOr sets the bits of the first key that are set in any other key.
+	BitOpAndOr
+	// BitOpOne sets the bits that are set in exactly one key.
+	BitOpOne
+)
+
+// bytesValue returns the bytes of the string value of the object, which must
+// not be modified.
+func (obj *Object) bytesValue() []byte {
+	if obj.Encoding == EncodingBytes {
+		return obj.Value.([]byte)
+	}
+	return []byte(obj.StringValue())
+}
+
+// SetBit sets the bit at the offset of the string of the key, growing the
+// string with zero bytes if needed, and returns the original bit.
+func (db *Database) SetBit(key string, offset int64, value int) (int, error) {
+	if offset>>3 >= db.maxStringLength {
+		return 0, ErrStringTooLarge
+	}
+
+	obj, err := db.lookupKey(key, TypeString, true)
+	if err != nil {
+		return 0, err
+	}
+
+	if obj == nil {
+		obj = db.newObject()
+		obj.Type = TypeString
+		obj.Value = []byte{}
+		obj.Encoding = EncodingBytes
+		db.setKey(key, obj)
+	}
+
+	buf := growBytes(obj.mutableBytes(), int(offset>>3)+1)
+	mask := byte(1) << (7 - offset&7)
+	old := 0
+	if buf[offset>>3]&mask != 0 {
+		old = 1
+	}
+	if value != 0 {
+		buf[offset>>3] |= mask
+	} else {
+		buf[offset>>3] &^= mask
+	}
+	obj.Value = buf
+	db.trackMemory(key, obj)
+	return old, nil
+}
+
+// GetBit returns the bit at the offset of the string of the key, where the bits
+// out of the string are 0.
+func (db *Database) GetBit(key string, offset int64) (int, error) {
+	obj, err := db.lookupKey(key, TypeString, true)
+	if err != nil || obj == nil {
+		return 0, err
+	}
+
+	buf := obj.bytesValue()
+	if offset>>3 >= int64(len(buf)) {
+		return 0, nil
+	}
+	return int(buf[offset>>3]>>(7-offset&7)) & 1, nil
+}
+
+// BitCount returns the number of set bits of the string of the key between the
+// start and the end offsets, both inclusive. The offsets are in bits if isBit is
+// true, or in bytes otherwise, and they are clamped like GETRANGE.
+func (db *Database) BitCount(key string, start, end int64, isBit bool) (int64, error) {
+	obj, err := db.lookupKey(key, TypeString, true)
+	if err != nil || obj == nil {
+		return 0, err
+	}
+
+	buf := obj.bytesValue()
+	size := int64(len(buf))
+	if isBit {
+		size *= 8
+	}
+	start, end, ok := clampRange(start, end, size)
+	if !ok {
+		return 0, nil
+	}
+
+	if !isBit {
+		return popcount(buf[start : end+1]), nil
+	}
+
+	firstByte, lastByte := start>>3, end>>3
+	first := buf[firstByte] & (0xff >> (start & 7))
+	last := buf[lastByte] & (0xff << (7 - end&7))
+	if firstByte == lastByte {
+		return int64(bits.OnesCount8(first & last)), nil
+	}
+	return int64(bits.OnesCount8(first)+bits.OnesCount8(last)) + popcount(buf[firstByte+1:lastByte]), nil
+}
+
+// BitPos returns the position of the first bit of the value in the string of
+// the key between the start and the end offsets, or -1 if there is no such bit.
+// The offsets are in bits if isBit is true, or in bytes otherwise. When clear
+// bits are looked for without an end offset, the string is considered to be
+// padded with clear bits on the right.
+func (db *Database) BitPos(key string, bit int, start, end int64, hasEnd, isBit bool) (int64, error) {
+	obj, err := db.lookupKey(key, TypeString, true)
+	if err != nil {
+		return 0, err
+	}
+	if obj == nil {
+		if bit == 0 {
+			return 0, nil
+		}
+		return -1, nil
+	}
+
+	buf := obj.bytesValue()
+	size := int64(len(buf))
+	if isBit {
+		size *= 8
+	}
+	start, end, ok := clampRange(start, end, size)
+	if !ok {
+		return -1, nil
+	}
+
+	first, last := start*8, end*8+7
+	if isBit {
+		first, last = start, end
+	}
+
+	skip := byte(0)
+	if bit == 0 {
+		skip = 0xff
+	}
+	for pos := first; pos <= last; {
+		if pos&7 == 0 && pos+7 <= last && buf[pos>>3] == skip {
+			pos += 8
+			continue
+		}
+		if int(buf[pos>>3]>>(7-pos&7))&1 == bit {
+			return pos, nil
+		}
+		pos++
+	}
+
+	if bit == 0 && !hasEnd {
+		return last + 1, nil
+	}
+	return -1, nil
+}
+
+// BitOp performs the bitwise operation between the strings of the keys, where
+// missing keys and the strings shorter than the longest one are considered to
+// be padded with zero bytes, and stores the result into the destination key. It
+// returns the length of the result, and deletes the destination key if the
+// result is empty.
+func (db *Database) BitOp(op BitOp, dest string, keys ...string) (int64, error) {
+	values := make([][]byte, len(keys))
+	maxLen := 0
+	for i, key := range keys {
+		obj, err := db.lookupKey(key, TypeString, true)
+		if err != nil {
+			return 0, err
+		}
+		if obj != nil {
+			values[i] = obj.bytesValue()
+			if len(values[i]) > maxLen {
+				maxLen = len(values[i])
+			}
+		}
+	}
+
+	result := make([]byte, maxLen)
+	byteAt := func(value []byte, i int) byte {
+		if i < len(value) {
+			return value[i]
+		}
+		return 0
+	}
+	for i := range result {
+		b := byteAt(values[0], i)
+		switch op {
+		case BitOpNot:
+			b = ^b
+		case BitOpDiff, BitOpAndOr:
+			others := byte(0)
+			for _, value := range values[1:] {
+				others |= byteAt(value, i)
+			}
+			if op == BitOpDiff {
+				b &^= others
+			} else {
+				b &= others
+			}
+		case BitOpOne:
+			seen := b
+			for _, value := range values[1:] {
+				v := byteAt(value, i)
+				b = (b &^ v) | (v &^ seen)
+				seen |= v
+			}
+		default:
+			for _, value := range values[1:] {
+				v := byteAt(value, i)
+				switch op {
+				case BitOpAnd:
+					b &= v
+				case BitOpOr:
+					b |= v
+				case BitOpXor:
+					b ^= v
+				}
+			}
+		}
+		result[i] = b
+	}
+
+	obj, err := db.lookupKey(dest, TypeNone, true)
+	if err != nil {
+		return 0, err
+	}
+	if maxLen == 0 {
+		if obj != nil {
+			db.removeKey(dest, obj)
+		}
+		return 0, nil
+	}
+
+	if obj == nil {
+		obj = db.newObject()
+		db.setKey(dest, obj)
+	}
+	obj.Type = TypeString
+	obj.Value = result
+	obj.Encoding = EncodingBytes
+	db.setExpire(dest, obj, 0)
+	db.trackMemory(dest, obj)
+	return int64(maxLen), nil
+}
+
+// clampRange converts the negative offsets of the range into offsets from the
+// start, and clamps the range into the size. It returns false if the range is
+// empty.
+func clampRange(start, end, size int64) (int64, int64, bool) {
+	if start < 0 && end < 0 && start > end {
+		return 0, 0, false
+	}
+	if start < 0 {
+		start += size
+	}
+	if end < 0 {
+		end += size
+	}
+	if start < 0 {
+		start = 0
+	}
+	if end < 0 {
+		end = 0
+	}
+	if end >= size {
+		end = size - 1
+	}
+	if size == 0 || start > end {
+		return 0, 0, false
+	}
+	return start, end, true
+}
+
+// popcount returns the number of set bits of the bytes, counting eight bytes
+// at a time.
+func popcount(buf []byte) int64 {
+	cnt := 0
+	for len(buf) >= 8 {
+		cnt += bits.OnesCount64(binary.LittleEndian.Uint64(buf))
+		buf = buf[8:]
+	}
+	for _, b := range buf {
+		cnt += bits.OnesCount8(b)
+	}
+	return int64(cnt)
+}
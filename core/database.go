@@ -1,21 +1,41 @@
 package core
 
 import (
-	"context"
-	"log"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type Database struct {
-	data    map[string]*Object
-	expires map[string]int64
-	pool    sync.Pool
+	data        *Dict[*Object]
+	expires     map[string]*expireEntry
+	expireIndex expireHeap
+	pool        sync.Pool
+	used        atomic.Int64
+	expired     atomic.Int64
+	// keys and expiring mirror the sizes of the main dictionary and the expires
+	// dictionary, so they can be read from other goroutines.
+	keys     atomic.Int64
+	expiring atomic.Int64
+
+	maxStringLength int64
+	// listMaxListpackSize and listCompressDepth are the fill and the compress
+	// depth of the new lists.
+	listMaxListpackSize int
+	listCompressDepth   int
+
+	// onStreamRemoved is called with the key of a stream when it is removed,
+	// to wake up the clients blocked on it.
+	onStreamRemoved func(key string)
 }
 
 func NewDatabase() *Database {
 	db := new(Database)
-	db.data = make(map[string]*Object)
-	db.expires = make(map[string]int64)
+	db.data = NewDict[*Object]()
+	db.expires = make(map[string]*expireEntry)
+	db.maxStringLength = DefaultMaxStringLength
+	db.listMaxListpackSize = DefaultListMaxListpackSize
+	db.listCompressDepth = DefaultListCompressDepth
 	db.pool = sync.Pool{
 		New: func() any {
 			return new(Object)
@@ -24,73 +44,116 @@ func NewDatabase() *Database {
 	return db
 }
 
-func (db *Database) Clear() {
-	for key, obj := range db.data {
-		db.removeKey(key, obj)
-	}
+// SetMaxStringLength sets the maximum length in bytes of the string values,
+// which also bounds the offsets of the bitmap commands.
+func (db *Database) SetMaxStringLength(length int64) {
+	db.maxStringLength = length
 }
 
-func (db *Database) Size() int64 {
-	return int64(len(db.data))
+func (db *Database) SetListMaxListpackSize(size int) {
+	db.listMaxListpackSize = size
 }
 
-func (db *Database) CheckExpire(ctx context.Context, sample int) int {
-	keys := make([]string, 0, len(db.expires))
-	i := 0
-	for key := range db.expires {
-		keys = append(keys, key)
-		i++
-		if i >= sample {
-			break
-		}
-	}
+func (db *Database) SetListCompressDepth(depth int) {
+	db.listCompressDepth = depth
+}
 
-	cnt := 0
-	for _, key := range keys {
-		select {
-		case <-ctx.Done():
-			return cnt
-		default:
-			obj, err := db.lookupKey(key, TypeNone, false)
-			if err != nil {
-				continue
-			}
-			if obj != nil && obj.IsExpired() {
-				db.removeKey(key, obj)
-				cnt++
-				log.Print("expired key: ", key)
-			}
-		}
+// SetStreamRemovedHook sets the function called with the key of a stream when
+// the stream is removed, overwritten or moved away.
+func (db *Database) SetStreamRemovedHook(fn func(key string)) {
+	db.onStreamRemoved = fn
+}
+
+func (db *Database) streamRemoved(key string, obj *Object) {
+	if obj.Type == TypeStream && db.onStreamRemoved != nil {
+		db.onStreamRemoved(key)
 	}
+}
+
+func (db *Database) Clear() {
+	db.data.Range(func(key string, obj *Object) bool {
+		db.streamRemoved(key, obj)
+		db.pool.Put(obj)
+		return true
+	})
+	db.data.Clear()
+	db.expires = make(map[string]*expireEntry)
+	db.expireIndex = nil
+	db.used.Store(0)
+	db.keys.Store(0)
+	db.expiring.Store(0)
+}
 
-	return cnt
+// Size returns the number of keys in the database. It is safe to be called from
+// any goroutine.
+func (db *Database) Size() int64 {
+	return db.keys.Load()
+}
+
+// UsedMemory returns the estimated memory usage of all keys in the database. It
+// is safe to be called from any goroutine.
+func (db *Database) UsedMemory() int64 {
+	return db.used.Load()
 }
 
 func (db *Database) newObject() *Object {
-	return db.pool.Get().(*Object)
+	obj := db.pool.Get().(*Object)
+	*obj = Object{}
+	return obj
+}
+
+func (db *Database) setKey(key string, obj *Object) {
+	obj.AccessTime = time.Now().UnixMilli()
+	obj.Frequency = lfuInitValue
+	db.data.Set(key, obj)
+	db.keys.Store(int64(db.data.Len()))
 }
 
 func (db *Database) removeKey(key string, obj *Object) {
-	delete(db.data, key)
+	db.streamRemoved(key, obj)
+	db.data.Delete(key)
+	db.keys.Store(int64(db.data.Len()))
 	if obj.Expires != 0 {
-		delete(db.expires, key)
+		db.removeExpire(key)
 	}
+	db.used.Add(-obj.size)
+	obj.size = 0
 	db.pool.Put(obj)
 }
 
+// setExpire sets the expiration of the object to the unix time in milliseconds,
+// or removes it if expires is 0, and keeps the expires dictionary in sync.
+func (db *Database) setExpire(key string, obj *Object, expires int64) {
+	obj.Expires = expires
+	if expires != 0 {
+		db.addExpire(key, expires)
+	} else {
+		db.removeExpire(key)
+	}
+}
+
+// trackMemory re-estimates the memory usage of the object after it has been
+// modified, and applies the difference to the used memory of the database.
+func (db *Database) trackMemory(key string, obj *Object) {
+	size := obj.memoryUsage(key, defaultMemorySamples)
+	db.used.Add(size - obj.size)
+	obj.size = size
+}
+
 func (db *Database) lookupKey(key string, expectedType ObjectType, isEvict bool) (*Object, error) {
-	obj, found := db.data[key]
+	obj, found := db.data.Get(key)
 	if !found || obj == nil {
 		return nil, nil
 	}
 
 	if obj.IsExpired() {
 		if !isEvict {
-			delete(db.expires, key)
+			db.setExpire(key, obj, 0)
+			db.expired.Add(1)
 			return obj, nil
 		}
 
-		db.removeKey(key, obj)
+		db.expireKey(key, obj)
 		return nil, nil
 	}
 
@@ -98,5 +161,7 @@ func (db *Database) lookupKey(key string, expectedType ObjectType, isEvict bool)
 		return nil, ErrWrongType
 	}
 
+	obj.Touch(time.Now().UnixMilli())
+
 	return obj, nil
 }
@@ -0,0 +1,204 @@
+package core
+
+import (
+	"hash/maphash"
+	"math/bits"
+	"math/rand"
+)
+
+const dictMinSize = 4
+
+var dictSeed = maphash.MakeSeed()
+
+type dictEntry[V any] struct {
+	key   string
+	value V
+	next  *dictEntry[V]
+}
+
+// Dict is a chained hash table with a power of two number of buckets. Unlike
+// the builtin map, its buckets have a stable order that allows to iterate it
+// incrementally with a cursor, and to sample random entries.
+type Dict[V any] struct {
+	table []*dictEntry[V]
+	size  int
+}
+
+func NewDict[V any]() *Dict[V] {
+	return &Dict[V]{
+		table: make([]*dictEntry[V], dictMinSize),
+	}
+}
+
+func (d *Dict[V]) Len() int {
+	return d.size
+}
+
+func (d *Dict[V]) Get(key string) (V, bool) {
+	for e := d.table[d.bucket(key)]; e != nil; e = e.next {
+		if e.key == key {
+			return e.value, true
+		}
+	}
+
+	var zero V
+	return zero, false
+}
+
+// Set adds or updates the value of the key, and returns true if the key is new.
+func (d *Dict[V]) Set(key string, value V) bool {
+	idx := d.bucket(key)
+	for e := d.table[idx]; e != nil; e = e.next {
+		if e.key == key {
+			e.value = value
+			return false
+		}
+	}
+
+	d.table[idx] = &dictEntry[V]{key: key, value: value, next: d.table[idx]}
+	d.size++
+	if d.size > len(d.table) {
+		d.resize(len(d.table) * 2)
+	}
+	return true
+}
+
+// Delete removes the key, and returns true if the key existed.
+func (d *Dict[V]) Delete(key string) bool {
+	idx := d.bucket(key)
+	var prev *dictEntry[V]
+	for e := d.table[idx]; e != nil; e = e.next {
+		if e.key != key {
+			prev = e
+			continue
+		}
+
+		if prev == nil {
+			d.table[idx] = e.next
+		} else {
+			prev.next = e.next
+		}
+		d.size--
+		if len(d.table) > dictMinSize && d.size < len(d.table)/8 {
+			d.resize(len(d.table) / 2)
+		}
+		return true
+	}
+
+	return false
+}
+
+func (d *Dict[V]) Clear() {
+	d.table = make([]*dictEntry[V], dictMinSize)
+	d.size = 0
+}
+
+// Range calls fn for every entry until fn returns false. The dict must not be
+// modified during the iteration.
+func (d *Dict[V]) Range(fn func(key string, value V) bool) {
+	for _, e := range d.table {
+		for ; e != nil; e = e.next {
+			if !fn(e.key, e.value) {
+				return
+			}
+		}
+	}
+}
+
+// Scan calls fn for the entries of the bucket pointed by the cursor, and
+// returns the cursor of the next bucket, or 0 if the iteration is complete.
+//
+// The cursor is incremented on its reversed bits, so the buckets already
+// visited are still skipped after the table grows or shrinks, and every entry
+// present during the whole iteration is returned at least once.
+func (d *Dict[V]) Scan(cursor uint64, fn func(key string, value V)) uint64 {
+	if d.size == 0 {
+		return 0
+	}
+
+	mask := uint64(len(d.table) - 1)
+	for e := d.table[cursor&mask]; e != nil; e = e.next {
+		fn(e.key, e.value)
+	}
+
+	cursor |= ^mask
+	cursor = bits.Reverse64(cursor)
+	cursor++
+	cursor = bits.Reverse64(cursor)
+	return cursor
+}
+
+// Sample calls fn for up to count entries, starting from a random bucket, until
+// fn returns false. The dict must not be modified during the sampling.
+func (d *Dict[V]) Sample(count int, fn func(key string, value V) bool) {
+	if d.size == 0 || count <= 0 {
+		return
+	}
+
+	mask := len(d.table) - 1
+	idx := rand.Intn(len(d.table))
+	for visited := 0; visited < len(d.table); visited++ {
+		for e := d.table[idx]; e != nil; e = e.next {
+			if !fn(e.key, e.value) {
+				return
+			}
+			count--
+			if count == 0 {
+				return
+			}
+		}
+		idx = (idx + 1) & mask
+	}
+}
+
+// RandomKey returns a random key of the dict.
+func (d *Dict[V]) RandomKey() (string, V, bool) {
+	var zero V
+	if d.size == 0 {
+		return "", zero, false
+	}
+
+	// Pick a random non-empty bucket first, and then a random entry of its
+	// chain.
+	var head *dictEntry[V]
+	for head == nil {
+		head = d.table[rand.Intn(len(d.table))]
+	}
+	length := 0
+	for e := head; e != nil; e = e.next {
+		length++
+	}
+	e := head
+	for i := rand.Intn(length); i > 0; i-- {
+		e = e.next
+	}
+	return e.key, e.value, true
+}
+
+func dictKeys[V any](d *Dict[V]) []string {
+	keys := make([]string, 0, d.Len())
+	d.Range(func(key string, _ V) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+func (d *Dict[V]) bucket(key string) int {
+	return int(maphash.String(dictSeed, key) & uint64(len(d.table)-1))
+}
+
+func (d *Dict[V]) resize(size int) {
+	table := make([]*dictEntry[V], size)
+	mask := uint64(size - 1)
+	for _, e := range d.table {
+		for e != nil {
+			next := e.next
+			idx := maphash.String(dictSeed, e.key) & mask
+			e.next = table[idx]
+			table[idx] = e
+			e = next
+		}
+	}
+	d.table = table
+}
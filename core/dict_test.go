@@ -0,0 +1,145 @@
+package core
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestDictScanDuringResize(t *testing.T) {
+	tests := []struct {
+		name string
+		// stable is the number of keys present during the whole iteration, and
+		// transient is the number of keys added before the iteration.
+		stable    int
+		transient int
+		// mutate changes the dict after the step of the scan.
+		mutate func(d *Dict[int], step int)
+		// resized is whether the table is resized during the scan. Every key is
+		// returned exactly once if the table is not resized.
+		resized bool
+	}{
+		{"no change", 1000, 0, func(d *Dict[int], step int) {}, false},
+		{"grow", 100, 0, func(d *Dict[int], step int) {
+			// The scan may not terminate if the dict grows without a bound.
+			if step >= 100 {
+				return
+			}
+			for i := 0; i < 50; i++ {
+				d.Set("new:"+strconv.Itoa(step*50+i), 0)
+			}
+		}, true},
+		{"shrink", 100, 5000, func(d *Dict[int], step int) {
+			for i := 0; i < 200; i++ {
+				d.Delete("transient:" + strconv.Itoa(step*200+i))
+			}
+		}, true},
+		{"grow and shrink", 200, 0, func(d *Dict[int], step int) {
+			// Grows the table to 4096 buckets and shrinks it back to 256
+			// buckets in turn.
+			if step%4 == 0 {
+				for i := 0; i < 4000; i++ {
+					d.Set("transient:"+strconv.Itoa(i), 0)
+				}
+			} else if step%4 == 2 {
+				for i := 0; i < 4000; i++ {
+					d.Delete("transient:" + strconv.Itoa(i))
+				}
+			}
+		}, true},
+		{"update and reinsert", 500, 500, func(d *Dict[int], step int) {
+			for i := 0; i < 20; i++ {
+				key := "transient:" + strconv.Itoa((step*20+i)%500)
+				d.Delete(key)
+				d.Set(key, step)
+				d.Set("stable:"+strconv.Itoa((step*20+i)%500), step)
+			}
+		}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDict[int]()
+			for i := 0; i < tt.stable; i++ {
+				d.Set("stable:"+strconv.Itoa(i), 0)
+			}
+			for i := 0; i < tt.transient; i++ {
+				d.Set("transient:"+strconv.Itoa(i), 0)
+			}
+
+			seen := make(map[string]int)
+			sizes := map[int]bool{len(d.table): true}
+			cursor := uint64(0)
+			steps := 0
+			for {
+				cursor = d.Scan(cursor, func(key string, _ int) {
+					seen[key]++
+				})
+				if cursor == 0 {
+					break
+				}
+				tt.mutate(d, steps)
+				sizes[len(d.table)] = true
+				steps++
+				if steps > 1<<20 {
+					t.Fatalf("scan does not terminate after %d steps", steps)
+				}
+			}
+
+			for i := 0; i < tt.stable; i++ {
+				if key := "stable:" + strconv.Itoa(i); seen[key] == 0 {
+					t.Errorf("expected %s returned", key)
+				}
+			}
+			if tt.resized && len(sizes) < 2 {
+				t.Errorf("expected the table resized during the scan, got sizes %v", sizes)
+			} else if !tt.resized {
+				for key, n := range seen {
+					if n != 1 {
+						t.Errorf("expected %s returned once, got %d", key, n)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestDatabaseScanDuringResize(t *testing.T) {
+	db := NewDatabase()
+	for i := 0; i < 300; i++ {
+		db.Set("stable:"+strconv.Itoa(i), "v", 0, 0)
+	}
+
+	seen := make(map[string]bool)
+	cursor := uint64(0)
+	for step := 0; ; step++ {
+		var keys []string
+		var err error
+		cursor, keys, err = db.Scan(cursor, 10, "stable:*", TypeNone)
+		if err != nil {
+			t.Fatalf("Scan: unexpected error %v", err)
+		}
+		for _, key := range keys {
+			seen[key] = true
+		}
+		if cursor == 0 {
+			break
+		}
+		if step > 10000 {
+			t.Fatalf("scan does not terminate after %d steps", step)
+		}
+
+		// Grows and shrinks the table with the keys not matched by the pattern.
+		for i := 0; i < 500; i++ {
+			key := "transient:" + strconv.Itoa(i)
+			if step%2 == 0 {
+				db.Set(key, "v", 0, 0)
+			} else {
+				db.Del(key)
+			}
+		}
+	}
+
+	if len(seen) != 300 {
+		t.Errorf("expected all 300 keys returned, got %d", len(seen))
+	}
+}
@@ -7,4 +7,30 @@ var (
 	ErrNoSuchKey  = errors.New("no such key")
 	ErrNotInteger = errors.New("value is not an integer or out of range")
 	ErrOutOfRange = errors.New("index out of range")
+
+	ErrNotFloat       = errors.New("value is not a valid float")
+	ErrFloatNaN       = errors.New("increment would produce NaN or Infinity")
+	ErrStringTooLarge = errors.New("string exceeds maximum allowed size (proto-max-bulk-len)")
+
+	ErrNotHLL       = errors.New("WRONGTYPE Key is not a valid HyperLogLog string value.")
+	ErrHLLCorrupted = errors.New("INVALIDOBJ Corrupted HLL object detected")
+	ErrHLLNotSparse = errors.New("HLL encoding is not sparse")
+
+	ErrGeoMemberNotFound = errors.New("could not decode requested zset member")
+
+	ErrInvalidStreamID  = errors.New("Invalid stream ID specified as stream command argument")
+	ErrStreamIDZero     = errors.New("The ID specified in XADD must be greater than 0-0")
+	ErrStreamIDTooSmall = errors.New("The ID specified in XADD is equal or smaller than the target stream top item")
+	ErrStreamExhausted  = errors.New("The stream has exhausted the last possible ID, unable to add more items")
+	ErrBusyGroup        = errors.New("BUSYGROUP Consumer Group name already exists")
 )
+
+// NoGroupError is returned when the key or its consumer group does not exist.
+type NoGroupError struct {
+	Key   string
+	Group string
+}
+
+func (e *NoGroupError) Error() string {
+	return "NOGROUP No such key '" + e.Key + "' or consumer group '" + e.Group + "'"
+}
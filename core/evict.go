@@ -0,0 +1,113 @@
+package core
+
+import (
+	"strings"
+	"time"
+)
+
+type EvictionPolicy int
+
+const (
+	EvictionNoEviction EvictionPolicy = iota
+	EvictionAllKeysLRU
+	EvictionVolatileLRU
+	EvictionAllKeysLFU
+	EvictionVolatileLFU
+	EvictionAllKeysRandom
+	EvictionVolatileRandom
+	EvictionVolatileTTL
+)
+
+var evictionPolicyNames = map[EvictionPolicy]string{
+	EvictionNoEviction:     "noeviction",
+	EvictionAllKeysLRU:     "allkeys-lru",
+	EvictionVolatileLRU:    "volatile-lru",
+	EvictionAllKeysLFU:     "allkeys-lfu",
+	EvictionVolatileLFU:    "volatile-lfu",
+	EvictionAllKeysRandom:  "allkeys-random",
+	EvictionVolatileRandom: "volatile-random",
+	EvictionVolatileTTL:    "volatile-ttl",
+}
+
+func ParseEvictionPolicy(name string) (EvictionPolicy, bool) {
+	name = strings.ToLower(name)
+	for policy, policyName := range evictionPolicyNames {
+		if policyName == name {
+			return policy, true
+		}
+	}
+	return EvictionNoEviction, false
+}
+
+func (p EvictionPolicy) String() string {
+	if name, ok := evictionPolicyNames[p]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+func (p EvictionPolicy) isVolatile() bool {
+	switch p {
+	case EvictionVolatileLRU, EvictionVolatileLFU, EvictionVolatileRandom, EvictionVolatileTTL:
+		return true
+	}
+	return false
+}
+
+// Evict samples up to samples keys that are candidates for the policy, and
+// removes the best one of them. It returns the estimated bytes freed, and false
+// if the database has no candidate key.
+func (db *Database) Evict(policy EvictionPolicy, samples int) (int64, bool) {
+	if policy == EvictionNoEviction {
+		return 0, false
+	}
+
+	now := time.Now().UnixMilli()
+	bestKey := ""
+	var bestObj *Object
+	sampled := 0
+
+	consider := func(key string, obj *Object) bool {
+		if bestObj == nil || isBetterEvictionCandidate(policy, now, obj, bestObj) {
+			bestKey = key
+			bestObj = obj
+		}
+		sampled++
+		return sampled < samples
+	}
+
+	if policy.isVolatile() {
+		for key := range db.expires {
+			obj, ok := db.data.Get(key)
+			if ok && !consider(key, obj) {
+				break
+			}
+		}
+	} else {
+		db.data.Sample(samples, consider)
+	}
+
+	if bestObj == nil {
+		return 0, false
+	}
+
+	freed := bestObj.size
+	db.removeKey(bestKey, bestObj)
+	return freed, true
+}
+
+func isBetterEvictionCandidate(policy EvictionPolicy, now int64, obj, best *Object) bool {
+	switch policy {
+	case EvictionAllKeysLRU, EvictionVolatileLRU:
+		return obj.AccessTime < best.AccessTime
+	case EvictionAllKeysLFU, EvictionVolatileLFU:
+		freq, bestFreq := obj.DecayedFrequency(now), best.DecayedFrequency(now)
+		if freq != bestFreq {
+			return freq < bestFreq
+		}
+		return obj.AccessTime < best.AccessTime
+	case EvictionVolatileTTL:
+		return obj.Expires < best.Expires
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseEvictionPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy EvictionPolicy
+		ok     bool
+	}{
+		{"noeviction", EvictionNoEviction, true},
+		{"allkeys-lru", EvictionAllKeysLRU, true},
+		{"volatile-lru", EvictionVolatileLRU, true},
+		{"allkeys-lfu", EvictionAllKeysLFU, true},
+		{"volatile-lfu", EvictionVolatileLFU, true},
+		{"allkeys-random", EvictionAllKeysRandom, true},
+		{"volatile-random", EvictionVolatileRandom, true},
+		{"volatile-ttl", EvictionVolatileTTL, true},
+		{"ALLKEYS-LRU", EvictionAllKeysLRU, true},
+		{"allkeys", EvictionNoEviction, false},
+		{"", EvictionNoEviction, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, ok := ParseEvictionPolicy(tt.name)
+			if policy != tt.policy || ok != tt.ok {
+				t.Errorf("ParseEvictionPolicy(%q) = (%v, %v), expected (%v, %v)", tt.name, policy, ok, tt.policy, tt.ok)
+			}
+		})
+	}
+}
+
+func TestEvict(t *testing.T) {
+	type testKey struct {
+		name string
+		// ttl is the time to live in milliseconds, or 0 for no expiration.
+		ttl int64
+		// idle is the time in milliseconds since the last access.
+		idle int64
+		freq uint8
+	}
+	tests := []struct {
+		name    string
+		policy  EvictionPolicy
+		keys    []testKey
+		evicted string
+	}{
+		{"noeviction", EvictionNoEviction, []testKey{{"a", 0, 1000, 5}}, ""},
+		{"empty", EvictionAllKeysLRU, nil, ""},
+		{"allkeys-lru", EvictionAllKeysLRU, []testKey{
+			{"a", 0, 1000, 5}, {"b", 0, 5000, 5}, {"c", 100000, 10, 5},
+		}, "b"},
+		{"allkeys-lru volatile", EvictionAllKeysLRU, []testKey{
+			{"a", 0, 1000, 5}, {"b", 0, 5000, 5}, {"c", 100000, 9000, 5},
+		}, "c"},
+		{"volatile-lru", EvictionVolatileLRU, []testKey{
+			{"a", 100000, 1000, 5}, {"b", 0, 5000, 5}, {"c", 100000, 10, 5},
+		}, "a"},
+		{"volatile-lru without volatile keys", EvictionVolatileLRU, []testKey{
+			{"a", 0, 1000, 5}, {"b", 0, 5000, 5},
+		}, ""},
+		{"allkeys-lfu", EvictionAllKeysLFU, []testKey{
+			{"a", 0, 0, 10}, {"b", 0, 0, 2}, {"c", 100000, 0, 20},
+		}, "b"},
+		{"allkeys-lfu same frequency", EvictionAllKeysLFU, []testKey{
+			{"a", 0, 100, 5}, {"b", 0, 200, 5}, {"c", 0, 0, 5},
+		}, "b"},
+		{"allkeys-lfu decayed", EvictionAllKeysLFU, []testKey{
+			{"a", 0, 9 * lfuDecayMilli, 10}, {"b", 0, 0, 5},
+		}, "a"},
+		{"volatile-lfu", EvictionVolatileLFU, []testKey{
+			{"a", 0, 0, 1}, {"b", 100000, 0, 3}, {"c", 100000, 0, 8},
+		}, "b"},
+		{"volatile-lfu without volatile keys", EvictionVolatileLFU, []testKey{{"a", 0, 0, 1}}, ""},
+		{"allkeys-random", EvictionAllKeysRandom, []testKey{{"a", 0, 0, 5}}, "a"},
+		{"volatile-random", EvictionVolatileRandom, []testKey{
+			{"a", 0, 0, 5}, {"b", 100000, 0, 5}, {"c", 0, 0, 5},
+		}, "b"},
+		{"volatile-random without volatile keys", EvictionVolatileRandom, []testKey{{"a", 0, 0, 5}}, ""},
+		{"volatile-ttl", EvictionVolatileTTL, []testKey{
+			{"a", 100000, 0, 5}, {"b", 10000, 0, 5}, {"c", 0, 0, 5}, {"d", 50000, 0, 5},
+		}, "b"},
+		{"volatile-ttl without volatile keys", EvictionVolatileTTL, []testKey{{"a", 0, 0, 5}}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := NewDatabase()
+			now := time.Now().UnixMilli()
+			for _, key := range tt.keys {
+				expires := int64(0)
+				if key.ttl > 0 {
+					expires = now + key.ttl
+				}
+				if _, _, _, err := db.Set(key.name, "value", 0, expires); err != nil {
+					t.Fatalf("Set %s: unexpected error %v", key.name, err)
+				}
+				obj, _ := db.data.Get(key.name)
+				obj.AccessTime = now - key.idle
+				obj.Frequency = key.freq
+			}
+
+			used := db.UsedMemory()
+			freed, ok := db.Evict(tt.policy, len(tt.keys)+1)
+			if ok != (tt.evicted != "") {
+				t.Fatalf("Evict: expected evicted %v, got %v", tt.evicted != "", ok)
+			}
+			if !ok {
+				if db.Size() != int64(len(tt.keys)) || db.UsedMemory() != used {
+					t.Errorf("expected no key evicted, got %d of %d keys", db.Size(), len(tt.keys))
+				}
+				return
+			}
+
+			if _, found := db.data.Get(tt.evicted); found {
+				t.Errorf("expected %s evicted", tt.evicted)
+			}
+			if db.Size() != int64(len(tt.keys)-1) {
+				t.Errorf("expected one key evicted, got %d of %d keys", db.Size(), len(tt.keys))
+			}
+			if freed <= 0 || db.UsedMemory() != used-freed {
+				t.Errorf("expected used memory %d - %d, got %d", used, freed, db.UsedMemory())
+			}
+			if _, found := db.expires[tt.evicted]; found {
+				t.Errorf("expected the expiration of %s removed", tt.evicted)
+			}
+		})
+	}
+}
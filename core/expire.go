@@ -0,0 +1,126 @@
+package core
+
+import (
+	"container/heap"
+	"time"
+)
+
+type expireEntry struct {
+	key     string
+	expires int64
+	index   int
+}
+
+// expireHeap is a min-heap of the keys with expiration ordered by their
+// expiration time, so the active expiration never visits keys that are not
+// expired yet.
+type expireHeap []*expireEntry
+
+func (h expireHeap) Len() int {
+	return len(h)
+}
+
+func (h expireHeap) Less(i, j int) bool {
+	return h[i].expires < h[j].expires
+}
+
+func (h expireHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *expireHeap) Push(x any) {
+	entry := x.(*expireEntry)
+	entry.index = len(*h)
+	*h = append(*h, entry)
+}
+
+func (h *expireHeap) Pop() any {
+	old := *h
+	n := len(old)
+	entry := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return entry
+}
+
+// ExpiresSize returns the number of keys with an expiration. It is safe to be
+// called from any goroutine.
+func (db *Database) ExpiresSize() int64 {
+	return db.expiring.Load()
+}
+
+// ExpiredKeys returns the number of keys expired by both lazy and active
+// expiration since the database was created. It is safe to be called from any
+// goroutine.
+func (db *Database) ExpiredKeys() int64 {
+	return db.expired.Load()
+}
+
+// ExpireKeys removes the expired keys in order of their expiration time, until
+// no expired key is left or the deadline is reached. The deadline is checked
+// after every batch keys. It returns the number of removed keys, and whether
+// expired keys are left.
+func (db *Database) ExpireKeys(deadline time.Time, batch int) (int, bool) {
+	cnt := 0
+	now := time.Now().UnixMilli()
+
+	for len(db.expireIndex) > 0 {
+		if cnt > 0 && cnt%batch == 0 {
+			current := time.Now()
+			if !current.Before(deadline) {
+				return cnt, db.hasExpiredKeys(current.UnixMilli())
+			}
+			now = current.UnixMilli()
+		}
+
+		entry := db.expireIndex[0]
+		if entry.expires >= now {
+			break
+		}
+
+		obj, found := db.data.Get(entry.key)
+		if !found {
+			db.removeExpire(entry.key)
+			continue
+		}
+		db.expireKey(entry.key, obj)
+		cnt++
+	}
+
+	return cnt, false
+}
+
+func (db *Database) hasExpiredKeys(now int64) bool {
+	return len(db.expireIndex) > 0 && db.expireIndex[0].expires < now
+}
+
+func (db *Database) expireKey(key string, obj *Object) {
+	db.removeKey(key, obj)
+	db.expired.Add(1)
+}
+
+func (db *Database) addExpire(key string, expires int64) {
+	if entry, ok := db.expires[key]; ok {
+		entry.expires = expires
+		heap.Fix(&db.expireIndex, entry.index)
+		return
+	}
+
+	entry := &expireEntry{key: key, expires: expires}
+	heap.Push(&db.expireIndex, entry)
+	db.expires[key] = entry
+	db.expiring.Store(int64(len(db.expires)))
+}
+
+func (db *Database) removeExpire(key string) {
+	entry, ok := db.expires[key]
+	if !ok {
+		return
+	}
+
+	heap.Remove(&db.expireIndex, entry.index)
+	delete(db.expires, key)
+	db.expiring.Store(int64(len(db.expires)))
+}
@@ -30,21 +30,61 @@ func (db *Database) Exists(keys ...string) int {
 	return cnt
 }
 
-func (db *Database) Expire(key string, expire int64) bool {
+type ExpireFlag int
+
+const (
+	ExpireFlagNX ExpireFlag = 1 << iota
+	ExpireFlagXX
+	ExpireFlagGT
+	ExpireFlagLT
+)
+
+// Expire sets the expiration of the key to the unix time in milliseconds, if
+// the conditions of the flags are satisfied. A key without expiration is
+// considered to have an infinite TTL for GT and LT. The key is deleted if the
+// time is already in the past.
+func (db *Database) Expire(key string, expire int64, flag ExpireFlag) bool {
 	obj, err := db.lookupKey(key, TypeNone, true)
 	if err != nil || obj == nil {
 		return false
 	}
-	if expire < time.Now().UnixMilli() {
+
+	if flag&ExpireFlagNX != 0 && obj.Expires != 0 {
+		return false
+	}
+	if flag&ExpireFlagXX != 0 && obj.Expires == 0 {
+		return false
+	}
+	if flag&ExpireFlagGT != 0 && (obj.Expires == 0 || expire <= obj.Expires) {
+		return false
+	}
+	if flag&ExpireFlagLT != 0 && obj.Expires != 0 && expire >= obj.Expires {
+		return false
+	}
+
+	if expire <= time.Now().UnixMilli() {
 		db.removeKey(key, obj)
 		return true
 	}
-	obj.Expires = expire
-	db.expires[key] = expire
+	db.setExpire(key, obj, expire)
+	db.trackMemory(key, obj)
 
 	return true
 }
 
+// Persist removes the expiration of the key, and returns true if the key had
+// an expiration.
+func (db *Database) Persist(key string) bool {
+	obj, err := db.lookupKey(key, TypeNone, true)
+	if err != nil || obj == nil || obj.Expires == 0 {
+		return false
+	}
+
+	db.setExpire(key, obj, 0)
+	db.trackMemory(key, obj)
+	return true
+}
+
 func (db *Database) Keys(globPattern string) ([]string, error) {
 	pattern, err := util.GlobToRegexp(globPattern)
 	if err != nil {
@@ -53,46 +93,63 @@ func (db *Database) Keys(globPattern string) ([]string, error) {
 
 	keys := make([]string, 0)
 
-	for key := range db.data {
-		_, err := db.lookupKey(key, TypeNone, true)
-		if err != nil {
-			continue
-		}
-
-		if pattern.MatchString(key) {
+	db.data.Range(func(key string, obj *Object) bool {
+		if !obj.IsExpired() && pattern.MatchString(key) {
 			keys = append(keys, key)
 		}
-	}
+		return true
+	})
 
 	return keys, nil
 }
 
-func (db *Database) Move(key string, dest *Database) bool {
+// Move moves the key to another database, and returns false if the key does not
+// exist, or it can not be added to the destination. The object is removed before
+// it is handed to add, which adds it to the destination by AddMovedKey in the
+// goroutine of the destination, and is restored if it is not added.
+func (db *Database) Move(key string, add func(obj *Object) bool) bool {
 	obj, err := db.lookupKey(key, TypeNone, true)
 	if err != nil || obj == nil {
 		return false
 	}
 
-	destObj, err := dest.lookupKey(key, TypeNone, true)
+	db.data.Delete(key)
+	db.keys.Store(int64(db.data.Len()))
+	if obj.Expires != 0 {
+		db.removeExpire(key)
+	}
+	db.used.Add(-obj.size)
+	db.streamRemoved(key, obj)
+
+	if add(obj) {
+		return true
+	}
+	// The key can only be recreated meanwhile by another MOVE into this
+	// database, which wins over the restored object.
+	db.AddMovedKey(key, obj)
+	return false
+}
+
+// AddMovedKey adds the object moved from another database by Move, and returns
+// false if the key already exists.
+func (db *Database) AddMovedKey(key string, obj *Object) bool {
+	destObj, err := db.lookupKey(key, TypeNone, true)
 	if err != nil || destObj != nil {
 		return false
 	}
 
-	delete(db.data, key)
-	dest.data[key] = obj
+	db.data.Set(key, obj)
+	db.keys.Store(int64(db.data.Len()))
 	if obj.Expires != 0 {
-		delete(db.expires, key)
-		dest.expires[key] = obj.Expires
+		db.addExpire(key, obj.Expires)
 	}
+	db.used.Add(obj.size)
 	return true
 }
 
 func (db *Database) RandomKey() (string, bool) {
-	for key := range db.data {
-		return key, true
-	}
-
-	return "", false
+	key, _, ok := db.data.RandomKey()
+	return key, ok
 }
 
 func (db *Database) Rename(key, newKey string, nx bool) (bool, error) {
@@ -101,6 +158,10 @@ func (db *Database) Rename(key, newKey string, nx bool) (bool, error) {
 		return false, ErrNoSuchKey
 	}
 
+	if key == newKey {
+		return true, nil
+	}
+
 	if nx {
 		newKeyObj, err := db.lookupKey(newKey, TypeNone, true)
 		if err == nil && newKeyObj != nil {
@@ -108,15 +169,59 @@ func (db *Database) Rename(key, newKey string, nx bool) (bool, error) {
 		}
 	}
 
-	db.data[newKey] = obj
-	delete(db.data, key)
+	if oldObj, _ := db.lookupKey(newKey, TypeNone, true); oldObj != nil {
+		db.removeKey(newKey, oldObj)
+	}
+
+	db.streamRemoved(key, obj)
+	db.data.Set(newKey, obj)
+	db.data.Delete(key)
+	db.keys.Store(int64(db.data.Len()))
 	if obj.Expires != 0 {
-		db.expires[newKey] = obj.Expires
-		delete(db.expires, key)
+		db.removeExpire(key)
+		db.addExpire(newKey, obj.Expires)
 	}
+	db.trackMemory(newKey, obj)
 	return true, nil
 }
 
+// Scan iterates the keyspace from the cursor until at least count keys have
+// been visited, and returns the next cursor with the visited keys that match the
+// pattern and the type. An empty pattern matches all keys, and TypeNone matches
+// all types.
+func (db *Database) Scan(cursor uint64, count int, globPattern string, typ ObjectType) (uint64, []string, error) {
+	pattern, err := compileScanPattern(globPattern)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	type entry struct {
+		key string
+		obj *Object
+	}
+	entries := make([]entry, 0, count)
+	cursor = scanDict(db.data, cursor, count, func(key string, obj *Object) {
+		entries = append(entries, entry{key, obj})
+	})
+
+	keys := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.obj.IsExpired() {
+			db.expireKey(e.key, e.obj)
+			continue
+		}
+		if pattern != nil && !pattern.MatchString(e.key) {
+			continue
+		}
+		if typ != TypeNone && e.obj.Type != typ {
+			continue
+		}
+		keys = append(keys, e.key)
+	}
+
+	return cursor, keys, nil
+}
+
 func (db *Database) TTL(key string) int64 {
 	obj, err := db.lookupKey(key, TypeNone, true)
 	if err != nil || obj == nil {
@@ -136,3 +241,14 @@ func (db *Database) Type(key string) string {
 
 	return obj.Type.String()
 }
+
+// ObjectEncoding returns the encoding of the value of the key without touching
+// it.
+func (db *Database) ObjectEncoding(key string) (ObjectEncoding, bool) {
+	obj, found := db.data.Get(key)
+	if !found || obj.IsExpired() {
+		return 0, false
+	}
+
+	return obj.encoding(), true
+}
@@ -0,0 +1,300 @@
+package core
+
+import (
+	"math"
+	"sort"
+)
+
+// GeoSet is a set of members with the 52 bits geohashes of their positions,
+// ordered by the geohashes.
+type GeoSet struct {
+	dict *Dict[uint64]
+	zsl  *SkipList
+}
+
+func NewGeoSet() *GeoSet {
+	return &GeoSet{
+		dict: NewDict[uint64](),
+		zsl:  NewSkipList(),
+	}
+}
+
+func (gs *GeoSet) Len() int {
+	return gs.dict.Len()
+}
+
+func (gs *GeoSet) Get(member string) (uint64, bool) {
+	return gs.dict.Get(member)
+}
+
+// Set sets the geohash of the member, and returns true if the member is new.
+func (gs *GeoSet) Set(member string, hash uint64) bool {
+	old, found := gs.dict.Get(member)
+	if found {
+		if old == hash {
+			return false
+		}
+		gs.zsl.Delete(old, member)
+	}
+	gs.dict.Set(member, hash)
+	gs.zsl.Insert(hash, member)
+	return !found
+}
+
+type GeoShape int
+
+const (
+	GeoShapeRadius GeoShape = iota
+	GeoShapeBox
+)
+
+type GeoSort int
+
+const (
+	GeoSortNone GeoSort = iota
+	GeoSortAsc
+	GeoSortDesc
+)
+
+// GeoSearchQuery is a query of GEOSEARCH. The center is the position of the
+// member if FromMember is true. The radius, the width and the height are in
+// meters. Count limits the number of results if it is positive, and the search
+// stops once Count results are found if Any is true.
+type GeoSearchQuery struct {
+	FromMember bool
+	Member     string
+	Center     GeoPoint
+	Shape      GeoShape
+	Radius     float64
+	Width      float64
+	Height     float64
+	Sort       GeoSort
+	Count      int
+	Any        bool
+}
+
+// GeoSearchResult is a member found by GEOSEARCH, with the distance in meters
+// from the center.
+type GeoSearchResult struct {
+	Member   string
+	Hash     uint64
+	Position GeoPoint
+	Distance float64
+}
+
+// GeoAdd adds the members at the points to the geo set of the key. Existing
+// members are only updated if nx is false, and new members are only added if xx
+// is false. It returns the number of added members, plus the number of updated
+// members if ch is true.
+func (db *Database) GeoAdd(key string, members []string, points []GeoPoint, nx, xx, ch bool) (int64, error) {
+	obj, err := db.lookupKey(key, TypeGeo, true)
+	if err != nil {
+		return 0, err
+	}
+	if obj == nil {
+		if xx {
+			return 0, nil
+		}
+		obj = db.newObject()
+		obj.Type = TypeGeo
+		obj.Value = NewGeoSet()
+		db.setKey(key, obj)
+	}
+
+	gs := obj.Value.(*GeoSet)
+	cnt := int64(0)
+	for i, member := range members {
+		old, found := gs.Get(member)
+		if (found && nx) || (!found && xx) {
+			continue
+		}
+		hash := geohashEncode(points[i], GeoLatitudeMin, GeoLatitudeMax, GeoStep)
+		if gs.Set(member, hash) || (ch && old != hash) {
+			cnt++
+		}
+	}
+	db.trackMemory(key, obj)
+	return cnt, nil
+}
+
+// GeoPos returns the positions of the members, or nil for missing members.
+func (db *Database) GeoPos(key string, members ...string) ([]*GeoPoint, error) {
+	obj, err := db.lookupKey(key, TypeGeo, true)
+	if err != nil {
+		return nil, err
+	}
+
+	points := make([]*GeoPoint, len(members))
+	if obj == nil {
+		return points, nil
+	}
+	gs := obj.Value.(*GeoSet)
+	for i, member := range members {
+		if hash, found := gs.Get(member); found {
+			p := geohashDecode(hash)
+			points[i] = &p
+		}
+	}
+	return points, nil
+}
+
+// GeoDist returns the distance in meters between the members, and false if any
+// of them does not exist.
+func (db *Database) GeoDist(key, member1, member2 string) (float64, bool, error) {
+	obj, err := db.lookupKey(key, TypeGeo, true)
+	if err != nil || obj == nil {
+		return 0, false, err
+	}
+
+	gs := obj.Value.(*GeoSet)
+	hash1, found1 := gs.Get(member1)
+	hash2, found2 := gs.Get(member2)
+	if !found1 || !found2 {
+		return 0, false, nil
+	}
+	return geoDistance(geohashDecode(hash1), geohashDecode(hash2)), true, nil
+}
+
+// GeoHash returns the standard geohash strings of the members, or empty strings
+// for missing members.
+func (db *Database) GeoHash(key string, members ...string) ([]string, error) {
+	obj, err := db.lookupKey(key, TypeGeo, true)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]string, len(members))
+	if obj == nil {
+		return hashes, nil
+	}
+	gs := obj.Value.(*GeoSet)
+	for i, member := range members {
+		if hash, found := gs.Get(member); found {
+			hashes[i] = geohashString(geohashDecode(hash))
+		}
+	}
+	return hashes, nil
+}
+
+// GeoSearch returns the members of the geo set of the key within the shape of
+// the query.
+func (db *Database) GeoSearch(key string, query *GeoSearchQuery) ([]GeoSearchResult, error) {
+	obj, err := db.lookupKey(key, TypeGeo, true)
+	if err != nil || obj == nil {
+		return nil, err
+	}
+
+	gs := obj.Value.(*GeoSet)
+	center := query.Center
+	if query.FromMember {
+		hash, found := gs.Get(query.Member)
+		if !found {
+			return nil, ErrGeoMemberNotFound
+		}
+		center = geohashDecode(hash)
+	}
+	return gs.search(center, query), nil
+}
+
+// GeoSearchStore stores the members found by GeoSearch with their positions
+// into the destination key, or deletes the destination key if nothing is
+// found. It returns the number of the stored members.
+func (db *Database) GeoSearchStore(dest, key string, query *GeoSearchQuery) (int64, error) {
+	results, err := db.GeoSearch(key, query)
+	if err != nil {
+		return 0, err
+	}
+
+	obj, err := db.lookupKey(dest, TypeNone, true)
+	if err != nil {
+		return 0, err
+	}
+	if len(results) == 0 {
+		if obj != nil {
+			db.removeKey(dest, obj)
+		}
+		return 0, nil
+	}
+
+	gs := NewGeoSet()
+	for _, result := range results {
+		gs.Set(result.Member, result.Hash)
+	}
+	if obj == nil {
+		obj = db.newObject()
+		db.setKey(dest, obj)
+	}
+	obj.Type = TypeGeo
+	obj.Value = gs
+	obj.Encoding = EncodingRaw
+	db.setExpire(dest, obj, 0)
+	db.trackMemory(dest, obj)
+	return int64(len(results)), nil
+}
+
+// search scans the areas of geohashes that cover the bounding box of the shape,
+// and checks the distance of each member in the areas from the center.
+func (gs *GeoSet) search(center GeoPoint, query *GeoSearchQuery) []GeoSearchResult {
+	width, height := query.Width, query.Height
+	if query.Shape == GeoShapeRadius {
+		width, height = query.Radius*2, query.Radius*2
+	}
+
+	latDelta := radToDeg(height / 2 / EarthRadius)
+	lonDelta := 180.0
+	if math.Abs(center.Latitude)+latDelta < 90 {
+		// The box is wider at the latitude nearer to the pole.
+		lat := math.Abs(center.Latitude) + latDelta
+		lonDelta = math.Min(radToDeg(width/2/EarthRadius/math.Cos(degToRad(lat))), 180)
+	}
+
+	limit := 0
+	if query.Any {
+		limit = query.Count
+	}
+	results := make([]GeoSearchResult, 0)
+	ranges := geohashRanges(center.Longitude-lonDelta, center.Latitude-latDelta,
+		center.Longitude+lonDelta, center.Latitude+latDelta)
+	for _, r := range ranges {
+		gs.zsl.RangeByScore(r[0], r[1], func(member string, hash uint64) bool {
+			p := geohashDecode(hash)
+			if dist, ok := query.within(center, p); ok {
+				results = append(results, GeoSearchResult{Member: member, Hash: hash, Position: p, Distance: dist})
+			}
+			return limit == 0 || len(results) < limit
+		})
+		if limit > 0 && len(results) >= limit {
+			break
+		}
+	}
+
+	switch query.Sort {
+	case GeoSortAsc:
+		sort.SliceStable(results, func(i, j int) bool { return results[i].Distance < results[j].Distance })
+	case GeoSortDesc:
+		sort.SliceStable(results, func(i, j int) bool { return results[i].Distance > results[j].Distance })
+	}
+	if query.Count > 0 && len(results) > query.Count {
+		results = results[:query.Count]
+	}
+	return results
+}
+
+// within returns the distance of the point from the center, and whether it is
+// within the shape of the query.
+func (query *GeoSearchQuery) within(center, p GeoPoint) (float64, bool) {
+	if query.Shape == GeoShapeBox {
+		if geoLatDistance(center.Latitude, p.Latitude) > query.Height/2 {
+			return 0, false
+		}
+		if geoDistance(GeoPoint{Longitude: center.Longitude, Latitude: p.Latitude}, p) > query.Width/2 {
+			return 0, false
+		}
+	}
+
+	dist := geoDistance(center, p)
+	if query.Shape == GeoShapeRadius && dist > query.Radius {
+		return 0, false
+	}
+	return dist, true
+}
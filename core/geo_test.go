@@ -0,0 +1,212 @@
+package core
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+// referenceDistance is the haversine distance in meters between the points,
+// with the earth radius used by Redis.
+func referenceDistance(lon1, lat1, lon2, lat2 float64) float64 {
+	const radius = 6372797.560856
+
+	phi1, phi2 := lat1*math.Pi/180, lat2*math.Pi/180
+	dPhi := (lat2 - lat1) * math.Pi / 180
+	dLambda := (lon2 - lon1) * math.Pi / 180
+	h := math.Pow(math.Sin(dPhi/2), 2) + math.Cos(phi1)*math.Cos(phi2)*math.Pow(math.Sin(dLambda/2), 2)
+	return 2 * radius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// referenceWithin returns the distance of the point from the center, and
+// whether it is within the radius, or the box if width and height are set.
+func referenceWithin(center, p GeoPoint, radius, width, height float64) (float64, bool) {
+	dist := referenceDistance(center.Longitude, center.Latitude, p.Longitude, p.Latitude)
+	if width == 0 {
+		return dist, dist <= radius
+	}
+
+	latDist := referenceDistance(0, center.Latitude, 0, p.Latitude)
+	lonDist := referenceDistance(center.Longitude, p.Latitude, p.Longitude, p.Latitude)
+	return dist, latDist <= height/2 && lonDist <= width/2
+}
+
+// randomGeoPoints returns n random points within the degrees of the center,
+// where the longitude wraps around the antimeridian and the latitude is clamped
+// to the range of the geohash.
+func randomGeoPoints(rnd *rand.Rand, n int, center GeoPoint, lonDegrees, latDegrees float64) []GeoPoint {
+	points := make([]GeoPoint, n)
+	for i := range points {
+		lon := center.Longitude + (rnd.Float64()*2-1)*lonDegrees
+		lon = math.Mod(lon+540, 360) - 180
+		lat := center.Latitude + (rnd.Float64()*2-1)*latDegrees
+		lat = math.Max(GeoLatitudeMin, math.Min(GeoLatitudeMax, lat))
+		points[i] = GeoPoint{Longitude: lon, Latitude: lat}
+	}
+	return points
+}
+
+func TestGeoSearch(t *testing.T) {
+	tests := []struct {
+		name    string
+		center  GeoPoint
+		radius  float64
+		width   float64
+		height  float64
+		spread  [2]float64
+		members int
+	}{
+		{"radius", GeoPoint{13.361389, 38.115556}, 200000, 0, 0, [2]float64{4, 4}, 2000},
+		{"box", GeoPoint{13.361389, 38.115556}, 0, 400000, 200000, [2]float64{4, 4}, 2000},
+		{"small radius", GeoPoint{-122.4194, 37.7749}, 500, 0, 0, [2]float64{0.01, 0.01}, 2000},
+		{"large radius", GeoPoint{0, 0}, 5000000, 0, 0, [2]float64{180, 85}, 2000},
+		{"radius at antimeridian", GeoPoint{179.9, -16.5}, 100000, 0, 0, [2]float64{3, 3}, 2000},
+		{"radius at negative antimeridian", GeoPoint{-179.95, 51.8}, 150000, 0, 0, [2]float64{4, 4}, 2000},
+		{"radius on antimeridian", GeoPoint{180, 0}, 300000, 0, 0, [2]float64{5, 5}, 2000},
+		{"box at antimeridian", GeoPoint{179.5, 65}, 0, 300000, 100000, [2]float64{6, 3}, 2000},
+		{"box at negative antimeridian", GeoPoint{-179.8, -40}, 0, 200000, 200000, [2]float64{4, 4}, 2000},
+		{"radius near north pole", GeoPoint{30, 84.5}, 200000, 0, 0, [2]float64{180, 3}, 2000},
+		{"radius around north pole", GeoPoint{-60, 84.9}, 700000, 0, 0, [2]float64{180, 10}, 2000},
+		{"radius across north pole", GeoPoint{0, 84.9}, 1500000, 0, 0, [2]float64{180, 20}, 2000},
+		{"radius near south pole", GeoPoint{170, -84.8}, 300000, 0, 0, [2]float64{180, 4}, 2000},
+		{"box near north pole", GeoPoint{120, 84}, 0, 400000, 300000, [2]float64{180, 4}, 2000},
+		{"box around south pole", GeoPoint{-179, -85}, 0, 1000000, 1000000, [2]float64{180, 10}, 2000},
+		{"box across south pole", GeoPoint{0, -85}, 0, 3000000, 2000000, [2]float64{180, 20}, 2000},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rnd := rand.New(rand.NewSource(int64(i) + 1))
+			points := randomGeoPoints(rnd, tt.members, tt.center, tt.spread[0], tt.spread[1])
+			members := make([]string, len(points))
+			for j := range members {
+				members[j] = strconv.Itoa(j)
+			}
+
+			db := NewDatabase()
+			if _, err := db.GeoAdd("geo", members, points, false, false, false); err != nil {
+				t.Fatalf("GeoAdd: unexpected error %v", err)
+			}
+			positions, err := db.GeoPos("geo", members...)
+			if err != nil {
+				t.Fatalf("GeoPos: unexpected error %v", err)
+			}
+
+			query := &GeoSearchQuery{
+				Center: tt.center,
+				Shape:  GeoShapeRadius,
+				Radius: tt.radius,
+				Width:  tt.width,
+				Height: tt.height,
+				Sort:   GeoSortAsc,
+			}
+			if tt.width != 0 {
+				query.Shape = GeoShapeBox
+			}
+			results, err := db.GeoSearch("geo", query)
+			if err != nil {
+				t.Fatalf("GeoSearch: unexpected error %v", err)
+			}
+
+			found := make(map[string]GeoSearchResult, len(results))
+			for _, result := range results {
+				found[result.Member] = result
+			}
+
+			expected := 0
+			for j, member := range members {
+				p := *positions[j]
+				dist, ok := referenceWithin(tt.center, p, tt.radius, tt.width, tt.height)
+				result, isFound := found[member]
+				if ok {
+					expected++
+				}
+				if ok != isFound {
+					t.Errorf("member %s at (%f, %f) with distance %f: expected found %v, got %v",
+						member, p.Longitude, p.Latitude, dist, ok, isFound)
+				} else if isFound && math.Abs(result.Distance-dist) > 1e-6 {
+					t.Errorf("member %s: expected distance %f, got %f", member, dist, result.Distance)
+				}
+			}
+			if len(results) != len(found) {
+				t.Errorf("expected no duplicate members, got %d results of %d members", len(results), len(found))
+			}
+			if expected == 0 || expected == len(members) {
+				t.Errorf("expected some but not all of the members within the shape, got %d of %d",
+					expected, len(members))
+			}
+
+			if !sort.SliceIsSorted(results, func(i, j int) bool { return results[i].Distance < results[j].Distance }) {
+				t.Errorf("expected results sorted by distance")
+			}
+		})
+	}
+}
+
+func TestGeoDist(t *testing.T) {
+	tests := []struct {
+		name string
+		p1   GeoPoint
+		p2   GeoPoint
+	}{
+		{"same point", GeoPoint{13.361389, 38.115556}, GeoPoint{13.361389, 38.115556}},
+		{"Palermo and Catania", GeoPoint{13.361389, 38.115556}, GeoPoint{15.087269, 37.502669}},
+		{"same longitude", GeoPoint{10, -40}, GeoPoint{10, 60}},
+		{"same latitude", GeoPoint{-100, 45}, GeoPoint{100, 45}},
+		{"across antimeridian", GeoPoint{179.99, 10}, GeoPoint{-179.99, 10}},
+		{"antimeridian", GeoPoint{180, 0}, GeoPoint{-180, 0}},
+		{"antipodes", GeoPoint{0, 0}, GeoPoint{180, 0}},
+		{"across north pole", GeoPoint{0, 85}, GeoPoint{180, 85}},
+		{"near south pole", GeoPoint{-45, -85.05}, GeoPoint{45, -85.05}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := NewDatabase()
+			if _, err := db.GeoAdd("geo", []string{"a", "b"}, []GeoPoint{tt.p1, tt.p2}, false, false, false); err != nil {
+				t.Fatalf("GeoAdd: unexpected error %v", err)
+			}
+			dist, ok, err := db.GeoDist("geo", "a", "b")
+			if err != nil || !ok {
+				t.Fatalf("GeoDist: unexpected result (%v, %v)", ok, err)
+			}
+
+			positions, _ := db.GeoPos("geo", "a", "b")
+			a, b := *positions[0], *positions[1]
+			if expected := referenceDistance(a.Longitude, a.Latitude, b.Longitude, b.Latitude); math.Abs(dist-expected) > 1e-6 {
+				t.Errorf("expected distance %f of the stored positions, got %f", expected, dist)
+			}
+			// The geohash of 52 bits is precise to less than a meter.
+			if expected := referenceDistance(tt.p1.Longitude, tt.p1.Latitude, tt.p2.Longitude, tt.p2.Latitude); math.Abs(dist-expected) > 1 {
+				t.Errorf("expected distance %f, got %f", expected, dist)
+			}
+		})
+	}
+}
+
+func TestGeoDistRandom(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	points := randomGeoPoints(rnd, 1000, GeoPoint{}, 180, 90)
+	members := make([]string, len(points))
+	for i := range members {
+		members[i] = strconv.Itoa(i)
+	}
+
+	db := NewDatabase()
+	if _, err := db.GeoAdd("geo", members, points, false, false, false); err != nil {
+		t.Fatalf("GeoAdd: unexpected error %v", err)
+	}
+	positions, _ := db.GeoPos("geo", members...)
+	for i := 1; i < len(members); i++ {
+		dist, ok, err := db.GeoDist("geo", members[i-1], members[i])
+		if err != nil || !ok {
+			t.Fatalf("GeoDist: unexpected result (%v, %v)", ok, err)
+		}
+		a, b := *positions[i-1], *positions[i]
+		if expected := referenceDistance(a.Longitude, a.Latitude, b.Longitude, b.Latitude); math.Abs(dist-expected) > 1e-6 {
+			t.Errorf("%s and %s: expected distance %f, got %f", members[i-1], members[i], expected, dist)
+		}
+	}
+}
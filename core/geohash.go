@@ -0,0 +1,166 @@
+package core
+
+import "math"
+
+const (
+	// GeoStep is the number of bits of each coordinate in a geohash of 52 bits.
+	GeoStep = 26
+
+	GeoLongitudeMin = -180.0
+	GeoLongitudeMax = 180.0
+	GeoLatitudeMin  = -85.05112878
+	GeoLatitudeMax  = 85.05112878
+
+	// EarthRadius is the earth radius in meters used by the haversine formula.
+	EarthRadius = 6372797.560856
+
+	geoAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+)
+
+// GeoPoint is a position of longitude and latitude in degrees.
+type GeoPoint struct {
+	Longitude float64
+	Latitude  float64
+}
+
+// Valid returns false if the point is out of the range that can be indexed by
+// a geohash, which is the range of EPSG:3857.
+func (p GeoPoint) Valid() bool {
+	return p.Longitude >= GeoLongitudeMin && p.Longitude <= GeoLongitudeMax &&
+		p.Latitude >= GeoLatitudeMin && p.Latitude <= GeoLatitudeMax
+}
+
+// geohashEncode interleaves the offsets of the latitude and the longitude of
+// step bits each into a geohash, with the latitude bits at the even positions.
+func geohashEncode(p GeoPoint, latMin, latMax float64, step int) uint64 {
+	latOffset := (p.Latitude - latMin) / (latMax - latMin)
+	lonOffset := (p.Longitude - GeoLongitudeMin) / (GeoLongitudeMax - GeoLongitudeMin)
+	cells := float64(uint64(1) << step)
+	return interleaveBits(uint32(math.Min(latOffset*cells, cells-1)), uint32(math.Min(lonOffset*cells, cells-1)))
+}
+
+// geohashDecode returns the center of the area of the geohash of 52 bits.
+func geohashDecode(hash uint64) GeoPoint {
+	latIndex, lonIndex := deinterleaveBits(hash)
+	cells := float64(uint64(1) << GeoStep)
+	latScale := (GeoLatitudeMax - GeoLatitudeMin) / cells
+	lonScale := (GeoLongitudeMax - GeoLongitudeMin) / cells
+
+	p := GeoPoint{
+		Longitude: GeoLongitudeMin + (float64(lonIndex)+0.5)*lonScale,
+		Latitude:  GeoLatitudeMin + (float64(latIndex)+0.5)*latScale,
+	}
+	p.Longitude = math.Max(GeoLongitudeMin, math.Min(GeoLongitudeMax, p.Longitude))
+	p.Latitude = math.Max(GeoLatitudeMin, math.Min(GeoLatitudeMax, p.Latitude))
+	return p
+}
+
+// geohashString returns the standard 11 characters geohash of the point, which
+// uses the latitude range of [-90, 90] instead of the range of EPSG:3857.
+func geohashString(p GeoPoint) string {
+	hash := geohashEncode(p, -90, 90, GeoStep)
+	buf := make([]byte, 11)
+	for i := range buf {
+		// The last character has only 2 bits of the 52 bits geohash, which is
+		// padded with zeros like Redis.
+		idx := uint64(0)
+		if i < 10 {
+			idx = hash >> (52 - (i+1)*5) & 0x1f
+		}
+		buf[i] = geoAlphabet[idx]
+	}
+	return string(buf)
+}
+
+func interleaveBits(x, y uint32) uint64 {
+	return spreadBits(x) | spreadBits(y)<<1
+}
+
+func deinterleaveBits(hash uint64) (uint32, uint32) {
+	return squashBits(hash), squashBits(hash >> 1)
+}
+
+// spreadBits moves the bits of v to the even positions of the result.
+func spreadBits(v uint32) uint64 {
+	x := uint64(v)
+	x = (x | x<<16) & 0x0000ffff0000ffff
+	x = (x | x<<8) & 0x00ff00ff00ff00ff
+	x = (x | x<<4) & 0x0f0f0f0f0f0f0f0f
+	x = (x | x<<2) & 0x3333333333333333
+	x = (x | x<<1) & 0x5555555555555555
+	return x
+}
+
+func squashBits(x uint64) uint32 {
+	x &= 0x5555555555555555
+	x = (x | x>>1) & 0x3333333333333333
+	x = (x | x>>2) & 0x0f0f0f0f0f0f0f0f
+	x = (x | x>>4) & 0x00ff00ff00ff00ff
+	x = (x | x>>8) & 0x0000ffff0000ffff
+	x = (x | x>>16) & 0x00000000ffffffff
+	return uint32(x)
+}
+
+// geoDistance returns the distance in meters between the points by the
+// haversine formula.
+func geoDistance(p1, p2 GeoPoint) float64 {
+	lat1, lat2 := degToRad(p1.Latitude), degToRad(p2.Latitude)
+	v := math.Sin((degToRad(p2.Longitude) - degToRad(p1.Longitude)) / 2)
+	if v == 0 {
+		return geoLatDistance(p1.Latitude, p2.Latitude)
+	}
+	u := math.Sin((lat2 - lat1) / 2)
+	a := u*u + math.Cos(lat1)*math.Cos(lat2)*v*v
+	return 2 * EarthRadius * math.Asin(math.Sqrt(a))
+}
+
+func geoLatDistance(lat1, lat2 float64) float64 {
+	return EarthRadius * math.Abs(degToRad(lat2)-degToRad(lat1))
+}
+
+func degToRad(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func radToDeg(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
+
+// geohashRanges returns the ranges of the 52 bits geohashes of the areas that
+// cover the bounding box, at the largest step where at most 9 areas are
+// needed.
+func geohashRanges(minLon, minLat, maxLon, maxLat float64) [][2]uint64 {
+	minLat = math.Max(minLat, GeoLatitudeMin)
+	maxLat = math.Min(maxLat, GeoLatitudeMax)
+
+	step := GeoStep
+	var latLow, latHigh, lonLow, lonHigh, cells int64
+	for ; step > 0; step-- {
+		cells = int64(1) << step
+		latLow = max(geoCellIndex(minLat, GeoLatitudeMin, GeoLatitudeMax, cells), 0)
+		latHigh = min(geoCellIndex(maxLat, GeoLatitudeMin, GeoLatitudeMax, cells), cells-1)
+		lonLow = geoCellIndex(minLon, GeoLongitudeMin, GeoLongitudeMax, cells)
+		lonHigh = geoCellIndex(maxLon, GeoLongitudeMin, GeoLongitudeMax, cells)
+		if lonHigh-lonLow+1 >= cells {
+			lonLow, lonHigh = 0, cells-1
+		}
+		if (latHigh-latLow+1)*(lonHigh-lonLow+1) <= 9 {
+			break
+		}
+	}
+
+	shift := 2 * (GeoStep - step)
+	ranges := make([][2]uint64, 0, 9)
+	for lat := latLow; lat <= latHigh; lat++ {
+		for lon := lonLow; lon <= lonHigh; lon++ {
+			// The longitude wraps around the antimeridian.
+			index := interleaveBits(uint32(lat), uint32((lon%cells+cells)%cells))
+			ranges = append(ranges, [2]uint64{index << shift, (index+1)<<shift - 1})
+		}
+	}
+	return ranges
+}
+
+func geoCellIndex(val, lower, upper float64, cells int64) int64 {
+	return int64(math.Floor((val - lower) / (upper - lower) * float64(cells)))
+}
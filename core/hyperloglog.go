@@ -0,0 +1,532 @@
+package core
+
+import (
+	"encoding/binary"
+	"math"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// The HyperLogLog is stored in a string with the same layout as Redis, so that
+// it can be dumped and restored between them. The string starts with a 16 bytes
+// header of the "HYLL" magic, the encoding, 3 unused bytes and the cached
+// cardinality in little endian, whose most significant bit is set if the cache
+// is invalid. The header is followed by 16384 6-bit registers in the dense
+// encoding, or the run-length encoded registers in the sparse encoding.
+const (
+	hllP            = 14
+	hllQ            = 64 - hllP
+	hllRegisters    = 1 << hllP
+	hllPMask        = hllRegisters - 1
+	hllBits         = 6
+	hllRegisterMax  = 1<<hllBits - 1
+	hllHeaderSize   = 16
+	hllDenseSize    = hllHeaderSize + (hllRegisters*hllBits+7)/8
+	hllMagic        = "HYLL"
+	hllEncodingByte = 4
+	hllCacheByte    = 8
+	hllAlphaInf     = 0.721347520444481703680
+
+	hllDense  = 0
+	hllSparse = 1
+
+	// The sparse encoding has three opcodes: ZERO (00xxxxxx) for up to 64
+	// zero registers, XZERO (01xxxxxx yyyyyyyy) for up to 16384 zero registers,
+	// and VAL (1vvvvvxx) for up to 4 registers of the value up to 32.
+	hllSparseZeroMaxLen  = 64
+	hllSparseXZeroMaxLen = 16384
+	hllSparseValMax      = 32
+	hllSparseValMaxLen   = 4
+
+	// hllSparseMaxBytes is the size beyond which a sparse HyperLogLog is
+	// converted into the dense encoding.
+	hllSparseMaxBytes = 3000
+)
+
+// PFAdd adds the elements to the HyperLogLog of the key, creating it if it does
+// not exist. It returns true if the key was created or any register was
+// updated.
+func (db *Database) PFAdd(key string, elements ...string) (bool, error) {
+	obj, err := db.lookupHLL(key)
+	if err != nil {
+		return false, err
+	}
+
+	created := false
+	if obj == nil {
+		obj = db.newObject()
+		obj.Type = TypeString
+		obj.Value = newHLL()
+		obj.Encoding = EncodingBytes
+		db.setKey(key, obj)
+		created = true
+	}
+
+	buf := obj.mutableBytes()
+	updated := false
+	if buf[hllEncodingByte] == hllDense {
+		for _, ele := range elements {
+			index, count := hllPatLen(ele)
+			if hllDenseGet(buf[hllHeaderSize:], index) < count {
+				hllDenseSet(buf[hllHeaderSize:], index, count)
+				updated = true
+			}
+		}
+	} else {
+		regs := make([]uint8, hllRegisters)
+		if err := hllSparseMerge(regs, buf); err != nil {
+			return false, err
+		}
+		for _, ele := range elements {
+			index, count := hllPatLen(ele)
+			if regs[index] < count {
+				regs[index] = count
+				updated = true
+			}
+		}
+		if updated {
+			buf = hllFromRegisters(regs, false)
+		}
+	}
+
+	if updated {
+		hllInvalidateCache(buf)
+		obj.Value = buf
+		db.trackMemory(key, obj)
+	}
+	return created || updated, nil
+}
+
+// PFCount returns the approximated cardinality of the HyperLogLog of the key,
+// or of the union of the HyperLogLogs of the keys. The cardinality of a single
+// key is cached in the header until the HyperLogLog is modified.
+func (db *Database) PFCount(keys ...string) (int64, error) {
+	if len(keys) == 1 {
+		obj, err := db.lookupHLL(keys[0])
+		if err != nil || obj == nil {
+			return 0, err
+		}
+
+		buf := obj.mutableBytes()
+		if buf[hllHeaderSize-1]&0x80 == 0 {
+			return int64(binary.LittleEndian.Uint64(buf[hllCacheByte:])), nil
+		}
+		regs := make([]uint8, hllRegisters)
+		if err := hllMerge(regs, buf); err != nil {
+			return 0, err
+		}
+		card := hllCount(regs)
+		binary.LittleEndian.PutUint64(buf[hllCacheByte:], card)
+		db.trackMemory(keys[0], obj)
+		return int64(card), nil
+	}
+
+	regs := make([]uint8, hllRegisters)
+	for _, key := range keys {
+		obj, err := db.lookupHLL(key)
+		if err != nil {
+			return 0, err
+		}
+		if obj == nil {
+			continue
+		}
+		if err := hllMerge(regs, obj.bytesValue()); err != nil {
+			return 0, err
+		}
+	}
+	return int64(hllCount(regs)), nil
+}
+
+// PFMerge merges the HyperLogLogs of the destination key and the source keys
+// into the destination key, creating it if it does not exist. The result is
+// dense if any of the HyperLogLogs is dense.
+func (db *Database) PFMerge(dest string, keys ...string) error {
+	regs := make([]uint8, hllRegisters)
+	dense := false
+	for _, key := range append([]string{dest}, keys...) {
+		obj, err := db.lookupHLL(key)
+		if err != nil {
+			return err
+		}
+		if obj == nil {
+			continue
+		}
+		buf := obj.bytesValue()
+		if buf[hllEncodingByte] == hllDense {
+			dense = true
+		}
+		if err := hllMerge(regs, buf); err != nil {
+			return err
+		}
+	}
+
+	obj, err := db.lookupKey(dest, TypeString, true)
+	if err != nil {
+		return err
+	}
+	if obj == nil {
+		obj = db.newObject()
+		obj.Type = TypeString
+		db.setKey(dest, obj)
+	}
+	obj.Value = hllFromRegisters(regs, dense)
+	obj.Encoding = EncodingBytes
+	db.trackMemory(dest, obj)
+	return nil
+}
+
+// PFDebugGetReg converts the HyperLogLog of the key into the dense encoding,
+// and returns its registers.
+func (db *Database) PFDebugGetReg(key string) ([]uint8, error) {
+	obj, err := db.lookupHLLForDebug(key)
+	if err != nil {
+		return nil, err
+	}
+
+	regs := make([]uint8, hllRegisters)
+	if err := hllMerge(regs, obj.bytesValue()); err != nil {
+		return nil, err
+	}
+	if _, err := db.PFDebugToDense(key); err != nil {
+		return nil, err
+	}
+	return regs, nil
+}
+
+// PFDebugDecode returns the opcodes of the sparse HyperLogLog of the key, like
+// "z:10 v:2,1 Z:16373", where z and Z are the ZERO and XZERO opcodes with their
+// lengths, and v is the VAL opcode with its value and length.
+func (db *Database) PFDebugDecode(key string) (string, error) {
+	obj, err := db.lookupHLLForDebug(key)
+	if err != nil {
+		return "", err
+	}
+
+	buf := obj.bytesValue()
+	if buf[hllEncodingByte] != hllSparse {
+		return "", ErrHLLNotSparse
+	}
+
+	ops := make([]string, 0)
+	for p := hllHeaderSize; p < len(buf); p++ {
+		op := buf[p]
+		switch {
+		case op&0xc0 == 0:
+			ops = append(ops, "z:"+strconv.Itoa(int(op&0x3f)+1))
+		case op&0xc0 == 0x40:
+			if p+1 >= len(buf) {
+				return "", ErrHLLCorrupted
+			}
+			ops = append(ops, "Z:"+strconv.Itoa(int(op&0x3f)<<8|int(buf[p+1])+1))
+			p++
+		default:
+			ops = append(ops, "v:"+strconv.Itoa(int(op>>2&0x1f)+1)+","+strconv.Itoa(int(op&0x3)+1))
+		}
+	}
+	return strings.Join(ops, " "), nil
+}
+
+// PFDebugEncoding returns the encoding of the HyperLogLog of the key, "sparse"
+// or "dense".
+func (db *Database) PFDebugEncoding(key string) (string, error) {
+	obj, err := db.lookupHLLForDebug(key)
+	if err != nil {
+		return "", err
+	}
+
+	if obj.bytesValue()[hllEncodingByte] == hllSparse {
+		return "sparse", nil
+	}
+	return "dense", nil
+}
+
+// PFDebugToDense converts the HyperLogLog of the key into the dense encoding,
+// and returns true if it was sparse.
+func (db *Database) PFDebugToDense(key string) (bool, error) {
+	obj, err := db.lookupHLLForDebug(key)
+	if err != nil {
+		return false, err
+	}
+
+	buf := obj.bytesValue()
+	if buf[hllEncodingByte] == hllDense {
+		return false, nil
+	}
+	regs := make([]uint8, hllRegisters)
+	if err := hllSparseMerge(regs, buf); err != nil {
+		return false, err
+	}
+	obj.Value = hllFromRegisters(regs, true)
+	obj.Encoding = EncodingBytes
+	db.trackMemory(key, obj)
+	return true, nil
+}
+
+// lookupHLL returns the object of the key, or ErrNotHLL if the string of the key
+// is not a HyperLogLog.
+func (db *Database) lookupHLL(key string) (*Object, error) {
+	obj, err := db.lookupKey(key, TypeString, true)
+	if err != nil || obj == nil {
+		return nil, err
+	}
+	if !isHLL(obj.bytesValue()) {
+		return nil, ErrNotHLL
+	}
+	return obj, nil
+}
+
+func (db *Database) lookupHLLForDebug(key string) (*Object, error) {
+	obj, err := db.lookupHLL(key)
+	if err == nil && obj == nil {
+		err = ErrNoSuchKey
+	}
+	return obj, err
+}
+
+func isHLL(buf []byte) bool {
+	if len(buf) < hllHeaderSize || string(buf[:len(hllMagic)]) != hllMagic {
+		return false
+	}
+	switch buf[hllEncodingByte] {
+	case hllDense:
+		return len(buf) == hllDenseSize
+	case hllSparse:
+		return true
+	}
+	return false
+}
+
+// newHLL returns an empty sparse HyperLogLog, whose cached cardinality is 0.
+func newHLL() []byte {
+	buf := make([]byte, hllHeaderSize, hllHeaderSize+2)
+	copy(buf, hllMagic)
+	buf[hllEncodingByte] = hllSparse
+	return append(buf, 0x40|byte((hllRegisters-1)>>8), byte((hllRegisters-1)&0xff))
+}
+
+// hllFromRegisters encodes the registers into a HyperLogLog with an invalid
+// cache. It is sparse unless dense is true, any register does not fit the VAL
+// opcode, or the sparse encoding exceeds hllSparseMaxBytes.
+func hllFromRegisters(regs []uint8, dense bool) []byte {
+	if !dense {
+		if buf, ok := hllSparseEncode(regs); ok && len(buf) <= hllSparseMaxBytes {
+			hllInvalidateCache(buf)
+			return buf
+		}
+	}
+
+	buf := make([]byte, hllDenseSize)
+	copy(buf, hllMagic)
+	buf[hllEncodingByte] = hllDense
+	for i, val := range regs {
+		if val != 0 {
+			hllDenseSet(buf[hllHeaderSize:], i, val)
+		}
+	}
+	hllInvalidateCache(buf)
+	return buf
+}
+
+func hllSparseEncode(regs []uint8) ([]byte, bool) {
+	buf := make([]byte, hllHeaderSize, hllHeaderSize+64)
+	copy(buf, hllMagic)
+	buf[hllEncodingByte] = hllSparse
+
+	for i := 0; i < len(regs); {
+		val := regs[i]
+		if val > hllSparseValMax {
+			return nil, false
+		}
+		run := 1
+		for i+run < len(regs) && regs[i+run] == val {
+			run++
+		}
+		i += run
+
+		for run > 0 {
+			switch {
+			case val != 0:
+				n := min(run, hllSparseValMaxLen)
+				buf = append(buf, 0x80|(val-1)<<2|byte(n-1))
+				run -= n
+			case run > hllSparseZeroMaxLen:
+				n := min(run, hllSparseXZeroMaxLen)
+				buf = append(buf, 0x40|byte((n-1)>>8), byte((n-1)&0xff))
+				run -= n
+			default:
+				buf = append(buf, byte(run-1))
+				run = 0
+			}
+		}
+	}
+	return buf, true
+}
+
+// hllMerge sets each register to the maximum of itself and the register of the
+// HyperLogLog.
+func hllMerge(regs []uint8, buf []byte) error {
+	if buf[hllEncodingByte] == hllSparse {
+		return hllSparseMerge(regs, buf)
+	}
+
+	for i := range regs {
+		if val := hllDenseGet(buf[hllHeaderSize:], i); val > regs[i] {
+			regs[i] = val
+		}
+	}
+	return nil
+}
+
+func hllSparseMerge(regs []uint8, buf []byte) error {
+	index := 0
+	for p := hllHeaderSize; p < len(buf); p++ {
+		op := buf[p]
+		switch {
+		case op&0xc0 == 0:
+			index += int(op&0x3f) + 1
+		case op&0xc0 == 0x40:
+			if p+1 >= len(buf) {
+				return ErrHLLCorrupted
+			}
+			index += int(op&0x3f)<<8 | int(buf[p+1]) + 1
+			p++
+		default:
+			val, run := op>>2&0x1f+1, int(op&0x3)+1
+			if index+run > hllRegisters {
+				return ErrHLLCorrupted
+			}
+			for i := index; i < index+run; i++ {
+				if val > regs[i] {
+					regs[i] = val
+				}
+			}
+			index += run
+		}
+		if index > hllRegisters {
+			return ErrHLLCorrupted
+		}
+	}
+
+	if index != hllRegisters {
+		return ErrHLLCorrupted
+	}
+	return nil
+}
+
+// hllDenseGet returns the register at the index of the dense registers, which
+// are packed from the least significant bit of each byte.
+func hllDenseGet(regs []byte, index int) uint8 {
+	pos := index * hllBits
+	b, shift := pos>>3, pos&7
+	val := regs[b] >> shift
+	if b+1 < len(regs) {
+		val |= regs[b+1] << (8 - shift)
+	}
+	return val & hllRegisterMax
+}
+
+func hllDenseSet(regs []byte, index int, val uint8) {
+	pos := index * hllBits
+	b, shift := pos>>3, pos&7
+	regs[b] &^= hllRegisterMax << shift
+	regs[b] |= val << shift
+	if b+1 < len(regs) {
+		regs[b+1] &^= hllRegisterMax >> (8 - shift)
+		regs[b+1] |= val >> (8 - shift)
+	}
+}
+
+func hllInvalidateCache(buf []byte) {
+	buf[hllHeaderSize-1] |= 0x80
+}
+
+// hllPatLen returns the register index of the element, and the position of the
+// first set bit of the rest bits of its hash, which is the value of the
+// register.
+func hllPatLen(ele string) (int, uint8) {
+	hash := murmurHash64A([]byte(ele), 0xadc83b19)
+	index := int(hash & hllPMask)
+	hash >>= hllP
+	hash |= 1 << hllQ
+	return index, uint8(bits.TrailingZeros64(hash) + 1)
+}
+
+// hllCount estimates the cardinality from the registers with the estimator of
+// Otmar Ertl's "New cardinality estimation algorithms for HyperLogLog
+// sketches".
+func hllCount(regs []uint8) uint64 {
+	var histogram [64]int
+	for _, val := range regs {
+		histogram[val]++
+	}
+
+	m := float64(hllRegisters)
+	z := m * hllTau((m-float64(histogram[hllQ+1]))/m)
+	for j := hllQ; j >= 1; j-- {
+		z += float64(histogram[j])
+		z *= 0.5
+	}
+	z += m * hllSigma(float64(histogram[0])/m)
+	return uint64(math.Round(hllAlphaInf * m * m / z))
+}
+
+func hllSigma(x float64) float64 {
+	if x == 1 {
+		return math.Inf(1)
+	}
+	y, z := 1.0, x
+	for {
+		x *= x
+		prev := z
+		z += x * y
+		y += y
+		if prev == z {
+			return z
+		}
+	}
+}
+
+func hllTau(x float64) float64 {
+	if x == 0 || x == 1 {
+		return 0
+	}
+	y, z := 1.0, 1-x
+	for {
+		x = math.Sqrt(x)
+		prev := z
+		y *= 0.5
+		z -= math.Pow(1-x, 2) * y
+		if prev == z {
+			return z / 3
+		}
+	}
+}
+
+// murmurHash64A is the 64-bit MurmurHash2 used by Redis to hash the elements.
+func murmurHash64A(data []byte, seed uint64) uint64 {
+	const m = 0xc6a4a7935bd1e995
+	const r = 47
+
+	h := seed ^ uint64(len(data))*m
+	for ; len(data) >= 8; data = data[8:] {
+		k := binary.LittleEndian.Uint64(data)
+		k *= m
+		k ^= k >> r
+		k *= m
+		h ^= k
+		h *= m
+	}
+
+	if len(data) > 0 {
+		for i := len(data) - 1; i >= 0; i-- {
+			h ^= uint64(data[i]) << (8 * i)
+		}
+		h *= m
+	}
+
+	h ^= h >> r
+	h *= m
+	h ^= h >> r
+	return h
+}
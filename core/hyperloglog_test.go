@@ -0,0 +1,344 @@
+package core
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+)
+
+// hllMaxError is three times the standard error of the HyperLogLog with 16384
+// registers.
+var hllMaxError = 3 * 1.04 / math.Sqrt(hllRegisters)
+
+// hllElements returns the elements from start to end exclusive.
+func hllElements(start, end int) []string {
+	elements := make([]string, 0, end-start)
+	for i := start; i < end; i++ {
+		elements = append(elements, "ele:"+strconv.Itoa(i))
+	}
+	return elements
+}
+
+// hllReferenceRegisters returns the registers of a HyperLogLog of the elements.
+func hllReferenceRegisters(elements []string) []uint8 {
+	regs := make([]uint8, hllRegisters)
+	for _, ele := range elements {
+		index, count := hllPatLen(ele)
+		regs[index] = max(regs[index], count)
+	}
+	return regs
+}
+
+// hllRegistersOf returns the registers of the HyperLogLog of the key.
+func hllRegistersOf(t *testing.T, db *Database, key string) []uint8 {
+	t.Helper()
+
+	obj, err := db.lookupHLL(key)
+	if err != nil || obj == nil {
+		t.Fatalf("lookupHLL(%q): unexpected result (%v, %v)", key, obj, err)
+	}
+	regs := make([]uint8, hllRegisters)
+	if err := hllMerge(regs, obj.bytesValue()); err != nil {
+		t.Fatalf("hllMerge(%q): unexpected error %v", key, err)
+	}
+	return regs
+}
+
+func assertHLLEncoding(t *testing.T, db *Database, key, expected string) {
+	t.Helper()
+
+	encoding, err := db.PFDebugEncoding(key)
+	if err != nil {
+		t.Fatalf("PFDebugEncoding(%q): unexpected error %v", key, err)
+	}
+	if encoding != expected {
+		t.Errorf("expected encoding %s of %q, got %s", expected, key, encoding)
+	}
+}
+
+func assertHLLError(t *testing.T, count int64, expected int) {
+	t.Helper()
+
+	if e := math.Abs(float64(count)-float64(expected)) / float64(expected); e > hllMaxError {
+		t.Errorf("expected cardinality %d within %.2f%%, got %d (%.2f%%)",
+			expected, hllMaxError*100, count, e*100)
+	}
+}
+
+func TestPFCount(t *testing.T) {
+	tests := []struct {
+		elements int
+		encoding string
+	}{
+		{1, "sparse"},
+		{10, "sparse"},
+		{100, "sparse"},
+		{1000, "sparse"},
+		{1500, "sparse"},
+		{2000, "dense"},
+		{10000, "dense"},
+		{100000, "dense"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.elements), func(t *testing.T) {
+			db := NewDatabase()
+			elements := hllElements(0, tt.elements)
+			for i := 0; i < len(elements); i += 1000 {
+				if _, err := db.PFAdd("hll", elements[i:min(i+1000, len(elements))]...); err != nil {
+					t.Fatalf("PFAdd: unexpected error %v", err)
+				}
+			}
+			assertHLLEncoding(t, db, "hll", tt.encoding)
+
+			count, err := db.PFCount("hll")
+			if err != nil {
+				t.Fatalf("PFCount: unexpected error %v", err)
+			}
+			assertHLLError(t, count, tt.elements)
+			if expected := int64(hllCount(hllReferenceRegisters(elements))); count != expected {
+				t.Errorf("expected cardinality %d of the registers, got %d", expected, count)
+			}
+
+			// The cached cardinality is the same, and it is invalidated by any
+			// updated register.
+			if cached, _ := db.PFCount("hll"); cached != count {
+				t.Errorf("expected cached cardinality %d, got %d", count, cached)
+			}
+			if updated, _ := db.PFAdd("hll", elements...); updated {
+				t.Errorf("expected no register updated by the added elements")
+			}
+			db.PFAdd("hll", hllElements(tt.elements, tt.elements*2)...)
+			count, _ = db.PFCount("hll")
+			assertHLLError(t, count, tt.elements*2)
+		})
+	}
+
+	db := NewDatabase()
+	if count, err := db.PFCount("none"); count != 0 || err != nil {
+		t.Errorf("PFCount(none) = (%d, %v), expected (0, nil)", count, err)
+	}
+}
+
+func TestPFAddPromotion(t *testing.T) {
+	db := NewDatabase()
+	elements := hllElements(0, 5000)
+
+	promoted := -1
+	for i, ele := range elements {
+		updated, err := db.PFAdd("hll", ele)
+		if err != nil {
+			t.Fatalf("PFAdd(%s): unexpected error %v", ele, err)
+		}
+		encoding, _ := db.PFDebugEncoding("hll")
+		if promoted >= 0 {
+			if encoding != "dense" {
+				t.Fatalf("expected dense encoding after promoted at %d, got %s at %d", promoted, encoding, i)
+			}
+			continue
+		}
+		if encoding == "sparse" {
+			if !updated {
+				continue
+			}
+			obj, _ := db.lookupHLL("hll")
+			if size := len(obj.bytesValue()); size > hllSparseMaxBytes {
+				t.Fatalf("expected sparse size up to %d bytes, got %d", hllSparseMaxBytes, size)
+			}
+			continue
+		}
+
+		promoted = i
+		expected := hllReferenceRegisters(elements[:i+1])
+		regs := hllRegistersOf(t, db, "hll")
+		for j := range regs {
+			if regs[j] != expected[j] {
+				t.Fatalf("register %d after promoted: expected %d, got %d", j, expected[j], regs[j])
+			}
+		}
+		count, _ := db.PFCount("hll")
+		if c := int64(hllCount(expected)); count != c {
+			t.Errorf("expected cardinality %d after promoted, got %d", c, count)
+		}
+	}
+	if promoted < 0 {
+		t.Fatalf("expected promoted to dense encoding")
+	}
+	count, _ := db.PFCount("hll")
+	assertHLLError(t, count, len(elements))
+
+	// A register beyond the value of the VAL opcode is only encoded as dense.
+	regs := make([]uint8, hllRegisters)
+	regs[100] = hllSparseValMax
+	if buf := hllFromRegisters(regs, false); buf[hllEncodingByte] != hllSparse {
+		t.Errorf("expected sparse encoding of register value %d", hllSparseValMax)
+	}
+	regs[100] = hllSparseValMax + 1
+	buf := hllFromRegisters(regs, false)
+	if buf[hllEncodingByte] != hllDense {
+		t.Fatalf("expected dense encoding of register value %d", hllSparseValMax+1)
+	}
+	decoded := make([]uint8, hllRegisters)
+	hllMerge(decoded, buf)
+	if decoded[100] != hllSparseValMax+1 {
+		t.Errorf("expected register value %d, got %d", hllSparseValMax+1, decoded[100])
+	}
+}
+
+func TestPFDebugToDense(t *testing.T) {
+	db := NewDatabase()
+	elements := hllElements(0, 500)
+	db.PFAdd("hll", elements...)
+	sparse, _ := db.PFCount("hll")
+
+	if converted, err := db.PFDebugToDense("hll"); !converted || err != nil {
+		t.Fatalf("PFDebugToDense = (%v, %v), expected (true, nil)", converted, err)
+	}
+	assertHLLEncoding(t, db, "hll", "dense")
+	if dense, _ := db.PFCount("hll"); dense != sparse {
+		t.Errorf("expected the same cardinality %d of the dense encoding, got %d", sparse, dense)
+	}
+	if converted, _ := db.PFDebugToDense("hll"); converted {
+		t.Errorf("expected no conversion of the dense encoding")
+	}
+
+	// The merge result is dense if any source is dense, even though it fits the
+	// sparse encoding.
+	db.PFAdd("sparse", hllElements(500, 600)...)
+	if err := db.PFMerge("merged", "sparse", "hll"); err != nil {
+		t.Fatalf("PFMerge: unexpected error %v", err)
+	}
+	assertHLLEncoding(t, db, "merged", "dense")
+}
+
+func TestPFMerge(t *testing.T) {
+	tests := []struct {
+		name     string
+		sources  [][2]int
+		dest     [2]int
+		encoding string
+	}{
+		{"sparse", [][2]int{{0, 100}, {50, 200}}, [2]int{}, "sparse"},
+		{"sparse and dense", [][2]int{{0, 100}, {0, 10000}}, [2]int{}, "dense"},
+		{"dense", [][2]int{{0, 20000}, {10000, 30000}}, [2]int{}, "dense"},
+		{"disjoint", [][2]int{{0, 300}, {300, 600}, {600, 900}}, [2]int{}, "sparse"},
+		{"sparse beyond the size", [][2]int{{0, 1500}, {1500, 3000}}, [2]int{}, "dense"},
+		{"existing destination", [][2]int{{0, 100}}, [2]int{100, 300}, "sparse"},
+		{"dense destination", [][2]int{{0, 100}}, [2]int{0, 5000}, "dense"},
+		{"single source", [][2]int{{0, 1000}}, [2]int{}, "sparse"},
+		{"no source", nil, [2]int{0, 100}, "sparse"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := NewDatabase()
+			union := make(map[string]struct{})
+			var elements []string
+			add := func(key string, r [2]int) {
+				eles := hllElements(r[0], r[1])
+				if _, err := db.PFAdd(key, eles...); err != nil {
+					t.Fatalf("PFAdd(%q): unexpected error %v", key, err)
+				}
+				for _, ele := range eles {
+					if _, ok := union[ele]; !ok {
+						union[ele] = struct{}{}
+						elements = append(elements, ele)
+					}
+				}
+			}
+
+			keys := []string{"none"}
+			for i, r := range tt.sources {
+				key := "src" + strconv.Itoa(i)
+				add(key, r)
+				keys = append(keys, key)
+			}
+			if tt.dest[1] > 0 {
+				add("dest", tt.dest)
+			}
+
+			if err := db.PFMerge("dest", keys...); err != nil {
+				t.Fatalf("PFMerge: unexpected error %v", err)
+			}
+			assertHLLEncoding(t, db, "dest", tt.encoding)
+
+			expected := hllReferenceRegisters(elements)
+			regs := hllRegistersOf(t, db, "dest")
+			for i := range regs {
+				if regs[i] != expected[i] {
+					t.Fatalf("register %d: expected %d, got %d", i, expected[i], regs[i])
+				}
+			}
+
+			count, err := db.PFCount("dest")
+			if err != nil {
+				t.Fatalf("PFCount: unexpected error %v", err)
+			}
+			assertHLLError(t, count, len(union))
+			if c, _ := db.PFCount(append(keys, "dest")...); c != count {
+				t.Errorf("expected cardinality %d of the union of the keys, got %d", count, c)
+			}
+		})
+	}
+}
+
+func TestHLLInvalidValue(t *testing.T) {
+	dense := hllFromRegisters(make([]uint8, hllRegisters), true)
+	corrupted := newHLL()[:hllHeaderSize+1]
+	hllInvalidateCache(corrupted)
+	overflowed := append(newHLL(), 0x80)
+	hllInvalidateCache(overflowed)
+	short := append(newHLL()[:hllHeaderSize], 0x00)
+	hllInvalidateCache(short)
+
+	tests := []struct {
+		name  string
+		value string
+		err   error
+	}{
+		{"string", "foo", ErrNotHLL},
+		{"empty", "", ErrNotHLL},
+		{"header only", hllMagic, ErrNotHLL},
+		{"unknown encoding", "HYLL\x02" + string(make([]byte, hllHeaderSize)), ErrNotHLL},
+		{"truncated dense", string(dense[:len(dense)-1]), ErrNotHLL},
+		{"truncated sparse", string(corrupted), ErrHLLCorrupted},
+		{"overflowed sparse", string(overflowed), ErrHLLCorrupted},
+		{"short sparse", string(short), ErrHLLCorrupted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := NewDatabase()
+			db.Set("k", tt.value, 0, 0)
+			db.PFAdd("hll", "a")
+
+			if _, err := db.PFCount("k"); !errors.Is(err, tt.err) {
+				t.Errorf("PFCount: expected error %v, got %v", tt.err, err)
+			}
+			if _, err := db.PFCount("hll", "k"); !errors.Is(err, tt.err) {
+				t.Errorf("PFCount of keys: expected error %v, got %v", tt.err, err)
+			}
+			if err := db.PFMerge("hll", "k"); !errors.Is(err, tt.err) {
+				t.Errorf("PFMerge: expected error %v, got %v", tt.err, err)
+			}
+			if tt.err == ErrNotHLL {
+				if _, err := db.PFAdd("k", "a"); !errors.Is(err, tt.err) {
+					t.Errorf("PFAdd: expected error %v, got %v", tt.err, err)
+				}
+				if err := db.PFMerge("k", "hll"); !errors.Is(err, tt.err) {
+					t.Errorf("PFMerge into the key: expected error %v, got %v", tt.err, err)
+				}
+			}
+			if value, _, _ := db.Get("k"); value != tt.value {
+				t.Errorf("expected the value of the key unchanged")
+			}
+		})
+	}
+
+	db := NewDatabase()
+	db.ListPush("list", false, "a")
+	if _, err := db.PFAdd("list", "a"); !errors.Is(err, ErrWrongType) {
+		t.Errorf("PFAdd of a list: expected error %v, got %v", ErrWrongType, err)
+	}
+}
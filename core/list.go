@@ -1,17 +1,19 @@
 package core
 
+// newList returns an empty list with the list options of the database.
+func (db *Database) newList() *Quicklist {
+	return NewQuicklist(db.listMaxListpackSize, db.listCompressDepth)
+}
+
 func (db *Database) ListIndex(key string, index int) (string, bool, error) {
 	obj, err := db.lookupKey(key, TypeList, true)
 	if err != nil || obj == nil {
 		return "", false, err
 	}
 
-	list := obj.Value.(*LinkedList)
-	node := list.IndexAt(index)
-	if node == nil {
-		return "", false, nil
-	}
-	return node.Value, true, nil
+	list := obj.Value.(*Quicklist)
+	value, ok := list.Index(index)
+	return value, ok, nil
 }
 
 func (db *Database) ListLen(key string) (int, error) {
@@ -20,8 +22,8 @@ func (db *Database) ListLen(key string) (int, error) {
 		return 0, err
 	}
 
-	list := obj.Value.(*LinkedList)
-	return list.Size, nil
+	list := obj.Value.(*Quicklist)
+	return list.Len(), nil
 }
 
 func (db *Database) ListPop(key string, left bool) (string, bool, error) {
@@ -30,7 +32,7 @@ func (db *Database) ListPop(key string, left bool) (string, bool, error) {
 		return "", false, err
 	}
 
-	list := obj.Value.(*LinkedList)
+	list := obj.Value.(*Quicklist)
 	var value string
 	var ok bool
 
@@ -43,34 +45,108 @@ func (db *Database) ListPop(key string, left bool) (string, bool, error) {
 	if !ok {
 		return "", false, nil
 	}
-	if list.Size == 0 {
+	if list.Len() == 0 {
 		db.removeKey(key, obj)
+	} else {
+		db.trackMemory(key, obj)
 	}
 	return value, true, nil
 }
 
-func (db *Database) ListPush(key string, value string, left bool) (int, error) {
+// ListPush pushes the values to the head or the tail of the list one by one,
+// and returns the length of the list.
+func (db *Database) ListPush(key string, left bool, values ...string) (int, error) {
 	obj, err := db.lookupKey(key, TypeList, true)
 	if err != nil {
 		return 0, err
 	}
 
-	var list *LinkedList
 	if obj == nil {
-		list = &LinkedList{}
-		obj = &Object{
-			Type:  TypeList,
-			Value: list,
+		obj = db.newObject()
+		obj.Type = TypeList
+		obj.Value = db.newList()
+		db.setKey(key, obj)
+	}
+	list := obj.Value.(*Quicklist)
+	for _, value := range values {
+		if left {
+			list.LPush(value)
+		} else {
+			list.RPush(value)
 		}
-		db.data[key] = obj
 	}
-	list = obj.Value.(*LinkedList)
-	if left {
-		list.LPush(value)
-	} else {
-		list.RPush(value)
+	db.trackMemory(key, obj)
+	return list.Len(), nil
+}
+
+// ListPushX is like ListPush, but does nothing and returns 0 if the list does
+// not exist.
+func (db *Database) ListPushX(key string, left bool, values ...string) (int, error) {
+	obj, err := db.lookupKey(key, TypeList, true)
+	if err != nil || obj == nil {
+		return 0, err
+	}
+	return db.ListPush(key, left, values...)
+}
+
+// ListInsert inserts the value before or after the first element equal to the
+// pivot, and returns the length of the list, or -1 if the pivot is not found.
+func (db *Database) ListInsert(key string, before bool, pivot, value string) (int, error) {
+	obj, err := db.lookupKey(key, TypeList, true)
+	if err != nil || obj == nil {
+		return 0, err
+	}
+
+	list := obj.Value.(*Quicklist)
+	it := list.iterator(0, true)
+	for entry, ok := it.next(); ok; entry, ok = it.next() {
+		if string(entry) != pivot {
+			continue
+		}
+		it.insert(value, !before)
+		db.trackMemory(key, obj)
+		return list.Len(), nil
+	}
+	return -1, nil
+}
+
+// ListPos returns the indexes of up to count elements equal to the value, or
+// of all of them if count is 0, skipping the first rank-1 matches. A negative
+// rank searches from the tail. No more than maxLen elements are compared if
+// maxLen is positive.
+func (db *Database) ListPos(key, value string, rank, count, maxLen int) ([]int, error) {
+	obj, err := db.lookupKey(key, TypeList, true)
+	if err != nil || obj == nil {
+		return nil, err
+	}
+
+	list := obj.Value.(*Quicklist)
+	positions := make([]int, 0)
+	it, index, step := list.iterator(0, true), 0, 1
+	if rank < 0 {
+		rank = -rank
+		it, index, step = list.iterator(-1, false), list.Len()-1, -1
+	}
+	defer it.release()
+
+	for compared := 0; maxLen <= 0 || compared < maxLen; compared++ {
+		entry, ok := it.next()
+		if !ok {
+			break
+		}
+		if string(entry) == value {
+			if rank > 1 {
+				rank--
+			} else {
+				positions = append(positions, index)
+				if count > 0 && len(positions) == count {
+					break
+				}
+			}
+		}
+		index += step
 	}
-	return list.Size, nil
+	return positions, nil
 }
 
 func (db *Database) ListRange(key string, start int, end int) ([]string, bool, error) {
@@ -79,24 +155,28 @@ func (db *Database) ListRange(key string, start int, end int) ([]string, bool, e
 		return nil, false, err
 	}
 
-	list := obj.Value.(*LinkedList)
+	list := obj.Value.(*Quicklist)
 
 	if start < 0 {
-		start = list.Size + start
+		start = list.Len() + start
 	}
 	if start < 0 {
 		start = 0
 	}
 	if end < 0 {
-		end = list.Size + end
+		end = list.Len() + end
 	}
 
 	values := make([]string, 0)
-	node := list.IndexAt(start)
+	it := list.iterator(start, true)
+	defer it.release()
 
-	for i := start; i <= end && node != nil; i++ {
-		values = append(values, node.Value)
-		node = node.Next
+	for i := start; i <= end; i++ {
+		entry, ok := it.next()
+		if !ok {
+			break
+		}
+		values = append(values, string(entry))
 	}
 
 	return values, true, nil
@@ -108,32 +188,30 @@ func (db *Database) ListRemove(key string, count int, value string) (int64, erro
 		return 0, err
 	}
 
-	list := obj.Value.(*LinkedList)
+	list := obj.Value.(*Quicklist)
 	cnt := int64(0)
 
-	if count >= 0 {
-		for node := list.Head; node != nil && (count == 0 || cnt < int64(count)); node = node.Next {
-			if node.Value == value {
-				tmp := node.Next
-				list.RemoveNode(node)
-				cnt++
-				node = tmp
-			}
-		}
-	} else {
+	it := list.iterator(0, true)
+	if count < 0 {
 		count = -count
-		for node := list.Tail; node != nil && (count == 0 || cnt < int64(count)); node = node.Prev {
-			if node.Value == value {
-				tmp := node.Prev
-				list.RemoveNode(node)
-				cnt++
-				node = tmp
-			}
+		it = list.iterator(-1, false)
+	}
+	for count == 0 || cnt < int64(count) {
+		entry, ok := it.next()
+		if !ok {
+			break
+		}
+		if string(entry) == value {
+			it.delete()
+			cnt++
 		}
 	}
+	it.release()
 
-	if list.Size == 0 {
+	if list.Len() == 0 {
 		db.removeKey(key, obj)
+	} else {
+		db.trackMemory(key, obj)
 	}
 
 	return cnt, nil
@@ -147,8 +225,12 @@ func (db *Database) ListSet(key string, index int, value string) error {
 		return ErrNoSuchKey
 	}
 
-	list := obj.Value.(*LinkedList)
-	return list.Set(index, value)
+	list := obj.Value.(*Quicklist)
+	if err := list.Set(index, value); err != nil {
+		return err
+	}
+	db.trackMemory(key, obj)
+	return nil
 }
 
 func (db *Database) ListTrim(key string, start int, end int) error {
@@ -157,66 +239,107 @@ func (db *Database) ListTrim(key string, start int, end int) error {
 		return err
 	}
 
-	list := obj.Value.(*LinkedList)
+	list := obj.Value.(*Quicklist)
 
 	if start < 0 {
-		start = list.Size + start
+		start = list.Len() + start
 	}
 	if start < 0 {
 		start = 0
 	}
 	if end < 0 {
-		end = list.Size + end
+		end = list.Len() + end
 	}
 
-	currentIndex := 0
-	currentNode := list.Head
-
-	for currentNode != nil {
-		nextNode := currentNode.Next
-		if currentIndex < start || currentIndex > end {
-			list.RemoveNode(currentNode)
-		}
-		currentNode = nextNode
-		currentIndex++
+	if end < start {
+		list.DeleteRange(0, list.Len())
+	} else {
+		list.DeleteRange(end+1, list.Len())
+		list.DeleteRange(0, start)
 	}
 
-	if list.Size == 0 {
+	if list.Len() == 0 {
 		db.removeKey(key, obj)
+	} else {
+		db.trackMemory(key, obj)
 	}
 
 	return nil
 }
 
-func (db *Database) ListRPopLPush(sourceKey, destKey string) (string, bool, error) {
-	sourceObj, err := db.lookupKey(sourceKey, TypeList, true)
+// ListMove pops an element from the head or the tail of the source list, and
+// pushes it to the head or the tail of the destination list. The source and
+// the destination may be the same list.
+func (db *Database) ListMove(source, dest string, sourceLeft, destLeft bool) (string, bool, error) {
+	sourceObj, err := db.lookupKey(source, TypeList, true)
 	if err != nil || sourceObj == nil {
 		return "", false, err
 	}
-	destObj, err := db.lookupKey(destKey, TypeList, true)
+	destObj, err := db.lookupKey(dest, TypeList, true)
 	if err != nil {
 		return "", false, err
 	}
 
-	sourceList := sourceObj.Value.(*LinkedList)
-	value, ok := sourceList.RPop()
-	if !ok {
-		return "", false, nil
-	}
-	if sourceList.Size == 0 {
-		db.removeKey(sourceKey, sourceObj)
+	sourceList := sourceObj.Value.(*Quicklist)
+	var value string
+	if sourceLeft {
+		value, _ = sourceList.LPop()
+	} else {
+		value, _ = sourceList.RPop()
 	}
 
+	// Push before removing the empty source, which may be the destination.
 	if destObj == nil {
-		destObj = &Object{
-			Type:  TypeList,
-			Value: &LinkedList{},
-		}
-		db.data[destKey] = destObj
+		destObj = db.newObject()
+		destObj.Type = TypeList
+		destObj.Value = db.newList()
+		db.setKey(dest, destObj)
 	}
+	destList := destObj.Value.(*Quicklist)
+	if destLeft {
+		destList.LPush(value)
+	} else {
+		destList.RPush(value)
+	}
+	db.trackMemory(dest, destObj)
 
-	destList := destObj.Value.(*LinkedList)
-	destList.LPush(value)
-
+	if sourceList.Len() == 0 {
+		db.removeKey(source, sourceObj)
+	} else if source != dest {
+		db.trackMemory(source, sourceObj)
+	}
 	return value, true, nil
 }
+
+// ListMPop pops up to count elements from the head or the tail of the first
+// non-empty list of the keys, and returns the key and the popped elements. It
+// returns an empty key if all the lists are empty.
+func (db *Database) ListMPop(keys []string, left bool, count int) (string, []string, error) {
+	for _, key := range keys {
+		obj, err := db.lookupKey(key, TypeList, true)
+		if err != nil {
+			return "", nil, err
+		} else if obj == nil {
+			continue
+		}
+
+		list := obj.Value.(*Quicklist)
+		values := make([]string, 0, min(count, list.Len()))
+		for len(values) < count && list.Len() > 0 {
+			var value string
+			if left {
+				value, _ = list.LPop()
+			} else {
+				value, _ = list.RPop()
+			}
+			values = append(values, value)
+		}
+		if list.Len() == 0 {
+			db.removeKey(key, obj)
+		} else {
+			db.trackMemory(key, obj)
+		}
+		return key, values, nil
+	}
+	return "", nil, nil
+}
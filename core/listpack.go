@@ -0,0 +1,97 @@
+package core
+
+import "encoding/binary"
+
+// A listpack is a byte slice of packed entries. Every entry is the length of
+// its value as an uvarint, the value, and the backlen, which is the size of the
+// length and the value encoded to be read from its end, so the entries can be
+// walked in both directions:
+//
+//	<len> <value> <backlen>
+//
+// The bytes of the backlen hold 7 bits each with the most significant first,
+// and all bytes but the first have the high bit set.
+
+// lpEntrySize returns the bytes of an entry with the value of n bytes.
+func lpEntrySize(n int) int {
+	l := uvarintSize(uint64(n)) + n
+	return l + lpBacklenSize(l)
+}
+
+func uvarintSize(n uint64) int {
+	size := 1
+	for ; n >= 0x80; n >>= 7 {
+		size++
+	}
+	return size
+}
+
+func lpBacklenSize(l int) int {
+	return uvarintSize(uint64(l))
+}
+
+// lpPutEntry writes the entry of the value to buf, which must have the room of
+// lpEntrySize(len(value)) bytes.
+func lpPutEntry(buf []byte, value string) {
+	n := binary.PutUvarint(buf, uint64(len(value)))
+	n += copy(buf[n:], value)
+
+	size := lpBacklenSize(n)
+	for i := 0; i < size; i++ {
+		b := byte(n>>(7*(size-1-i))) & 0x7f
+		if i > 0 {
+			b |= 0x80
+		}
+		buf[n+i] = b
+	}
+}
+
+// lpEntry returns the value of the entry at the offset, and the bytes of the
+// entry.
+func lpEntry(lp []byte, offset int) ([]byte, int) {
+	n, size := binary.Uvarint(lp[offset:])
+	start := offset + size
+	l := size + int(n)
+	return lp[start : start+int(n)], l + lpBacklenSize(l)
+}
+
+// lpPrev returns the offset of the entry before the entry at the offset, which
+// must not be the first entry.
+func lpPrev(lp []byte, offset int) int {
+	l, shift := 0, 0
+	i := offset - 1
+	for {
+		b := lp[i]
+		l |= int(b&0x7f) << shift
+		if b&0x80 == 0 {
+			break
+		}
+		shift += 7
+		i--
+	}
+	return i - l
+}
+
+// lpLast returns the offset of the last entry of the non-empty listpack.
+func lpLast(lp []byte) int {
+	return lpPrev(lp, len(lp))
+}
+
+// lpSeek returns the offset of the entry at the index of the listpack with
+// count entries, walking from the nearer end.
+func lpSeek(lp []byte, count, index int) int {
+	if index < count/2 {
+		offset := 0
+		for i := 0; i < index; i++ {
+			_, size := lpEntry(lp, offset)
+			offset += size
+		}
+		return offset
+	}
+
+	offset := lpLast(lp)
+	for i := count - 1; i > index; i-- {
+		offset = lpPrev(lp, offset)
+	}
+	return offset
+}
@@ -0,0 +1,223 @@
+package core
+
+import "unsafe"
+
+const defaultMemorySamples = 5
+
+const (
+	pointerSize       = int64(unsafe.Sizeof(uintptr(0)))
+	stringHeaderSize  = int64(unsafe.Sizeof(""))
+	sliceHeaderSize   = int64(unsafe.Sizeof([]byte(nil)))
+	objectSize        = int64(unsafe.Sizeof(Object{}))
+	quicklistSize     = int64(unsafe.Sizeof(Quicklist{}))
+	quicklistNodeSize = int64(unsafe.Sizeof(quicklistNode{}))
+	dictSize          = int64(unsafe.Sizeof(Dict[struct{}]{}))
+	geoSetSize        = int64(unsafe.Sizeof(GeoSet{})) + dictSize + int64(unsafe.Sizeof(SkipList{}))
+	streamSize        = int64(unsafe.Sizeof(Stream{}))
+	streamGroupSize   = int64(unsafe.Sizeof(StreamGroup{}))
+	streamEntrySize   = int64(unsafe.Sizeof(StreamEntry{}))
+
+	// Chunks of a stream are allocated with the room of streamChunkMaxEntries
+	// entries, and referenced by the chunk slice of the stream.
+	streamChunkSize = int64(unsafe.Sizeof(streamChunk{})) + pointerSize +
+		streamChunkMaxEntries*streamEntrySize
+
+	// Entries of a Dict are allocated separately, and referenced by a bucket
+	// of its table that has at least one slot per entry.
+	dataEntrySize = int64(unsafe.Sizeof(dictEntry[*Object]{})) + pointerSize
+	setEntrySize  = int64(unsafe.Sizeof(dictEntry[struct{}]{})) + pointerSize
+
+	// Members of a geo set are also referenced by a node of its skip list,
+	// which has 1/(1-p) = 4/3 levels on average.
+	geoEntrySize = int64(unsafe.Sizeof(dictEntry[uint64]{})) + pointerSize +
+		int64(unsafe.Sizeof(skipListNode{})) + pointerSize*4/3
+
+	// Pending entries of a consumer group are referenced by the maps of the
+	// group and of the consumer, and by the sorted IDs of the group.
+	streamNACKSize = int64(unsafe.Sizeof(streamNACK{})) +
+		(int64(unsafe.Sizeof(StreamID{}))+pointerSize+1)*16/13*2 + int64(unsafe.Sizeof(StreamID{}))
+	streamConsumerSize = int64(unsafe.Sizeof(StreamConsumer{})) + (stringHeaderSize+pointerSize+1)*16/13
+
+	// Go maps store entries in buckets of eight slots with one byte of hash per
+	// slot, and grow when the average load reaches 6.5 entries per bucket, so
+	// every entry costs about 8/6.5 = 16/13 of its slot. An expiration is also
+	// referenced by a slot of the expiration heap.
+	expiresEntrySize = (stringHeaderSize+pointerSize+1)*16/13 +
+		int64(unsafe.Sizeof(expireEntry{})) + pointerSize
+)
+
+// memoryUsage estimates the bytes used by the object and its key. Aggregate
+// values are estimated by sampling up to samples elements, or all elements if
+// samples is not positive.
+func (obj *Object) memoryUsage(key string, samples int) int64 {
+	size := dataEntrySize + int64(len(key)) + objectSize
+	if obj.Expires != 0 {
+		size += expiresEntrySize
+	}
+
+	switch obj.Type {
+	case TypeString:
+		size += stringMemoryUsage(obj)
+	case TypeList:
+		size += listMemoryUsage(obj.Value.(*Quicklist), samples)
+	case TypeSet:
+		size += setMemoryUsage(obj.Value.(*Dict[struct{}]), samples)
+	case TypeGeo:
+		size += geoMemoryUsage(obj.Value.(*GeoSet), samples)
+	case TypeStream:
+		size += streamMemoryUsage(obj.Value.(*Stream), samples)
+	}
+
+	return size
+}
+
+func stringMemoryUsage(obj *Object) int64 {
+	switch obj.Encoding {
+	case EncodingInt:
+		return 8
+	case EncodingRaw:
+		return stringHeaderSize + int64(len(obj.Value.(string)))
+	case EncodingBytes:
+		return sliceHeaderSize + int64(cap(obj.Value.([]byte)))
+	}
+	return 0
+}
+
+// listMemoryUsage estimates the bytes of the list by sampling its nodes from
+// the head, whose listpacks may be compressed.
+func listMemoryUsage(list *Quicklist, samples int) int64 {
+	size := quicklistSize + int64(list.nodes)*quicklistNodeSize
+	if list.nodes == 0 {
+		return size
+	}
+
+	sampled := 0
+	bytes := int64(0)
+	for node := list.head; node != nil && (samples <= 0 || sampled < samples); node = node.next {
+		bytes += int64(cap(node.entries))
+		sampled++
+	}
+
+	return size + bytes*int64(list.nodes)/int64(sampled)
+}
+
+func setMemoryUsage(set *Dict[struct{}], samples int) int64 {
+	size := dictSize + int64(set.Len())*setEntrySize
+	if set.Len() == 0 {
+		return size
+	}
+
+	if samples <= 0 {
+		samples = set.Len()
+	}
+	sampled := 0
+	bytes := int64(0)
+	set.Sample(samples, func(member string, _ struct{}) bool {
+		bytes += int64(len(member))
+		sampled++
+		return true
+	})
+
+	return size + bytes*int64(set.Len())/int64(sampled)
+}
+
+func geoMemoryUsage(gs *GeoSet, samples int) int64 {
+	size := geoSetSize + int64(gs.Len())*geoEntrySize
+	if gs.Len() == 0 {
+		return size
+	}
+
+	if samples <= 0 {
+		samples = gs.Len()
+	}
+	sampled := 0
+	bytes := int64(0)
+	gs.dict.Sample(samples, func(member string, _ uint64) bool {
+		bytes += int64(len(member))
+		sampled++
+		return true
+	})
+
+	return size + bytes*int64(gs.Len())/int64(sampled)
+}
+
+func streamMemoryUsage(s *Stream, samples int) int64 {
+	size := streamSize + int64(len(s.chunks))*streamChunkSize
+	for _, g := range s.groups {
+		size += streamGroupSize + int64(len(g.name)) + int64(len(g.pending))*streamNACKSize
+		for _, c := range g.consumers {
+			size += streamConsumerSize + int64(len(c.name))
+		}
+	}
+	if s.length == 0 {
+		return size
+	}
+
+	sampled := 0
+	bytes := int64(0)
+	s.rangeEntries(StreamID{}, MaxStreamID, false, func(entry *StreamEntry) bool {
+		bytes += int64(len(entry.Fields)) * stringHeaderSize
+		for _, field := range entry.Fields {
+			bytes += int64(len(field))
+		}
+		sampled++
+		return samples <= 0 || sampled < samples
+	})
+
+	return size + bytes*int64(s.length)/int64(sampled)
+}
+
+type KeyMemoryUsage struct {
+	Key   string
+	Type  ObjectType
+	Bytes int64
+}
+
+// MemoryUsage estimates the bytes used by the key and its value, without
+// touching the access time of the key.
+func (db *Database) MemoryUsage(key string, samples int) (int64, bool) {
+	obj, found := db.data.Get(key)
+	if !found || obj.IsExpired() {
+		return 0, false
+	}
+
+	return obj.memoryUsage(key, samples), true
+}
+
+// Overhead returns the estimated bytes used by the main dictionary and the
+// expires dictionary of the database, excluding the keys and values. It is safe
+// to be called from any goroutine.
+func (db *Database) Overhead() (int64, int64) {
+	main := db.Size() * (dataEntrySize + objectSize)
+	expires := db.ExpiresSize() * expiresEntrySize
+	return main, expires
+}
+
+// BigKeys scans the whole database, and returns up to count keys that use the
+// most memory in descending order.
+func (db *Database) BigKeys(count int, samples int) []KeyMemoryUsage {
+	keys := make([]KeyMemoryUsage, 0, count)
+
+	db.data.Range(func(key string, obj *Object) bool {
+		if obj.IsExpired() {
+			return true
+		}
+
+		usage := KeyMemoryUsage{Key: key, Type: obj.Type, Bytes: obj.memoryUsage(key, samples)}
+		i := len(keys)
+		for i > 0 && keys[i-1].Bytes < usage.Bytes {
+			i--
+		}
+		if i >= count {
+			return true
+		}
+		if len(keys) < count {
+			keys = append(keys, KeyMemoryUsage{})
+		}
+		copy(keys[i+1:], keys[i:len(keys)-1])
+		keys[i] = usage
+		return true
+	})
+
+	return keys
+}
@@ -1,15 +1,28 @@
 package core
 
 import (
+	"math/rand"
 	"strconv"
 	"time"
 )
 
+const (
+	lfuInitValue  = 5
+	lfuLogFactor  = 10
+	lfuDecayMilli = 60 * 1000
+)
+
 type Object struct {
-	Type     ObjectType
-	Encoding ObjectEncoding
-	Value    any
-	Expires  int64
+	Type       ObjectType
+	Encoding   ObjectEncoding
+	Value      any
+	Expires    int64
+	AccessTime int64
+	Frequency  uint8
+
+	// size is the estimated memory usage of the object last accounted into the
+	// database, including the key and the expires entry.
+	size int64
 }
 
 func (obj *Object) IsExpired() bool {
@@ -20,6 +33,58 @@ func (obj *Object) IsExpired() bool {
 	return obj.Expires < time.Now().UnixMilli()
 }
 
+// Touch updates the access time and the logarithmic access frequency counter of
+// the object.
+func (obj *Object) Touch(now int64) {
+	counter := obj.DecayedFrequency(now)
+	if counter < 255 {
+		baseVal := float64(0)
+		if counter > lfuInitValue {
+			baseVal = float64(counter - lfuInitValue)
+		}
+		p := 1.0 / (baseVal*lfuLogFactor + 1)
+		if rand.Float64() < p {
+			counter++
+		}
+	}
+
+	obj.Frequency = counter
+	obj.AccessTime = now
+}
+
+// DecayedFrequency returns the access frequency counter of the object after
+// decrementing it by one for every decay period elapsed since the last access.
+func (obj *Object) DecayedFrequency(now int64) uint8 {
+	if obj.AccessTime == 0 {
+		return lfuInitValue
+	}
+
+	periods := (now - obj.AccessTime) / lfuDecayMilli
+	if periods <= 0 {
+		return obj.Frequency
+	} else if periods >= int64(obj.Frequency) {
+		return 0
+	}
+	return obj.Frequency - uint8(periods)
+}
+
+// encoding returns the encoding of the value, which is derived from the value
+// for the types other than string.
+func (obj *Object) encoding() ObjectEncoding {
+	switch obj.Type {
+	case TypeList:
+		return obj.Value.(*Quicklist).Encoding()
+	case TypeSet:
+		return EncodingHashtable
+	case TypeGeo:
+		return EncodingSkiplist
+	case TypeStream:
+		return EncodingStream
+	}
+
+	return obj.Encoding
+}
+
 func (obj *Object) SetStringValue(val string) {
 	if intVal, err := strconv.ParseInt(val, 10, 64); err == nil {
 		obj.Value = intVal
@@ -41,11 +106,23 @@ func (obj *Object) StringValue() string {
 		return obj.Value.(string)
 	case EncodingInt:
 		return strconv.FormatInt(obj.Value.(int64), 10)
+	case EncodingBytes:
+		return string(obj.Value.([]byte))
 	}
 
 	return ""
 }
 
+// mutableBytes converts the string value of the object into the bytes encoding
+// if it is not, and returns the bytes to be modified in place.
+func (obj *Object) mutableBytes() []byte {
+	if obj.Encoding != EncodingBytes {
+		obj.Value = []byte(obj.StringValue())
+		obj.Encoding = EncodingBytes
+	}
+	return obj.Value.([]byte)
+}
+
 func (obj *Object) IntValue() (int64, error) {
 	if obj == nil || obj.Type != TypeString {
 		return 0, ErrWrongType
@@ -60,6 +137,12 @@ func (obj *Object) IntValue() (int64, error) {
 		return val, nil
 	case EncodingInt:
 		return obj.Value.(int64), nil
+	case EncodingBytes:
+		val, err := strconv.ParseInt(string(obj.Value.([]byte)), 10, 64)
+		if err != nil {
+			return 0, ErrNotInteger
+		}
+		return val, nil
 	}
 
 	return 0, ErrNotInteger
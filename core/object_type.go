@@ -1,5 +1,7 @@
 package core
 
+import "strings"
+
 type ObjectType int
 
 const (
@@ -7,6 +9,8 @@ const (
 	TypeString
 	TypeList
 	TypeSet
+	TypeGeo
+	TypeStream
 )
 
 func (t ObjectType) String() string {
@@ -17,14 +21,66 @@ func (t ObjectType) String() string {
 		return "list"
 	case TypeSet:
 		return "set"
+	case TypeGeo:
+		return "geo"
+	case TypeStream:
+		return "stream"
 	}
 
 	return "unknown"
 }
 
+func ParseObjectType(name string) (ObjectType, bool) {
+	switch strings.ToLower(name) {
+	case "string":
+		return TypeString, true
+	case "list":
+		return TypeList, true
+	case "set":
+		return TypeSet, true
+	case "geo":
+		return TypeGeo, true
+	case "stream":
+		return TypeStream, true
+	}
+
+	return TypeNone, false
+}
+
 type ObjectEncoding int
 
 const (
 	EncodingRaw ObjectEncoding = iota
 	EncodingInt
+	// EncodingBytes is a mutable byte slice, which a string is converted into
+	// when it is modified in place, like by APPEND or SETRANGE.
+	EncodingBytes
+	// EncodingListpack is a list of a single packed node, which becomes an
+	// EncodingQuicklist list when it grows into more nodes.
+	EncodingListpack
+	EncodingQuicklist
+	EncodingHashtable
+	EncodingSkiplist
+	EncodingStream
 )
+
+func (e ObjectEncoding) String() string {
+	switch e {
+	case EncodingRaw, EncodingBytes:
+		return "raw"
+	case EncodingInt:
+		return "int"
+	case EncodingListpack:
+		return "listpack"
+	case EncodingQuicklist:
+		return "quicklist"
+	case EncodingHashtable:
+		return "hashtable"
+	case EncodingSkiplist:
+		return "skiplist"
+	case EncodingStream:
+		return "stream"
+	}
+
+	return "unknown"
+}
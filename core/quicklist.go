@@ -0,0 +1,592 @@
+package core
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+	"sync"
+)
+
+const (
+	// DefaultListMaxListpackSize limits the nodes of a quicklist to 8 KB.
+	DefaultListMaxListpackSize = -2
+	// DefaultListCompressDepth disables the compression of quicklists.
+	DefaultListCompressDepth = 0
+)
+
+const (
+	// quicklistSafetyLimit limits the bytes of a node when the fill of the list
+	// is a number of entries, so a node of large entries can not grow unbounded.
+	quicklistSafetyLimit = 8192
+
+	// Nodes smaller than quicklistMinCompressBytes are never compressed, and a
+	// node is compressed only if it saves quicklistMinCompressGain bytes.
+	quicklistMinCompressBytes = 48
+	quicklistMinCompressGain  = 8
+)
+
+// quicklistSizeLimits are the bytes limits of a node for the negative fills
+// from -1 to -5.
+var quicklistSizeLimits = [...]int{4096, 8192, 16384, 32768, 65536}
+
+var flateWriters = sync.Pool{
+	New: func() any {
+		w, _ := flate.NewWriter(nil, flate.BestSpeed)
+		return w
+	},
+}
+
+// Quicklist is a doubly linked list of listpacks. The fill limits every node to
+// the number of entries if it is positive, or to the bytes of the limit for the
+// negative fill from -1 (4 KB) to -5 (64 KB). The nodes but compressDepth nodes
+// from both ends are compressed if compressDepth is positive.
+type Quicklist struct {
+	head          *quicklistNode
+	tail          *quicklistNode
+	count         int
+	nodes         int
+	fill          int
+	compressDepth int
+}
+
+type quicklistNode struct {
+	prev *quicklistNode
+	next *quicklistNode
+	// entries is the listpack of the node, or the DEFLATE compressed listpack
+	// if the node is compressed.
+	entries    []byte
+	count      int
+	size       int
+	compressed bool
+}
+
+// quicklistIter iterates the entries of a quicklist from an index in either
+// direction, and can delete the current entry, or insert an entry next to it.
+type quicklistIter struct {
+	ql      *Quicklist
+	node    *quicklistNode
+	offset  int
+	size    int
+	forward bool
+	// pending is true if the entry at the offset is not returned yet.
+	pending bool
+	// deleted is true if any entry of the node is deleted, and behindDeleted
+	// is true if any entry of the node behind it is deleted, so the node may be
+	// merged with the node behind when the iterator leaves it.
+	deleted       bool
+	behindDeleted bool
+}
+
+func NewQuicklist(fill, compressDepth int) *Quicklist {
+	if fill == 0 {
+		fill = 1
+	} else if fill < -len(quicklistSizeLimits) {
+		fill = -len(quicklistSizeLimits)
+	}
+	return &Quicklist{fill: fill, compressDepth: max(compressDepth, 0)}
+}
+
+func (ql *Quicklist) Len() int {
+	return ql.count
+}
+
+// Encoding returns EncodingListpack if the list has a single node, or
+// EncodingQuicklist otherwise.
+func (ql *Quicklist) Encoding() ObjectEncoding {
+	if ql.nodes <= 1 {
+		return EncodingListpack
+	}
+	return EncodingQuicklist
+}
+
+func (ql *Quicklist) LPush(value string) {
+	if ql.allowInsert(ql.head, len(value)) {
+		ql.head.insertAt(0, value)
+		ql.compress(ql.head)
+	} else {
+		node := &quicklistNode{}
+		node.insertAt(0, value)
+		ql.linkAfter(nil, node)
+	}
+	ql.count++
+}
+
+func (ql *Quicklist) RPush(value string) {
+	if ql.allowInsert(ql.tail, len(value)) {
+		ql.tail.insertAt(ql.tail.size, value)
+		ql.compress(ql.tail)
+	} else {
+		node := &quicklistNode{}
+		node.insertAt(0, value)
+		ql.linkAfter(ql.tail, node)
+	}
+	ql.count++
+}
+
+func (ql *Quicklist) LPop() (string, bool) {
+	if ql.count == 0 {
+		return "", false
+	}
+	return ql.pop(ql.head, 0), true
+}
+
+func (ql *Quicklist) RPop() (string, bool) {
+	if ql.count == 0 {
+		return "", false
+	}
+	return ql.pop(ql.tail, lpLast(ql.tail.listpack())), true
+}
+
+func (ql *Quicklist) pop(node *quicklistNode, offset int) string {
+	value, size := lpEntry(node.listpack(), offset)
+	s := string(value)
+	node.removeRange(offset, offset+size, 1)
+	ql.count--
+	if node.count == 0 {
+		ql.unlink(node)
+	} else {
+		ql.compress(node)
+	}
+	return s
+}
+
+// Index returns the entry at the index, where a negative index counts from the
+// tail.
+func (ql *Quicklist) Index(index int) (string, bool) {
+	node, i := ql.locate(index)
+	if node == nil {
+		return "", false
+	}
+	lp := node.listpack()
+	value, _ := lpEntry(lp, lpSeek(lp, node.count, i))
+	s := string(value)
+	ql.compress(node)
+	return s, true
+}
+
+// Set replaces the entry at the index, where a negative index counts from the
+// tail.
+func (ql *Quicklist) Set(index int, value string) error {
+	node, i := ql.locate(index)
+	if node == nil {
+		return ErrOutOfRange
+	}
+	lp := node.listpack()
+	offset := lpSeek(lp, node.count, i)
+	_, size := lpEntry(lp, offset)
+	node.removeRange(offset, offset+size, 1)
+	node.insertAt(offset, value)
+	ql.split(node)
+	ql.compress(node)
+	return nil
+}
+
+// DeleteRange deletes up to n entries from the index, where a negative index
+// counts from the tail. The nodes at both sides of the deleted entries are
+// merged if they fit the fill together.
+func (ql *Quicklist) DeleteRange(index, n int) {
+	node, i := ql.locate(index)
+	if node == nil || n <= 0 {
+		return
+	}
+	left := node.prev
+	if i > 0 {
+		left = node
+	}
+
+	for n > 0 && node != nil {
+		next := node.next
+		if i == 0 && n >= node.count {
+			n -= node.count
+			ql.count -= node.count
+			ql.unlink(node)
+		} else {
+			lp := node.listpack()
+			cnt := min(n, node.count-i)
+			start := lpSeek(lp, node.count, i)
+			end := start
+			for j := 0; j < cnt; j++ {
+				_, size := lpEntry(lp, end)
+				end += size
+			}
+			node.removeRange(start, end, cnt)
+			n -= cnt
+			ql.count -= cnt
+			ql.compress(node)
+		}
+		node, i = next, 0
+	}
+	ql.mergeNext(left)
+}
+
+// locate returns the node of the entry at the index, and the index of the
+// entry in the node, walking from the nearer end of the list.
+func (ql *Quicklist) locate(index int) (*quicklistNode, int) {
+	if index < 0 {
+		index += ql.count
+	}
+	if index < 0 || index >= ql.count {
+		return nil, 0
+	}
+
+	if index < ql.count/2 {
+		node := ql.head
+		for index >= node.count {
+			index -= node.count
+			node = node.next
+		}
+		return node, index
+	}
+
+	node := ql.tail
+	index = ql.count - 1 - index
+	for index >= node.count {
+		index -= node.count
+		node = node.prev
+	}
+	return node, node.count - 1 - index
+}
+
+// allowInsert returns true if an entry of n bytes fits the node.
+func (ql *Quicklist) allowInsert(node *quicklistNode, n int) bool {
+	if node == nil {
+		return false
+	}
+	size := node.size + lpEntrySize(n)
+	if ql.fill < 0 {
+		return size <= quicklistSizeLimits[-ql.fill-1]
+	}
+	return node.count < ql.fill && size <= quicklistSafetyLimit
+}
+
+// allowMerge returns true if the entries of both nodes fit a node.
+func (ql *Quicklist) allowMerge(a, b *quicklistNode) bool {
+	size := a.size + b.size
+	if ql.fill < 0 {
+		return size <= quicklistSizeLimits[-ql.fill-1]
+	}
+	return a.count+b.count <= ql.fill && size <= quicklistSafetyLimit
+}
+
+// exceeds returns true if the node of more than one entry exceeds the fill.
+func (ql *Quicklist) exceeds(node *quicklistNode) bool {
+	if node.count <= 1 {
+		return false
+	}
+	if ql.fill < 0 {
+		return node.size > quicklistSizeLimits[-ql.fill-1]
+	}
+	return node.count > ql.fill || node.size > quicklistSafetyLimit
+}
+
+// split splits the node into halves if it exceeds the fill, and splits the
+// halves again until none of them exceeds the fill, as a half may still exceed
+// it by a large entry.
+func (ql *Quicklist) split(node *quicklistNode) {
+	if !ql.exceeds(node) {
+		return
+	}
+
+	lp := node.listpack()
+	offset, half := 0, node.count/2
+	for i := 0; i < half; i++ {
+		_, size := lpEntry(lp, offset)
+		offset += size
+	}
+
+	newNode := &quicklistNode{
+		entries: append([]byte(nil), lp[offset:]...),
+		count:   node.count - half,
+		size:    len(lp) - offset,
+	}
+	node.entries = append([]byte(nil), lp[:offset]...)
+	node.count = half
+	node.size = offset
+	ql.linkAfter(node, newNode)
+	ql.split(newNode)
+	ql.split(node)
+	ql.compress(node)
+}
+
+// mergeNext merges the next node into the node if they fit the fill together.
+func (ql *Quicklist) mergeNext(node *quicklistNode) {
+	if node == nil || node.next == nil || !ql.allowMerge(node, node.next) {
+		return
+	}
+
+	next := node.next
+	node.entries = append(node.listpack(), next.listpack()...)
+	node.count += next.count
+	node.size = len(node.entries)
+	ql.unlink(next)
+	ql.compress(node)
+}
+
+// linkAfter links the new node after the node, or as the head if the node is
+// nil.
+func (ql *Quicklist) linkAfter(node, newNode *quicklistNode) {
+	newNode.prev = node
+	if node == nil {
+		newNode.next = ql.head
+		ql.head = newNode
+	} else {
+		newNode.next = node.next
+		node.next = newNode
+	}
+	if newNode.next == nil {
+		ql.tail = newNode
+	} else {
+		newNode.next.prev = newNode
+	}
+	ql.nodes++
+	ql.compress(newNode)
+}
+
+func (ql *Quicklist) unlink(node *quicklistNode) {
+	if node.prev == nil {
+		ql.head = node.next
+	} else {
+		node.prev.next = node.next
+	}
+	if node.next == nil {
+		ql.tail = node.prev
+	} else {
+		node.next.prev = node.prev
+	}
+	ql.nodes--
+	ql.compress(nil)
+}
+
+// compress keeps compressDepth nodes from both ends uncompressed, and
+// compresses the node if it is not one of them, and the nodes right after the
+// uncompressed ends.
+func (ql *Quicklist) compress(node *quicklistNode) {
+	if ql.compressDepth == 0 || ql.nodes < ql.compressDepth*2 {
+		return
+	}
+
+	forward, reverse := ql.head, ql.tail
+	inDepth := false
+	for depth := 0; depth < ql.compressDepth; depth++ {
+		forward.decompress()
+		reverse.decompress()
+		if forward == node || reverse == node {
+			inDepth = true
+		}
+		if forward == reverse || forward.next == reverse {
+			return
+		}
+		forward, reverse = forward.next, reverse.prev
+	}
+
+	if node != nil && !inDepth {
+		node.compress()
+	}
+	forward.compress()
+	reverse.compress()
+}
+
+// iterator returns an iterator from the entry at the index, where a negative
+// index counts from the tail. The iterator must be released if it is stopped
+// before the end of the list.
+func (ql *Quicklist) iterator(index int, forward bool) *quicklistIter {
+	it := &quicklistIter{ql: ql, forward: forward, pending: true}
+	node, i := ql.locate(index)
+	if node != nil {
+		lp := node.listpack()
+		it.node = node
+		it.offset = lpSeek(lp, node.count, i)
+	}
+	return it
+}
+
+// next returns the next entry, which is valid until the list is modified.
+func (it *quicklistIter) next() ([]byte, bool) {
+	if !it.pending {
+		it.advance()
+	}
+	it.pending = false
+	if it.node == nil {
+		return nil, false
+	}
+	value, size := lpEntry(it.node.listpack(), it.offset)
+	it.size = size
+	return value, true
+}
+
+func (it *quicklistIter) advance() {
+	node := it.node
+	if node == nil {
+		return
+	}
+	if it.forward {
+		if offset := it.offset + it.size; offset < node.size {
+			it.offset = offset
+			return
+		}
+		it.moveTo(node.next)
+	} else {
+		if it.offset > 0 {
+			it.offset = lpPrev(node.listpack(), it.offset)
+			return
+		}
+		it.moveTo(node.prev)
+	}
+}
+
+// moveTo leaves the current node, and moves to the first entry of the node in
+// the direction of the iterator. The left node is merged with the node behind
+// the iterator if any entry of either of them is deleted.
+func (it *quicklistIter) moveTo(node *quicklistNode) {
+	if it.node != nil {
+		it.ql.compress(it.node)
+		if it.deleted || it.behindDeleted {
+			it.mergeBehind(it.node)
+		}
+	}
+	it.node, it.offset, it.size = node, 0, 0
+	it.deleted, it.behindDeleted = false, it.deleted
+	if node != nil && !it.forward {
+		it.offset = lpLast(node.listpack())
+	}
+}
+
+// delete deletes the current entry, and the next call of next returns the
+// entry after it.
+func (it *quicklistIter) delete() {
+	node := it.node
+	node.removeRange(it.offset, it.offset+it.size, 1)
+	it.ql.count--
+	it.size = 0
+	it.deleted = true
+
+	if node.count > 0 {
+		// The next entry of a forward iterator is at the offset now, and the
+		// next entry of a backward iterator is still before the offset.
+		switch {
+		case it.forward && it.offset < node.size:
+			it.pending = true
+		case it.forward:
+			it.moveTo(node.next)
+			it.pending = true
+		case it.offset == 0:
+			it.moveTo(node.prev)
+			it.pending = true
+		}
+		return
+	}
+
+	next := node.next
+	if !it.forward {
+		next = node.prev
+	}
+	it.node = nil
+	it.ql.unlink(node)
+	it.moveTo(next)
+	it.pending = true
+}
+
+// insert inserts the value before or after the current entry, and releases
+// the iterator.
+func (it *quicklistIter) insert(value string, after bool) {
+	node := it.node
+	offset := it.offset
+	if after {
+		offset += it.size
+	}
+	node.insertAt(offset, value)
+	it.ql.count++
+	it.ql.split(node)
+	it.ql.compress(node)
+	it.node = nil
+}
+
+// release recompresses the current node, and merges it with the nodes at both
+// sides if any of its entries is deleted, or with the node behind if any entry
+// of that node is deleted.
+func (it *quicklistIter) release() {
+	node := it.node
+	if node == nil {
+		return
+	}
+	it.node = nil
+	it.ql.compress(node)
+
+	if it.deleted {
+		it.ql.mergeNext(node)
+		it.ql.mergeNext(node.prev)
+	} else if it.behindDeleted {
+		it.mergeBehind(node)
+	}
+}
+
+// mergeBehind merges the node with the node behind the iterator.
+func (it *quicklistIter) mergeBehind(node *quicklistNode) {
+	if it.forward {
+		it.ql.mergeNext(node.prev)
+	} else {
+		it.ql.mergeNext(node)
+	}
+}
+
+// listpack returns the listpack of the node, decompressing it if it is
+// compressed.
+func (node *quicklistNode) listpack() []byte {
+	node.decompress()
+	return node.entries
+}
+
+func (node *quicklistNode) insertAt(offset int, value string) {
+	lp := node.listpack()
+	size := lpEntrySize(len(value))
+	lp = append(lp, make([]byte, size)...)
+	copy(lp[offset+size:], lp[offset:len(lp)-size])
+	lpPutEntry(lp[offset:], value)
+	node.entries = lp
+	node.count++
+	node.size = len(lp)
+}
+
+func (node *quicklistNode) removeRange(start, end, cnt int) {
+	lp := node.listpack()
+	lp = append(lp[:start], lp[end:]...)
+	// Release the room of the removed entries if most of it is unused.
+	if cap(lp) > 4*len(lp)+quicklistMinCompressBytes {
+		lp = append([]byte(nil), lp...)
+	}
+	node.entries = lp
+	node.count -= cnt
+	node.size = len(lp)
+}
+
+func (node *quicklistNode) compress() {
+	if node.compressed || node.size < quicklistMinCompressBytes {
+		return
+	}
+
+	var buf bytes.Buffer
+	w := flateWriters.Get().(*flate.Writer)
+	w.Reset(&buf)
+	w.Write(node.entries)
+	w.Close()
+	flateWriters.Put(w)
+
+	if buf.Len()+quicklistMinCompressGain > node.size {
+		return
+	}
+	node.entries = append([]byte(nil), buf.Bytes()...)
+	node.compressed = true
+}
+
+func (node *quicklistNode) decompress() {
+	if !node.compressed {
+		return
+	}
+
+	lp := make([]byte, node.size)
+	r := flate.NewReader(bytes.NewReader(node.entries))
+	io.ReadFull(r, lp)
+	r.Close()
+	node.entries = lp
+	node.compressed = false
+}
@@ -0,0 +1,500 @@
+package core
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// quicklistNodeEntries decodes the entries of the node without decompressing
+// the node.
+func quicklistNodeEntries(t *testing.T, node *quicklistNode) []string {
+	t.Helper()
+
+	lp := node.entries
+	if node.compressed {
+		r := flate.NewReader(bytes.NewReader(node.entries))
+		defer r.Close()
+		var err error
+		if lp, err = io.ReadAll(r); err != nil {
+			t.Fatalf("failed to decompress node: %v", err)
+		}
+	}
+	if len(lp) != node.size {
+		t.Fatalf("expected node size %d, got %d", node.size, len(lp))
+	}
+
+	entries := make([]string, 0, node.count)
+	for offset := 0; offset < len(lp); {
+		value, size := lpEntry(lp, offset)
+		entries = append(entries, string(value))
+		offset += size
+	}
+	if len(entries) != node.count {
+		t.Fatalf("expected %d entries of node, got %d", node.count, len(entries))
+	}
+	return entries
+}
+
+// checkQuicklist checks the entries of the list, the links and the fill of its
+// nodes, and that the nodes but compressDepth nodes from both ends are
+// compressed.
+func checkQuicklist(t *testing.T, ql *Quicklist, expected []string) {
+	t.Helper()
+
+	entries := make([]string, 0, ql.count)
+	nodes := 0
+	var prev *quicklistNode
+	for node := ql.head; node != nil; prev, node = node, node.next {
+		if node.prev != prev {
+			t.Fatalf("node %d: unexpected previous node", nodes)
+		}
+		if node.count == 0 {
+			t.Fatalf("node %d: unexpected empty node", nodes)
+		}
+		if ql.exceeds(node) {
+			t.Fatalf("node %d: %d entries of %d bytes exceed fill %d", nodes, node.count, node.size, ql.fill)
+		}
+		entries = append(entries, quicklistNodeEntries(t, node)...)
+
+		// A node out of the depth is left uncompressed only if it is too small
+		// or does not gain enough, which compressing a copy of it tells.
+		compressible := *node
+		compressible.compress()
+		inDepth := nodes < ql.compressDepth || ql.nodes-nodes <= ql.compressDepth
+		switch {
+		case ql.compressDepth == 0 && node.compressed:
+			t.Fatalf("node %d: unexpected compressed node without compress depth", nodes)
+		case inDepth && ql.nodes >= ql.compressDepth*2 && node.compressed:
+			t.Fatalf("node %d of %d: unexpected compressed node in depth %d", nodes, ql.nodes, ql.compressDepth)
+		case ql.compressDepth > 0 && !inDepth && compressible.compressed && !node.compressed:
+			t.Fatalf("node %d of %d: expected compressed node of %d bytes out of depth %d",
+				nodes, ql.nodes, node.size, ql.compressDepth)
+		}
+		nodes++
+	}
+	if ql.tail != prev {
+		t.Fatalf("unexpected tail node")
+	}
+	if nodes != ql.nodes {
+		t.Fatalf("expected %d nodes, got %d", ql.nodes, nodes)
+	}
+	if ql.count != len(expected) || len(entries) != len(expected) {
+		t.Fatalf("expected %d entries, got %d of %d", len(expected), len(entries), ql.count)
+	}
+	for i := range expected {
+		if entries[i] != expected[i] {
+			t.Fatalf("entry %d: expected %q, got %q", i, expected[i], entries[i])
+		}
+	}
+}
+
+// quicklistNodeCounts returns the number of entries of every node.
+func quicklistNodeCounts(ql *Quicklist) []int {
+	counts := make([]int, 0, ql.nodes)
+	for node := ql.head; node != nil; node = node.next {
+		counts = append(counts, node.count)
+	}
+	return counts
+}
+
+func assertNodeCounts(t *testing.T, ql *Quicklist, expected ...int) {
+	t.Helper()
+
+	counts := quicklistNodeCounts(ql)
+	if len(counts) != len(expected) {
+		t.Fatalf("expected nodes of %v entries, got %v", expected, counts)
+	}
+	for i := range counts {
+		if counts[i] != expected[i] {
+			t.Fatalf("expected nodes of %v entries, got %v", expected, counts)
+		}
+	}
+}
+
+func quicklistValues(prefix string, n int) []string {
+	values := make([]string, n)
+	for i := range values {
+		values[i] = prefix + strconv.Itoa(i)
+	}
+	return values
+}
+
+func TestQuicklistFill(t *testing.T) {
+	tests := []struct {
+		name string
+		fill int
+		size int
+		// entries is the number of entries of the size that fit a node.
+		entries int
+	}{
+		{"one entry", 1, 10, 1},
+		{"entries", 4, 10, 4},
+		{"zero fill", 0, 10, 1},
+		{"safety limit", 1000, 1000, quicklistSafetyLimit / lpEntrySize(1000)},
+		{"4 KB", -1, 100, 4096 / lpEntrySize(100)},
+		{"8 KB", -2, 100, 8192 / lpEntrySize(100)},
+		{"16 KB", -3, 1000, 16384 / lpEntrySize(1000)},
+		{"64 KB", -5, 1000, 65536 / lpEntrySize(1000)},
+		{"beyond 64 KB", -10, 1000, 65536 / lpEntrySize(1000)},
+		{"large entry", -1, 5000, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value := strings.Repeat("x", tt.size)
+			for _, left := range []bool{false, true} {
+				ql := NewQuicklist(tt.fill, 0)
+				expected := make([]string, 0)
+				for i := 0; i < tt.entries; i++ {
+					if left {
+						ql.LPush(value)
+					} else {
+						ql.RPush(value)
+					}
+					expected = append(expected, value)
+				}
+				checkQuicklist(t, ql, expected)
+				if encoding := ql.Encoding(); encoding != EncodingListpack {
+					t.Fatalf("expected encoding %v of %d entries, got %v", EncodingListpack, tt.entries, encoding)
+				}
+
+				if left {
+					ql.LPush(value)
+				} else {
+					ql.RPush(value)
+				}
+				checkQuicklist(t, ql, append(expected, value))
+				if encoding := ql.Encoding(); encoding != EncodingQuicklist {
+					t.Fatalf("expected encoding %v of %d entries, got %v", EncodingQuicklist, tt.entries+1, encoding)
+				}
+				if left {
+					assertNodeCounts(t, ql, 1, tt.entries)
+				} else {
+					assertNodeCounts(t, ql, tt.entries, 1)
+				}
+
+				// Popping the single entry of the new node leaves a single node.
+				if left {
+					ql.LPop()
+				} else {
+					ql.RPop()
+				}
+				checkQuicklist(t, ql, expected)
+				if encoding := ql.Encoding(); encoding != EncodingListpack {
+					t.Fatalf("expected encoding %v after popped, got %v", EncodingListpack, encoding)
+				}
+			}
+		})
+	}
+}
+
+func TestQuicklistSplit(t *testing.T) {
+	ql := NewQuicklist(4, 0)
+	expected := quicklistValues("v", 8)
+	for _, value := range expected {
+		ql.RPush(value)
+	}
+	assertNodeCounts(t, ql, 4, 4)
+
+	// Inserting into a full node splits it into halves.
+	it := ql.iterator(1, true)
+	it.next()
+	it.insert("a", true)
+	expected = append(expected[:2], append([]string{"a"}, expected[2:]...)...)
+	checkQuicklist(t, ql, expected)
+	assertNodeCounts(t, ql, 2, 3, 4)
+
+	// Inserting into a node with room does not split it.
+	it = ql.iterator(0, true)
+	it.next()
+	it.insert("b", false)
+	expected = append([]string{"b"}, expected...)
+	checkQuicklist(t, ql, expected)
+	assertNodeCounts(t, ql, 3, 3, 4)
+
+	// Replacing an entry of a node limited by bytes splits the node if it
+	// exceeds the limit.
+	ql = NewQuicklist(-1, 0)
+	value := strings.Repeat("x", 1000)
+	expected = []string{value, value, value, value}
+	for _, value := range expected {
+		ql.RPush(value)
+	}
+	assertNodeCounts(t, ql, 4)
+	if err := ql.Set(3, strings.Repeat("y", 100)); err != nil {
+		t.Fatalf("Set: unexpected error %v", err)
+	}
+	expected[3] = strings.Repeat("y", 100)
+	checkQuicklist(t, ql, expected)
+	assertNodeCounts(t, ql, 4)
+	if err := ql.Set(1, strings.Repeat("y", 2000)); err != nil {
+		t.Fatalf("Set: unexpected error %v", err)
+	}
+	expected[1] = strings.Repeat("y", 2000)
+	checkQuicklist(t, ql, expected)
+	assertNodeCounts(t, ql, 2, 2)
+	if err := ql.Set(4, "z"); err != ErrOutOfRange {
+		t.Errorf("Set out of range: expected error %v, got %v", ErrOutOfRange, err)
+	}
+}
+
+func TestQuicklistMerge(t *testing.T) {
+	tests := []struct {
+		name   string
+		delete func(ql *Quicklist)
+		// deleted is the indexes of the deleted entries.
+		deleted []int
+		counts  []int
+	}{
+		{"range within a node", func(ql *Quicklist) { ql.DeleteRange(1, 2) }, []int{1, 2}, []int{2, 4, 4}},
+		{"range to fill", func(ql *Quicklist) { ql.DeleteRange(2, 4) }, []int{2, 3, 4, 5}, []int{4, 4}},
+		{"range below fill", func(ql *Quicklist) { ql.DeleteRange(2, 5) }, []int{2, 3, 4, 5, 6}, []int{3, 4}},
+		{"range above fill", func(ql *Quicklist) { ql.DeleteRange(3, 2) }, []int{3, 4}, []int{3, 3, 4}},
+		{"range of a node", func(ql *Quicklist) { ql.DeleteRange(4, 4) }, []int{4, 5, 6, 7}, []int{4, 4}},
+		{"range from a node", func(ql *Quicklist) { ql.DeleteRange(4, 6) }, []int{4, 5, 6, 7, 8, 9}, []int{4, 2}},
+		{"range of the head", func(ql *Quicklist) { ql.DeleteRange(0, 6) }, []int{0, 1, 2, 3, 4, 5}, []int{2, 4}},
+		{"range of the tail", func(ql *Quicklist) { ql.DeleteRange(-3, 3) }, []int{9, 10, 11}, []int{4, 4, 1}},
+		{"iterator above fill", func(ql *Quicklist) { deleteEntries(ql, true, 2, 5) }, []int{2, 5}, []int{3, 3, 4}},
+		{"iterator below fill", func(ql *Quicklist) { deleteEntries(ql, true, 1, 2, 4, 5) }, []int{1, 2, 4, 5}, []int{4, 4}},
+		{"iterator to the end", func(ql *Quicklist) { deleteEntries(ql, true, 9, 10) }, []int{9, 10}, []int{4, 4, 2}},
+		{"iterator across nodes", func(ql *Quicklist) { deleteEntries(ql, true, 1, 2, 4, 5, 9) }, []int{1, 2, 4, 5, 9}, []int{4, 3}},
+		{"iterator stopped", func(ql *Quicklist) { deleteEntries(ql, true, 6, 7, 8) }, []int{6, 7, 8}, []int{4, 2, 3}},
+		{"iterator stopped in a node", func(ql *Quicklist) { deleteEntries(ql, true, 6, 7, 8, 9) }, []int{6, 7, 8, 9}, []int{4, 4}},
+		{"backward iterator", func(ql *Quicklist) { deleteEntries(ql, false, 10, 9, 6, 5) }, []int{5, 6, 9, 10}, []int{4, 4}},
+		{"backward iterator across nodes", func(ql *Quicklist) { deleteEntries(ql, false, 10, 9, 6, 5, 1) }, []int{1, 5, 6, 9, 10}, []int{3, 4}},
+		{"backward iterator stopped", func(ql *Quicklist) { deleteEntries(ql, false, 4, 3, 2) }, []int{2, 3, 4}, []int{2, 3, 4}},
+		{"backward iterator of a node", func(ql *Quicklist) { deleteEntries(ql, false, 7, 6, 5, 4) }, []int{4, 5, 6, 7}, []int{4, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, depth := range []int{0, 1} {
+				ql := NewQuicklist(4, depth)
+				values := quicklistValues(strings.Repeat("v", 50), 12)
+				for _, value := range values {
+					ql.RPush(value)
+				}
+				assertNodeCounts(t, ql, 4, 4, 4)
+
+				tt.delete(ql)
+				expected, deleted := make([]string, 0), tt.deleted
+				for i, value := range values {
+					if len(deleted) > 0 && deleted[0] == i {
+						deleted = deleted[1:]
+						continue
+					}
+					expected = append(expected, value)
+				}
+				checkQuicklist(t, ql, expected)
+				assertNodeCounts(t, ql, tt.counts...)
+			}
+		})
+	}
+}
+
+func TestQuicklistMergeAhead(t *testing.T) {
+	tests := []struct {
+		name    string
+		forward bool
+		// stop is the index of the entry where the iterator is released, or -1
+		// to iterate all the entries.
+		stop int
+	}{
+		{"forward", true, -1},
+		{"forward stopped", true, 8},
+		{"backward", false, -1},
+		{"backward stopped", false, 1},
+	}
+
+	for _, tt := range tests {
+		forward := tt.forward
+		t.Run(tt.name, func(t *testing.T) {
+			ql := NewQuicklist(4, 0)
+			values := quicklistValues("v", 10)
+			if forward {
+				for _, value := range values {
+					ql.RPush(value)
+				}
+				assertNodeCounts(t, ql, 4, 4, 2)
+			} else {
+				for i := len(values) - 1; i >= 0; i-- {
+					ql.LPush(values[i])
+				}
+				assertNodeCounts(t, ql, 2, 4, 4)
+			}
+
+			// Deleting the entries of a node merges it with the untouched node
+			// ahead of the iterator when the iterator leaves that node.
+			deleted := []int{4, 5}
+			if !forward {
+				deleted = []int{5, 4}
+			}
+			it := ql.iterator(0, true)
+			index, step := 0, 1
+			if !forward {
+				it, index, step = ql.iterator(-1, false), ql.Len()-1, -1
+			}
+			for _, ok := it.next(); ok && index != tt.stop; _, ok = it.next() {
+				if len(deleted) > 0 && index == deleted[0] {
+					it.delete()
+					deleted = deleted[1:]
+				}
+				index += step
+			}
+			it.release()
+
+			checkQuicklist(t, ql, append(values[:4:4], values[6:]...))
+			assertNodeCounts(t, ql, 4, 4)
+		})
+	}
+}
+
+// deleteEntries deletes the entries at the indexes in the order of the
+// iterator, and releases the iterator at the last index.
+func deleteEntries(ql *Quicklist, forward bool, indexes ...int) {
+	start, step := 0, 1
+	if !forward {
+		start, step = ql.Len()-1, -1
+	}
+
+	it := ql.iterator(start, forward)
+	defer it.release()
+	for index := start; len(indexes) > 0; index += step {
+		if _, ok := it.next(); !ok {
+			return
+		}
+		if index == indexes[0] {
+			it.delete()
+			indexes = indexes[1:]
+		}
+	}
+}
+
+func TestQuicklistCompress(t *testing.T) {
+	for _, depth := range []int{1, 2, 3} {
+		t.Run(strconv.Itoa(depth), func(t *testing.T) {
+			ql := NewQuicklist(4, depth)
+			expected := make([]string, 0)
+			for i := 0; i < 40; i++ {
+				value := strings.Repeat(strconv.Itoa(i%10), 100)
+				ql.RPush(value)
+				expected = append(expected, value)
+				checkQuicklist(t, ql, expected)
+			}
+
+			compressed := 0
+			for node := ql.head; node != nil; node = node.next {
+				if node.compressed {
+					compressed++
+				}
+			}
+			if compressed != ql.nodes-depth*2 {
+				t.Fatalf("expected %d compressed nodes, got %d", ql.nodes-depth*2, compressed)
+			}
+
+			// Reading an entry of a compressed node keeps it compressed.
+			for i := range expected {
+				if value, ok := ql.Index(i); !ok || value != expected[i] {
+					t.Fatalf("Index(%d) = (%q, %v), expected (%q, true)", i, value, ok, expected[i])
+				}
+				checkQuicklist(t, ql, expected)
+			}
+
+			for len(expected) > 0 {
+				ql.LPop()
+				expected = expected[1:]
+				checkQuicklist(t, ql, expected)
+			}
+		})
+	}
+}
+
+func TestQuicklistRandom(t *testing.T) {
+	fills := []int{1, 2, 4, 16, -1, -2}
+	depths := []int{0, 1, 2}
+
+	for _, fill := range fills {
+		for _, depth := range depths {
+			t.Run(strconv.Itoa(fill)+" "+strconv.Itoa(depth), func(t *testing.T) {
+				rnd := rand.New(rand.NewSource(int64(fill*10 + depth)))
+				ql := NewQuicklist(fill, depth)
+				expected := make([]string, 0)
+
+				randomValue := func() string {
+					n := rnd.Intn(100)
+					if rnd.Intn(20) == 0 {
+						n = rnd.Intn(10000)
+					}
+					return strings.Repeat(string(rune('a'+rnd.Intn(4))), n)
+				}
+
+				for i := 0; i < 2000; i++ {
+					switch op := rnd.Intn(10); {
+					case op < 2:
+						value := randomValue()
+						ql.LPush(value)
+						expected = append([]string{value}, expected...)
+					case op < 4:
+						value := randomValue()
+						ql.RPush(value)
+						expected = append(expected, value)
+					case op == 4 && len(expected) > 0:
+						if value, _ := ql.LPop(); value != expected[0] {
+							t.Fatalf("LPop: expected %q, got %q", expected[0], value)
+						}
+						expected = expected[1:]
+					case op == 5 && len(expected) > 0:
+						if value, _ := ql.RPop(); value != expected[len(expected)-1] {
+							t.Fatalf("RPop: expected %q, got %q", expected[len(expected)-1], value)
+						}
+						expected = expected[:len(expected)-1]
+					case op == 6 && len(expected) > 0:
+						index, value := rnd.Intn(len(expected)), randomValue()
+						ql.Set(index-len(expected)*rnd.Intn(2), value)
+						expected[index] = value
+					case op == 7 && len(expected) > 0:
+						index, value, after := rnd.Intn(len(expected)), randomValue(), rnd.Intn(2) == 0
+						it := ql.iterator(index, rnd.Intn(2) == 0)
+						it.next()
+						it.insert(value, after)
+						if after {
+							index++
+						}
+						expected = append(expected[:index], append([]string{value}, expected[index:]...)...)
+					case op == 8 && len(expected) > 0:
+						index, n := rnd.Intn(len(expected)), rnd.Intn(10)
+						ql.DeleteRange(index, n)
+						expected = append(expected[:index], expected[min(index+n, len(expected)):]...)
+					case op == 9:
+						// Delete the entries of the value by the iterator in either
+						// direction, like LREM.
+						value, forward := string(rune('a'+rnd.Intn(4))), rnd.Intn(2) == 0
+						remaining := make([]string, 0, len(expected))
+						it := ql.iterator(0, true)
+						if !forward {
+							it = ql.iterator(-1, false)
+						}
+						for entry, ok := it.next(); ok; entry, ok = it.next() {
+							if strings.HasPrefix(string(entry), value) && len(entry) < 50 {
+								it.delete()
+							}
+						}
+						it.release()
+						for _, entry := range expected {
+							if !strings.HasPrefix(entry, value) || len(entry) >= 50 {
+								remaining = append(remaining, entry)
+							}
+						}
+						expected = remaining
+					}
+					checkQuicklist(t, ql, expected)
+				}
+			})
+		}
+	}
+}
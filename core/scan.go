@@ -0,0 +1,32 @@
+package core
+
+import (
+	"regexp"
+
+	"github.com/ghosind/antdb/util"
+)
+
+// scanDict scans the dict from the cursor until at least count entries have
+// been visited or the iteration is complete, and returns the next cursor. As
+// buckets may be empty, at most count*10 buckets are visited in a call.
+func scanDict[V any](d *Dict[V], cursor uint64, count int, fn func(key string, value V)) uint64 {
+	visited := 0
+	maxIterations := count * 10
+	for {
+		cursor = d.Scan(cursor, func(key string, value V) {
+			fn(key, value)
+			visited++
+		})
+		maxIterations--
+		if cursor == 0 || visited >= count || maxIterations <= 0 {
+			return cursor
+		}
+	}
+}
+
+func compileScanPattern(globPattern string) (*regexp.Regexp, error) {
+	if globPattern == "" || globPattern == "*" {
+		return nil, nil
+	}
+	return util.GlobToRegexp(globPattern)
+}
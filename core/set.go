@@ -6,25 +6,25 @@ func (db *Database) SetAdd(key string, members ...string) (int, error) {
 		return 0, err
 	}
 
-	var set map[string]struct{}
+	var set *Dict[struct{}]
 	if obj == nil {
-		set = make(map[string]struct{})
+		set = NewDict[struct{}]()
 		obj = &Object{
 			Type:  TypeSet,
 			Value: set,
 		}
-		db.data[key] = obj
+		db.setKey(key, obj)
 	} else {
-		set = obj.Value.(map[string]struct{})
+		set = obj.Value.(*Dict[struct{}])
 	}
 
 	cnt := 0
 	for _, member := range members {
-		if _, exists := set[member]; !exists {
-			set[member] = struct{}{}
+		if set.Set(member, struct{}{}) {
 			cnt++
 		}
 	}
+	db.trackMemory(key, obj)
 
 	return cnt, nil
 }
@@ -35,8 +35,8 @@ func (db *Database) SetCard(key string) (int, error) {
 		return 0, err
 	}
 
-	set := obj.Value.(map[string]struct{})
-	return len(set), nil
+	set := obj.Value.(*Dict[struct{}])
+	return set.Len(), nil
 }
 
 func (db *Database) SetIsMember(key string, member string) (bool, error) {
@@ -45,8 +45,8 @@ func (db *Database) SetIsMember(key string, member string) (bool, error) {
 		return false, err
 	}
 
-	set := obj.Value.(map[string]struct{})
-	_, exists := set[member]
+	set := obj.Value.(*Dict[struct{}])
+	_, exists := set.Get(member)
 	return exists, nil
 }
 
@@ -56,13 +56,8 @@ func (db *Database) SetMembers(key string) ([]string, error) {
 		return nil, err
 	}
 
-	set := obj.Value.(map[string]struct{})
-	members := make([]string, 0, len(set))
-	for member := range set {
-		members = append(members, member)
-	}
-
-	return members, nil
+	set := obj.Value.(*Dict[struct{}])
+	return dictKeys(set), nil
 }
 
 func (db *Database) SetMove(src, dest, member string) (bool, error) {
@@ -70,30 +65,37 @@ func (db *Database) SetMove(src, dest, member string) (bool, error) {
 	if err != nil || srcObj == nil {
 		return false, err
 	}
-	destObj, err := db.lookupKey(dest, TypeSet, false)
+	destObj, err := db.lookupKey(dest, TypeSet, true)
 	if err != nil {
 		return false, err
 	}
 
-	srcSet := srcObj.Value.(map[string]struct{})
-	if _, ok := srcSet[member]; !ok {
+	srcSet := srcObj.Value.(*Dict[struct{}])
+	if src == dest {
+		_, exists := srcSet.Get(member)
+		return exists, nil
+	}
+	if !srcSet.Delete(member) {
 		return false, nil
 	}
 
-	delete(srcSet, member)
+	if srcSet.Len() == 0 {
+		db.removeKey(src, srcObj)
+	} else {
+		db.trackMemory(src, srcObj)
+	}
 
-	if destObj.IsExpired() {
-		destObj.Value = map[string]struct{}{}
-	} else if destObj == nil {
+	if destObj == nil {
 		destObj = &Object{
-			Value: map[string]struct{}{},
+			Value: NewDict[struct{}](),
 			Type:  TypeSet,
 		}
-		db.data[dest] = destObj
+		db.setKey(dest, destObj)
 	}
 
-	destSet := destObj.Value.(map[string]struct{})
-	destSet[member] = struct{}{}
+	destSet := destObj.Value.(*Dict[struct{}])
+	destSet.Set(member, struct{}{})
+	db.trackMemory(dest, destObj)
 
 	return true, nil
 }
@@ -104,19 +106,16 @@ func (db *Database) SetPop(key string) (string, bool, error) {
 		return "", false, err
 	}
 
-	set := obj.Value.(map[string]struct{})
-	defer func() {
-		if len(set) == 0 {
-			db.removeKey(key, obj)
-		}
-	}()
-
-	for member := range set {
-		delete(set, member)
-		return member, true, nil
+	set := obj.Value.(*Dict[struct{}])
+	member, _, _ := set.RandomKey()
+	set.Delete(member)
+	if set.Len() == 0 {
+		db.removeKey(key, obj)
+	} else {
+		db.trackMemory(key, obj)
 	}
 
-	return "", true, nil
+	return member, true, nil
 }
 
 func (db *Database) SetRandMember(key string) (string, error) {
@@ -125,12 +124,9 @@ func (db *Database) SetRandMember(key string) (string, error) {
 		return "", err
 	}
 
-	set := obj.Value.(map[string]struct{})
-	for member := range set {
-		return member, nil
-	}
-
-	return "", nil
+	set := obj.Value.(*Dict[struct{}])
+	member, _, _ := set.RandomKey()
+	return member, nil
 }
 
 func (db *Database) SetRemove(key string, members ...string) (int, error) {
@@ -139,17 +135,18 @@ func (db *Database) SetRemove(key string, members ...string) (int, error) {
 		return 0, err
 	}
 
-	set := obj.Value.(map[string]struct{})
+	set := obj.Value.(*Dict[struct{}])
 	cnt := 0
 	for _, member := range members {
-		if _, exists := set[member]; exists {
-			delete(set, member)
+		if set.Delete(member) {
 			cnt++
 		}
 	}
 
-	if len(set) == 0 {
+	if set.Len() == 0 {
 		db.removeKey(key, obj)
+	} else {
+		db.trackMemory(key, obj)
 	}
 
 	return cnt, nil
@@ -161,11 +158,12 @@ func (db *Database) SetDiff(key, dest string, keys []string) ([]string, error) {
 		return nil, err
 	}
 
-	set := obj.Value.(map[string]struct{})
-	diff := make(map[string]struct{}, len(set))
-	for k := range set {
-		diff[k] = struct{}{}
-	}
+	set := obj.Value.(*Dict[struct{}])
+	diff := NewDict[struct{}]()
+	set.Range(func(k string, _ struct{}) bool {
+		diff.Set(k, struct{}{})
+		return true
+	})
 
 	for _, k := range keys {
 		kObj, err := db.lookupKey(k, TypeSet, true)
@@ -175,10 +173,11 @@ func (db *Database) SetDiff(key, dest string, keys []string) ([]string, error) {
 			continue
 		}
 
-		ks := kObj.Value.(map[string]struct{})
-		for kk := range ks {
-			delete(diff, kk)
-		}
+		ks := kObj.Value.(*Dict[struct{}])
+		ks.Range(func(kk string, _ struct{}) bool {
+			diff.Delete(kk)
+			return true
+		})
 	}
 
 	if dest != "" {
@@ -191,18 +190,14 @@ func (db *Database) SetDiff(key, dest string, keys []string) ([]string, error) {
 				Type:  TypeSet,
 				Value: diff,
 			}
-			db.data[dest] = destObj
+			db.setKey(dest, destObj)
 		} else {
 			destObj.Value = diff
 		}
+		db.trackMemory(dest, destObj)
 	}
 
-	res := make([]string, 0, len(diff))
-	for k := range diff {
-		res = append(res, k)
-	}
-
-	return res, nil
+	return dictKeys(diff), nil
 }
 
 func (db *Database) SetInter(key, dest string, keys []string) ([]string, error) {
@@ -211,12 +206,13 @@ func (db *Database) SetInter(key, dest string, keys []string) ([]string, error)
 		return nil, err
 	}
 
-	set := obj.Value.(map[string]struct{})
-	cnt := make(map[string]int, len(set))
-	inter := make(map[string]struct{}, 0)
-	for k := range set {
+	set := obj.Value.(*Dict[struct{}])
+	cnt := make(map[string]int, set.Len())
+	inter := NewDict[struct{}]()
+	set.Range(func(k string, _ struct{}) bool {
 		cnt[k]++
-	}
+		return true
+	})
 
 	for _, k := range keys {
 		kObj, err := db.lookupKey(k, TypeSet, true)
@@ -226,15 +222,16 @@ func (db *Database) SetInter(key, dest string, keys []string) ([]string, error)
 			continue
 		}
 
-		ks := kObj.Value.(map[string]struct{})
-		for kk := range ks {
+		ks := kObj.Value.(*Dict[struct{}])
+		ks.Range(func(kk string, _ struct{}) bool {
 			cnt[kk]++
-		}
+			return true
+		})
 	}
 
 	for k := range cnt {
 		if cnt[k] == len(keys)+1 {
-			inter[k] = struct{}{}
+			inter.Set(k, struct{}{})
 		}
 	}
 
@@ -248,18 +245,14 @@ func (db *Database) SetInter(key, dest string, keys []string) ([]string, error)
 				Type:  TypeSet,
 				Value: inter,
 			}
-			db.data[dest] = destObj
+			db.setKey(dest, destObj)
 		} else {
 			destObj.Value = inter
 		}
+		db.trackMemory(dest, destObj)
 	}
 
-	res := make([]string, 0, len(inter))
-	for k := range inter {
-		res = append(res, k)
-	}
-
-	return res, nil
+	return dictKeys(inter), nil
 }
 
 func (db *Database) SetUnion(key, dest string, keys []string) ([]string, error) {
@@ -268,11 +261,12 @@ func (db *Database) SetUnion(key, dest string, keys []string) ([]string, error)
 		return nil, err
 	}
 
-	set := obj.Value.(map[string]struct{})
-	union := make(map[string]struct{}, len(set))
-	for k := range set {
-		union[k] = struct{}{}
-	}
+	set := obj.Value.(*Dict[struct{}])
+	union := NewDict[struct{}]()
+	set.Range(func(k string, _ struct{}) bool {
+		union.Set(k, struct{}{})
+		return true
+	})
 
 	for _, k := range keys {
 		kObj, err := db.lookupKey(k, TypeSet, true)
@@ -282,10 +276,11 @@ func (db *Database) SetUnion(key, dest string, keys []string) ([]string, error)
 			continue
 		}
 
-		ks := kObj.Value.(map[string]struct{})
-		for kk := range ks {
-			union[kk] = struct{}{}
-		}
+		ks := kObj.Value.(*Dict[struct{}])
+		ks.Range(func(kk string, _ struct{}) bool {
+			union.Set(kk, struct{}{})
+			return true
+		})
 	}
 
 	if dest != "" {
@@ -298,16 +293,37 @@ func (db *Database) SetUnion(key, dest string, keys []string) ([]string, error)
 				Type:  TypeSet,
 				Value: union,
 			}
-			db.data[dest] = destObj
+			db.setKey(dest, destObj)
 		} else {
 			destObj.Value = union
 		}
+		db.trackMemory(dest, destObj)
 	}
 
-	res := make([]string, 0, len(union))
-	for k := range union {
-		res = append(res, k)
+	return dictKeys(union), nil
+}
+
+// SetScan iterates the members of the set from the cursor until at least count
+// members have been visited, and returns the next cursor with the visited
+// members that match the pattern.
+func (db *Database) SetScan(key string, cursor uint64, count int, globPattern string) (uint64, []string, error) {
+	pattern, err := compileScanPattern(globPattern)
+	if err != nil {
+		return 0, nil, err
 	}
 
-	return res, nil
+	obj, err := db.lookupKey(key, TypeSet, true)
+	if err != nil || obj == nil {
+		return 0, nil, err
+	}
+
+	set := obj.Value.(*Dict[struct{}])
+	members := make([]string, 0, count)
+	cursor = scanDict(set, cursor, count, func(member string, _ struct{}) {
+		if pattern == nil || pattern.MatchString(member) {
+			members = append(members, member)
+		}
+	})
+
+	return cursor, members, nil
 }
@@ -0,0 +1,116 @@
+package core
+
+import "math/rand"
+
+const (
+	skipListMaxLevel = 32
+	skipListP        = 0.25
+)
+
+type skipListNode struct {
+	member string
+	score  uint64
+	next   []*skipListNode
+}
+
+// SkipList keeps members ordered by their scores, and by the members for the
+// same score.
+type SkipList struct {
+	head  *skipListNode
+	level int
+	size  int
+}
+
+func NewSkipList() *SkipList {
+	return &SkipList{
+		head:  &skipListNode{next: make([]*skipListNode, skipListMaxLevel)},
+		level: 1,
+	}
+}
+
+func (sl *SkipList) Len() int {
+	return sl.size
+}
+
+// Insert inserts the member with the score, which must not be in the list.
+func (sl *SkipList) Insert(score uint64, member string) {
+	var update [skipListMaxLevel]*skipListNode
+	node := sl.head
+	for i := sl.level - 1; i >= 0; i-- {
+		for node.next[i] != nil && node.next[i].less(score, member) {
+			node = node.next[i]
+		}
+		update[i] = node
+	}
+
+	level := randomSkipListLevel()
+	if level > sl.level {
+		for i := sl.level; i < level; i++ {
+			update[i] = sl.head
+		}
+		sl.level = level
+	}
+
+	node = &skipListNode{member: member, score: score, next: make([]*skipListNode, level)}
+	for i := 0; i < level; i++ {
+		node.next[i] = update[i].next[i]
+		update[i].next[i] = node
+	}
+	sl.size++
+}
+
+// Delete deletes the member with the score, and returns false if it is not in
+// the list.
+func (sl *SkipList) Delete(score uint64, member string) bool {
+	var update [skipListMaxLevel]*skipListNode
+	node := sl.head
+	for i := sl.level - 1; i >= 0; i-- {
+		for node.next[i] != nil && node.next[i].less(score, member) {
+			node = node.next[i]
+		}
+		update[i] = node
+	}
+
+	node = node.next[0]
+	if node == nil || node.score != score || node.member != member {
+		return false
+	}
+
+	for i := 0; i < sl.level && update[i].next[i] == node; i++ {
+		update[i].next[i] = node.next[i]
+	}
+	for sl.level > 1 && sl.head.next[sl.level-1] == nil {
+		sl.level--
+	}
+	sl.size--
+	return true
+}
+
+// RangeByScore calls fn for the members with the scores between start and end,
+// both inclusive, in order until fn returns false.
+func (sl *SkipList) RangeByScore(start, end uint64, fn func(member string, score uint64) bool) {
+	node := sl.head
+	for i := sl.level - 1; i >= 0; i-- {
+		for node.next[i] != nil && node.next[i].score < start {
+			node = node.next[i]
+		}
+	}
+
+	for node = node.next[0]; node != nil && node.score <= end; node = node.next[0] {
+		if !fn(node.member, node.score) {
+			return
+		}
+	}
+}
+
+func (node *skipListNode) less(score uint64, member string) bool {
+	return node.score < score || (node.score == score && node.member < member)
+}
+
+func randomSkipListLevel() int {
+	level := 1
+	for level < skipListMaxLevel && rand.Float64() < skipListP {
+		level++
+	}
+	return level
+}
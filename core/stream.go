@@ -0,0 +1,852 @@
+package core
+
+import (
+	"sort"
+	"time"
+)
+
+// StreamAddArgs are the arguments of XADD. The ID is generated if AutoID is
+// true, or its sequence number is generated if AutoSeq is true. The stream is
+// not created if NoMkStream is true, and it is trimmed by Trim if it is not
+// nil.
+type StreamAddArgs struct {
+	ID         StreamID
+	AutoID     bool
+	AutoSeq    bool
+	NoMkStream bool
+	Trim       *StreamTrimArgs
+	Fields     []string
+}
+
+// StreamReadArg is a stream read by XREAD or XREADGROUP from the entries after
+// the ID. For XREAD, Last reads the entries added after the call, and XRead
+// replaces it with the last ID of the stream. For XREADGROUP, Last reads the
+// entries never delivered to the group.
+type StreamReadArg struct {
+	Key  string
+	ID   StreamID
+	Last bool
+}
+
+// StreamReadResult is the entries read from the stream of the key.
+type StreamReadResult struct {
+	Key     string
+	Entries []StreamEntry
+}
+
+// StreamClaimArgs are the options of XCLAIM. The delivery time is set to Idle
+// milliseconds ago or to Time if they are not negative, and the delivery count
+// is set to RetryCount if it is not negative. Force creates the pending entries
+// that do not exist, JustID does not increase the delivery count, and the last
+// delivered ID of the group is set to LastID if it is greater.
+type StreamClaimArgs struct {
+	Idle       int64
+	Time       int64
+	RetryCount int64
+	Force      bool
+	JustID     bool
+	LastID     *StreamID
+}
+
+// StreamPendingEntry is an entry of the PEL of a consumer group, with the idle
+// time in milliseconds since it was last delivered.
+type StreamPendingEntry struct {
+	ID            StreamID
+	Consumer      string
+	DeliveryTime  int64
+	Idle          int64
+	DeliveryCount int64
+}
+
+// StreamPendingSummary is the summary of the PEL of a consumer group by XPENDING.
+type StreamPendingSummary struct {
+	Count     int64
+	First     StreamID
+	Last      StreamID
+	Consumers []StreamConsumerPending
+}
+
+type StreamConsumerPending struct {
+	Name  string
+	Count int64
+}
+
+// StreamInfo is the information of a stream by XINFO STREAM, where Entries and
+// the PEL and the consumers of Groups are only filled for the FULL option.
+type StreamInfo struct {
+	Length       int64
+	Chunks       int64
+	LastID       StreamID
+	MaxDeletedID StreamID
+	EntriesAdded int64
+	FirstID      StreamID
+	FirstEntry   *StreamEntry
+	LastEntry    *StreamEntry
+	Entries      []StreamEntry
+	Groups       []StreamGroupInfo
+}
+
+// StreamGroupInfo is the information of a consumer group, where EntriesRead and
+// Lag are -1 if they are unknown.
+type StreamGroupInfo struct {
+	Name            string
+	Consumers       []StreamConsumerInfo
+	Pending         int64
+	LastDeliveredID StreamID
+	EntriesRead     int64
+	Lag             int64
+	PEL             []StreamPendingEntry
+}
+
+// StreamConsumerInfo is the information of a consumer, where Inactive is -1 if
+// the consumer has never read or claimed any entry.
+type StreamConsumerInfo struct {
+	Name       string
+	Pending    int64
+	SeenTime   int64
+	ActiveTime int64
+	Idle       int64
+	Inactive   int64
+	PEL        []StreamPendingEntry
+}
+
+// XAdd adds the entry to the stream of the key, and returns its ID. It returns
+// false if the key does not exist and NoMkStream is true.
+func (db *Database) XAdd(key string, args *StreamAddArgs) (StreamID, bool, error) {
+	obj, err := db.lookupKey(key, TypeStream, true)
+	if err != nil {
+		return StreamID{}, false, err
+	}
+
+	s := NewStream()
+	if obj != nil {
+		s = obj.Value.(*Stream)
+	} else if args.NoMkStream {
+		return StreamID{}, false, nil
+	}
+
+	id, err := s.nextID(args)
+	if err != nil {
+		return StreamID{}, false, err
+	}
+
+	if obj == nil {
+		obj = db.newObject()
+		obj.Type = TypeStream
+		obj.Value = s
+		db.setKey(key, obj)
+	}
+	s.append(id, append([]string(nil), args.Fields...))
+	if args.Trim != nil {
+		s.trim(args.Trim)
+	}
+	db.trackMemory(key, obj)
+	return id, true, nil
+}
+
+func (db *Database) XLen(key string) (int64, error) {
+	obj, err := db.lookupKey(key, TypeStream, true)
+	if err != nil || obj == nil {
+		return 0, err
+	}
+	return int64(obj.Value.(*Stream).Len()), nil
+}
+
+// XRange returns up to count entries with the IDs between start and end, both
+// inclusive, or all the entries if count is not positive. The entries are in
+// reverse order if rev is true.
+func (db *Database) XRange(key string, start, end StreamID, count int, rev bool) ([]StreamEntry, error) {
+	obj, err := db.lookupKey(key, TypeStream, true)
+	if err != nil || obj == nil {
+		return nil, err
+	}
+
+	entries := make([]StreamEntry, 0)
+	obj.Value.(*Stream).rangeEntries(start, end, rev, func(entry *StreamEntry) bool {
+		entries = append(entries, *entry)
+		return count <= 0 || len(entries) < count
+	})
+	return entries, nil
+}
+
+// XDel deletes the entries of the IDs, and returns the number of the deleted
+// entries.
+func (db *Database) XDel(key string, ids ...StreamID) (int64, error) {
+	obj, err := db.lookupKey(key, TypeStream, true)
+	if err != nil || obj == nil {
+		return 0, err
+	}
+
+	s := obj.Value.(*Stream)
+	cnt := int64(0)
+	for _, id := range ids {
+		if s.delete(id) {
+			cnt++
+		}
+	}
+	db.trackMemory(key, obj)
+	return cnt, nil
+}
+
+// XTrim trims the stream of the key, and returns the number of the removed
+// entries.
+func (db *Database) XTrim(key string, args *StreamTrimArgs) (int64, error) {
+	obj, err := db.lookupKey(key, TypeStream, true)
+	if err != nil || obj == nil {
+		return 0, err
+	}
+
+	cnt := obj.Value.(*Stream).trim(args)
+	db.trackMemory(key, obj)
+	return cnt, nil
+}
+
+// XRead returns up to count entries after the IDs of the streams, or all the
+// entries if count is not positive. The streams without any entry are not
+// returned.
+func (db *Database) XRead(streams []StreamReadArg, count int) ([]StreamReadResult, error) {
+	results := make([]StreamReadResult, 0)
+	for i := range streams {
+		arg := &streams[i]
+		obj, err := db.lookupKey(arg.Key, TypeStream, true)
+		if err != nil {
+			return nil, err
+		}
+		if arg.Last {
+			arg.ID, arg.Last = StreamID{}, false
+			if obj != nil {
+				arg.ID = obj.Value.(*Stream).lastID
+			}
+		}
+		if obj == nil {
+			continue
+		}
+
+		start, ok := arg.ID.Next()
+		if !ok {
+			continue
+		}
+		entries := make([]StreamEntry, 0)
+		obj.Value.(*Stream).rangeEntries(start, MaxStreamID, false, func(entry *StreamEntry) bool {
+			entries = append(entries, *entry)
+			return count <= 0 || len(entries) < count
+		})
+		if len(entries) > 0 {
+			results = append(results, StreamReadResult{Key: arg.Key, Entries: entries})
+		}
+	}
+	return results, nil
+}
+
+// XReadGroup reads the entries of the streams for the consumer of the group,
+// creating the consumer if it does not exist. For the streams of Last, it
+// delivers up to count entries never delivered to the group, and adds them to
+// the PEL unless noack is true. For other streams, it returns the pending
+// entries of the consumer after the IDs, where the deleted entries have nil
+// fields.
+func (db *Database) XReadGroup(group, consumer string, streams []StreamReadArg, count int, noack bool) ([]StreamReadResult, error) {
+	groups := make([]*StreamGroup, len(streams))
+	for i, arg := range streams {
+		_, g, err := db.lookupStreamGroup(arg.Key, group)
+		if err != nil {
+			return nil, err
+		}
+		groups[i] = g
+	}
+
+	now := time.Now().UnixMilli()
+	results := make([]StreamReadResult, 0)
+	for i, arg := range streams {
+		obj, _ := db.lookupKey(arg.Key, TypeStream, true)
+		s, g := obj.Value.(*Stream), groups[i]
+		c := g.consumer(consumer, now)
+		entries := make([]StreamEntry, 0)
+
+		if arg.Last {
+			start, ok := g.lastID.Next()
+			if !ok {
+				continue
+			}
+			s.rangeEntries(start, MaxStreamID, false, func(entry *StreamEntry) bool {
+				s.advanceGroup(g, entry.ID)
+				if !noack {
+					g.deliver(entry.ID, c, now)
+				}
+				entries = append(entries, *entry)
+				return count <= 0 || len(entries) < count
+			})
+			if len(entries) == 0 {
+				continue
+			}
+			c.activeTime = now
+			results = append(results, StreamReadResult{Key: arg.Key, Entries: entries})
+			db.trackMemory(arg.Key, obj)
+			continue
+		}
+
+		if start, ok := arg.ID.Next(); ok {
+			g.rangePending(start, MaxStreamID, func(id StreamID, nack *streamNACK) bool {
+				if nack.consumer != c {
+					return true
+				}
+				if entry, found := s.get(id); found {
+					entries = append(entries, *entry)
+					nack.deliveryTime = now
+					nack.deliveryCount++
+				} else {
+					entries = append(entries, StreamEntry{ID: id})
+				}
+				return count <= 0 || len(entries) < count
+			})
+		}
+		results = append(results, StreamReadResult{Key: arg.Key, Entries: entries})
+	}
+	return results, nil
+}
+
+// XGroupCreate creates the consumer group of the stream with the last
+// delivered ID, which is the last ID of the stream if last is true. The stream
+// is created if it does not exist and mkstream is true.
+func (db *Database) XGroupCreate(key, group string, id StreamID, last, mkstream bool, entriesRead int64) error {
+	obj, err := db.lookupKey(key, TypeStream, true)
+	if err != nil {
+		return err
+	}
+	if obj == nil {
+		if !mkstream {
+			return ErrNoSuchKey
+		}
+		obj = db.newObject()
+		obj.Type = TypeStream
+		obj.Value = NewStream()
+		db.setKey(key, obj)
+	}
+
+	s := obj.Value.(*Stream)
+	if _, ok := s.groups[group]; ok {
+		return ErrBusyGroup
+	}
+	if last {
+		id = s.lastID
+	}
+	s.groups[group] = newStreamGroup(group, id, entriesRead)
+	db.trackMemory(key, obj)
+	return nil
+}
+
+// XGroupSetID sets the last delivered ID of the consumer group, which is the
+// last ID of the stream if last is true.
+func (db *Database) XGroupSetID(key, group string, id StreamID, last bool, entriesRead int64) error {
+	obj, g, err := db.lookupStreamGroupOfKey(key, group)
+	if err != nil {
+		return err
+	}
+
+	if last {
+		id = obj.Value.(*Stream).lastID
+	}
+	g.lastID = id
+	g.entriesRead = entriesRead
+	return nil
+}
+
+// XGroupDestroy destroys the consumer group, and returns false if it does not
+// exist.
+func (db *Database) XGroupDestroy(key, group string) (bool, error) {
+	obj, err := db.lookupKey(key, TypeStream, true)
+	if err != nil {
+		return false, err
+	} else if obj == nil {
+		return false, ErrNoSuchKey
+	}
+
+	s := obj.Value.(*Stream)
+	if _, ok := s.groups[group]; !ok {
+		return false, nil
+	}
+	delete(s.groups, group)
+	db.trackMemory(key, obj)
+	return true, nil
+}
+
+// XGroupCreateConsumer creates the consumer of the group, and returns false if
+// it already exists.
+func (db *Database) XGroupCreateConsumer(key, group, consumer string) (bool, error) {
+	obj, g, err := db.lookupStreamGroupOfKey(key, group)
+	if err != nil {
+		return false, err
+	}
+
+	_, created := g.createConsumer(consumer, time.Now().UnixMilli())
+	db.trackMemory(key, obj)
+	return created, nil
+}
+
+// XGroupDelConsumer deletes the consumer of the group with its pending entries,
+// and returns the number of its pending entries.
+func (db *Database) XGroupDelConsumer(key, group, consumer string) (int64, error) {
+	obj, g, err := db.lookupStreamGroupOfKey(key, group)
+	if err != nil {
+		return 0, err
+	}
+
+	cnt := g.deleteConsumer(consumer)
+	db.trackMemory(key, obj)
+	return cnt, nil
+}
+
+// XAck removes the entries from the PEL of the group, and returns the number of
+// the acknowledged entries.
+func (db *Database) XAck(key, group string, ids ...StreamID) (int64, error) {
+	obj, err := db.lookupKey(key, TypeStream, true)
+	if err != nil || obj == nil {
+		return 0, err
+	}
+	g, ok := obj.Value.(*Stream).groups[group]
+	if !ok {
+		return 0, nil
+	}
+
+	cnt := int64(0)
+	for _, id := range ids {
+		if g.removePending(id) {
+			cnt++
+		}
+	}
+	db.trackMemory(key, obj)
+	return cnt, nil
+}
+
+// XPendingSummary returns the number of the pending entries of the group, the
+// smallest and the greatest IDs of them, and the number of the pending entries
+// of each consumer ordered by name.
+func (db *Database) XPendingSummary(key, group string) (*StreamPendingSummary, error) {
+	_, g, err := db.lookupStreamGroup(key, group)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &StreamPendingSummary{Count: int64(len(g.pendingIDs))}
+	if summary.Count == 0 {
+		return summary, nil
+	}
+	summary.First, summary.Last = g.pendingIDs[0], g.pendingIDs[len(g.pendingIDs)-1]
+	for _, c := range g.sortedConsumers() {
+		if len(c.pending) > 0 {
+			summary.Consumers = append(summary.Consumers, StreamConsumerPending{Name: c.name, Count: int64(len(c.pending))})
+		}
+	}
+	return summary, nil
+}
+
+// XPending returns up to count pending entries of the group with the IDs
+// between start and end, which are idle for at least minIdle milliseconds. The
+// entries are only of the consumer if it is not empty.
+func (db *Database) XPending(key, group string, start, end StreamID, count int, consumer string, minIdle int64) ([]StreamPendingEntry, error) {
+	_, g, err := db.lookupStreamGroup(key, group)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UnixMilli()
+	entries := make([]StreamPendingEntry, 0)
+	if count <= 0 {
+		return entries, nil
+	}
+	g.rangePending(start, end, func(id StreamID, nack *streamNACK) bool {
+		if (consumer == "" || nack.consumer.name == consumer) && now-nack.deliveryTime >= minIdle {
+			entries = append(entries, nack.info(id, now))
+		}
+		return len(entries) < count
+	})
+	return entries, nil
+}
+
+// XClaim transfers the pending entries of the IDs that are idle for at least
+// minIdle milliseconds to the consumer, and returns the claimed entries, whose
+// fields are nil for JustID. The deleted entries are removed from the PEL.
+func (db *Database) XClaim(key, group, consumer string, minIdle int64, ids []StreamID, args *StreamClaimArgs) ([]StreamEntry, error) {
+	obj, g, err := db.lookupStreamGroup(key, group)
+	if err != nil {
+		return nil, err
+	}
+
+	s := obj.Value.(*Stream)
+	now := time.Now().UnixMilli()
+	deliveryTime := now
+	if args.Idle >= 0 {
+		deliveryTime = now - args.Idle
+	} else if args.Time >= 0 {
+		deliveryTime = args.Time
+	}
+	if deliveryTime < 0 || deliveryTime > now {
+		deliveryTime = now
+	}
+	if args.LastID != nil && g.lastID.Less(*args.LastID) {
+		g.lastID = *args.LastID
+	}
+
+	c := g.consumer(consumer, now)
+	claimed := make([]StreamEntry, 0)
+	for _, id := range ids {
+		nack, ok := g.pending[id]
+		entry, found := s.get(id)
+		if !ok {
+			if !args.Force || !found {
+				continue
+			}
+			g.deliver(id, c, now)
+			nack = g.pending[id]
+			nack.deliveryCount = 0
+		} else if now-nack.deliveryTime < minIdle {
+			continue
+		}
+		if !found {
+			g.removePending(id)
+			continue
+		}
+
+		g.assign(id, nack, c)
+		nack.deliveryTime = deliveryTime
+		if args.RetryCount >= 0 {
+			nack.deliveryCount = args.RetryCount
+		} else if !args.JustID {
+			nack.deliveryCount++
+		}
+		c.activeTime = now
+		if args.JustID {
+			claimed = append(claimed, StreamEntry{ID: id})
+		} else {
+			claimed = append(claimed, *entry)
+		}
+	}
+	db.trackMemory(key, obj)
+	return claimed, nil
+}
+
+// XAutoClaim claims up to count pending entries from the start ID that are idle
+// for at least minIdle milliseconds, scanning at most 10 times count entries of
+// the PEL. It returns the ID to continue the scan from, or 0-0 if the scan is
+// complete, the claimed entries, whose fields are nil if justID is true, and the
+// IDs of the deleted entries that are removed from the PEL.
+func (db *Database) XAutoClaim(key, group, consumer string, minIdle int64, start StreamID, count int, justID bool) (StreamID, []StreamEntry, []StreamID, error) {
+	obj, g, err := db.lookupStreamGroup(key, group)
+	if err != nil {
+		return StreamID{}, nil, nil, err
+	}
+
+	s := obj.Value.(*Stream)
+	now := time.Now().UnixMilli()
+	c := g.consumer(consumer, now)
+	claimed := make([]StreamEntry, 0)
+	deleted := make([]StreamID, 0)
+	attempts := count * 10
+	next := StreamID{}
+	g.rangePending(start, MaxStreamID, func(id StreamID, nack *streamNACK) bool {
+		if attempts == 0 || len(claimed) == count {
+			next = id
+			return false
+		}
+		attempts--
+
+		if now-nack.deliveryTime < minIdle {
+			return true
+		}
+		entry, found := s.get(id)
+		if !found {
+			g.removePending(id)
+			deleted = append(deleted, id)
+			return true
+		}
+
+		g.assign(id, nack, c)
+		nack.deliveryTime = now
+		if !justID {
+			nack.deliveryCount++
+		}
+		c.activeTime = now
+		if justID {
+			claimed = append(claimed, StreamEntry{ID: id})
+		} else {
+			claimed = append(claimed, *entry)
+		}
+		return true
+	})
+	db.trackMemory(key, obj)
+	return next, claimed, deleted, nil
+}
+
+// XInfoStream returns the information of the stream of the key. For the full
+// information, up to count entries, and up to count pending entries of each
+// group and each consumer are returned, or all of them if count is not
+// positive.
+func (db *Database) XInfoStream(key string, full bool, count int) (*StreamInfo, error) {
+	obj, err := db.lookupKey(key, TypeStream, true)
+	if err != nil {
+		return nil, err
+	} else if obj == nil {
+		return nil, ErrNoSuchKey
+	}
+
+	s := obj.Value.(*Stream)
+	info := &StreamInfo{
+		Length:       int64(s.length),
+		Chunks:       int64(len(s.chunks)),
+		LastID:       s.lastID,
+		MaxDeletedID: s.maxDeletedID,
+		EntriesAdded: int64(s.entriesAdded),
+		FirstID:      s.firstID(),
+	}
+	now := time.Now().UnixMilli()
+	for _, g := range s.sortedGroups() {
+		info.Groups = append(info.Groups, s.groupInfo(g, full, count, now))
+	}
+
+	if !full {
+		if s.length > 0 {
+			first := s.chunks[0].entries[0]
+			lastChunk := s.chunks[len(s.chunks)-1].entries
+			last := lastChunk[len(lastChunk)-1]
+			info.FirstEntry, info.LastEntry = &first, &last
+		}
+		return info, nil
+	}
+
+	info.Entries = make([]StreamEntry, 0)
+	s.rangeEntries(StreamID{}, MaxStreamID, false, func(entry *StreamEntry) bool {
+		info.Entries = append(info.Entries, *entry)
+		return count <= 0 || len(info.Entries) < count
+	})
+	return info, nil
+}
+
+// XInfoGroups returns the information of the consumer groups of the stream.
+func (db *Database) XInfoGroups(key string) ([]StreamGroupInfo, error) {
+	obj, err := db.lookupKey(key, TypeStream, true)
+	if err != nil {
+		return nil, err
+	} else if obj == nil {
+		return nil, ErrNoSuchKey
+	}
+
+	s := obj.Value.(*Stream)
+	now := time.Now().UnixMilli()
+	groups := make([]StreamGroupInfo, 0, len(s.groups))
+	for _, g := range s.sortedGroups() {
+		groups = append(groups, s.groupInfo(g, false, 0, now))
+	}
+	return groups, nil
+}
+
+// XInfoConsumers returns the information of the consumers of the group.
+func (db *Database) XInfoConsumers(key, group string) ([]StreamConsumerInfo, error) {
+	_, g, err := db.lookupStreamGroupOfKey(key, group)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UnixMilli()
+	consumers := make([]StreamConsumerInfo, 0, len(g.consumers))
+	for _, c := range g.sortedConsumers() {
+		consumers = append(consumers, c.info(g, false, 0, now))
+	}
+	return consumers, nil
+}
+
+// lookupStreamGroup returns the stream of the key and its consumer group, or a
+// NoGroupError if the key or the group does not exist.
+func (db *Database) lookupStreamGroup(key, group string) (*Object, *StreamGroup, error) {
+	obj, err := db.lookupKey(key, TypeStream, true)
+	if err != nil {
+		return nil, nil, err
+	} else if obj == nil {
+		return nil, nil, &NoGroupError{Key: key, Group: group}
+	}
+
+	g, ok := obj.Value.(*Stream).groups[group]
+	if !ok {
+		return nil, nil, &NoGroupError{Key: key, Group: group}
+	}
+	return obj, g, nil
+}
+
+// lookupStreamGroupOfKey is like lookupStreamGroup, but returns ErrNoSuchKey if
+// the key does not exist.
+func (db *Database) lookupStreamGroupOfKey(key, group string) (*Object, *StreamGroup, error) {
+	obj, err := db.lookupKey(key, TypeStream, true)
+	if err != nil {
+		return nil, nil, err
+	} else if obj == nil {
+		return nil, nil, ErrNoSuchKey
+	}
+	return db.lookupStreamGroup(key, group)
+}
+
+// nextID returns the ID of the entry to add by the arguments of XADD.
+func (s *Stream) nextID(args *StreamAddArgs) (StreamID, error) {
+	if s.lastID == MaxStreamID {
+		return StreamID{}, ErrStreamExhausted
+	}
+
+	switch {
+	case args.AutoID:
+		ms := uint64(time.Now().UnixMilli())
+		if ms > s.lastID.Ms {
+			return StreamID{Ms: ms}, nil
+		}
+		id, _ := s.lastID.Next()
+		return id, nil
+	case args.AutoSeq:
+		if args.ID.Ms > s.lastID.Ms {
+			return StreamID{Ms: args.ID.Ms}, nil
+		} else if args.ID.Ms == s.lastID.Ms && s.lastID.Seq < MaxStreamID.Seq {
+			return StreamID{Ms: args.ID.Ms, Seq: s.lastID.Seq + 1}, nil
+		}
+		return StreamID{}, ErrStreamIDTooSmall
+	}
+
+	if args.ID.IsZero() {
+		return StreamID{}, ErrStreamIDZero
+	} else if !s.lastID.Less(args.ID) {
+		return StreamID{}, ErrStreamIDTooSmall
+	}
+	return args.ID, nil
+}
+
+// advanceGroup sets the last delivered ID of the group to the ID delivered by
+// XREADGROUP, and counts the entries read by the group.
+func (s *Stream) advanceGroup(g *StreamGroup, id StreamID) {
+	if g.entriesRead >= 0 && !s.hasTombstones(id, MaxStreamID) {
+		g.entriesRead++
+	} else if s.entriesAdded > 0 {
+		g.entriesRead = s.estimateEntriesRead(id)
+	}
+	g.lastID = id
+}
+
+// hasTombstones returns true if any entry between start and end may have been
+// deleted by XDEL.
+func (s *Stream) hasTombstones(start, end StreamID) bool {
+	if s.length == 0 || s.maxDeletedID.IsZero() {
+		return false
+	}
+	return !s.maxDeletedID.Less(start) && !end.Less(s.maxDeletedID)
+}
+
+// estimateEntriesRead returns the logical number of the entries up to the ID
+// since the stream was created, or -1 if it cannot be known because of the
+// deleted entries.
+func (s *Stream) estimateEntriesRead(id StreamID) int64 {
+	if s.entriesAdded == 0 {
+		return 0
+	}
+	if s.length == 0 && !s.lastID.Less(id) {
+		return int64(s.entriesAdded)
+	}
+	switch id.Compare(s.lastID) {
+	case 0:
+		return int64(s.entriesAdded)
+	case 1:
+		return -1
+	}
+
+	first := s.firstID()
+	if s.maxDeletedID.IsZero() || s.maxDeletedID.Less(first) {
+		switch id.Compare(first) {
+		case -1:
+			return int64(s.entriesAdded) - int64(s.length)
+		case 0:
+			return int64(s.entriesAdded) - int64(s.length) + 1
+		}
+	}
+	return -1
+}
+
+// lag returns the number of the entries not read by the group yet, or -1 if it
+// cannot be known.
+func (s *Stream) lag(g *StreamGroup) int64 {
+	if s.entriesAdded == 0 {
+		return 0
+	}
+	if g.entriesRead >= 0 && !s.hasTombstones(g.lastID, MaxStreamID) {
+		return int64(s.entriesAdded) - g.entriesRead
+	}
+	if read := s.estimateEntriesRead(g.lastID); read >= 0 {
+		return int64(s.entriesAdded) - read
+	}
+	return -1
+}
+
+func (s *Stream) sortedGroups() []*StreamGroup {
+	groups := make([]*StreamGroup, 0, len(s.groups))
+	for _, g := range s.groups {
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].name < groups[j].name })
+	return groups
+}
+
+func (s *Stream) groupInfo(g *StreamGroup, full bool, count int, now int64) StreamGroupInfo {
+	info := StreamGroupInfo{
+		Name:            g.name,
+		Pending:         int64(len(g.pendingIDs)),
+		LastDeliveredID: g.lastID,
+		EntriesRead:     g.entriesRead,
+		Lag:             s.lag(g),
+	}
+	info.Consumers = make([]StreamConsumerInfo, 0, len(g.consumers))
+	for _, c := range g.sortedConsumers() {
+		info.Consumers = append(info.Consumers, c.info(g, full, count, now))
+	}
+	if full {
+		info.PEL = make([]StreamPendingEntry, 0)
+		g.rangePending(StreamID{}, MaxStreamID, func(id StreamID, nack *streamNACK) bool {
+			info.PEL = append(info.PEL, nack.info(id, now))
+			return count <= 0 || len(info.PEL) < count
+		})
+	}
+	return info
+}
+
+func (g *StreamGroup) sortedConsumers() []*StreamConsumer {
+	consumers := make([]*StreamConsumer, 0, len(g.consumers))
+	for _, c := range g.consumers {
+		consumers = append(consumers, c)
+	}
+	sort.Slice(consumers, func(i, j int) bool { return consumers[i].name < consumers[j].name })
+	return consumers
+}
+
+func (c *StreamConsumer) info(g *StreamGroup, full bool, count int, now int64) StreamConsumerInfo {
+	info := StreamConsumerInfo{
+		Name:       c.name,
+		Pending:    int64(len(c.pending)),
+		SeenTime:   c.seenTime,
+		ActiveTime: c.activeTime,
+		Idle:       now - c.seenTime,
+		Inactive:   -1,
+	}
+	if c.activeTime >= 0 {
+		info.Inactive = now - c.activeTime
+	}
+	if full {
+		info.PEL = make([]StreamPendingEntry, 0)
+		g.rangePending(StreamID{}, MaxStreamID, func(id StreamID, nack *streamNACK) bool {
+			if nack.consumer == c {
+				info.PEL = append(info.PEL, nack.info(id, now))
+			}
+			return count <= 0 || len(info.PEL) < count
+		})
+	}
+	return info
+}
+
+func (nack *streamNACK) info(id StreamID, now int64) StreamPendingEntry {
+	return StreamPendingEntry{
+		ID:            id,
+		Consumer:      nack.consumer.name,
+		DeliveryTime:  nack.deliveryTime,
+		Idle:          now - nack.deliveryTime,
+		DeliveryCount: nack.deliveryCount,
+	}
+}
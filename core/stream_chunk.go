@@ -0,0 +1,227 @@
+package core
+
+import "sort"
+
+// streamChunkMaxEntries is the maximum number of entries of a chunk, like the
+// stream-node-max-entries of Redis.
+const streamChunkMaxEntries = 100
+
+// StreamEntry is an entry of a stream, where Fields are the field-value pairs.
+// Fields is nil for an entry that has been deleted from the stream.
+type StreamEntry struct {
+	ID     StreamID
+	Fields []string
+}
+
+type streamChunk struct {
+	entries []StreamEntry
+}
+
+// Stream is an append-only log of entries ordered by their IDs. The entries are
+// stored in chunks of up to streamChunkMaxEntries entries, so appending does not
+// copy the whole log, and approximate trimming can drop whole chunks.
+type Stream struct {
+	chunks       []*streamChunk
+	length       int
+	lastID       StreamID
+	maxDeletedID StreamID
+	entriesAdded uint64
+	groups       map[string]*StreamGroup
+}
+
+func NewStream() *Stream {
+	return &Stream{
+		groups: make(map[string]*StreamGroup),
+	}
+}
+
+func (s *Stream) Len() int {
+	return s.length
+}
+
+func (s *Stream) LastID() StreamID {
+	return s.lastID
+}
+
+// firstID returns the ID of the first entry, or 0-0 if the stream is empty.
+func (s *Stream) firstID() StreamID {
+	if s.length == 0 {
+		return StreamID{}
+	}
+	return s.chunks[0].entries[0].ID
+}
+
+// append appends the entry, whose ID must be greater than the last ID.
+func (s *Stream) append(id StreamID, fields []string) {
+	var chunk *streamChunk
+	if n := len(s.chunks); n > 0 && len(s.chunks[n-1].entries) < streamChunkMaxEntries {
+		chunk = s.chunks[n-1]
+	} else {
+		chunk = &streamChunk{entries: make([]StreamEntry, 0, streamChunkMaxEntries)}
+		s.chunks = append(s.chunks, chunk)
+	}
+	chunk.entries = append(chunk.entries, StreamEntry{ID: id, Fields: fields})
+	s.length++
+	s.lastID = id
+	s.entriesAdded++
+}
+
+// seek returns the position of the first entry whose ID is not less than the
+// ID, which is len(s.chunks) if there is no such entry.
+func (s *Stream) seek(id StreamID) (int, int) {
+	i := sort.Search(len(s.chunks), func(i int) bool {
+		entries := s.chunks[i].entries
+		return !entries[len(entries)-1].ID.Less(id)
+	})
+	if i == len(s.chunks) {
+		return i, 0
+	}
+	entries := s.chunks[i].entries
+	return i, sort.Search(len(entries), func(j int) bool { return !entries[j].ID.Less(id) })
+}
+
+// get returns the entry of the ID.
+func (s *Stream) get(id StreamID) (*StreamEntry, bool) {
+	i, j := s.seek(id)
+	if i == len(s.chunks) || s.chunks[i].entries[j].ID != id {
+		return nil, false
+	}
+	return &s.chunks[i].entries[j], true
+}
+
+// delete deletes the entry of the ID, and returns false if it does not exist.
+func (s *Stream) delete(id StreamID) bool {
+	i, j := s.seek(id)
+	if i == len(s.chunks) || s.chunks[i].entries[j].ID != id {
+		return false
+	}
+
+	chunk := s.chunks[i]
+	chunk.removeRange(j, j+1)
+	if len(chunk.entries) == 0 {
+		s.chunks = append(s.chunks[:i], s.chunks[i+1:]...)
+	}
+	s.length--
+	if s.maxDeletedID.Less(id) {
+		s.maxDeletedID = id
+	}
+	return true
+}
+
+// removeRange removes the entries between the positions i and j, and clears
+// the vacated slots to release the fields.
+func (chunk *streamChunk) removeRange(i, j int) {
+	n := copy(chunk.entries[i:], chunk.entries[j:])
+	clear(chunk.entries[i+n:])
+	chunk.entries = chunk.entries[:i+n]
+}
+
+// rangeEntries calls fn for the entries with the IDs between start and end,
+// both inclusive, in order or in reverse order if rev is true, until fn
+// returns false.
+func (s *Stream) rangeEntries(start, end StreamID, rev bool, fn func(entry *StreamEntry) bool) {
+	if end.Less(start) {
+		return
+	}
+
+	if !rev {
+		for i, j := s.seek(start); i < len(s.chunks); i, j = i+1, 0 {
+			entries := s.chunks[i].entries
+			for ; j < len(entries); j++ {
+				if end.Less(entries[j].ID) || !fn(&entries[j]) {
+					return
+				}
+			}
+		}
+		return
+	}
+
+	// Start from the last entry whose ID is not greater than the end.
+	i, j := s.seek(end)
+	if i == len(s.chunks) || s.chunks[i].entries[j].ID != end {
+		if j > 0 {
+			j--
+		} else if i--; i >= 0 {
+			j = len(s.chunks[i].entries) - 1
+		}
+	}
+	for ; i >= 0; i-- {
+		entries := s.chunks[i].entries
+		for ; j >= 0; j-- {
+			if entries[j].ID.Less(start) || !fn(&entries[j]) {
+				return
+			}
+		}
+		if i > 0 {
+			j = len(s.chunks[i-1].entries) - 1
+		}
+	}
+}
+
+// StreamTrimStrategy is the strategy of trimming a stream by XADD and XTRIM.
+type StreamTrimStrategy int
+
+const (
+	StreamTrimMaxLen StreamTrimStrategy = iota
+	StreamTrimMinID
+)
+
+// StreamTrimArgs are the arguments of trimming a stream. The stream is trimmed
+// to MaxLen entries, or the entries with IDs less than MinID are removed. When
+// Approx is true, only whole chunks are removed, and no more than Limit entries
+// are removed if Limit is positive.
+type StreamTrimArgs struct {
+	Strategy StreamTrimStrategy
+	MaxLen   int64
+	MinID    StreamID
+	Approx   bool
+	Limit    int64
+}
+
+// DefaultStreamTrimLimit is the default limit of the entries removed by an
+// approximate trimming.
+const DefaultStreamTrimLimit = 100 * streamChunkMaxEntries
+
+// trim removes the entries from the head of the stream by the arguments, and
+// returns the number of the removed entries.
+func (s *Stream) trim(args *StreamTrimArgs) int64 {
+	shouldTrim := func(entries []StreamEntry) bool {
+		if args.Strategy == StreamTrimMaxLen {
+			return int64(s.length-len(entries)) >= args.MaxLen
+		}
+		return entries[len(entries)-1].ID.Less(args.MinID)
+	}
+
+	removed := int64(0)
+	for len(s.chunks) > 0 {
+		entries := s.chunks[0].entries
+		if !shouldTrim(entries) {
+			break
+		}
+		if args.Approx && args.Limit > 0 && removed+int64(len(entries)) > args.Limit {
+			return removed
+		}
+		s.chunks[0] = nil
+		s.chunks = s.chunks[1:]
+		s.length -= len(entries)
+		removed += int64(len(entries))
+	}
+	if args.Approx || len(s.chunks) == 0 {
+		return removed
+	}
+
+	// Remove the remaining entries one by one from the first chunk.
+	chunk := s.chunks[0]
+	n := 0
+	for n < len(chunk.entries) {
+		if args.Strategy == StreamTrimMaxLen && int64(s.length-n) <= args.MaxLen {
+			break
+		} else if args.Strategy == StreamTrimMinID && !chunk.entries[n].ID.Less(args.MinID) {
+			break
+		}
+		n++
+	}
+	chunk.removeRange(0, n)
+	s.length -= n
+	return removed + int64(n)
+}
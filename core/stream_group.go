@@ -0,0 +1,155 @@
+package core
+
+import "sort"
+
+// StreamGroup is a consumer group of a stream. The entries delivered to its
+// consumers but not acknowledged yet are kept in the pending entries list, aka
+// the PEL, until they are acknowledged by XACK.
+type StreamGroup struct {
+	name   string
+	lastID StreamID
+	// entriesRead is the logical number of the entries read by the group, or
+	// -1 if it is unknown.
+	entriesRead int64
+	pending     map[StreamID]*streamNACK
+	pendingIDs  []StreamID
+	consumers   map[string]*StreamConsumer
+}
+
+// StreamConsumer is a consumer of a consumer group, with the entries delivered
+// to it and not acknowledged yet.
+type StreamConsumer struct {
+	name       string
+	seenTime   int64
+	activeTime int64
+	pending    map[StreamID]*streamNACK
+}
+
+// streamNACK is an entry of a PEL, which is delivered to the consumer at the
+// delivery time in unix milliseconds.
+type streamNACK struct {
+	consumer      *StreamConsumer
+	deliveryTime  int64
+	deliveryCount int64
+}
+
+func newStreamGroup(name string, lastID StreamID, entriesRead int64) *StreamGroup {
+	return &StreamGroup{
+		name:        name,
+		lastID:      lastID,
+		entriesRead: entriesRead,
+		pending:     make(map[StreamID]*streamNACK),
+		consumers:   make(map[string]*StreamConsumer),
+	}
+}
+
+// consumer returns the consumer of the name, creating it if it does not exist,
+// and updates its seen time.
+func (g *StreamGroup) consumer(name string, now int64) *StreamConsumer {
+	c, _ := g.createConsumer(name, now)
+	c.seenTime = now
+	return c
+}
+
+// createConsumer creates the consumer of the name, and returns false if it
+// already exists.
+func (g *StreamGroup) createConsumer(name string, now int64) (*StreamConsumer, bool) {
+	if c, ok := g.consumers[name]; ok {
+		return c, false
+	}
+	c := &StreamConsumer{
+		name:       name,
+		seenTime:   now,
+		activeTime: -1,
+		pending:    make(map[StreamID]*streamNACK),
+	}
+	g.consumers[name] = c
+	return c, true
+}
+
+// deleteConsumer deletes the consumer and its pending entries, and returns the
+// number of its pending entries.
+func (g *StreamGroup) deleteConsumer(name string) int64 {
+	c, ok := g.consumers[name]
+	if !ok {
+		return 0
+	}
+	cnt := int64(len(c.pending))
+	for id := range c.pending {
+		g.removePending(id)
+	}
+	delete(g.consumers, name)
+	return cnt
+}
+
+// deliver adds the entry to the PEL as delivered to the consumer, or assigns
+// it to the consumer with a new delivery if it is already pending.
+func (g *StreamGroup) deliver(id StreamID, c *StreamConsumer, now int64) {
+	nack, ok := g.pending[id]
+	if !ok {
+		nack = &streamNACK{}
+		g.pending[id] = nack
+		g.insertPendingID(id)
+	}
+	g.assign(id, nack, c)
+	nack.deliveryTime = now
+	nack.deliveryCount = 1
+}
+
+// assign moves the pending entry to the consumer.
+func (g *StreamGroup) assign(id StreamID, nack *streamNACK, c *StreamConsumer) {
+	if nack.consumer == c {
+		return
+	}
+	if nack.consumer != nil {
+		delete(nack.consumer.pending, id)
+	}
+	nack.consumer = c
+	c.pending[id] = nack
+}
+
+// removePending removes the entry from the PEL, and returns false if it is not
+// pending.
+func (g *StreamGroup) removePending(id StreamID) bool {
+	nack, ok := g.pending[id]
+	if !ok {
+		return false
+	}
+	delete(nack.consumer.pending, id)
+	delete(g.pending, id)
+
+	i := g.searchPendingID(id)
+	g.pendingIDs = append(g.pendingIDs[:i], g.pendingIDs[i+1:]...)
+	return true
+}
+
+// rangePending calls fn for the pending entries with the IDs between start and
+// end, both inclusive, in order until fn returns false. The entries may be
+// removed by fn.
+func (g *StreamGroup) rangePending(start, end StreamID, fn func(id StreamID, nack *streamNACK) bool) {
+	for i := g.searchPendingID(start); i < len(g.pendingIDs); {
+		id := g.pendingIDs[i]
+		if end.Less(id) || !fn(id, g.pending[id]) {
+			return
+		}
+		if i < len(g.pendingIDs) && g.pendingIDs[i] == id {
+			i++
+		}
+	}
+}
+
+func (g *StreamGroup) insertPendingID(id StreamID) {
+	n := len(g.pendingIDs)
+	if n == 0 || g.pendingIDs[n-1].Less(id) {
+		g.pendingIDs = append(g.pendingIDs, id)
+		return
+	}
+	i := g.searchPendingID(id)
+	g.pendingIDs = append(g.pendingIDs, StreamID{})
+	copy(g.pendingIDs[i+1:], g.pendingIDs[i:])
+	g.pendingIDs[i] = id
+}
+
+func (g *StreamGroup) searchPendingID(id StreamID) int {
+	return sort.Search(len(g.pendingIDs), func(i int) bool { return !g.pendingIDs[i].Less(id) })
+}
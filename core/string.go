@@ -1,10 +1,22 @@
 package core
 
+import (
+	"math"
+	"strconv"
+	"time"
+)
+
+// DefaultMaxStringLength is the default maximum length of a string value in
+// bytes.
+const DefaultMaxStringLength = 512 * 1024 * 1024
+
 type SetFlag int
 
 const (
 	SetFlagNX SetFlag = 1 << iota
 	SetFlagXX
+	SetFlagKeepTTL
+	SetFlagGet
 )
 
 func (db *Database) Get(key string) (string, bool, error) {
@@ -30,7 +42,7 @@ func (db *Database) Incr(key string, delta int64) (int64, error) {
 		obj = db.newObject()
 		obj.Type = TypeString
 		obj.Value = int64(val)
-		db.data[key] = obj
+		db.setKey(key, obj)
 	} else {
 		v, err := obj.IntValue()
 		if err != nil {
@@ -41,6 +53,7 @@ func (db *Database) Incr(key string, delta int64) (int64, error) {
 	}
 
 	obj.Encoding = EncodingInt
+	db.trackMemory(key, obj)
 	return val, nil
 }
 
@@ -68,47 +81,226 @@ func (db *Database) MSet(isNX bool, pairs ...string) (bool, error) {
 		obj := objs[i/2]
 		if obj == nil {
 			obj = db.newObject()
-			db.data[key] = obj
-		} else {
-			delete(db.expires, key)
+			db.setKey(key, obj)
 		}
 		obj.SetStringValue(value)
-		obj.Expires = 0
+		db.setExpire(key, obj, 0)
+		db.trackMemory(key, obj)
 	}
 
 	return true, nil
 }
 
-func (db *Database) Set(key string, value string, flag SetFlag, expires int64) (bool, string, error) {
-	obj, err := db.lookupKey(key, TypeNone, false)
+// Set sets the string value of the key with the expiration in unix time in
+// milliseconds, or without expiration if expires is 0. It returns false if the
+// value was not set for the NX or XX flags, and the old value of the key if it
+// was a string.
+func (db *Database) Set(key string, value string, flag SetFlag, expires int64) (bool, string, bool, error) {
+	obj, err := db.lookupKey(key, TypeNone, true)
 	if err != nil {
-		return false, "", err
-	}
-	switch flag {
-	case SetFlagNX:
-		if obj != nil && !obj.IsExpired() {
-			return false, "", nil
-		}
-	case SetFlagXX:
-		if obj == nil || obj.IsExpired() {
-			return false, "", nil
-		}
+		return false, "", false, err
 	}
 
 	oldVal := ""
+	oldFound := false
+	if obj != nil && obj.Type == TypeString {
+		oldVal = obj.StringValue()
+		oldFound = true
+	} else if obj != nil && flag&SetFlagGet != 0 {
+		return false, "", false, ErrWrongType
+	}
+
+	if (flag&SetFlagNX != 0 && obj != nil) || (flag&SetFlagXX != 0 && obj == nil) {
+		return false, oldVal, oldFound, nil
+	}
 
 	if obj == nil {
 		obj = db.newObject()
-		db.data[key] = obj
+		db.setKey(key, obj)
 	} else {
-		oldVal = obj.StringValue()
+		db.streamRemoved(key, obj)
 	}
 
 	obj.SetStringValue(value)
-	obj.Expires = expires
-	if expires > 0 {
-		db.expires[key] = expires
+	if flag&SetFlagKeepTTL == 0 {
+		db.setExpire(key, obj, expires)
+	}
+	db.trackMemory(key, obj)
+
+	return true, oldVal, oldFound, nil
+}
+
+// Append appends the value to the string of the key, or sets the value if the
+// key does not exist, and returns the length of the string after the append.
+func (db *Database) Append(key string, value string) (int64, error) {
+	obj, err := db.lookupKey(key, TypeString, true)
+	if err != nil {
+		return 0, err
+	}
+
+	if obj == nil {
+		if int64(len(value)) > db.maxStringLength {
+			return 0, ErrStringTooLarge
+		}
+		obj = db.newObject()
+		obj.SetStringValue(value)
+		db.setKey(key, obj)
+		db.trackMemory(key, obj)
+		return int64(len(value)), nil
+	}
+
+	buf := obj.mutableBytes()
+	if int64(len(buf)+len(value)) > db.maxStringLength {
+		return 0, ErrStringTooLarge
+	}
+	buf = append(buf, value...)
+	obj.Value = buf
+	db.trackMemory(key, obj)
+	return int64(len(buf)), nil
+}
+
+func (db *Database) StrLen(key string) (int64, error) {
+	obj, err := db.lookupKey(key, TypeString, true)
+	if err != nil || obj == nil {
+		return 0, err
+	}
+
+	switch obj.Encoding {
+	case EncodingBytes:
+		return int64(len(obj.Value.([]byte))), nil
+	case EncodingRaw:
+		return int64(len(obj.Value.(string))), nil
+	}
+	return int64(len(obj.StringValue())), nil
+}
+
+// GetRange returns the substring of the string of the key between the start
+// and the end offsets, both inclusive. Negative offsets count from the end of
+// the string, and the offsets out of the string are clamped to it.
+func (db *Database) GetRange(key string, start, end int64) (string, error) {
+	val, found, err := db.Get(key)
+	if err != nil || !found {
+		return "", err
+	}
+
+	start, end, ok := clampRange(start, end, int64(len(val)))
+	if !ok {
+		return "", nil
+	}
+	return val[start : end+1], nil
+}
+
+// SetRange overwrites the string of the key from the offset with the value,
+// padding the string with zero bytes if it is shorter than the offset, and
+// returns the length of the string after the modification.
+func (db *Database) SetRange(key string, offset int64, value string) (int64, error) {
+	obj, err := db.lookupKey(key, TypeString, true)
+	if err != nil {
+		return 0, err
+	}
+
+	if obj == nil && len(value) == 0 {
+		return 0, nil
+	}
+	if offset+int64(len(value)) > db.maxStringLength {
+		return 0, ErrStringTooLarge
+	}
+
+	if obj == nil {
+		obj = db.newObject()
+		obj.Type = TypeString
+		obj.Value = []byte{}
+		obj.Encoding = EncodingBytes
+		db.setKey(key, obj)
+	}
+
+	buf := obj.mutableBytes()
+	if len(value) == 0 {
+		return int64(len(buf)), nil
+	}
+
+	buf = growBytes(buf, int(offset)+len(value))
+	copy(buf[offset:], value)
+	obj.Value = buf
+	db.trackMemory(key, obj)
+	return int64(len(buf)), nil
+}
+
+// GetDel returns the string of the key and deletes the key.
+func (db *Database) GetDel(key string) (string, bool, error) {
+	obj, err := db.lookupKey(key, TypeString, true)
+	if err != nil || obj == nil {
+		return "", false, err
+	}
+
+	val := obj.StringValue()
+	db.removeKey(key, obj)
+	return val, true, nil
+}
+
+// GetEx returns the string of the key, and sets its expiration to the unix time
+// in milliseconds if expires is not 0, or removes its expiration if persist is
+// true. The key is deleted if the expiration is already in the past.
+func (db *Database) GetEx(key string, expires int64, persist bool) (string, bool, error) {
+	obj, err := db.lookupKey(key, TypeString, true)
+	if err != nil || obj == nil {
+		return "", false, err
+	}
+
+	val := obj.StringValue()
+	if expires != 0 && expires <= time.Now().UnixMilli() {
+		db.removeKey(key, obj)
+	} else if expires != 0 || (persist && obj.Expires != 0) {
+		db.setExpire(key, obj, expires)
+		db.trackMemory(key, obj)
+	}
+	return val, true, nil
+}
+
+// IncrByFloat increments the string of the key as a float by the delta, and
+// returns the formatted result that is stored as the new value.
+func (db *Database) IncrByFloat(key string, delta float64) (string, error) {
+	obj, err := db.lookupKey(key, TypeString, true)
+	if err != nil {
+		return "", err
+	}
+
+	val := 0.0
+	if obj != nil {
+		val, err = strconv.ParseFloat(obj.StringValue(), 64)
+		if err != nil || math.IsNaN(val) || math.IsInf(val, 0) {
+			return "", ErrNotFloat
+		}
+	}
+
+	val += delta
+	if math.IsNaN(val) || math.IsInf(val, 0) {
+		return "", ErrFloatNaN
+	}
+
+	str := strconv.FormatFloat(val, 'f', -1, 64)
+	if obj == nil {
+		obj = db.newObject()
+		db.setKey(key, obj)
+	}
+	obj.SetStringValue(str)
+	db.trackMemory(key, obj)
+	return str, nil
+}
+
+// growBytes extends the bytes with zero bytes to the length if they are shorter,
+// with extra capacity for further growth.
+func growBytes(buf []byte, length int) []byte {
+	if length <= len(buf) {
+		return buf
+	} else if length <= cap(buf) {
+		old := len(buf)
+		buf = buf[:length]
+		clear(buf[old:])
+		return buf
 	}
 
-	return true, oldVal, nil
+	grown := make([]byte, length, length+length/4)
+	copy(grown, buf)
+	return grown
 }
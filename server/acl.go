@@ -0,0 +1,687 @@
+package server
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ghosind/antdb/client"
+	"github.com/ghosind/antdb/util"
+)
+
+const (
+	aclDefaultUser = "default"
+
+	aclLogMaxLen = 128
+	// aclLogGroupWindow is the time in which a denial similar to a previous one
+	// increases the count of the previous log entry instead of adding one.
+	aclLogGroupWindow = 60 * time.Second
+)
+
+const (
+	aclDeniedCommand = "command"
+	aclDeniedKey     = "key"
+	aclDeniedAuth    = "auth"
+)
+
+// aclCategories are the command categories that can be used in ACL rules,
+// derived from the flags of the commands.
+var aclCategories = []struct {
+	Name string
+	Flag CommandFlags
+}{
+	{Name: "read", Flag: CommandFlagRead},
+	{Name: "write", Flag: CommandFlagWrite},
+	{Name: "admin", Flag: CommandFlagAdmin},
+	{Name: "dangerous", Flag: CommandFlagDangerous},
+	{Name: "blocking", Flag: CommandFlagBlocking},
+}
+
+type aclUser struct {
+	name         string
+	enabled      bool
+	nopass       bool
+	passwords    []string
+	commands     map[string]bool
+	commandRules []string
+	allKeys      bool
+	keyPatterns  []aclKeyPattern
+}
+
+// aclKeyPermissions are the permissions that a key pattern grants to the keys
+// that match it.
+type aclKeyPermissions int
+
+const (
+	aclKeyRead aclKeyPermissions = 1 << iota
+	aclKeyWrite
+
+	aclKeyReadWrite = aclKeyRead | aclKeyWrite
+)
+
+type aclKeyPattern struct {
+	pattern     string
+	re          *regexp.Regexp
+	permissions aclKeyPermissions
+}
+
+type aclLogEntry struct {
+	count      int
+	reason     string
+	context    string
+	object     string
+	username   string
+	clientInfo string
+	entryID    int64
+	created    time.Time
+	updated    time.Time
+}
+
+type acl struct {
+	mu      sync.RWMutex
+	users   map[string]*aclUser
+	file    string
+	log     []*aclLogEntry
+	nextLog int64
+}
+
+func newACL(file string, requirePass string) *acl {
+	a := &acl{
+		users: make(map[string]*aclUser),
+		file:  file,
+	}
+
+	user := newDefaultACLUser()
+	if requirePass != "" {
+		user.setRule("resetpass")
+		user.setRule(">" + requirePass)
+	}
+	a.users[user.name] = user
+
+	return a
+}
+
+func newACLUser(name string) *aclUser {
+	return &aclUser{
+		name:     name,
+		commands: make(map[string]bool),
+	}
+}
+
+// newDefaultACLUser creates the default user, which is used by new connections
+// and can run all commands on all keys without password.
+func newDefaultACLUser() *aclUser {
+	user := newACLUser(aclDefaultUser)
+	for _, rule := range []string{"on", "nopass", "allkeys", "allcommands"} {
+		user.setRule(rule)
+	}
+	return user
+}
+
+func (u *aclUser) clone() *aclUser {
+	user := &aclUser{
+		name:         u.name,
+		enabled:      u.enabled,
+		nopass:       u.nopass,
+		passwords:    append([]string(nil), u.passwords...),
+		commands:     make(map[string]bool, len(u.commands)),
+		commandRules: append([]string(nil), u.commandRules...),
+		allKeys:      u.allKeys,
+		keyPatterns:  append([]aclKeyPattern(nil), u.keyPatterns...),
+	}
+	for name := range u.commands {
+		user.commands[name] = true
+	}
+	return user
+}
+
+// setRule applies an ACL rule to the user, as defined by ACL SETUSER.
+func (u *aclUser) setRule(rule string) error {
+	switch strings.ToLower(rule) {
+	case "on":
+		u.enabled = true
+		return nil
+	case "off":
+		u.enabled = false
+		return nil
+	case "nopass":
+		u.nopass = true
+		u.passwords = nil
+		return nil
+	case "resetpass":
+		u.nopass = false
+		u.passwords = nil
+		return nil
+	case "allkeys":
+		u.allKeys = true
+		u.keyPatterns = nil
+		return nil
+	case "resetkeys":
+		u.allKeys = false
+		u.keyPatterns = nil
+		return nil
+	case "allcommands":
+		return u.setCommandRule("+@all")
+	case "nocommands":
+		return u.setCommandRule("-@all")
+	case "reset":
+		for _, r := range []string{"resetpass", "resetkeys", "off", "nocommands"} {
+			u.setRule(r)
+		}
+		return nil
+	}
+
+	if rule == "" {
+		return newACLRuleError(rule, "Syntax error")
+	}
+
+	switch rule[0] {
+	case '>':
+		u.addPassword(hashPassword(rule[1:]))
+	case '<':
+		u.removePassword(hashPassword(rule[1:]))
+	case '#':
+		hash := strings.ToLower(rule[1:])
+		if !isPasswordHash(hash) {
+			return newACLRuleError(rule, "The password hash must be exactly 64 characters and contain only lowercase hexadecimal characters")
+		}
+		u.addPassword(hash)
+	case '!':
+		hash := strings.ToLower(rule[1:])
+		if !isPasswordHash(hash) {
+			return newACLRuleError(rule, "The password hash must be exactly 64 characters and contain only lowercase hexadecimal characters")
+		}
+		u.removePassword(hash)
+	case '~', '%':
+		return u.setKeyRule(rule)
+	case '+', '-':
+		return u.setCommandRule(rule)
+	default:
+		return newACLRuleError(rule, "Syntax error")
+	}
+
+	return nil
+}
+
+// setKeyRule adds a key pattern, in the form of ~<pattern> for read and write
+// access, or %<R|W|RW>~<pattern> for the specified access.
+func (u *aclUser) setKeyRule(rule string) error {
+	permissions := aclKeyReadWrite
+	pattern := rule[1:]
+	if rule[0] == '%' {
+		i := strings.IndexByte(rule, '~')
+		if i < 0 {
+			return newACLRuleError(rule, "Syntax error")
+		}
+		permissions = 0
+		for _, c := range strings.ToUpper(rule[1:i]) {
+			switch c {
+			case 'R':
+				permissions |= aclKeyRead
+			case 'W':
+				permissions |= aclKeyWrite
+			default:
+				return newACLRuleError(rule, "Syntax error")
+			}
+		}
+		if permissions == 0 {
+			return newACLRuleError(rule, "Syntax error")
+		}
+		pattern = rule[i+1:]
+	}
+
+	if u.allKeys {
+		return newACLRuleError(rule, "Adding a pattern after the * pattern (or the 'allkeys' flag) is not valid and does not have any effect")
+	}
+	if pattern == "*" && permissions == aclKeyReadWrite {
+		return u.setRule("allkeys")
+	}
+	re, err := util.GlobToRegexp(pattern)
+	if err != nil {
+		return newACLRuleError(rule, "Syntax error")
+	}
+	u.keyPatterns = append(u.keyPatterns, aclKeyPattern{
+		pattern:     pattern,
+		re:          re,
+		permissions: permissions,
+	})
+	return nil
+}
+
+func (u *aclUser) setCommandRule(rule string) error {
+	allow := rule[0] == '+'
+	name := strings.ToUpper(rule[1:])
+
+	if strings.HasPrefix(name, "@") {
+		category := strings.ToLower(name[1:])
+		if category == "all" {
+			u.commandRules = nil
+			u.commands = make(map[string]bool)
+			if allow {
+				for name := range dbCommands {
+					u.commands[name] = true
+				}
+			}
+			u.commandRules = append(u.commandRules, rule[:1]+"@all")
+			return nil
+		}
+
+		flag, ok := lookupACLCategory(category)
+		if !ok {
+			return newACLRuleError(rule, "Unknown command or category name in ACL")
+		}
+		for name, cmd := range dbCommands {
+			if cmd.Flags&flag != 0 {
+				u.allowCommand(name, allow)
+			}
+		}
+		u.commandRules = append(u.commandRules, rule[:1]+"@"+category)
+		return nil
+	}
+
+	if _, ok := dbCommands[name]; !ok {
+		return newACLRuleError(rule, "Unknown command or category name in ACL")
+	}
+	u.allowCommand(name, allow)
+	u.commandRules = append(u.commandRules, rule[:1]+strings.ToLower(name))
+	return nil
+}
+
+func (u *aclUser) allowCommand(name string, allow bool) {
+	if allow {
+		u.commands[name] = true
+	} else {
+		delete(u.commands, name)
+	}
+}
+
+func (u *aclUser) addPassword(hash string) {
+	u.nopass = false
+	for _, p := range u.passwords {
+		if p == hash {
+			return
+		}
+	}
+	u.passwords = append(u.passwords, hash)
+}
+
+func (u *aclUser) removePassword(hash string) {
+	for i, p := range u.passwords {
+		if p == hash {
+			u.passwords = append(u.passwords[:i], u.passwords[i+1:]...)
+			return
+		}
+	}
+}
+
+func (u *aclUser) checkPassword(password string) bool {
+	if !u.enabled {
+		return false
+	}
+	if u.nopass {
+		return true
+	}
+
+	hash := hashPassword(password)
+	for _, p := range u.passwords {
+		if p == hash {
+			return true
+		}
+	}
+	return false
+}
+
+// canAccessKey returns true if the patterns of the user grant all the
+// permissions to the key. A key may get the permissions from different
+// patterns.
+func (u *aclUser) canAccessKey(key string, permissions aclKeyPermissions) bool {
+	if u.allKeys {
+		return true
+	}
+
+	var granted aclKeyPermissions
+	for _, p := range u.keyPatterns {
+		if p.re.MatchString(key) {
+			granted |= p.permissions
+			if granted&permissions == permissions {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (u *aclUser) flags() []string {
+	flags := make([]string, 0, 2)
+	if u.enabled {
+		flags = append(flags, "on")
+	} else {
+		flags = append(flags, "off")
+	}
+	if u.nopass {
+		flags = append(flags, "nopass")
+	}
+	return flags
+}
+
+func (u *aclUser) describeCommands() string {
+	if len(u.commandRules) == 0 {
+		return "-@all"
+	}
+	return strings.Join(u.commandRules, " ")
+}
+
+func (u *aclUser) describeKeys() string {
+	if u.allKeys {
+		return "~*"
+	}
+
+	patterns := make([]string, 0, len(u.keyPatterns))
+	for _, p := range u.keyPatterns {
+		switch p.permissions {
+		case aclKeyRead:
+			patterns = append(patterns, "%R~"+p.pattern)
+		case aclKeyWrite:
+			patterns = append(patterns, "%W~"+p.pattern)
+		default:
+			patterns = append(patterns, "~"+p.pattern)
+		}
+	}
+	return strings.Join(patterns, " ")
+}
+
+// describe returns the rules that recreate the user, in the format of ACL LIST
+// and of the ACL file.
+func (u *aclUser) describe() string {
+	parts := []string{"user", u.name}
+	parts = append(parts, u.flags()...)
+	for _, p := range u.passwords {
+		parts = append(parts, "#"+p)
+	}
+	if keys := u.describeKeys(); keys != "" {
+		parts = append(parts, keys)
+	}
+	parts = append(parts, u.describeCommands())
+	return strings.Join(parts, " ")
+}
+
+func (a *acl) getUser(name string) *aclUser {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return a.users[name]
+}
+
+// hasAllKeys returns true if the user can access all keys, which is required
+// by the commands that return keys from the whole keyspace.
+func (a *acl) hasAllKeys(username string) bool {
+	user := a.getUser(username)
+	return user != nil && user.allKeys
+}
+
+// isDefaultUserNoPass returns true if new connections are authenticated as the
+// default user without AUTH.
+func (a *acl) isDefaultUserNoPass() bool {
+	user := a.getUser(aclDefaultUser)
+	return user != nil && user.enabled && user.nopass
+}
+
+func (a *acl) authenticate(username, password string) bool {
+	user := a.getUser(username)
+	return user != nil && user.checkPassword(password)
+}
+
+// setUser applies the rules to the user, creating it if it does not exist. The
+// user is not changed if any of the rules is invalid.
+func (a *acl) setUser(name string, rules ...string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var user *aclUser
+	if old, ok := a.users[name]; ok {
+		user = old.clone()
+	} else {
+		user = newACLUser(name)
+	}
+
+	for _, rule := range rules {
+		if err := user.setRule(rule); err != nil {
+			return err
+		}
+	}
+
+	a.users[name] = user
+	return nil
+}
+
+func (a *acl) deleteUsers(names ...string) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	cnt := 0
+	for _, name := range names {
+		if name == aclDefaultUser {
+			return cnt, ErrACLDeleteDefaultUser
+		}
+		if _, ok := a.users[name]; ok {
+			delete(a.users, name)
+			cnt++
+		}
+	}
+	return cnt, nil
+}
+
+func (a *acl) sortedUsers() []*aclUser {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	users := make([]*aclUser, 0, len(a.users))
+	for _, user := range a.users {
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].name < users[j].name
+	})
+	return users
+}
+
+// checkPermission checks that the user can run the command with the arguments,
+// and returns the reason and the denied object if it can not.
+func (a *acl) checkPermission(username, name string, cmd *DBCommand, args []string) (string, string, bool) {
+	user := a.getUser(username)
+	if user == nil || !user.enabled || !user.commands[name] {
+		return aclDeniedCommand, strings.ToLower(name), false
+	}
+
+	if !user.allKeys {
+		permissions := cmd.keyPermissions()
+		for _, key := range cmd.keys(args) {
+			if !user.canAccessKey(key, permissions) {
+				return aclDeniedKey, key, false
+			}
+		}
+	}
+
+	return "", "", true
+}
+
+func (a *acl) addLogEntry(cli *client.Client, reason, object, username string) {
+	context := "toplevel"
+	if cli.Flag&client.CLIENT_MULTI != 0 {
+		context = "multi"
+	}
+	now := time.Now()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, entry := range a.log {
+		if entry.reason == reason && entry.context == context && entry.object == object &&
+			entry.username == username && now.Sub(entry.updated) < aclLogGroupWindow {
+			entry.count++
+			entry.updated = now
+			entry.clientInfo = formatClientInfo(cli.Info(), now)
+			return
+		}
+	}
+
+	entry := &aclLogEntry{
+		count:      1,
+		reason:     reason,
+		context:    context,
+		object:     object,
+		username:   username,
+		clientInfo: formatClientInfo(cli.Info(), now),
+		entryID:    a.nextLog,
+		created:    now,
+		updated:    now,
+	}
+	a.nextLog++
+
+	a.log = append([]*aclLogEntry{entry}, a.log...)
+	if len(a.log) > aclLogMaxLen {
+		a.log = a.log[:aclLogMaxLen]
+	}
+}
+
+func (a *acl) logEntries(count int) []aclLogEntry {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if count < 0 || count > len(a.log) {
+		count = len(a.log)
+	}
+	entries := make([]aclLogEntry, count)
+	for i := 0; i < count; i++ {
+		entries[i] = *a.log[i]
+	}
+	return entries
+}
+
+func (a *acl) resetLog() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.log = nil
+}
+
+// load replaces all users with the users of the ACL file. The users are not
+// changed if the file has any error.
+func (a *acl) load() error {
+	if a.file == "" {
+		return ErrACLNoFile
+	}
+
+	f, err := os.Open(a.file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	users := make(map[string]*aclUser)
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if fields[0] != "user" || len(fields) < 2 {
+			return fmt.Errorf("%s:%d: line should start with user keyword", a.file, lineNum)
+		}
+
+		name := fields[1]
+		if _, ok := users[name]; ok {
+			return fmt.Errorf("%s:%d: duplicate user '%s' found", a.file, lineNum, name)
+		}
+		user := newACLUser(name)
+		for _, rule := range fields[2:] {
+			if err := user.setRule(rule); err != nil {
+				return fmt.Errorf("%s:%d: %v", a.file, lineNum, err)
+			}
+		}
+		users[name] = user
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if _, ok := users[aclDefaultUser]; !ok {
+		users[aclDefaultUser] = newDefaultACLUser()
+	}
+
+	a.mu.Lock()
+	a.users = users
+	a.mu.Unlock()
+
+	return nil
+}
+
+// save writes all users into the ACL file, replacing it atomically.
+func (a *acl) save() error {
+	if a.file == "" {
+		return ErrACLNoFile
+	}
+
+	var buf strings.Builder
+	for _, user := range a.sortedUsers() {
+		buf.WriteString(user.describe())
+		buf.WriteString("\n")
+	}
+
+	tmpFile := a.file + ".tmp"
+	if err := os.WriteFile(tmpFile, []byte(buf.String()), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, a.file)
+}
+
+// keyPermissions returns the permissions that the command requires on its keys,
+// which are derived from the read and write flags of the command. A command
+// without both flags requires read and write permissions.
+func (cmd *DBCommand) keyPermissions() aclKeyPermissions {
+	var permissions aclKeyPermissions
+	if cmd.Flags&CommandFlagRead != 0 {
+		permissions |= aclKeyRead
+	}
+	if cmd.Flags&CommandFlagWrite != 0 {
+		permissions |= aclKeyWrite
+	}
+	if permissions == 0 {
+		permissions = aclKeyReadWrite
+	}
+	return permissions
+}
+
+func lookupACLCategory(name string) (CommandFlags, bool) {
+	for _, category := range aclCategories {
+		if category.Name == name {
+			return category.Flag, true
+		}
+	}
+	return 0, false
+}
+
+func hashPassword(password string) string {
+	sum := sha256.Sum256([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
+
+func isPasswordHash(hash string) bool {
+	if len(hash) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil
+}
+
+func newACLRuleError(rule, reason string) error {
+	return errors.New("Error in ACL SETUSER modifier '" + rule + "': " + reason)
+}
@@ -0,0 +1,464 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestACLSetRule(t *testing.T) {
+	hashA, hashB := hashPassword("a"), hashPassword("b")
+
+	tests := []struct {
+		name     string
+		rules    []string
+		describe string
+		err      string
+	}{
+		{name: "new user", rules: nil, describe: "user alice off -@all"},
+		{name: "on", rules: []string{"on"}, describe: "user alice on -@all"},
+		{name: "off", rules: []string{"on", "OFF"}, describe: "user alice off -@all"},
+		{name: "add password", rules: []string{">a", ">b", ">a"}, describe: "user alice off #" + hashA + " #" + hashB + " -@all"},
+		{name: "remove password", rules: []string{">a", ">b", "<a"}, describe: "user alice off #" + hashB + " -@all"},
+		{name: "add hash", rules: []string{"#" + strings.ToUpper(hashA)}, describe: "user alice off #" + hashA + " -@all"},
+		{name: "remove hash", rules: []string{">a", ">b", "!" + hashB}, describe: "user alice off #" + hashA + " -@all"},
+		{
+			name:  "invalid hash",
+			rules: []string{"#" + hashA[:63]},
+			err:   "Error in ACL SETUSER modifier '#" + hashA[:63] + "': The password hash must be exactly 64 characters and contain only lowercase hexadecimal characters",
+		},
+		{
+			name:  "invalid remove hash",
+			rules: []string{"!" + hashA[:63] + "g"},
+			err:   "Error in ACL SETUSER modifier '!" + hashA[:63] + "g': The password hash must be exactly 64 characters and contain only lowercase hexadecimal characters",
+		},
+		{name: "nopass", rules: []string{">a", "nopass"}, describe: "user alice off nopass -@all"},
+		{name: "password after nopass", rules: []string{"nopass", ">a"}, describe: "user alice off #" + hashA + " -@all"},
+		{name: "resetpass", rules: []string{"nopass", ">a", "resetpass"}, describe: "user alice off -@all"},
+		{name: "key patterns", rules: []string{"~foo:*", "%R~bar:*", "%W~baz:*", "%RW~qux:*", "%wr~quux"}, describe: "user alice off ~foo:* %R~bar:* %W~baz:* ~qux:* ~quux -@all"},
+		{name: "all keys pattern", rules: []string{"~foo:*", "~*"}, describe: "user alice off ~* -@all"},
+		{name: "all keys read and write", rules: []string{"%RW~*"}, describe: "user alice off ~* -@all"},
+		{name: "all keys read", rules: []string{"%R~*"}, describe: "user alice off %R~* -@all"},
+		{name: "allkeys", rules: []string{"~foo:*", "allkeys"}, describe: "user alice off ~* -@all"},
+		{name: "resetkeys", rules: []string{"allkeys", "resetkeys", "~foo:*"}, describe: "user alice off ~foo:* -@all"},
+		{
+			name:  "pattern after allkeys",
+			rules: []string{"allkeys", "~foo:*"},
+			err:   "Error in ACL SETUSER modifier '~foo:*': Adding a pattern after the * pattern (or the 'allkeys' flag) is not valid and does not have any effect",
+		},
+		{
+			name:  "read pattern after allkeys",
+			rules: []string{"~*", "%R~foo:*"},
+			err:   "Error in ACL SETUSER modifier '%R~foo:*': Adding a pattern after the * pattern (or the 'allkeys' flag) is not valid and does not have any effect",
+		},
+		{name: "invalid permission", rules: []string{"%X~foo"}, err: "Error in ACL SETUSER modifier '%X~foo': Syntax error"},
+		{name: "no permission", rules: []string{"%~foo"}, err: "Error in ACL SETUSER modifier '%~foo': Syntax error"},
+		{name: "no pattern", rules: []string{"%R"}, err: "Error in ACL SETUSER modifier '%R': Syntax error"},
+		{name: "add category", rules: []string{"+@read"}, describe: "user alice off +@read"},
+		{name: "add commands", rules: []string{"+@read", "-get", "+SET"}, describe: "user alice off +@read -get +set"},
+		{name: "remove category", rules: []string{"allcommands", "-@dangerous"}, describe: "user alice off +@all -@dangerous"},
+		{name: "all commands", rules: []string{"+get", "+@all"}, describe: "user alice off +@all"},
+		{name: "no commands", rules: []string{"allcommands", "-get", "nocommands"}, describe: "user alice off -@all"},
+		{name: "unknown category", rules: []string{"+@unknown"}, err: "Error in ACL SETUSER modifier '+@unknown': Unknown command or category name in ACL"},
+		{name: "unknown command", rules: []string{"-unknown"}, err: "Error in ACL SETUSER modifier '-unknown': Unknown command or category name in ACL"},
+		{
+			name:     "reset",
+			rules:    []string{"on", ">a", "~foo:*", "allcommands", "reset"},
+			describe: "user alice off -@all",
+		},
+		{name: "empty rule", rules: []string{""}, err: "Error in ACL SETUSER modifier '': Syntax error"},
+		{name: "invalid rule", rules: []string{"unknown"}, err: "Error in ACL SETUSER modifier 'unknown': Syntax error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := newACLUser("alice")
+			var err error
+			for _, rule := range tt.rules {
+				if err = user.setRule(rule); err != nil {
+					break
+				}
+			}
+
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("expected error %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to set rules: %v", err)
+			}
+			if describe := user.describe(); describe != tt.describe {
+				t.Errorf("expected user %q, got %q", tt.describe, describe)
+			}
+		})
+	}
+}
+
+func TestACLUserPassword(t *testing.T) {
+	tests := []struct {
+		name      string
+		rules     []string
+		passwords map[string]bool
+	}{
+		{name: "no password", rules: []string{"on"}, passwords: map[string]bool{"": false, "a": false}},
+		{name: "nopass", rules: []string{"on", "nopass"}, passwords: map[string]bool{"": true, "a": true}},
+		{name: "off", rules: []string{"nopass", ">a"}, passwords: map[string]bool{"a": false}},
+		{name: "passwords", rules: []string{"on", ">a", ">b"}, passwords: map[string]bool{"a": true, "b": true, "c": false}},
+		{name: "hash", rules: []string{"on", "#" + hashPassword("a")}, passwords: map[string]bool{"a": true, hashPassword("a"): false}},
+		{name: "removed password", rules: []string{"on", ">a", ">b", "<a"}, passwords: map[string]bool{"a": false, "b": true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := newACLUser("alice")
+			for _, rule := range tt.rules {
+				if err := user.setRule(rule); err != nil {
+					t.Fatalf("failed to set rule %q: %v", rule, err)
+				}
+			}
+			for password, expected := range tt.passwords {
+				if ok := user.checkPassword(password); ok != expected {
+					t.Errorf("password %q: expected %v, got %v", password, expected, ok)
+				}
+			}
+		})
+	}
+}
+
+func TestACLCategories(t *testing.T) {
+	for _, category := range aclCategories {
+		var expected []string
+		for name, cmd := range dbCommands {
+			if cmd.Flags&category.Flag != 0 {
+				expected = append(expected, name)
+			}
+		}
+		if len(expected) == 0 {
+			t.Errorf("@%s: expected commands in category", category.Name)
+		}
+		sort.Strings(expected)
+
+		user := newACLUser("alice")
+		if err := user.setRule("+@" + category.Name); err != nil {
+			t.Fatalf("failed to add category %s: %v", category.Name, err)
+		}
+		if commands := aclUserCommands(user); strings.Join(commands, " ") != strings.Join(expected, " ") {
+			t.Errorf("+@%s: expected commands %v, got %v", category.Name, expected, commands)
+		}
+
+		user = newACLUser("alice")
+		if err := user.setRule("allcommands"); err != nil {
+			t.Fatalf("failed to add all commands: %v", err)
+		}
+		if err := user.setRule("-@" + category.Name); err != nil {
+			t.Fatalf("failed to remove category %s: %v", category.Name, err)
+		}
+		if len(user.commands) != len(dbCommands)-len(expected) {
+			t.Errorf("-@%s: expected %d commands, got %d", category.Name, len(dbCommands)-len(expected), len(user.commands))
+		}
+		for _, name := range expected {
+			if user.commands[name] {
+				t.Errorf("-@%s: expected %s removed", category.Name, name)
+			}
+		}
+	}
+
+	for category, name := range map[string]string{
+		"read":      "GET",
+		"write":     "SET",
+		"admin":     "SLOWLOG",
+		"dangerous": "FLUSHALL",
+		"blocking":  "XREAD",
+	} {
+		flag, ok := lookupACLCategory(category)
+		if !ok {
+			t.Fatalf("expected category %s", category)
+		}
+		if dbCommands[name].Flags&flag == 0 {
+			t.Errorf("expected %s in category @%s", name, category)
+		}
+	}
+}
+
+// aclUserCommands returns the sorted names of the commands that the user can
+// run.
+func aclUserCommands(user *aclUser) []string {
+	commands := make([]string, 0, len(user.commands))
+	for name := range user.commands {
+		commands = append(commands, name)
+	}
+	sort.Strings(commands)
+	return commands
+}
+
+func TestACLCheckPermission(t *testing.T) {
+	tests := []struct {
+		name   string
+		rules  []string
+		args   []string
+		reason string
+		object string
+	}{
+		{name: "allowed", rules: []string{"on", "+get", "~foo:*"}, args: []string{"GET", "foo:1"}},
+		{name: "command denied", rules: []string{"on", "+get", "~foo:*"}, args: []string{"SET", "foo:1", "v"}, reason: aclDeniedCommand, object: "set"},
+		{name: "category denied", rules: []string{"on", "+@all", "-@write", "~foo:*"}, args: []string{"DEL", "foo:1"}, reason: aclDeniedCommand, object: "del"},
+		{name: "user off", rules: []string{"+get", "~foo:*"}, args: []string{"GET", "foo:1"}, reason: aclDeniedCommand, object: "get"},
+		{name: "key denied", rules: []string{"on", "+get", "~foo:*"}, args: []string{"GET", "bar"}, reason: aclDeniedKey, object: "bar"},
+		{name: "no key patterns", rules: []string{"on", "+get"}, args: []string{"GET", "foo:1"}, reason: aclDeniedKey, object: "foo:1"},
+		{name: "all keys", rules: []string{"on", "+get", "allkeys"}, args: []string{"GET", "bar"}},
+		{name: "keyless command", rules: []string{"on", "+ping"}, args: []string{"PING"}},
+		{name: "multiple keys", rules: []string{"on", "+mget", "~foo:*", "~bar"}, args: []string{"MGET", "foo:1", "bar", "foo:2"}},
+		{name: "multiple keys denied", rules: []string{"on", "+mget", "~foo:*"}, args: []string{"MGET", "foo:1", "bar", "foo:2"}, reason: aclDeniedKey, object: "bar"},
+		{name: "key step", rules: []string{"on", "+mset", "~foo:*"}, args: []string{"MSET", "foo:1", "bar", "foo:2", "baz"}},
+		{name: "key step denied", rules: []string{"on", "+mset", "~foo:*"}, args: []string{"MSET", "foo:1", "v", "bar", "v"}, reason: aclDeniedKey, object: "bar"},
+		{name: "last key", rules: []string{"on", "+lmove", "~foo:*"}, args: []string{"LMOVE", "foo:1", "bar", "LEFT", "RIGHT"}, reason: aclDeniedKey, object: "bar"},
+		{name: "memory usage", rules: []string{"on", "+memory", "~foo:*"}, args: []string{"MEMORY", "USAGE", "foo:1"}},
+		{name: "memory usage denied", rules: []string{"on", "+memory", "~foo:*"}, args: []string{"MEMORY", "USAGE", "bar", "SAMPLES", "0"}, reason: aclDeniedKey, object: "bar"},
+		{name: "memory stats", rules: []string{"on", "+memory", "~foo:*"}, args: []string{"MEMORY", "STATS"}},
+		{name: "xread keys", rules: []string{"on", "+xread", "~foo:*"}, args: []string{"XREAD", "COUNT", "1", "STREAMS", "foo:1", "foo:2", "0", "0"}},
+		{name: "xread keys denied", rules: []string{"on", "+xread", "~foo:*"}, args: []string{"XREAD", "STREAMS", "foo:1", "bar", "0", "0"}, reason: aclDeniedKey, object: "bar"},
+		{name: "read pattern", rules: []string{"on", "+get", "+set", "%R~foo:*"}, args: []string{"GET", "foo:1"}},
+		{name: "read pattern write", rules: []string{"on", "+get", "+set", "%R~foo:*"}, args: []string{"SET", "foo:1", "v"}, reason: aclDeniedKey, object: "foo:1"},
+		{name: "write pattern", rules: []string{"on", "+get", "+set", "%W~foo:*"}, args: []string{"SET", "foo:1", "v"}},
+		{name: "write pattern read", rules: []string{"on", "+get", "+set", "%W~foo:*"}, args: []string{"GET", "foo:1"}, reason: aclDeniedKey, object: "foo:1"},
+		{name: "read all keys", rules: []string{"on", "+get", "+set", "%R~*"}, args: []string{"SET", "foo:1", "v"}, reason: aclDeniedKey, object: "foo:1"},
+		{name: "write pattern memory usage", rules: []string{"on", "+memory", "%W~foo:*"}, args: []string{"MEMORY", "USAGE", "foo:1"}, reason: aclDeniedKey, object: "foo:1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newACL("", "")
+			if err := a.setUser("alice", tt.rules...); err != nil {
+				t.Fatalf("failed to set user: %v", err)
+			}
+
+			name := tt.args[0]
+			cmd := dbCommands[name]
+			reason, object, ok := a.checkPermission("alice", name, &cmd, tt.args[1:])
+			if ok != (tt.reason == "") || reason != tt.reason || object != tt.object {
+				t.Errorf("expected (%q, %q, %v), got (%q, %q, %v)", tt.reason, tt.object, tt.reason == "", reason, object, ok)
+			}
+		})
+	}
+
+	a := newACL("", "")
+	cmd := dbCommands["GET"]
+	if reason, object, ok := a.checkPermission("unknown", "GET", &cmd, []string{"foo"}); ok || reason != aclDeniedCommand || object != "get" {
+		t.Errorf("unknown user: expected command denied, got (%q, %q, %v)", reason, object, ok)
+	}
+}
+
+func TestACLKeyPermissions(t *testing.T) {
+	// A command without the read and write flags requires both permissions on
+	// its keys, which may be granted by different patterns.
+	cmd := &DBCommand{Keys: KeySpec{1, 1, 1}}
+	if permissions := cmd.keyPermissions(); permissions != aclKeyReadWrite {
+		t.Fatalf("expected read and write permissions, got %d", permissions)
+	}
+
+	tests := []struct {
+		rules []string
+		ok    bool
+	}{
+		{rules: []string{"%R~foo"}, ok: false},
+		{rules: []string{"%W~foo"}, ok: false},
+		{rules: []string{"%R~foo", "%W~bar"}, ok: false},
+		{rules: []string{"%R~f*", "%W~foo"}, ok: true},
+		{rules: []string{"%RW~foo"}, ok: true},
+		{rules: []string{"~f*"}, ok: true},
+	}
+
+	for _, tt := range tests {
+		user := newACLUser("alice")
+		for _, rule := range tt.rules {
+			if err := user.setRule(rule); err != nil {
+				t.Fatalf("failed to set rule %q: %v", rule, err)
+			}
+		}
+		if ok := user.canAccessKey("foo", cmd.keyPermissions()); ok != tt.ok {
+			t.Errorf("%v: expected %v, got %v", tt.rules, tt.ok, ok)
+		}
+	}
+}
+
+func TestACLDefaultUser(t *testing.T) {
+	a := newACL("", "")
+	if !a.isDefaultUserNoPass() {
+		t.Errorf("expected default user without password")
+	}
+	if !a.authenticate(aclDefaultUser, "any") {
+		t.Errorf("expected default user authenticated with any password")
+	}
+	cmd := dbCommands["FLUSHALL"]
+	if _, _, ok := a.checkPermission(aclDefaultUser, "FLUSHALL", &cmd, nil); !ok {
+		t.Errorf("expected default user to run all commands")
+	}
+
+	a = newACL("", "secret")
+	if a.isDefaultUserNoPass() {
+		t.Errorf("expected default user with requirepass to require password")
+	}
+	if a.authenticate(aclDefaultUser, "wrong") {
+		t.Errorf("expected default user not authenticated with wrong password")
+	}
+	if !a.authenticate(aclDefaultUser, "secret") {
+		t.Errorf("expected default user authenticated with requirepass")
+	}
+
+	if _, err := a.deleteUsers(aclDefaultUser); err != ErrACLDeleteDefaultUser {
+		t.Errorf("expected error %v, got %v", ErrACLDeleteDefaultUser, err)
+	}
+}
+
+func TestACLSetUserAtomic(t *testing.T) {
+	a := newACL("", "")
+	if err := a.setUser("alice", "on", ">a", "~foo:*", "+get"); err != nil {
+		t.Fatalf("failed to set user: %v", err)
+	}
+	expected := a.getUser("alice").describe()
+
+	if err := a.setUser("alice", "off", "+set", "+@unknown"); err == nil {
+		t.Fatalf("expected error for unknown category")
+	}
+	if describe := a.getUser("alice").describe(); describe != expected {
+		t.Errorf("expected user unchanged %q, got %q", expected, describe)
+	}
+
+	if err := a.setUser("bob", "on", "invalid"); err == nil {
+		t.Fatalf("expected error for invalid rule")
+	}
+	if a.getUser("bob") != nil {
+		t.Errorf("expected user not created")
+	}
+}
+
+func TestACLFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "users.acl")
+
+	a := newACL(file, "")
+	users := map[string][]string{
+		aclDefaultUser: {"resetpass", ">secret"},
+		"alice":        {"on", ">a", ">b", "~foo:*", "%R~bar:*", "%W~baz:*", "+@read", "-keys", "+set"},
+		"bob":          {"off", "nopass", "allkeys", "allcommands", "-@dangerous"},
+		"carol":        {"on", "#" + hashPassword("c")},
+	}
+	for name, rules := range users {
+		if err := a.setUser(name, rules...); err != nil {
+			t.Fatalf("failed to set user %s: %v", name, err)
+		}
+	}
+	if err := a.save(); err != nil {
+		t.Fatalf("failed to save ACL file: %v", err)
+	}
+	if _, err := os.Stat(file + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("expected temporary file removed, got %v", err)
+	}
+
+	loaded := newACL(file, "")
+	if err := loaded.load(); err != nil {
+		t.Fatalf("failed to load ACL file: %v", err)
+	}
+	expected, got := aclDescribeUsers(a), aclDescribeUsers(loaded)
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("expected users %q, got %q", expected, got)
+	}
+	for name, user := range a.users {
+		if commands := aclUserCommands(loaded.getUser(name)); strings.Join(commands, " ") != strings.Join(aclUserCommands(user), " ") {
+			t.Errorf("user %s: expected commands %v, got %v", name, aclUserCommands(user), commands)
+		}
+	}
+
+	if !loaded.authenticate("alice", "b") || loaded.authenticate("alice", "c") {
+		t.Errorf("expected alice authenticated with her passwords only")
+	}
+	if !loaded.authenticate("carol", "c") {
+		t.Errorf("expected carol authenticated with password of hash")
+	}
+	if loaded.authenticate("bob", "") {
+		t.Errorf("expected disabled bob not authenticated")
+	}
+	if loaded.isDefaultUserNoPass() || !loaded.authenticate(aclDefaultUser, "secret") {
+		t.Errorf("expected default user with password")
+	}
+}
+
+func TestACLFileLoad(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		describe []string
+		err      string
+	}{
+		{
+			name:    "default user added",
+			content: "# users\n\nuser alice on nopass ~foo:* +get\n",
+			describe: []string{
+				"user alice on nopass ~foo:* +get",
+				"user default on nopass ~* +@all",
+			},
+		},
+		{
+			name:     "default user",
+			content:  "user default off",
+			describe: []string{"user default off -@all"},
+		},
+		{name: "no user keyword", content: "user alice on\nalice off\n", err: ":2: line should start with user keyword"},
+		{name: "no user name", content: "user\n", err: ":1: line should start with user keyword"},
+		{name: "duplicate user", content: "user alice on\nuser alice off\n", err: ":2: duplicate user 'alice' found"},
+		{name: "invalid rule", content: "user alice on +@unknown\n", err: ":1: Error in ACL SETUSER modifier '+@unknown': Unknown command or category name in ACL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "users.acl")
+			if err := os.WriteFile(file, []byte(tt.content), 0600); err != nil {
+				t.Fatalf("failed to write ACL file: %v", err)
+			}
+
+			a := newACL(file, "")
+			if err := a.setUser("bob", "on"); err != nil {
+				t.Fatalf("failed to set user: %v", err)
+			}
+			before := aclDescribeUsers(a)
+
+			err := a.load()
+			if tt.err != "" {
+				if err == nil || err.Error() != file+tt.err {
+					t.Fatalf("expected error %q, got %v", file+tt.err, err)
+				}
+				if got := aclDescribeUsers(a); strings.Join(got, "\n") != strings.Join(before, "\n") {
+					t.Errorf("expected users unchanged %q, got %q", before, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to load ACL file: %v", err)
+			}
+			if got := aclDescribeUsers(a); strings.Join(got, "\n") != strings.Join(tt.describe, "\n") {
+				t.Errorf("expected users %q, got %q", tt.describe, got)
+			}
+		})
+	}
+
+	a := newACL("", "")
+	if err := a.load(); err != ErrACLNoFile {
+		t.Errorf("load: expected error %v, got %v", ErrACLNoFile, err)
+	}
+	if err := a.save(); err != ErrACLNoFile {
+		t.Errorf("save: expected error %v, got %v", ErrACLNoFile, err)
+	}
+}
+
+// aclDescribeUsers returns the rules of all users, sorted by the user names.
+func aclDescribeUsers(a *acl) []string {
+	users := a.sortedUsers()
+	describe := make([]string, 0, len(users))
+	for _, user := range users {
+		describe = append(describe, user.describe())
+	}
+	return describe
+}
@@ -0,0 +1,217 @@
+package server
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ghosind/antdb/client"
+	"github.com/ghosind/antdb/core"
+)
+
+// blockedClient is a client blocked by a command like XREAD BLOCK, until any of
+// its keys is signaled as ready, or the deadline is reached.
+type blockedClient struct {
+	db       int
+	keys     []string
+	deadline time.Time
+	// cmd is the command to retry when the client is woken up.
+	cmd     *client.Command
+	ready   chan struct{}
+	blocked bool
+	killed  bool
+}
+
+type blockedClients struct {
+	mu      sync.Mutex
+	clients map[uint64]*blockedClient
+	keys    []map[string]map[uint64]*blockedClient
+}
+
+// blockClient blocks the client on the keys, and retries the command after the
+// command in progress returns if any key is signaled as ready. The client is
+// blocked forever if the timeout is zero. A client that is blocked again by the
+// retried command keeps its original deadline.
+func (s *Server) blockClient(cli *client.Client, keys []string, timeout time.Duration, cmd *client.Command) {
+	s.blocked.mu.Lock()
+	defer s.blocked.mu.Unlock()
+
+	b, ok := s.blocked.clients[cli.ID]
+	if !ok {
+		b = &blockedClient{ready: make(chan struct{}, 1)}
+		if timeout > 0 {
+			b.deadline = time.Now().Add(timeout)
+		}
+		s.blocked.clients[cli.ID] = b
+	}
+	b.db, b.keys, b.cmd, b.blocked = cli.DB, keys, cmd, true
+
+	dbKeys := s.blocked.keys[cli.DB]
+	for _, key := range keys {
+		clients, ok := dbKeys[key]
+		if !ok {
+			clients = make(map[uint64]*blockedClient)
+			dbKeys[key] = clients
+		}
+		clients[cli.ID] = b
+	}
+}
+
+// signalKeyAsReady wakes up the clients blocked on the key.
+func (s *Server) signalKeyAsReady(db int, key string) {
+	s.blocked.mu.Lock()
+	defer s.blocked.mu.Unlock()
+
+	for _, b := range s.blocked.keys[db][key] {
+		select {
+		case b.ready <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// unblockClient removes the client from its keys, and returns it, or nil if
+// the client is not blocked. The client is also forgotten if remove is true.
+func (s *Server) unblockClient(id uint64, remove bool) *blockedClient {
+	s.blocked.mu.Lock()
+	defer s.blocked.mu.Unlock()
+
+	b, ok := s.blocked.clients[id]
+	if !ok {
+		return nil
+	}
+	if remove || !b.blocked {
+		delete(s.blocked.clients, id)
+	}
+	if !b.blocked {
+		return nil
+	}
+
+	dbKeys := s.blocked.keys[b.db]
+	for _, key := range b.keys {
+		delete(dbKeys[key], id)
+		if len(dbKeys[key]) == 0 {
+			delete(dbKeys, key)
+		}
+	}
+	b.blocked = false
+	return b
+}
+
+// killBlockedClient wakes up the blocked client to be closed.
+func (s *Server) killBlockedClient(id uint64) {
+	s.blocked.mu.Lock()
+	defer s.blocked.mu.Unlock()
+
+	if b, ok := s.blocked.clients[id]; ok {
+		b.killed = true
+		select {
+		case b.ready <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (s *Server) blockedClientCount() int {
+	s.blocked.mu.Lock()
+	defer s.blocked.mu.Unlock()
+
+	return len(s.blocked.clients)
+}
+
+// watchConnection waits for the connection of the blocked client in the
+// background, and kills the blocked client if the connection is closed. The
+// returned function stops watching, and must be called before the connection is
+// read again.
+func (s *Server) watchConnection(cli *client.Client) func() {
+	cli.Conn.SetReadDeadline(time.Time{})
+
+	var stopping atomic.Bool
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// Peek does not consume the commands sent while the client is blocked.
+		if _, err := cli.Reader.Peek(1); err != nil && !stopping.Load() {
+			s.killBlockedClient(cli.ID)
+		}
+	}()
+
+	return func() {
+		stopping.Store(true)
+		cli.Conn.SetReadDeadline(time.Now())
+		<-done
+		cli.Conn.SetReadDeadline(time.Time{})
+	}
+}
+
+// retryBlockedCommand runs the command of the woken up client. Only the handler
+// runs, as the monitors, the stats, the latency and the slowlog have seen the
+// command when it was received.
+func (s *Server) retryBlockedCommand(cli *client.Client, cmd *client.Command) {
+	defer client.PutCommand(cmd)
+
+	if err := dbCommands[cmd.Command].Handler(s, cli, cmd.Args...); err != nil {
+		cli.ReplyError(err.Error())
+	}
+}
+
+// serveBlocked waits for the client blocked by its last command, and retries
+// the command in the database loop when it is woken up, until the command is
+// served without blocking again. A nil array is replied if the client is timed
+// out, and nothing if its connection is closed.
+func (s *Server) serveBlocked(cli *client.Client) {
+	var stopWatching func()
+	defer func() {
+		if stopWatching != nil {
+			stopWatching()
+		}
+	}()
+
+	for {
+		s.blocked.mu.Lock()
+		b, ok := s.blocked.clients[cli.ID]
+		blocked := ok && b.blocked
+		s.blocked.mu.Unlock()
+		if !ok {
+			return
+		}
+		if !blocked {
+			s.unblockClient(cli.ID, true)
+			return
+		}
+		if stopWatching == nil {
+			stopWatching = s.watchConnection(cli)
+		}
+
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if !b.deadline.IsZero() {
+			timer = time.NewTimer(time.Until(b.deadline))
+			timeout = timer.C
+		}
+
+		select {
+		case <-b.ready:
+			if timer != nil {
+				timer.Stop()
+			}
+			s.blocked.mu.Lock()
+			killed := b.killed
+			s.blocked.mu.Unlock()
+			s.unblockClient(cli.ID, killed)
+			if killed {
+				client.PutCommand(b.cmd)
+				return
+			}
+			cmd := b.cmd
+			s.runInDatabase(-1, b.db, func(db *core.Database) {
+				s.retryBlockedCommand(cli, cmd)
+			})
+		case <-timeout:
+			s.unblockClient(cli.ID, true)
+			client.PutCommand(b.cmd)
+			cli.ReplyArrayLength(-1)
+			return
+		}
+	}
+}
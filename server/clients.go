@@ -0,0 +1,154 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ghosind/antdb/client"
+)
+
+// addClient registers the client, and returns false if the number of clients
+// has reached maxclients.
+func (s *Server) addClient(cli *client.Client) bool {
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+
+	if len(s.clients) >= s.maxClients {
+		return false
+	}
+	s.clients[cli.ID] = cli
+	return true
+}
+
+func (s *Server) removeClient(cli *client.Client) {
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+
+	delete(s.clients, cli.ID)
+}
+
+func (s *Server) clientCount() int {
+	s.clientsMu.RLock()
+	defer s.clientsMu.RUnlock()
+
+	return len(s.clients)
+}
+
+// clientInfos returns the snapshots of the connected clients ordered by their
+// ids.
+func (s *Server) clientInfos() []client.Info {
+	s.clientsMu.RLock()
+	infos := make([]client.Info, 0, len(s.clients))
+	for _, cli := range s.clients {
+		infos = append(infos, cli.Info())
+	}
+	s.clientsMu.RUnlock()
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ID < infos[j].ID
+	})
+	return infos
+}
+
+// killClients closes the connections of the clients matched by the filter, and
+// returns the number of the killed clients. The current client is closed after
+// the reply.
+func (s *Server) killClients(cli *client.Client, match func(info client.Info) bool) int {
+	s.clientsMu.RLock()
+	defer s.clientsMu.RUnlock()
+
+	cnt := 0
+	for _, c := range s.clients {
+		if !match(c.Info()) {
+			continue
+		}
+		if c == cli {
+			cli.Flag |= client.CLIENT_CLOSE_AFTER_REPLY
+		} else {
+			c.Conn.Close()
+			s.killBlockedClient(c.ID)
+		}
+		cnt++
+	}
+	return cnt
+}
+
+func formatClientInfo(info client.Info, now time.Time) string {
+	return fmt.Sprintf("id=%d addr=%s laddr=%s name=%s age=%d idle=%d flags=%s db=%d multi=%d tot-mem=%d cmd=%s user=%s",
+		info.ID, info.Addr, info.LocalAddr, info.Name,
+		int64(now.Sub(info.CreatedAt).Seconds()), int64(now.Sub(info.LastInteraction).Seconds()),
+		info.Flags, info.DB, info.Multi, clientMemoryUsage, info.LastCommand, info.User)
+}
+
+// clientPause is the state of CLIENT PAUSE. The commands are paused until end,
+// or until resume is closed by CLIENT UNPAUSE.
+type clientPause struct {
+	mu        sync.Mutex
+	end       time.Time
+	writeOnly bool
+	resume    chan struct{}
+}
+
+// start pauses the clients until the time, or extends the current pause. A
+// pause of all commands is not reduced to a pause of write commands.
+func (p *clientPause) start(end time.Time, writeOnly bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if time.Now().Before(p.end) {
+		p.writeOnly = p.writeOnly && writeOnly
+		if end.After(p.end) {
+			p.end = end
+		}
+		return
+	}
+
+	p.end = end
+	p.writeOnly = writeOnly
+}
+
+func (p *clientPause) stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.end = time.Time{}
+	close(p.resume)
+	p.resume = make(chan struct{})
+}
+
+// isWritePaused returns true if the dataset must not be changed, so the
+// active expiration and the eviction are paused too.
+func (p *clientPause) isWritePaused() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return time.Now().Before(p.end)
+}
+
+// wait blocks until the command is not paused. The admin commands are never
+// paused, so the pause can be ended with CLIENT UNPAUSE.
+func (p *clientPause) wait(cmd *DBCommand) {
+	if cmd.Flags&CommandFlagAdmin != 0 {
+		return
+	}
+
+	for {
+		p.mu.Lock()
+		end, writeOnly, resume := p.end, p.writeOnly, p.resume
+		p.mu.Unlock()
+
+		remaining := time.Until(end)
+		if remaining <= 0 || (writeOnly && cmd.Flags&CommandFlagWrite == 0) {
+			return
+		}
+
+		timer := time.NewTimer(remaining)
+		select {
+		case <-timer.C:
+		case <-resume:
+		}
+		timer.Stop()
+	}
+}
@@ -9,80 +9,201 @@ type CommandFlags int
 const (
 	CommandFlagRead CommandFlags = 1 << iota
 	CommandFlagWrite
+	CommandFlagDenyOOM
+	CommandFlagAdmin
+	CommandFlagDangerous
+	CommandFlagSkipSlowlog
+	CommandFlagSkipMonitor
+	CommandFlagBlocking
 )
 
+// KeySpec describes the positions of the key arguments of a command, where the
+// command name is at position 0, and a negative last position counts from the
+// end of the arguments.
+type KeySpec struct {
+	First int
+	Last  int
+	Step  int
+}
+
 type DBCommand struct {
 	Handler func(*Server, *client.Client, ...string) error
 	Arity   int
 	Flags   CommandFlags
+	Keys    KeySpec
+	// GetKeys returns the key arguments of a command whose keys cannot be
+	// described by a KeySpec, like XREAD.
+	GetKeys func(args []string) []string
 	NoWait  bool
 }
 
 var dbCommands map[string]DBCommand
 
+// keys returns the key arguments of the command, where args does not include
+// the command name.
+func (cmd *DBCommand) keys(args []string) []string {
+	if cmd.GetKeys != nil {
+		return cmd.GetKeys(args)
+	}
+	if cmd.Keys.First <= 0 {
+		return nil
+	}
+
+	last := cmd.Keys.Last
+	if last < 0 {
+		last = len(args) + 1 + last
+	}
+	if last > len(args) {
+		last = len(args)
+	}
+	if last < cmd.Keys.First {
+		return nil
+	}
+
+	keys := make([]string, 0, (last-cmd.Keys.First)/cmd.Keys.Step+1)
+	for i := cmd.Keys.First; i <= last; i += cmd.Keys.Step {
+		keys = append(keys, args[i-1])
+	}
+	return keys
+}
+
 func init() {
 	dbCommands = map[string]DBCommand{
 		// Connection Management
-		"AUTH":   {Handler: (*Server).authCommand, Arity: 1, Flags: CommandFlagRead, NoWait: true},
+		"AUTH":   {Handler: (*Server).authCommand, Arity: -1, Flags: CommandFlagRead | CommandFlagSkipSlowlog | CommandFlagSkipMonitor, NoWait: true},
 		"ECHO":   {Handler: (*Server).echoCommand, Arity: 1, Flags: CommandFlagRead, NoWait: true},
 		"PING":   {Handler: (*Server).pingCommand, Arity: 0, Flags: CommandFlagRead, NoWait: true},
 		"SELECT": {Handler: (*Server).selectCommand, Arity: 1, Flags: CommandFlagRead, NoWait: true},
+		"CLIENT": {Handler: (*Server).clientCommand, Arity: -1, Flags: CommandFlagAdmin | CommandFlagDangerous, NoWait: true},
+		// ACL
+		"ACL": {
+			Handler: (*Server).aclCommand,
+			Arity:   -1,
+			Flags:   CommandFlagAdmin | CommandFlagDangerous | CommandFlagSkipSlowlog | CommandFlagSkipMonitor,
+			NoWait:  true,
+		},
+		// Bitmap
+		"BITCOUNT":    {Handler: (*Server).bitcountCommand, Arity: -1, Flags: CommandFlagRead, Keys: KeySpec{1, 1, 1}},
+		"BITFIELD":    {Handler: (*Server).bitfieldCommand, Arity: -1, Flags: CommandFlagWrite | CommandFlagDenyOOM, Keys: KeySpec{1, 1, 1}},
+		"BITFIELD_RO": {Handler: (*Server).bitfieldRoCommand, Arity: -1, Flags: CommandFlagRead, Keys: KeySpec{1, 1, 1}},
+		"BITOP":       {Handler: (*Server).bitopCommand, Arity: -3, Flags: CommandFlagWrite | CommandFlagDenyOOM, Keys: KeySpec{2, -1, 1}},
+		"BITPOS":      {Handler: (*Server).bitposCommand, Arity: -2, Flags: CommandFlagRead, Keys: KeySpec{1, 1, 1}},
+		"GETBIT":      {Handler: (*Server).getbitCommand, Arity: 2, Flags: CommandFlagRead, Keys: KeySpec{1, 1, 1}},
+		"SETBIT":      {Handler: (*Server).setbitCommand, Arity: 3, Flags: CommandFlagWrite | CommandFlagDenyOOM, Keys: KeySpec{1, 1, 1}},
 		// Generic
-		"DEL":       {Handler: (*Server).delCommand, Arity: -1, Flags: CommandFlagWrite},
-		"EXISTS":    {Handler: (*Server).existsCommand, Arity: -1, Flags: CommandFlagRead},
-		"EXPIRE":    {Handler: (*Server).expireCommand, Arity: 2, Flags: CommandFlagWrite},
-		"EXPIREAT":  {Handler: (*Server).expireAtCommand, Arity: 2, Flags: CommandFlagWrite},
-		"KEYS":      {Handler: (*Server).keysCommand, Arity: 1, Flags: CommandFlagRead},
-		"MOVE":      {Handler: (*Server).moveCommand, Arity: 2, Flags: CommandFlagWrite},
-		"RANDOMKEY": {Handler: (*Server).randomKeyCommand, Arity: 0, Flags: CommandFlagRead},
-		"RENAME":    {Handler: (*Server).renameCommand, Arity: 2, Flags: CommandFlagWrite},
-		"RENAMENX":  {Handler: (*Server).renameNxCommand, Arity: 2, Flags: CommandFlagWrite},
-		"TTL":       {Handler: (*Server).ttlCommand, Arity: 1, Flags: CommandFlagRead},
-		"TYPE":      {Handler: (*Server).typeCommand, Arity: 1, Flags: CommandFlagRead},
+		"DEL":         {Handler: (*Server).delCommand, Arity: -1, Flags: CommandFlagWrite, Keys: KeySpec{1, -1, 1}},
+		"EXISTS":      {Handler: (*Server).existsCommand, Arity: -1, Flags: CommandFlagRead, Keys: KeySpec{1, -1, 1}},
+		"EXPIRE":      {Handler: (*Server).expireCommand, Arity: -2, Flags: CommandFlagWrite, Keys: KeySpec{1, 1, 1}},
+		"EXPIREAT":    {Handler: (*Server).expireAtCommand, Arity: -2, Flags: CommandFlagWrite, Keys: KeySpec{1, 1, 1}},
+		"EXPIRETIME":  {Handler: (*Server).expireTimeCommand, Arity: 1, Flags: CommandFlagRead, Keys: KeySpec{1, 1, 1}},
+		"KEYS":        {Handler: (*Server).keysCommand, Arity: 1, Flags: CommandFlagRead | CommandFlagDangerous},
+		"MOVE":        {Handler: (*Server).moveCommand, Arity: 2, Flags: CommandFlagWrite, Keys: KeySpec{1, 1, 1}},
+		"OBJECT":      {Handler: (*Server).objectCommand, Arity: -1, Flags: CommandFlagRead, Keys: KeySpec{2, 2, 1}},
+		"PERSIST":     {Handler: (*Server).persistCommand, Arity: 1, Flags: CommandFlagWrite, Keys: KeySpec{1, 1, 1}},
+		"PEXPIRE":     {Handler: (*Server).pexpireCommand, Arity: -2, Flags: CommandFlagWrite, Keys: KeySpec{1, 1, 1}},
+		"PEXPIREAT":   {Handler: (*Server).pexpireAtCommand, Arity: -2, Flags: CommandFlagWrite, Keys: KeySpec{1, 1, 1}},
+		"PEXPIRETIME": {Handler: (*Server).pexpireTimeCommand, Arity: 1, Flags: CommandFlagRead, Keys: KeySpec{1, 1, 1}},
+		"PTTL":        {Handler: (*Server).pttlCommand, Arity: 1, Flags: CommandFlagRead, Keys: KeySpec{1, 1, 1}},
+		"RANDOMKEY":   {Handler: (*Server).randomKeyCommand, Arity: 0, Flags: CommandFlagRead},
+		"RENAME":      {Handler: (*Server).renameCommand, Arity: 2, Flags: CommandFlagWrite, Keys: KeySpec{1, 2, 1}},
+		"RENAMENX":    {Handler: (*Server).renameNxCommand, Arity: 2, Flags: CommandFlagWrite, Keys: KeySpec{1, 2, 1}},
+		"SCAN":        {Handler: (*Server).scanCommand, Arity: -1, Flags: CommandFlagRead},
+		"TTL":         {Handler: (*Server).ttlCommand, Arity: 1, Flags: CommandFlagRead, Keys: KeySpec{1, 1, 1}},
+		"TYPE":        {Handler: (*Server).typeCommand, Arity: 1, Flags: CommandFlagRead, Keys: KeySpec{1, 1, 1}},
+		// Geospatial
+		"GEOADD":         {Handler: (*Server).geoaddCommand, Arity: -4, Flags: CommandFlagWrite | CommandFlagDenyOOM, Keys: KeySpec{1, 1, 1}},
+		"GEODIST":        {Handler: (*Server).geodistCommand, Arity: -3, Flags: CommandFlagRead, Keys: KeySpec{1, 1, 1}},
+		"GEOHASH":        {Handler: (*Server).geohashCommand, Arity: -1, Flags: CommandFlagRead, Keys: KeySpec{1, 1, 1}},
+		"GEOPOS":         {Handler: (*Server).geoposCommand, Arity: -1, Flags: CommandFlagRead, Keys: KeySpec{1, 1, 1}},
+		"GEOSEARCH":      {Handler: (*Server).geosearchCommand, Arity: -6, Flags: CommandFlagRead, Keys: KeySpec{1, 1, 1}},
+		"GEOSEARCHSTORE": {Handler: (*Server).geosearchStoreCommand, Arity: -7, Flags: CommandFlagWrite | CommandFlagDenyOOM, Keys: KeySpec{1, 2, 1}},
+		// HyperLogLog
+		"PFADD":   {Handler: (*Server).pfaddCommand, Arity: -1, Flags: CommandFlagWrite | CommandFlagDenyOOM, Keys: KeySpec{1, 1, 1}},
+		"PFCOUNT": {Handler: (*Server).pfcountCommand, Arity: -1, Flags: CommandFlagRead, Keys: KeySpec{1, -1, 1}},
+		"PFDEBUG": {Handler: (*Server).pfdebugCommand, Arity: 2, Flags: CommandFlagWrite | CommandFlagDenyOOM | CommandFlagAdmin, Keys: KeySpec{2, 2, 1}},
+		"PFMERGE": {Handler: (*Server).pfmergeCommand, Arity: -1, Flags: CommandFlagWrite | CommandFlagDenyOOM, Keys: KeySpec{1, -1, 1}},
 		// List
-		"LINDEX":    {Handler: (*Server).lindexCommand, Arity: 2, Flags: CommandFlagRead},
-		"LLEN":      {Handler: (*Server).llenCommand, Arity: 1, Flags: CommandFlagRead},
-		"LPOP":      {Handler: (*Server).lpopCommand, Arity: 1, Flags: CommandFlagWrite},
-		"LPUSH":     {Handler: (*Server).lpushCommand, Arity: 2, Flags: CommandFlagWrite},
-		"LRANGE":    {Handler: (*Server).lrangeCommand, Arity: 3, Flags: CommandFlagRead},
-		"LREM":      {Handler: (*Server).lremCommand, Arity: 3, Flags: CommandFlagWrite},
-		"LSET":      {Handler: (*Server).lsetCommand, Arity: 3, Flags: CommandFlagWrite},
-		"LTRIM":     {Handler: (*Server).ltrimCommand, Arity: 3, Flags: CommandFlagWrite},
-		"RPOP":      {Handler: (*Server).rpopCommand, Arity: 1, Flags: CommandFlagWrite},
-		"RPOPLPUSH": {Handler: (*Server).rpoplpushCommand, Arity: 2, Flags: CommandFlagWrite},
-		"RPUSH":     {Handler: (*Server).rpushCommand, Arity: 2, Flags: CommandFlagWrite},
+		"LINDEX":    {Handler: (*Server).lindexCommand, Arity: 2, Flags: CommandFlagRead, Keys: KeySpec{1, 1, 1}},
+		"LINSERT":   {Handler: (*Server).linsertCommand, Arity: 4, Flags: CommandFlagWrite | CommandFlagDenyOOM, Keys: KeySpec{1, 1, 1}},
+		"LLEN":      {Handler: (*Server).llenCommand, Arity: 1, Flags: CommandFlagRead, Keys: KeySpec{1, 1, 1}},
+		"LMOVE":     {Handler: (*Server).lmoveCommand, Arity: 4, Flags: CommandFlagWrite | CommandFlagDenyOOM, Keys: KeySpec{1, 2, 1}},
+		"LMPOP":     {Handler: (*Server).lmpopCommand, Arity: -3, Flags: CommandFlagWrite, GetKeys: lmpopKeys},
+		"LPOP":      {Handler: (*Server).lpopCommand, Arity: 1, Flags: CommandFlagWrite, Keys: KeySpec{1, 1, 1}},
+		"LPOS":      {Handler: (*Server).lposCommand, Arity: -2, Flags: CommandFlagRead, Keys: KeySpec{1, 1, 1}},
+		"LPUSH":     {Handler: (*Server).lpushCommand, Arity: -2, Flags: CommandFlagWrite | CommandFlagDenyOOM, Keys: KeySpec{1, 1, 1}},
+		"LPUSHX":    {Handler: (*Server).lpushxCommand, Arity: -2, Flags: CommandFlagWrite | CommandFlagDenyOOM, Keys: KeySpec{1, 1, 1}},
+		"LRANGE":    {Handler: (*Server).lrangeCommand, Arity: 3, Flags: CommandFlagRead, Keys: KeySpec{1, 1, 1}},
+		"LREM":      {Handler: (*Server).lremCommand, Arity: 3, Flags: CommandFlagWrite, Keys: KeySpec{1, 1, 1}},
+		"LSET":      {Handler: (*Server).lsetCommand, Arity: 3, Flags: CommandFlagWrite | CommandFlagDenyOOM, Keys: KeySpec{1, 1, 1}},
+		"LTRIM":     {Handler: (*Server).ltrimCommand, Arity: 3, Flags: CommandFlagWrite, Keys: KeySpec{1, 1, 1}},
+		"RPOP":      {Handler: (*Server).rpopCommand, Arity: 1, Flags: CommandFlagWrite, Keys: KeySpec{1, 1, 1}},
+		"RPOPLPUSH": {Handler: (*Server).rpoplpushCommand, Arity: 2, Flags: CommandFlagWrite | CommandFlagDenyOOM, Keys: KeySpec{1, 2, 1}},
+		"RPUSH":     {Handler: (*Server).rpushCommand, Arity: -2, Flags: CommandFlagWrite | CommandFlagDenyOOM, Keys: KeySpec{1, 1, 1}},
+		"RPUSHX":    {Handler: (*Server).rpushxCommand, Arity: -2, Flags: CommandFlagWrite | CommandFlagDenyOOM, Keys: KeySpec{1, 1, 1}},
 		// Server Management
+		"COMMAND":  {Handler: (*Server).commandCommand, Arity: 0, Flags: CommandFlagRead, NoWait: true},
 		"DBSIZE":   {Handler: (*Server).dbSizeCommand, Arity: 0, Flags: CommandFlagRead},
-		"FLUSHALL": {Handler: (*Server).flushAllCommand, Arity: 0, Flags: CommandFlagWrite},
-		"FLUSHDB":  {Handler: (*Server).flushDBCommand, Arity: 0, Flags: CommandFlagWrite},
+		"FLUSHALL": {Handler: (*Server).flushAllCommand, Arity: 0, Flags: CommandFlagWrite | CommandFlagDangerous},
+		"FLUSHDB":  {Handler: (*Server).flushDBCommand, Arity: 0, Flags: CommandFlagWrite | CommandFlagDangerous},
+		"LATENCY":  {Handler: (*Server).latencyCommand, Arity: -1, Flags: CommandFlagAdmin | CommandFlagDangerous, NoWait: true},
+		"MONITOR":  {Handler: (*Server).monitorCommand, Arity: 0, Flags: CommandFlagAdmin | CommandFlagDangerous, NoWait: true},
+		"SLOWLOG":  {Handler: (*Server).slowlogCommand, Arity: -1, Flags: CommandFlagAdmin | CommandFlagDangerous, NoWait: true},
+		"INFO":     {Handler: (*Server).infoCommand, Arity: 0, Flags: CommandFlagRead | CommandFlagDangerous, NoWait: true},
+		"MEMORY":   {Handler: (*Server).memoryCommand, Arity: -1, Flags: CommandFlagRead, GetKeys: memoryKeys},
 		// Set
-		"SADD":        {Handler: (*Server).saddCommand, Arity: -2, Flags: CommandFlagWrite},
-		"SCARD":       {Handler: (*Server).scardCommand, Arity: 1, Flags: CommandFlagRead},
-		"SDIFF":       {Handler: (*Server).sdiffCommand, Arity: -1, Flags: CommandFlagRead},
-		"SDIFFSTORE":  {Handler: (*Server).sdiffStoreCommand, Arity: -2, Flags: CommandFlagWrite},
-		"SINTER":      {Handler: (*Server).sinterCommand, Arity: -1, Flags: CommandFlagRead},
-		"SINTERSTORE": {Handler: (*Server).sinterStoreCommand, Arity: -2, Flags: CommandFlagWrite},
-		"SISMEMBER":   {Handler: (*Server).sismemberCommand, Arity: 2, Flags: CommandFlagRead},
-		"SMOVE":       {Handler: (*Server).smoveCommand, Arity: 3, Flags: CommandFlagWrite},
-		"SMEMBERS":    {Handler: (*Server).smembersCommand, Arity: 1, Flags: CommandFlagRead},
-		"SPOP":        {Handler: (*Server).spopCommand, Arity: 1, Flags: CommandFlagWrite},
-		"SRANDMEMBER": {Handler: (*Server).srandmemberCommand, Arity: -1, Flags: CommandFlagRead},
-		"SREM":        {Handler: (*Server).sremCommand, Arity: -2, Flags: CommandFlagWrite},
-		"SUNION":      {Handler: (*Server).sunionCommand, Arity: -1, Flags: CommandFlagRead},
-		"SUNIONSTORE": {Handler: (*Server).sunionStoreCommand, Arity: -2, Flags: CommandFlagWrite},
+		"SADD":        {Handler: (*Server).saddCommand, Arity: -2, Flags: CommandFlagWrite | CommandFlagDenyOOM, Keys: KeySpec{1, 1, 1}},
+		"SCARD":       {Handler: (*Server).scardCommand, Arity: 1, Flags: CommandFlagRead, Keys: KeySpec{1, 1, 1}},
+		"SDIFF":       {Handler: (*Server).sdiffCommand, Arity: -1, Flags: CommandFlagRead, Keys: KeySpec{1, -1, 1}},
+		"SDIFFSTORE":  {Handler: (*Server).sdiffStoreCommand, Arity: -2, Flags: CommandFlagWrite | CommandFlagDenyOOM, Keys: KeySpec{1, -1, 1}},
+		"SINTER":      {Handler: (*Server).sinterCommand, Arity: -1, Flags: CommandFlagRead, Keys: KeySpec{1, -1, 1}},
+		"SINTERSTORE": {Handler: (*Server).sinterStoreCommand, Arity: -2, Flags: CommandFlagWrite | CommandFlagDenyOOM, Keys: KeySpec{1, -1, 1}},
+		"SISMEMBER":   {Handler: (*Server).sismemberCommand, Arity: 2, Flags: CommandFlagRead, Keys: KeySpec{1, 1, 1}},
+		"SMOVE":       {Handler: (*Server).smoveCommand, Arity: 3, Flags: CommandFlagWrite | CommandFlagDenyOOM, Keys: KeySpec{1, 2, 1}},
+		"SMEMBERS":    {Handler: (*Server).smembersCommand, Arity: 1, Flags: CommandFlagRead, Keys: KeySpec{1, 1, 1}},
+		"SPOP":        {Handler: (*Server).spopCommand, Arity: 1, Flags: CommandFlagWrite, Keys: KeySpec{1, 1, 1}},
+		"SRANDMEMBER": {Handler: (*Server).srandmemberCommand, Arity: -1, Flags: CommandFlagRead, Keys: KeySpec{1, 1, 1}},
+		"SREM":        {Handler: (*Server).sremCommand, Arity: -2, Flags: CommandFlagWrite, Keys: KeySpec{1, 1, 1}},
+		"SSCAN":       {Handler: (*Server).sscanCommand, Arity: -2, Flags: CommandFlagRead, Keys: KeySpec{1, 1, 1}},
+		"SUNION":      {Handler: (*Server).sunionCommand, Arity: -1, Flags: CommandFlagRead, Keys: KeySpec{1, -1, 1}},
+		"SUNIONSTORE": {Handler: (*Server).sunionStoreCommand, Arity: -2, Flags: CommandFlagWrite | CommandFlagDenyOOM, Keys: KeySpec{1, -1, 1}},
+		// Stream
+		"XACK":       {Handler: (*Server).xackCommand, Arity: -3, Flags: CommandFlagWrite, Keys: KeySpec{1, 1, 1}},
+		"XADD":       {Handler: (*Server).xaddCommand, Arity: -4, Flags: CommandFlagWrite | CommandFlagDenyOOM, Keys: KeySpec{1, 1, 1}},
+		"XAUTOCLAIM": {Handler: (*Server).xautoclaimCommand, Arity: -5, Flags: CommandFlagWrite, Keys: KeySpec{1, 1, 1}},
+		"XCLAIM":     {Handler: (*Server).xclaimCommand, Arity: -5, Flags: CommandFlagWrite, Keys: KeySpec{1, 1, 1}},
+		"XDEL":       {Handler: (*Server).xdelCommand, Arity: -2, Flags: CommandFlagWrite, Keys: KeySpec{1, 1, 1}},
+		"XGROUP":     {Handler: (*Server).xgroupCommand, Arity: -1, Flags: CommandFlagWrite | CommandFlagDenyOOM, Keys: KeySpec{2, 2, 1}},
+		"XINFO":      {Handler: (*Server).xinfoCommand, Arity: -1, Flags: CommandFlagRead, Keys: KeySpec{2, 2, 1}},
+		"XLEN":       {Handler: (*Server).xlenCommand, Arity: 1, Flags: CommandFlagRead, Keys: KeySpec{1, 1, 1}},
+		"XPENDING":   {Handler: (*Server).xpendingCommand, Arity: -2, Flags: CommandFlagRead, Keys: KeySpec{1, 1, 1}},
+		"XRANGE":     {Handler: (*Server).xrangeCommand, Arity: -3, Flags: CommandFlagRead, Keys: KeySpec{1, 1, 1}},
+		"XREAD":      {Handler: (*Server).xreadCommand, Arity: -3, Flags: CommandFlagRead | CommandFlagBlocking, GetKeys: streamReadKeys},
+		"XREADGROUP": {Handler: (*Server).xreadgroupCommand, Arity: -6, Flags: CommandFlagWrite | CommandFlagBlocking, GetKeys: streamReadKeys},
+		"XREVRANGE":  {Handler: (*Server).xrevrangeCommand, Arity: -3, Flags: CommandFlagRead, Keys: KeySpec{1, 1, 1}},
+		"XTRIM":      {Handler: (*Server).xtrimCommand, Arity: -3, Flags: CommandFlagWrite, Keys: KeySpec{1, 1, 1}},
 		// String
-		"DECR":   {Handler: (*Server).decrCommand, Arity: 1, Flags: CommandFlagWrite},
-		"DECRBY": {Handler: (*Server).decrByCommand, Arity: 2, Flags: CommandFlagWrite},
-		"GET":    {Handler: (*Server).getCommand, Arity: 1, Flags: CommandFlagRead},
-		"GETSET": {Handler: (*Server).getSetCommand, Arity: 2, Flags: CommandFlagWrite},
-		"INCR":   {Handler: (*Server).incrCommand, Arity: 1, Flags: CommandFlagWrite},
-		"INCRBY": {Handler: (*Server).incrByCommand, Arity: 2, Flags: CommandFlagWrite},
-		"MGET":   {Handler: (*Server).mgetCommand, Arity: -1, Flags: CommandFlagRead},
-		"MSET":   {Handler: (*Server).msetCommand, Arity: -2, Flags: CommandFlagWrite},
-		"MSETNX": {Handler: (*Server).msetnxCommand, Arity: -2, Flags: CommandFlagWrite},
-		"SET":    {Handler: (*Server).setCommand, Arity: -2, Flags: CommandFlagWrite},
-		"SETNX":  {Handler: (*Server).setnxCommand, Arity: 2, Flags: CommandFlagWrite},
-		"SUBSTR": {Handler: (*Server).substrCommand, Arity: 3, Flags: CommandFlagRead},
+		"APPEND":      {Handler: (*Server).appendCommand, Arity: 2, Flags: CommandFlagWrite | CommandFlagDenyOOM, Keys: KeySpec{1, 1, 1}},
+		"DECR":        {Handler: (*Server).decrCommand, Arity: 1, Flags: CommandFlagWrite | CommandFlagDenyOOM, Keys: KeySpec{1, 1, 1}},
+		"DECRBY":      {Handler: (*Server).decrByCommand, Arity: 2, Flags: CommandFlagWrite | CommandFlagDenyOOM, Keys: KeySpec{1, 1, 1}},
+		"GET":         {Handler: (*Server).getCommand, Arity: 1, Flags: CommandFlagRead, Keys: KeySpec{1, 1, 1}},
+		"GETDEL":      {Handler: (*Server).getDelCommand, Arity: 1, Flags: CommandFlagWrite, Keys: KeySpec{1, 1, 1}},
+		"GETEX":       {Handler: (*Server).getExCommand, Arity: -1, Flags: CommandFlagWrite, Keys: KeySpec{1, 1, 1}},
+		"GETRANGE":    {Handler: (*Server).getRangeCommand, Arity: 3, Flags: CommandFlagRead, Keys: KeySpec{1, 1, 1}},
+		"GETSET":      {Handler: (*Server).getSetCommand, Arity: 2, Flags: CommandFlagWrite | CommandFlagDenyOOM, Keys: KeySpec{1, 1, 1}},
+		"INCR":        {Handler: (*Server).incrCommand, Arity: 1, Flags: CommandFlagWrite | CommandFlagDenyOOM, Keys: KeySpec{1, 1, 1}},
+		"INCRBY":      {Handler: (*Server).incrByCommand, Arity: 2, Flags: CommandFlagWrite | CommandFlagDenyOOM, Keys: KeySpec{1, 1, 1}},
+		"INCRBYFLOAT": {Handler: (*Server).incrByFloatCommand, Arity: 2, Flags: CommandFlagWrite | CommandFlagDenyOOM, Keys: KeySpec{1, 1, 1}},
+		"LCS":         {Handler: (*Server).lcsCommand, Arity: -2, Flags: CommandFlagRead, Keys: KeySpec{1, 2, 1}},
+		"MGET":        {Handler: (*Server).mgetCommand, Arity: -1, Flags: CommandFlagRead, Keys: KeySpec{1, -1, 1}},
+		"MSET":        {Handler: (*Server).msetCommand, Arity: -2, Flags: CommandFlagWrite | CommandFlagDenyOOM, Keys: KeySpec{1, -1, 2}},
+		"MSETNX":      {Handler: (*Server).msetnxCommand, Arity: -2, Flags: CommandFlagWrite | CommandFlagDenyOOM, Keys: KeySpec{1, -1, 2}},
+		"PSETEX":      {Handler: (*Server).psetexCommand, Arity: 3, Flags: CommandFlagWrite | CommandFlagDenyOOM, Keys: KeySpec{1, 1, 1}},
+		"SET":         {Handler: (*Server).setCommand, Arity: -2, Flags: CommandFlagWrite | CommandFlagDenyOOM, Keys: KeySpec{1, 1, 1}},
+		"SETEX":       {Handler: (*Server).setexCommand, Arity: 3, Flags: CommandFlagWrite | CommandFlagDenyOOM, Keys: KeySpec{1, 1, 1}},
+		"SETNX":       {Handler: (*Server).setnxCommand, Arity: 2, Flags: CommandFlagWrite | CommandFlagDenyOOM, Keys: KeySpec{1, 1, 1}},
+		"SETRANGE":    {Handler: (*Server).setRangeCommand, Arity: 3, Flags: CommandFlagWrite | CommandFlagDenyOOM, Keys: KeySpec{1, 1, 1}},
+		"STRLEN":      {Handler: (*Server).strlenCommand, Arity: 1, Flags: CommandFlagRead, Keys: KeySpec{1, 1, 1}},
+		"SUBSTR":      {Handler: (*Server).substrCommand, Arity: 3, Flags: CommandFlagRead, Keys: KeySpec{1, 1, 1}},
 		// Transaction
 		"MULTI": {Handler: (*Server).multiCommand, Arity: 0, Flags: CommandFlagWrite, NoWait: true},
 		"EXEC":  {Handler: (*Server).execCommand, Arity: 0, Flags: CommandFlagWrite},
@@ -0,0 +1,243 @@
+package server
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ghosind/antdb/client"
+	"github.com/ghosind/antdb/core"
+)
+
+const defaultACLLogCount = 10
+
+func (s *Server) aclCommand(cli *client.Client, args ...string) error {
+	switch strings.ToUpper(args[0]) {
+	case "SETUSER":
+		return s.aclSetUserCommand(cli, args[1:]...)
+	case "GETUSER":
+		return s.aclGetUserCommand(cli, args[1:]...)
+	case "DELUSER":
+		return s.aclDelUserCommand(cli, args[1:]...)
+	case "LIST":
+		return s.aclListCommand(cli, args[1:]...)
+	case "USERS":
+		return s.aclUsersCommand(cli, args[1:]...)
+	case "WHOAMI":
+		return s.aclWhoAmICommand(cli, args[1:]...)
+	case "CAT":
+		return s.aclCatCommand(cli, args[1:]...)
+	case "LOG":
+		return s.aclLogCommand(cli, args[1:]...)
+	case "LOAD":
+		return s.aclLoadCommand(cli, args[1:]...)
+	case "SAVE":
+		return s.aclSaveCommand(cli, args[1:]...)
+	default:
+		return newUnknownSubcommandError("ACL", args[0])
+	}
+}
+
+func (s *Server) aclSetUserCommand(cli *client.Client, args ...string) error {
+	if len(args) < 1 {
+		return newWrongArityError("acl|setuser")
+	}
+
+	if err := s.acl.setUser(args[0], args[1:]...); err != nil {
+		return err
+	}
+
+	cli.ReplySimpleString("OK")
+	return nil
+}
+
+func (s *Server) aclGetUserCommand(cli *client.Client, args ...string) error {
+	if len(args) != 1 {
+		return newWrongArityError("acl|getuser")
+	}
+
+	user := s.acl.getUser(args[0])
+	if user == nil {
+		cli.ReplyNilBulk()
+		return nil
+	}
+
+	flags := user.flags()
+	if user.allKeys {
+		flags = append(flags, "allkeys")
+	}
+
+	cli.ReplyArrayLength(8)
+	cli.ReplyBulkString("flags")
+	cli.ReplyArrayLength(int64(len(flags)))
+	for _, flag := range flags {
+		cli.ReplyBulkString(flag)
+	}
+	cli.ReplyBulkString("passwords")
+	cli.ReplyArrayLength(int64(len(user.passwords)))
+	for _, password := range user.passwords {
+		cli.ReplyBulkString(password)
+	}
+	cli.ReplyBulkString("commands")
+	cli.ReplyBulkString(user.describeCommands())
+	cli.ReplyBulkString("keys")
+	cli.ReplyBulkString(user.describeKeys())
+	return nil
+}
+
+func (s *Server) aclDelUserCommand(cli *client.Client, args ...string) error {
+	if len(args) < 1 {
+		return newWrongArityError("acl|deluser")
+	}
+
+	cnt, err := s.acl.deleteUsers(args...)
+	if err != nil {
+		return err
+	}
+
+	cli.ReplyInteger(int64(cnt))
+	return nil
+}
+
+func (s *Server) aclListCommand(cli *client.Client, args ...string) error {
+	if len(args) != 0 {
+		return newWrongArityError("acl|list")
+	}
+
+	users := s.acl.sortedUsers()
+	cli.ReplyArrayLength(int64(len(users)))
+	for _, user := range users {
+		cli.ReplyBulkString(user.describe())
+	}
+	return nil
+}
+
+func (s *Server) aclUsersCommand(cli *client.Client, args ...string) error {
+	if len(args) != 0 {
+		return newWrongArityError("acl|users")
+	}
+
+	users := s.acl.sortedUsers()
+	cli.ReplyArrayLength(int64(len(users)))
+	for _, user := range users {
+		cli.ReplyBulkString(user.name)
+	}
+	return nil
+}
+
+func (s *Server) aclWhoAmICommand(cli *client.Client, args ...string) error {
+	if len(args) != 0 {
+		return newWrongArityError("acl|whoami")
+	}
+
+	cli.ReplyBulkString(cli.User)
+	return nil
+}
+
+func (s *Server) aclCatCommand(cli *client.Client, args ...string) error {
+	switch len(args) {
+	case 0:
+		cli.ReplyArrayLength(int64(len(aclCategories)))
+		for _, category := range aclCategories {
+			cli.ReplyBulkString(category.Name)
+		}
+		return nil
+	case 1:
+	default:
+		return newWrongArityError("acl|cat")
+	}
+
+	flag, ok := lookupACLCategory(strings.ToLower(args[0]))
+	if !ok {
+		return newUnknownACLCategoryError(args[0])
+	}
+
+	names := make([]string, 0)
+	for name, cmd := range dbCommands {
+		if cmd.Flags&flag != 0 {
+			names = append(names, strings.ToLower(name))
+		}
+	}
+	sort.Strings(names)
+
+	cli.ReplyArrayLength(int64(len(names)))
+	for _, name := range names {
+		cli.ReplyBulkString(name)
+	}
+	return nil
+}
+
+func (s *Server) aclLogCommand(cli *client.Client, args ...string) error {
+	count := defaultACLLogCount
+	switch len(args) {
+	case 0:
+	case 1:
+		if strings.ToUpper(args[0]) == "RESET" {
+			s.acl.resetLog()
+			cli.ReplySimpleString("OK")
+			return nil
+		}
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 0 {
+			return core.ErrNotInteger
+		}
+		count = n
+	default:
+		return ErrSyntax
+	}
+
+	now := time.Now()
+	entries := s.acl.logEntries(count)
+	cli.ReplyArrayLength(int64(len(entries)))
+	for _, entry := range entries {
+		cli.ReplyArrayLength(20)
+		cli.ReplyBulkString("count")
+		cli.ReplyInteger(int64(entry.count))
+		cli.ReplyBulkString("reason")
+		cli.ReplyBulkString(entry.reason)
+		cli.ReplyBulkString("context")
+		cli.ReplyBulkString(entry.context)
+		cli.ReplyBulkString("object")
+		cli.ReplyBulkString(entry.object)
+		cli.ReplyBulkString("username")
+		cli.ReplyBulkString(entry.username)
+		cli.ReplyBulkString("age-seconds")
+		cli.ReplyBulkString(strconv.FormatFloat(now.Sub(entry.created).Seconds(), 'f', 3, 64))
+		cli.ReplyBulkString("client-info")
+		cli.ReplyBulkString(entry.clientInfo)
+		cli.ReplyBulkString("entry-id")
+		cli.ReplyInteger(entry.entryID)
+		cli.ReplyBulkString("timestamp-created")
+		cli.ReplyInteger(entry.created.UnixMilli())
+		cli.ReplyBulkString("timestamp-last-updated")
+		cli.ReplyInteger(entry.updated.UnixMilli())
+	}
+	return nil
+}
+
+func (s *Server) aclLoadCommand(cli *client.Client, args ...string) error {
+	if len(args) != 0 {
+		return newWrongArityError("acl|load")
+	}
+
+	if err := s.acl.load(); err != nil {
+		return err
+	}
+
+	cli.ReplySimpleString("OK")
+	return nil
+}
+
+func (s *Server) aclSaveCommand(cli *client.Client, args ...string) error {
+	if len(args) != 0 {
+		return newWrongArityError("acl|save")
+	}
+
+	if err := s.acl.save(); err != nil {
+		return err
+	}
+
+	cli.ReplySimpleString("OK")
+	return nil
+}
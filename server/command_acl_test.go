@@ -0,0 +1,149 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestACLCommands(t *testing.T) {
+	c := newTestClient(t)
+	hash := hashPassword("pw")
+
+	c.mustDo("+OK\r\n", "ACL", "SETUSER", "alice", "on", ">pw", "~foo:*", "%R~bar:*", "+@read", "-keys")
+	c.mustDo("-Error in ACL SETUSER modifier '+@unknown': Unknown command or category name in ACL\r\n", "ACL", "SETUSER", "alice", "off", "+@unknown")
+	c.mustDo("*8\r\n"+
+		bulkReply("flags")+arrayReply("on")+
+		bulkReply("passwords")+arrayReply(hash)+
+		bulkReply("commands")+bulkReply("+@read -keys")+
+		bulkReply("keys")+bulkReply("~foo:* %R~bar:*"),
+		"ACL", "GETUSER", "alice")
+	c.mustDo("*8\r\n"+
+		bulkReply("flags")+arrayReply("on", "nopass", "allkeys")+
+		bulkReply("passwords")+"*0\r\n"+
+		bulkReply("commands")+bulkReply("+@all")+
+		bulkReply("keys")+bulkReply("~*"),
+		"ACL", "GETUSER", "default")
+	c.mustDo("$-1\r\n", "ACL", "GETUSER", "unknown")
+
+	c.mustDo("+OK\r\n", "ACL", "SETUSER", "bob")
+	c.mustDo(arrayReply("alice", "bob", "default"), "ACL", "USERS")
+	c.mustDo(arrayReply(
+		"user alice on #"+hash+" ~foo:* %R~bar:* +@read -keys",
+		"user bob off -@all",
+		"user default on nopass ~* +@all",
+	), "ACL", "LIST")
+
+	c.mustDo(":1\r\n", "ACL", "DELUSER", "bob", "unknown")
+	c.mustDo("-The 'default' user cannot be removed\r\n", "ACL", "DELUSER", "default")
+	c.mustDo(arrayReply("alice", "default"), "ACL", "USERS")
+
+	c.cli.User = aclDefaultUser
+	c.mustDo(bulkReply("default"), "ACL", "WHOAMI")
+
+	c.mustDo(arrayReply("read", "write", "admin", "dangerous", "blocking"), "ACL", "CAT")
+	c.mustDo("-Unknown category 'unknown'\r\n", "ACL", "CAT", "unknown")
+	if reply := c.do("ACL", "CAT", "blocking"); reply != arrayReply("xread", "xreadgroup") {
+		t.Errorf("ACL CAT blocking: expected xread and xreadgroup, got %q", reply)
+	}
+
+	c.mustDo("-This instance is not configured to use an ACL file\r\n", "ACL", "SAVE")
+	c.mustDo("-This instance is not configured to use an ACL file\r\n", "ACL", "LOAD")
+	c.mustDo("-unknown subcommand 'UNKNOWN' for 'ACL' command\r\n", "ACL", "UNKNOWN")
+}
+
+func TestAuth(t *testing.T) {
+	_, addr := startTestServer(t, WithRequirePass("secret"))
+
+	c := dialTestServer(t, addr)
+	c.mustDo("-operation not permitted\r\n", "GET", "foo")
+	c.mustDo("-WRONGPASS invalid username-password pair or user is disabled.\r\n", "AUTH", "wrong")
+	c.mustDo("-WRONGPASS invalid username-password pair or user is disabled.\r\n", "AUTH", "alice", "secret")
+	c.mustDo("-syntax error\r\n", "AUTH", "default", "secret", "secret")
+	c.mustDo("+OK\r\n", "AUTH", "secret")
+	c.mustDo(bulkReply("default"), "ACL", "WHOAMI")
+	c.mustDo("+OK\r\n", "AUTH", "default", "secret")
+
+	c.mustDo("+OK\r\n", "ACL", "SETUSER", "alice", "on", ">pw", "~foo:*", "%R~bar:*", "+@read", "+set", "+acl", "+memory")
+	c.mustDo("+OK\r\n", "ACL", "SETUSER", "bob", "off", ">pw", "allkeys", "allcommands")
+	c.mustDo("+OK\r\n", "SET", "bar:1", "v")
+
+	c.mustDo("-WRONGPASS invalid username-password pair or user is disabled.\r\n", "AUTH", "alice", "wrong")
+	c.mustDo("-WRONGPASS invalid username-password pair or user is disabled.\r\n", "AUTH", "bob", "pw")
+	c.mustDo(bulkReply("default"), "ACL", "WHOAMI")
+
+	c.mustDo("+OK\r\n", "AUTH", "alice", "pw")
+	c.mustDo(bulkReply("alice"), "ACL", "WHOAMI")
+	c.mustDo("+OK\r\n", "SET", "foo:1", "v")
+	c.mustDo(bulkReply("v"), "GET", "foo:1")
+	c.mustDo(bulkReply("v"), "GET", "bar:1")
+	c.mustDo("-NOPERM No permissions to access a key\r\n", "SET", "bar:1", "v")
+	c.mustDo("-NOPERM No permissions to access a key\r\n", "GET", "baz")
+	c.mustDo("-NOPERM No permissions to access a key\r\n", "MGET", "foo:1", "bar:1", "baz")
+	c.mustDo("-NOPERM No permissions to access a key\r\n", "MEMORY", "USAGE", "baz")
+	if reply := c.do("MEMORY", "USAGE", "foo:1"); !strings.HasPrefix(reply, ":") {
+		t.Errorf("MEMORY USAGE: expected integer reply, got %q", reply)
+	}
+	c.mustDo("-NOPERM User alice has no permissions to run the 'del' command\r\n", "DEL", "foo:1")
+
+	// A new connection is not authenticated when the default user has a
+	// password, and one authenticated with the default user can run all
+	// commands.
+	c2 := dialTestServer(t, addr)
+	c2.mustDo("-operation not permitted\r\n", "DEL", "foo:1")
+	c2.mustDo("+OK\r\n", "AUTH", "secret")
+	c2.mustDo(":1\r\n", "DEL", "foo:1")
+
+	if reply := c2.do("ACL", "LOG"); !strings.HasPrefix(reply, "*6\r\n") {
+		t.Errorf("ACL LOG: expected 6 entries, got %q", reply)
+	}
+	c2.mustDo("+OK\r\n", "ACL", "LOG", "RESET")
+	c2.mustDo("*0\r\n", "ACL", "LOG")
+}
+
+func TestAuthDefaultUser(t *testing.T) {
+	_, addr := startTestServer(t)
+
+	// A new connection is authenticated as the default user without password.
+	c := dialTestServer(t, addr)
+	c.mustDo(bulkReply("default"), "ACL", "WHOAMI")
+	c.mustDo("+OK\r\n", "SET", "foo", "v")
+	c.mustDo("+OK\r\n", "AUTH", "any")
+
+	c.mustDo("+OK\r\n", "ACL", "SETUSER", "default", "resetpass", ">secret")
+	c.mustDo("+OK\r\n", "SET", "foo", "v")
+
+	c2 := dialTestServer(t, addr)
+	c2.mustDo("-operation not permitted\r\n", "GET", "foo")
+	c2.mustDo("+OK\r\n", "AUTH", "secret")
+	c2.mustDo(bulkReply("v"), "GET", "foo")
+
+	c2.mustDo("+OK\r\n", "ACL", "SETUSER", "default", "-set")
+	c2.mustDo("-NOPERM User default has no permissions to run the 'set' command\r\n", "SET", "foo", "v")
+}
+
+func TestACLFileCommands(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "users.acl")
+	if err := os.WriteFile(file, []byte("user alice on >pw ~foo:* +get\n"), 0600); err != nil {
+		t.Fatalf("failed to write ACL file: %v", err)
+	}
+
+	c := newTestClient(t, WithACLFile(file))
+	c.mustDo("+OK\r\n", "ACL", "LOAD")
+	c.mustDo(arrayReply("alice", "default"), "ACL", "USERS")
+
+	c.mustDo("+OK\r\n", "ACL", "SETUSER", "bob", "on", "nopass", "%W~bar:*", "+set")
+	c.mustDo("+OK\r\n", "ACL", "SAVE")
+	list := c.do("ACL", "LIST")
+
+	c.mustDo(":1\r\n", "ACL", "DELUSER", "bob")
+	c.mustDo("+OK\r\n", "ACL", "LOAD")
+	c.mustDo(list, "ACL", "LIST")
+
+	if err := os.WriteFile(file, []byte("user carol on +unknown\n"), 0600); err != nil {
+		t.Fatalf("failed to write ACL file: %v", err)
+	}
+	c.mustDo("-"+file+":1: Error in ACL SETUSER modifier '+unknown': Unknown command or category name in ACL\r\n", "ACL", "LOAD")
+	c.mustDo(list, "ACL", "LIST")
+}
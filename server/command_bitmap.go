@@ -0,0 +1,311 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/ghosind/antdb/client"
+	"github.com/ghosind/antdb/core"
+)
+
+var bitOps = map[string]core.BitOp{
+	"AND":   core.BitOpAnd,
+	"OR":    core.BitOpOr,
+	"XOR":   core.BitOpXor,
+	"NOT":   core.BitOpNot,
+	"DIFF":  core.BitOpDiff,
+	"ANDOR": core.BitOpAndOr,
+	"ONE":   core.BitOpOne,
+}
+
+func (s *Server) bitcountCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	start, end := int64(0), int64(-1)
+	isBit := false
+	switch len(args) {
+	case 1:
+	case 3, 4:
+		var err error
+		start, end, isBit, err = parseBitRange(args[1], args[2], args[3:])
+		if err != nil {
+			return err
+		}
+	default:
+		return ErrSyntax
+	}
+
+	cnt, err := db.BitCount(args[0], start, end, isBit)
+	if err != nil {
+		return err
+	}
+
+	cli.ReplyInteger(cnt)
+	return nil
+}
+
+func (s *Server) bitfieldCommand(cli *client.Client, args ...string) error {
+	return s.genericBitfieldCommand(cli, false, args...)
+}
+
+func (s *Server) bitfieldRoCommand(cli *client.Client, args ...string) error {
+	return s.genericBitfieldCommand(cli, true, args...)
+}
+
+func (s *Server) genericBitfieldCommand(cli *client.Client, readOnly bool, args ...string) error {
+	db := s.databases[cli.DB]
+
+	ops := make([]core.BitFieldOp, 0, len(args)/3)
+	overflow := core.BitFieldOverflowWrap
+	for i := 1; i < len(args); {
+		subcommand := strings.ToUpper(args[i])
+		if readOnly && subcommand != "GET" {
+			return ErrBitFieldReadOnly
+		}
+
+		switch subcommand {
+		case "OVERFLOW":
+			if i+1 >= len(args) {
+				return ErrSyntax
+			}
+			switch strings.ToUpper(args[i+1]) {
+			case "WRAP":
+				overflow = core.BitFieldOverflowWrap
+			case "SAT":
+				overflow = core.BitFieldOverflowSat
+			case "FAIL":
+				overflow = core.BitFieldOverflowFail
+			default:
+				return ErrBitFieldOverflow
+			}
+			i += 2
+		case "GET", "SET", "INCRBY":
+			op := core.BitFieldOp{Kind: core.BitFieldGet, Overflow: overflow}
+			argc := 3
+			if subcommand != "GET" {
+				argc = 4
+			}
+			if i+argc > len(args) {
+				return ErrSyntax
+			}
+
+			var err error
+			op.Signed, op.Bits, err = parseBitFieldType(args[i+1])
+			if err != nil {
+				return err
+			}
+			op.Offset, err = s.parseBitFieldOffset(args[i+2], op.Bits)
+			if err != nil {
+				return err
+			}
+			if subcommand != "GET" {
+				op.Kind = core.BitFieldSet
+				if subcommand == "INCRBY" {
+					op.Kind = core.BitFieldIncrBy
+				}
+				op.Value, err = strconv.ParseInt(args[i+3], 10, 64)
+				if err != nil {
+					return core.ErrNotInteger
+				}
+			}
+			ops = append(ops, op)
+			i += argc
+		default:
+			return ErrSyntax
+		}
+	}
+
+	results, err := db.BitField(args[0], ops)
+	if err != nil {
+		return err
+	}
+
+	cli.ReplyArrayLength(int64(len(results)))
+	for _, result := range results {
+		if result.Failed {
+			cli.ReplyNilBulk()
+		} else {
+			cli.ReplyInteger(result.Value)
+		}
+	}
+	return nil
+}
+
+func (s *Server) bitopCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	name := strings.ToUpper(args[0])
+	op, ok := bitOps[name]
+	if !ok {
+		return ErrSyntax
+	}
+	keys := args[2:]
+	if op == core.BitOpNot && len(keys) != 1 {
+		return ErrBitOpNot
+	} else if (op == core.BitOpDiff || op == core.BitOpAndOr) && len(keys) < 2 {
+		return newBitOpSourceKeysError(name)
+	}
+
+	size, err := db.BitOp(op, args[1], keys...)
+	if err != nil {
+		return err
+	}
+
+	cli.ReplyInteger(size)
+	return nil
+}
+
+func (s *Server) bitposCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	bit, err := strconv.Atoi(args[1])
+	if err != nil || (bit != 0 && bit != 1) {
+		return ErrBitArgument
+	}
+
+	start, end := int64(0), int64(-1)
+	hasEnd, isBit := false, false
+	switch len(args) {
+	case 2:
+	case 3:
+		start, err = strconv.ParseInt(args[2], 10, 64)
+		if err != nil {
+			return core.ErrNotInteger
+		}
+	case 4, 5:
+		start, end, isBit, err = parseBitRange(args[2], args[3], args[4:])
+		if err != nil {
+			return err
+		}
+		hasEnd = true
+	default:
+		return ErrSyntax
+	}
+
+	pos, err := db.BitPos(args[0], bit, start, end, hasEnd, isBit)
+	if err != nil {
+		return err
+	}
+
+	cli.ReplyInteger(pos)
+	return nil
+}
+
+func (s *Server) getbitCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	offset, err := s.parseBitOffset(args[1])
+	if err != nil {
+		return err
+	}
+
+	bit, err := db.GetBit(args[0], offset)
+	if err != nil {
+		return err
+	}
+
+	cli.ReplyInteger(int64(bit))
+	return nil
+}
+
+func (s *Server) setbitCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	offset, err := s.parseBitOffset(args[1])
+	if err != nil {
+		return err
+	}
+	if args[2] != "0" && args[2] != "1" {
+		return ErrBitValue
+	}
+
+	bit, err := db.SetBit(args[0], offset, int(args[2][0]-'0'))
+	if err != nil {
+		return err
+	}
+
+	cli.ReplyInteger(int64(bit))
+	return nil
+}
+
+// parseBitOffset parses the bit offset, which must address a bit of a string
+// no longer than proto-max-bulk-len.
+func (s *Server) parseBitOffset(arg string) (int64, error) {
+	offset, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || offset < 0 || offset>>3 >= s.protoMaxBulkLen {
+		return 0, ErrBitOffset
+	}
+	return offset, nil
+}
+
+// parseBitFieldType parses the integer type of BITFIELD, like i8 or u16, and
+// returns whether it is signed and its width in bits.
+func parseBitFieldType(arg string) (bool, int, error) {
+	if len(arg) < 2 {
+		return false, 0, ErrBitFieldType
+	}
+
+	signed := false
+	switch arg[0] {
+	case 'i', 'I':
+		signed = true
+	case 'u', 'U':
+	default:
+		return false, 0, ErrBitFieldType
+	}
+
+	bits, err := strconv.Atoi(arg[1:])
+	if err != nil || bits < 1 || (signed && bits > 64) || (!signed && bits > 63) {
+		return false, 0, ErrBitFieldType
+	}
+	return signed, bits, nil
+}
+
+// parseBitFieldOffset parses the bit offset of BITFIELD, where an offset
+// prefixed with # is multiplied by the width of the type.
+func (s *Server) parseBitFieldOffset(arg string, bits int) (int64, error) {
+	multiply := strings.HasPrefix(arg, "#")
+	if multiply {
+		arg = arg[1:]
+	}
+
+	offset, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || offset < 0 {
+		return 0, ErrBitOffset
+	}
+	if multiply {
+		if offset > (s.protoMaxBulkLen*8)/int64(bits) {
+			return 0, ErrBitOffset
+		}
+		offset *= int64(bits)
+	}
+	if (offset+int64(bits)-1)>>3 >= s.protoMaxBulkLen {
+		return 0, ErrBitOffset
+	}
+	return offset, nil
+}
+
+// parseBitRange parses the start and the end offsets, and the optional BYTE or
+// BIT unit of BITCOUNT and BITPOS.
+func parseBitRange(startArg, endArg string, unit []string) (int64, int64, bool, error) {
+	start, err := strconv.ParseInt(startArg, 10, 64)
+	if err != nil {
+		return 0, 0, false, core.ErrNotInteger
+	}
+	end, err := strconv.ParseInt(endArg, 10, 64)
+	if err != nil {
+		return 0, 0, false, core.ErrNotInteger
+	}
+
+	isBit := false
+	if len(unit) == 1 {
+		switch strings.ToUpper(unit[0]) {
+		case "BYTE":
+		case "BIT":
+			isBit = true
+		default:
+			return 0, 0, false, ErrSyntax
+		}
+	}
+	return start, end, isBit, nil
+}
@@ -0,0 +1,222 @@
+package server
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBitCount(t *testing.T) {
+	tests := []struct {
+		args  []string
+		reply string
+	}{
+		{nil, ":26\r\n"},
+		{[]string{"0", "0"}, ":4\r\n"},
+		{[]string{"1", "1"}, ":6\r\n"},
+		{[]string{"1", "1", "BYTE"}, ":6\r\n"},
+		{[]string{"-2", "-1"}, ":7\r\n"},
+		{[]string{"-100", "100"}, ":26\r\n"},
+		{[]string{"3", "1"}, ":0\r\n"},
+		{[]string{"5", "30", "BIT"}, ":17\r\n"},
+		{[]string{"5", "30", "bit"}, ":17\r\n"},
+		{[]string{"-3", "-1", "BIT"}, ":1\r\n"},
+		{[]string{"0"}, "-syntax error\r\n"},
+		{[]string{"0", "1", "FOO"}, "-syntax error\r\n"},
+		{[]string{"a", "1"}, "-value is not an integer or out of range\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			c := newTestClient(t)
+			c.mustDo("+OK\r\n", "SET", "k", "foobar")
+			c.mustDo(tt.reply, append([]string{"BITCOUNT", "k"}, tt.args...)...)
+		})
+	}
+
+	c := newTestClient(t)
+	c.mustDo(":0\r\n", "BITCOUNT", "none")
+	c.mustDo(":0\r\n", "BITCOUNT", "none", "0", "-1", "BIT")
+}
+
+func TestBitPos(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		args  []string
+		reply string
+	}{
+		{"clear bit", "\xff\xf0\x00", []string{"0"}, ":12\r\n"},
+		{"set bit", "\x00\xff\xf0", []string{"1", "0"}, ":8\r\n"},
+		{"set bit from byte", "\x00\xff\xf0", []string{"1", "2"}, ":16\r\n"},
+		{"set bit in byte range", "\x00\xff\xf0", []string{"1", "2", "-1", "BYTE"}, ":16\r\n"},
+		{"set bit in bit range", "\x00\xff\xf0", []string{"1", "7", "15", "BIT"}, ":8\r\n"},
+		{"set bit in negative bit range", "\x00\xff\xf0", []string{"1", "7", "-3", "BIT"}, ":8\r\n"},
+		{"set bit out of bit range", "\x00\xff\xf0", []string{"1", "0", "7", "BIT"}, ":-1\r\n"},
+		{"clear bit in bit range", "\xff\xf0\x00", []string{"0", "2", "13", "BIT"}, ":12\r\n"},
+		{"no set bit", "\x00\x00", []string{"1"}, ":-1\r\n"},
+		// The string is considered padded with zeros on the right, unless the end
+		// of the range is given.
+		{"all ones", "\xff\xff\xff", []string{"0"}, ":24\r\n"},
+		{"all ones from start", "\xff\xff\xff", []string{"0", "0"}, ":24\r\n"},
+		{"all ones in byte range", "\xff\xff\xff", []string{"0", "0", "-1"}, ":-1\r\n"},
+		{"all ones in bit range", "\xff\xff\xff", []string{"0", "0", "-1", "BIT"}, ":-1\r\n"},
+		{"invalid bit", "\xff", []string{"2"}, "-The bit argument must be 1 or 0.\r\n"},
+		{"invalid unit", "\xff", []string{"0", "0", "-1", "FOO"}, "-syntax error\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t)
+			c.mustDo("+OK\r\n", "SET", "k", tt.value)
+			c.mustDo(tt.reply, append([]string{"BITPOS", "k"}, tt.args...)...)
+		})
+	}
+
+	c := newTestClient(t)
+	c.mustDo(":0\r\n", "BITPOS", "none", "0")
+	c.mustDo(":-1\r\n", "BITPOS", "none", "1")
+}
+
+func TestBitOp(t *testing.T) {
+	tests := []struct {
+		op    string
+		keys  []string
+		reply string
+		value string
+	}{
+		// The shorter operand is padded with zeros.
+		{"AND", []string{"a", "b"}, ":2\r\n", "\xf0\x00"},
+		{"OR", []string{"a", "b"}, ":2\r\n", "\xff\x0f"},
+		{"XOR", []string{"a", "b"}, ":2\r\n", "\x0f\x0f"},
+		{"and", []string{"b", "a"}, ":2\r\n", "\xf0\x00"},
+		{"OR", []string{"b", "none"}, ":1\r\n", "\xf0"},
+		{"AND", []string{"a", "none"}, ":2\r\n", "\x00\x00"},
+		{"XOR", []string{"a", "a", "b"}, ":2\r\n", "\xf0\x00"},
+		{"NOT", []string{"a"}, ":2\r\n", "\x00\xf0"},
+		{"NOT", []string{"b"}, ":1\r\n", "\x0f"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.op+" "+strings.Join(tt.keys, " "), func(t *testing.T) {
+			c := newTestClient(t)
+			c.mustDo("+OK\r\n", "MSET", "a", "\xff\x0f", "b", "\xf0")
+			c.mustDo(tt.reply, append([]string{"BITOP", tt.op, "d"}, tt.keys...)...)
+			c.mustDo(bulkReply(tt.value), "GET", "d")
+		})
+	}
+
+	c := newTestClient(t)
+	c.mustDo("+OK\r\n", "MSET", "a", "\xff", "b", "\xf0", "d", "old")
+	c.mustDo("-BITOP NOT must be called with a single source key.\r\n", "BITOP", "NOT", "d", "a", "b")
+	c.mustDo("-syntax error\r\n", "BITOP", "FOO", "d", "a")
+	c.mustDo(":0\r\n", "BITOP", "AND", "d", "none", "missing")
+	c.mustDo(":0\r\n", "EXISTS", "d")
+}
+
+func TestSetBit(t *testing.T) {
+	c := newTestClient(t)
+	c.mustDo(":0\r\n", "SETBIT", "k", "7", "1")
+	c.mustDo(bulkReply("\x01"), "GET", "k")
+	c.mustDo(":1\r\n", "SETBIT", "k", "7", "0")
+	c.mustDo(":0\r\n", "SETBIT", "k", "17", "1")
+	c.mustDo(bulkReply("\x00\x00\x40"), "GET", "k")
+	c.mustDo(":1\r\n", "GETBIT", "k", "17")
+	c.mustDo(":0\r\n", "GETBIT", "k", "100")
+	c.mustDo("-bit is not an integer or out of range\r\n", "SETBIT", "k", "0", "2")
+}
+
+func TestBitFieldOverflow(t *testing.T) {
+	tests := []struct {
+		name     string
+		typ      string
+		value    string
+		overflow string
+		incr     string
+		reply    string
+	}{
+		{"unsigned wrap up", "u8", "255", "WRAP", "10", ":9\r\n"},
+		{"unsigned wrap down", "u8", "0", "WRAP", "-1", ":255\r\n"},
+		{"unsigned sat up", "u8", "250", "SAT", "10", ":255\r\n"},
+		{"unsigned sat down", "u8", "10", "SAT", "-300", ":0\r\n"},
+		{"unsigned fail up", "u8", "250", "FAIL", "10", "$-1\r\n"},
+		{"unsigned fail down", "u8", "0", "FAIL", "-1", "$-1\r\n"},
+		{"unsigned no overflow", "u8", "9", "FAIL", "10", ":19\r\n"},
+		{"signed wrap up", "i8", "127", "WRAP", "1", ":-128\r\n"},
+		{"signed wrap down", "i8", "-128", "WRAP", "-1", ":127\r\n"},
+		{"signed sat up", "i8", "127", "SAT", "1", ":127\r\n"},
+		{"signed sat down", "i8", "0", "SAT", "-1000", ":-128\r\n"},
+		{"signed fail up", "i8", "127", "FAIL", "1", "$-1\r\n"},
+		{"signed fail down", "i8", "-128", "FAIL", "-1", "$-1\r\n"},
+		{"signed no overflow", "i8", "-100", "FAIL", "-28", ":-128\r\n"},
+		{"i64 wrap", "i64", "-9223372036854775808", "WRAP", "-1", ":9223372036854775807\r\n"},
+		{"i64 sat", "i64", "-9223372036854775808", "SAT", "-1", ":-9223372036854775808\r\n"},
+		{"u63 sat", "u63", "9223372036854775807", "SAT", "1", ":9223372036854775807\r\n"},
+		{"u63 wrap", "u63", "9223372036854775807", "WRAP", "1", ":0\r\n"},
+		{"i5 wrap", "i5", "15", "WRAP", "1", ":-16\r\n"},
+		{"u5 sat", "u5", "30", "SAT", "5", ":31\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t)
+			c.mustDo("*1\r\n:0\r\n", "BITFIELD", "k", "SET", tt.typ, "0", tt.value)
+			expected := tt.value
+			if tt.reply != "$-1\r\n" {
+				expected = strings.TrimSuffix(tt.reply[1:], "\r\n")
+			}
+			c.mustDo("*2\r\n"+tt.reply+":"+expected+"\r\n",
+				"BITFIELD", "k", "OVERFLOW", tt.overflow, "INCRBY", tt.typ, "0", tt.incr, "GET", tt.typ, "0")
+		})
+	}
+}
+
+func TestBitField(t *testing.T) {
+	c := newTestClient(t)
+	c.mustDo("*0\r\n", "BITFIELD", "k")
+	c.mustDo("*1\r\n:0\r\n", "BITFIELD", "k", "GET", "u8", "0")
+	c.mustDo(":0\r\n", "EXISTS", "k")
+
+	// The overflow applies to the following INCRBY and SET until changed.
+	c.mustDo("*4\r\n:0\r\n:255\r\n$-1\r\n:0\r\n",
+		"BITFIELD", "k", "SET", "u8", "0", "255", "OVERFLOW", "SAT", "INCRBY", "u8", "0", "1",
+		"OVERFLOW", "FAIL", "INCRBY", "u8", "0", "1", "OVERFLOW", "WRAP", "INCRBY", "u8", "0", "1")
+	c.mustDo("*1\r\n:0\r\n", "BITFIELD", "k", "SET", "u4", "4", "100")
+	c.mustDo(bulkReply("\x04"), "GET", "k")
+
+	// The offset prefixed with # is multiplied by the width of the type.
+	c.mustDo("*3\r\n:-16\r\n:15\r\n:15\r\n", "BITFIELD", "k", "SET", "i5", "#1", "15", "GET", "i5", "#1", "GET", "u5", "5")
+
+	errInvalidType := "-Invalid bitfield type. Use something like i16 u8. Note that u64 is not supported but i64 is.\r\n"
+	c.mustDo(errInvalidType, "BITFIELD", "k", "GET", "u64", "0")
+	c.mustDo(errInvalidType, "BITFIELD", "k", "GET", "i65", "0")
+	c.mustDo(errInvalidType, "BITFIELD", "k", "GET", "i0", "0")
+	c.mustDo("-Invalid OVERFLOW type specified\r\n", "BITFIELD", "k", "OVERFLOW", "FOO")
+	c.mustDo("-syntax error\r\n", "BITFIELD", "k", "GET", "u8")
+	c.mustDo("-syntax error\r\n", "BITFIELD", "k", "FOO", "u8", "0")
+}
+
+func TestBitFieldReadOnly(t *testing.T) {
+	c := newTestClient(t)
+	c.mustDo("*1\r\n:0\r\n", "BITFIELD", "k", "SET", "u8", "0", "255")
+
+	const errReadOnly = "-BITFIELD_RO only supports the GET subcommand\r\n"
+	tests := []struct {
+		name  string
+		args  []string
+		reply string
+	}{
+		{"GET", []string{"GET", "u8", "0"}, "*1\r\n:255\r\n"},
+		{"multiple GET", []string{"GET", "u4", "0", "GET", "i4", "#1"}, "*2\r\n:15\r\n:-1\r\n"},
+		{"SET", []string{"SET", "u8", "0", "1"}, errReadOnly},
+		{"INCRBY", []string{"INCRBY", "u8", "0", "1"}, errReadOnly},
+		{"OVERFLOW", []string{"OVERFLOW", "SAT"}, errReadOnly},
+		{"GET and SET", []string{"GET", "u8", "0", "SET", "u8", "0", "1"}, errReadOnly},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c.mustDo(tt.reply, append([]string{"BITFIELD_RO", "k"}, tt.args...)...)
+			c.mustDo(bulkReply("\xff"), "GET", "k")
+		})
+	}
+}
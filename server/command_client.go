@@ -0,0 +1,258 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ghosind/antdb/client"
+	"github.com/ghosind/antdb/core"
+)
+
+func (s *Server) clientCommand(cli *client.Client, args ...string) error {
+	switch strings.ToUpper(args[0]) {
+	case "LIST":
+		return s.clientListCommand(cli, args[1:]...)
+	case "INFO":
+		return s.clientInfoCommand(cli, args[1:]...)
+	case "KILL":
+		return s.clientKillCommand(cli, args[1:]...)
+	case "SETNAME":
+		return s.clientSetNameCommand(cli, args[1:]...)
+	case "GETNAME":
+		return s.clientGetNameCommand(cli, args[1:]...)
+	case "ID":
+		return s.clientIDCommand(cli, args[1:]...)
+	case "PAUSE":
+		return s.clientPauseCommand(cli, args[1:]...)
+	case "UNPAUSE":
+		return s.clientUnpauseCommand(cli, args[1:]...)
+	default:
+		return newUnknownSubcommandError("CLIENT", args[0])
+	}
+}
+
+func (s *Server) clientListCommand(cli *client.Client, args ...string) error {
+	var ids map[uint64]bool
+	normal := true
+
+	for i := 0; i < len(args); {
+		switch strings.ToUpper(args[i]) {
+		case "TYPE":
+			if i+1 >= len(args) {
+				return ErrSyntax
+			}
+			switch strings.ToLower(args[i+1]) {
+			case "normal":
+			case "master", "replica", "pubsub":
+				normal = false
+			default:
+				return newUnknownClientTypeError(args[i+1])
+			}
+			i += 2
+		case "ID":
+			if i+1 >= len(args) {
+				return ErrSyntax
+			}
+			ids = make(map[uint64]bool)
+			for i++; i < len(args); i++ {
+				id, err := strconv.ParseUint(args[i], 10, 64)
+				if err != nil || id == 0 {
+					return ErrInvalidClientID
+				}
+				ids[id] = true
+			}
+		default:
+			return ErrSyntax
+		}
+	}
+
+	var buf strings.Builder
+	if normal {
+		now := time.Now()
+		for _, info := range s.clientInfos() {
+			if ids != nil && !ids[info.ID] {
+				continue
+			}
+			buf.WriteString(formatClientInfo(info, now))
+			buf.WriteString("\n")
+		}
+	}
+
+	replyStringValue(cli, buf.String())
+	return nil
+}
+
+func (s *Server) clientInfoCommand(cli *client.Client, args ...string) error {
+	if len(args) != 0 {
+		return newWrongArityError("client|info")
+	}
+
+	cli.ReplyBulkString(formatClientInfo(cli.Info(), time.Now()) + "\n")
+	return nil
+}
+
+// clientKillCommand kills the clients matched by all the filters, or the client
+// with the address in the old form of CLIENT KILL addr:port.
+func (s *Server) clientKillCommand(cli *client.Client, args ...string) error {
+	switch len(args) {
+	case 0:
+		return newWrongArityError("client|kill")
+	case 1:
+		addr := args[0]
+		cnt := s.killClients(cli, func(info client.Info) bool {
+			return info.Addr == addr
+		})
+		if cnt == 0 {
+			return ErrNoSuchClient
+		}
+		cli.ReplySimpleString("OK")
+		return nil
+	}
+
+	if len(args)%2 != 0 {
+		return ErrSyntax
+	}
+
+	filters := make([]func(info client.Info) bool, 0, len(args)/2)
+	skipMe := true
+	for i := 0; i < len(args); i += 2 {
+		value := args[i+1]
+		switch strings.ToUpper(args[i]) {
+		case "ID":
+			id, err := strconv.ParseUint(value, 10, 64)
+			if err != nil || id == 0 {
+				return ErrInvalidClientID
+			}
+			filters = append(filters, func(info client.Info) bool {
+				return info.ID == id
+			})
+		case "ADDR":
+			filters = append(filters, func(info client.Info) bool {
+				return info.Addr == value
+			})
+		case "LADDR":
+			filters = append(filters, func(info client.Info) bool {
+				return info.LocalAddr == value
+			})
+		case "USER":
+			filters = append(filters, func(info client.Info) bool {
+				return info.User == value
+			})
+		case "TYPE":
+			switch strings.ToLower(value) {
+			case "normal":
+			case "master", "replica", "pubsub":
+				filters = append(filters, func(info client.Info) bool {
+					return false
+				})
+			default:
+				return newUnknownClientTypeError(value)
+			}
+		case "SKIPME":
+			switch strings.ToLower(value) {
+			case "yes":
+				skipMe = true
+			case "no":
+				skipMe = false
+			default:
+				return ErrSyntax
+			}
+		default:
+			return ErrSyntax
+		}
+	}
+
+	cnt := s.killClients(cli, func(info client.Info) bool {
+		if skipMe && info.ID == cli.ID {
+			return false
+		}
+		for _, filter := range filters {
+			if !filter(info) {
+				return false
+			}
+		}
+		return true
+	})
+
+	cli.ReplyInteger(int64(cnt))
+	return nil
+}
+
+func (s *Server) clientSetNameCommand(cli *client.Client, args ...string) error {
+	if len(args) != 1 {
+		return newWrongArityError("client|setname")
+	}
+
+	name := args[0]
+	for _, c := range name {
+		if c <= ' ' || c > '~' {
+			return ErrInvalidClientName
+		}
+	}
+
+	cli.Name = name
+	cli.ReplySimpleString("OK")
+	return nil
+}
+
+func (s *Server) clientGetNameCommand(cli *client.Client, args ...string) error {
+	if len(args) != 0 {
+		return newWrongArityError("client|getname")
+	}
+
+	if cli.Name == "" {
+		cli.ReplyNilBulk()
+	} else {
+		cli.ReplyBulkString(cli.Name)
+	}
+	return nil
+}
+
+func (s *Server) clientIDCommand(cli *client.Client, args ...string) error {
+	if len(args) != 0 {
+		return newWrongArityError("client|id")
+	}
+
+	cli.ReplyInteger(int64(cli.ID))
+	return nil
+}
+
+func (s *Server) clientPauseCommand(cli *client.Client, args ...string) error {
+	if len(args) != 1 && len(args) != 2 {
+		return newWrongArityError("client|pause")
+	}
+
+	timeout, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return core.ErrNotInteger
+	}
+	if timeout < 0 {
+		return ErrInvalidTimeout
+	}
+
+	writeOnly := false
+	if len(args) == 2 {
+		switch strings.ToUpper(args[1]) {
+		case "WRITE":
+			writeOnly = true
+		case "ALL":
+		default:
+			return ErrSyntax
+		}
+	}
+
+	s.pause.start(time.Now().Add(time.Duration(timeout)*time.Millisecond), writeOnly)
+	cli.ReplySimpleString("OK")
+	return nil
+}
+
+func (s *Server) clientUnpauseCommand(cli *client.Client, args ...string) error {
+	if len(args) != 0 {
+		return newWrongArityError("client|unpause")
+	}
+
+	s.pause.stop()
+	cli.ReplySimpleString("OK")
+	return nil
+}
@@ -0,0 +1,192 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClientListNoMatch(t *testing.T) {
+	_, addr := startTestServer(t)
+	c := dialTestServer(t, addr)
+
+	if reply := c.do("CLIENT", "LIST"); !strings.HasPrefix(reply, "$") || !strings.Contains(reply, "id=") {
+		t.Errorf("CLIENT LIST: expected the current client, got %q", reply)
+	}
+	c.mustDo("$0\r\n\r\n", "CLIENT", "LIST", "TYPE", "pubsub")
+	c.mustDo("$0\r\n\r\n", "CLIENT", "LIST", "ID", "99999")
+	c.mustDo("-"+ErrInvalidClientID.Error()+"\r\n", "CLIENT", "LIST", "ID", "0")
+	c.mustDo("-"+newUnknownClientTypeError("foo").Error()+"\r\n", "CLIENT", "LIST", "TYPE", "foo")
+}
+
+func TestClientSetName(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply string
+		get   string
+	}{
+		{"conn-1", "+OK\r\n", "$6\r\nconn-1\r\n"},
+		{"~!@#$%^&*()", "+OK\r\n", "$11\r\n~!@#$%^&*()\r\n"},
+		{"", "+OK\r\n", "$-1\r\n"},
+		{"with space", "-" + ErrInvalidClientName.Error() + "\r\n", "$-1\r\n"},
+		{"new\nline", "-" + ErrInvalidClientName.Error() + "\r\n", "$-1\r\n"},
+		{"tab\t", "-" + ErrInvalidClientName.Error() + "\r\n", "$-1\r\n"},
+		{"del\x7f", "-" + ErrInvalidClientName.Error() + "\r\n", "$-1\r\n"},
+		{"héllo", "-" + ErrInvalidClientName.Error() + "\r\n", "$-1\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Quote(tt.name), func(t *testing.T) {
+			c := newTestClient(t)
+			c.mustDo(tt.reply, "CLIENT", "SETNAME", tt.name)
+			c.mustDo(tt.get, "CLIENT", "GETNAME")
+		})
+	}
+}
+
+func TestClientKill(t *testing.T) {
+	tests := []struct {
+		name string
+		// args builds the arguments of CLIENT KILL from the id and the address
+		// of the target client, and the name of the user.
+		args  func(id, addr string) []string
+		reply string
+		// killed is whether the target and the current clients are closed.
+		killed   bool
+		killedMe bool
+	}{
+		{"ID", func(id, addr string) []string { return []string{"ID", id} }, ":1\r\n", true, false},
+		{"ADDR", func(id, addr string) []string { return []string{"ADDR", addr} }, ":1\r\n", true, false},
+		{"ID and ADDR", func(id, addr string) []string { return []string{"ID", id, "ADDR", addr} }, ":1\r\n", true, false},
+		{"ID and other ADDR", func(id, addr string) []string {
+			return []string{"ID", id, "ADDR", "127.0.0.1:1"}
+		}, ":0\r\n", false, false},
+		{"unknown ID", func(id, addr string) []string { return []string{"ID", "99999"} }, ":0\r\n", false, false},
+		{"USER", func(id, addr string) []string { return []string{"USER", "default"} }, ":1\r\n", true, false},
+		{"USER SKIPME yes", func(id, addr string) []string {
+			return []string{"USER", "default", "SKIPME", "yes"}
+		}, ":1\r\n", true, false},
+		{"USER SKIPME no", func(id, addr string) []string {
+			return []string{"USER", "default", "SKIPME", "no"}
+		}, ":2\r\n", true, true},
+		{"unknown USER", func(id, addr string) []string { return []string{"USER", "nobody"} }, ":0\r\n", false, false},
+		{"TYPE normal", func(id, addr string) []string { return []string{"TYPE", "normal"} }, ":1\r\n", true, false},
+		{"TYPE pubsub", func(id, addr string) []string { return []string{"TYPE", "pubsub"} }, ":0\r\n", false, false},
+		{"old form", func(id, addr string) []string { return []string{addr} }, "+OK\r\n", true, false},
+		{"old form unknown", func(id, addr string) []string { return []string{"127.0.0.1:1"} },
+			"-" + ErrNoSuchClient.Error() + "\r\n", false, false},
+		{"invalid ID", func(id, addr string) []string { return []string{"ID", "0"} },
+			"-" + ErrInvalidClientID.Error() + "\r\n", false, false},
+		{"invalid SKIPME", func(id, addr string) []string { return []string{"SKIPME", "maybe"} },
+			"-" + ErrSyntax.Error() + "\r\n", false, false},
+		{"missing value", func(id, addr string) []string { return []string{"ID", id, "ADDR"} },
+			"-" + ErrSyntax.Error() + "\r\n", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, addr := startTestServer(t)
+			me := dialTestServer(t, addr)
+			target := dialTestServer(t, addr)
+
+			id := strings.TrimSuffix(strings.TrimPrefix(target.do("CLIENT", "ID"), ":"), "\r\n")
+			targetAddr := target.conn.LocalAddr().String()
+
+			me.mustDo(tt.reply, append([]string{"CLIENT", "KILL"}, tt.args(id, targetAddr)...)...)
+			if tt.killed {
+				target.expectClosed(5 * time.Second)
+			} else {
+				target.mustDo("+PONG\r\n", "PING")
+			}
+			if tt.killedMe {
+				me.expectClosed(5 * time.Second)
+			} else {
+				me.mustDo("+PONG\r\n", "PING")
+			}
+		})
+	}
+}
+
+func TestClientPause(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		command []string
+		reply   string
+		paused  bool
+	}{
+		{"WRITE pauses SET", "WRITE", []string{"SET", "k", "v"}, "+OK\r\n", true},
+		{"WRITE runs GET", "WRITE", []string{"GET", "k"}, "$-1\r\n", false},
+		{"ALL pauses SET", "ALL", []string{"SET", "k", "v"}, "+OK\r\n", true},
+		{"ALL pauses GET", "ALL", []string{"GET", "k"}, "$-1\r\n", true},
+		{"ALL runs admin commands", "ALL", []string{"CLIENT", "ID"}, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, addr := startTestServer(t)
+			admin := dialTestServer(t, addr)
+			c := dialTestServer(t, addr)
+
+			admin.mustDo("+OK\r\n", "CLIENT", "PAUSE", "100000", tt.mode)
+			c.send(tt.command...)
+			reply, err := c.readReply(200 * time.Millisecond)
+			if tt.paused {
+				if err == nil {
+					t.Fatalf("%v: expected paused, got reply %q", tt.command, reply)
+				}
+				admin.mustDo("+OK\r\n", "CLIENT", "UNPAUSE")
+				reply = c.read(5 * time.Second)
+			} else if err != nil {
+				t.Fatalf("%v: expected not paused, got %v", tt.command, err)
+			}
+			if tt.reply != "" && reply != tt.reply {
+				t.Errorf("%v: expected reply %q, got %q", tt.command, tt.reply, reply)
+			}
+		})
+	}
+}
+
+func TestClientPauseTimeout(t *testing.T) {
+	_, addr := startTestServer(t)
+	admin := dialTestServer(t, addr)
+	c := dialTestServer(t, addr)
+
+	start := time.Now()
+	admin.mustDo("+OK\r\n", "CLIENT", "PAUSE", "200")
+	c.mustDo("+OK\r\n", "SET", "k", "v")
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("expected SET paused for about 200ms, got %v", elapsed)
+	}
+
+	admin.mustDo("-"+ErrInvalidTimeout.Error()+"\r\n", "CLIENT", "PAUSE", "-1")
+	admin.mustDo("-"+ErrSyntax.Error()+"\r\n", "CLIENT", "PAUSE", "100", "READ")
+}
+
+func TestMaxClients(t *testing.T) {
+	_, addr := startTestServer(t, WithMaxClients(2))
+	c1 := dialTestServer(t, addr)
+	c1.mustDo("+PONG\r\n", "PING")
+	c2 := dialTestServer(t, addr)
+	c2.mustDo("+PONG\r\n", "PING")
+
+	c3 := dialTestServer(t, addr)
+	if reply := c3.read(5 * time.Second); reply != "-"+ErrMaxClients.Error()+"\r\n" {
+		t.Fatalf("expected max clients error, got %q", reply)
+	}
+	c3.expectClosed(5 * time.Second)
+
+	// A closed client releases its slot.
+	c2.conn.Close()
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		c4 := dialTestServer(t, addr)
+		c4.send("PING")
+		if reply := c4.read(5 * time.Second); reply == "+PONG\r\n" {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatalf("expected a slot released, got %q", reply)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
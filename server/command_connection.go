@@ -7,16 +7,19 @@ import (
 )
 
 func (s *Server) authCommand(cli *client.Client, args ...string) error {
-	if s.requirePass == "" {
-		cli.ReplySimpleString("OK")
-		return nil
+	username, password := aclDefaultUser, args[0]
+	if len(args) == 2 {
+		username, password = args[0], args[1]
+	} else if len(args) > 2 {
+		return ErrSyntax
 	}
 
-	password := args[0]
-	if password != s.requirePass {
+	if !s.acl.authenticate(username, password) {
+		s.acl.addLogEntry(cli, aclDeniedAuth, "AUTH", username)
 		return ErrInvalidPassword
 	}
 
+	cli.User = username
 	cli.Authenticated = true
 	cli.ReplySimpleString("OK")
 	return nil
@@ -0,0 +1,144 @@
+package server
+
+type commandDoc struct {
+	Summary    string
+	Since      string
+	Group      string
+	Complexity string
+}
+
+// commandDocs are the documents of the commands replied by COMMAND DOCS.
+var commandDocs = map[string]commandDoc{
+	// Connection Management
+	"AUTH":   {Summary: "Authenticates the connection.", Since: "1.0.0", Group: "connection", Complexity: "O(N) where N is the number of passwords defined for the user"},
+	"CLIENT": {Summary: "A container for client connection commands.", Since: "2.4.0", Group: "connection", Complexity: "Depends on subcommand."},
+	"ECHO":   {Summary: "Returns the given string.", Since: "1.0.0", Group: "connection", Complexity: "O(1)"},
+	"PING":   {Summary: "Returns the server's liveliness response.", Since: "1.0.0", Group: "connection", Complexity: "O(1)"},
+	"SELECT": {Summary: "Changes the selected database.", Since: "1.0.0", Group: "connection", Complexity: "O(1)"},
+	// Bitmap
+	"BITCOUNT":    {Summary: "Counts the number of set bits (population counting) in a string.", Since: "2.6.0", Group: "bitmap", Complexity: "O(N)"},
+	"BITFIELD":    {Summary: "Performs arbitrary bitfield integer operations on strings.", Since: "3.2.0", Group: "bitmap", Complexity: "O(1) for each subcommand specified"},
+	"BITFIELD_RO": {Summary: "Performs arbitrary read-only bitfield integer operations on strings.", Since: "6.0.0", Group: "bitmap", Complexity: "O(1) for each subcommand specified"},
+	"BITOP":       {Summary: "Performs bitwise operations on multiple strings, and stores the result.", Since: "2.6.0", Group: "bitmap", Complexity: "O(N)"},
+	"BITPOS":      {Summary: "Finds the first set (1) or clear (0) bit in a string.", Since: "2.8.7", Group: "bitmap", Complexity: "O(N)"},
+	"GETBIT":      {Summary: "Returns a bit value by offset.", Since: "2.2.0", Group: "bitmap", Complexity: "O(1)"},
+	"SETBIT":      {Summary: "Sets or clears the bit at offset of the string value. Creates the key if it doesn't exist.", Since: "2.2.0", Group: "bitmap", Complexity: "O(1)"},
+	// Generic
+	"DEL":         {Summary: "Deletes one or more keys.", Since: "1.0.0", Group: "generic", Complexity: "O(N) where N is the number of keys that will be removed."},
+	"EXISTS":      {Summary: "Determines whether one or more keys exist.", Since: "1.0.0", Group: "generic", Complexity: "O(N) where N is the number of keys to check."},
+	"EXPIRE":      {Summary: "Sets the expiration time of a key in seconds.", Since: "1.0.0", Group: "generic", Complexity: "O(1)"},
+	"EXPIREAT":    {Summary: "Sets the expiration time of a key to a Unix timestamp.", Since: "1.2.0", Group: "generic", Complexity: "O(1)"},
+	"EXPIRETIME":  {Summary: "Returns the expiration time of a key as a Unix timestamp.", Since: "7.0.0", Group: "generic", Complexity: "O(1)"},
+	"KEYS":        {Summary: "Returns all key names that match a pattern.", Since: "1.0.0", Group: "generic", Complexity: "O(N) with N being the number of keys in the database."},
+	"MOVE":        {Summary: "Moves a key to another database.", Since: "1.0.0", Group: "generic", Complexity: "O(1)"},
+	"OBJECT":      {Summary: "A container for object introspection commands.", Since: "2.2.3", Group: "generic", Complexity: "Depends on subcommand."},
+	"PERSIST":     {Summary: "Removes the expiration time of a key.", Since: "2.2.0", Group: "generic", Complexity: "O(1)"},
+	"PEXPIRE":     {Summary: "Sets the expiration time of a key in milliseconds.", Since: "2.6.0", Group: "generic", Complexity: "O(1)"},
+	"PEXPIREAT":   {Summary: "Sets the expiration time of a key to a Unix milliseconds timestamp.", Since: "2.6.0", Group: "generic", Complexity: "O(1)"},
+	"PEXPIRETIME": {Summary: "Returns the expiration time of a key as a Unix milliseconds timestamp.", Since: "7.0.0", Group: "generic", Complexity: "O(1)"},
+	"PTTL":        {Summary: "Returns the expiration time in milliseconds of a key.", Since: "2.6.0", Group: "generic", Complexity: "O(1)"},
+	"RANDOMKEY":   {Summary: "Returns a random key name from the database.", Since: "1.0.0", Group: "generic", Complexity: "O(1)"},
+	"RENAME":      {Summary: "Renames a key and overwrites the destination.", Since: "1.0.0", Group: "generic", Complexity: "O(1)"},
+	"RENAMENX":    {Summary: "Renames a key only when the target key name doesn't exist.", Since: "1.0.0", Group: "generic", Complexity: "O(1)"},
+	"SCAN":        {Summary: "Iterates over the key names in the database.", Since: "2.8.0", Group: "generic", Complexity: "O(1) for every call. O(N) for a complete iteration."},
+	"TTL":         {Summary: "Returns the expiration time in seconds of a key.", Since: "1.0.0", Group: "generic", Complexity: "O(1)"},
+	"TYPE":        {Summary: "Determines the type of value stored at a key.", Since: "1.0.0", Group: "generic", Complexity: "O(1)"},
+	// Geospatial
+	"GEOADD":         {Summary: "Adds one or more members to a geospatial index. The key is created if it doesn't exist.", Since: "3.2.0", Group: "geo", Complexity: "O(log(N)) for each item added, where N is the number of elements in the index."},
+	"GEODIST":        {Summary: "Returns the distance between two members of a geospatial index.", Since: "3.2.0", Group: "geo", Complexity: "O(1)"},
+	"GEOHASH":        {Summary: "Returns members from a geospatial index as geohash strings.", Since: "3.2.0", Group: "geo", Complexity: "O(1) for each member requested."},
+	"GEOPOS":         {Summary: "Returns the longitude and latitude of members from a geospatial index.", Since: "3.2.0", Group: "geo", Complexity: "O(1) for each member requested."},
+	"GEOSEARCH":      {Summary: "Queries a geospatial index for members inside an area of a box or a circle.", Since: "6.2.0", Group: "geo", Complexity: "O(N+log(M)) where N is the number of elements in the grid-aligned bounding box area around the shape provided as the filter and M is the number of items inside the shape"},
+	"GEOSEARCHSTORE": {Summary: "Queries a geospatial index for members inside an area of a box or a circle, optionally stores the result.", Since: "6.2.0", Group: "geo", Complexity: "O(N+log(M)) where N is the number of elements in the grid-aligned bounding box area around the shape provided as the filter and M is the number of items inside the shape"},
+	// HyperLogLog
+	"PFADD":   {Summary: "Adds elements to a HyperLogLog key. Creates the key if it doesn't exist.", Since: "2.8.9", Group: "hyperloglog", Complexity: "O(1) to add every element."},
+	"PFCOUNT": {Summary: "Returns the approximated cardinality of the set(s) observed by the HyperLogLog key(s).", Since: "2.8.9", Group: "hyperloglog", Complexity: "O(1) with a very small average constant time when called with a single key. O(N) with N being the number of keys, and much bigger constant times, when called with multiple keys."},
+	"PFDEBUG": {Summary: "Internal commands for debugging HyperLogLog values.", Since: "2.8.9", Group: "hyperloglog", Complexity: "N/A"},
+	"PFMERGE": {Summary: "Merges one or more HyperLogLog values into a single key.", Since: "2.8.9", Group: "hyperloglog", Complexity: "O(N) to merge N HyperLogLogs, but with high constant times."},
+	// List
+	"LINDEX":    {Summary: "Returns an element from a list by its index.", Since: "1.0.0", Group: "list", Complexity: "O(N) where N is the number of elements to traverse to get to the element at index."},
+	"LINSERT":   {Summary: "Inserts an element before or after another element in a list.", Since: "2.2.0", Group: "list", Complexity: "O(N) where N is the number of elements to traverse before seeing the value pivot."},
+	"LLEN":      {Summary: "Returns the length of a list.", Since: "1.0.0", Group: "list", Complexity: "O(1)"},
+	"LMOVE":     {Summary: "Returns an element after popping it from one list and pushing it to another. Deletes the list if the last element was moved.", Since: "6.2.0", Group: "list", Complexity: "O(1)"},
+	"LMPOP":     {Summary: "Returns multiple elements from a list after removing them. Deletes the list if the last element was popped.", Since: "7.0.0", Group: "list", Complexity: "O(N+M) where N is the number of provided keys and M is the number of elements returned."},
+	"LPOP":      {Summary: "Returns the first element of a list after removing it.", Since: "1.0.0", Group: "list", Complexity: "O(1)"},
+	"LPOS":      {Summary: "Returns the index of matching elements in a list.", Since: "6.0.6", Group: "list", Complexity: "O(N) where N is the number of elements in the list, for the average case. When searching for elements near the head or the tail of the list, or when the MAXLEN option is provided, the command may run in constant time."},
+	"LPUSH":     {Summary: "Prepends one or more elements to a list. Creates the key if it doesn't exist.", Since: "1.0.0", Group: "list", Complexity: "O(1) for each element added, so O(N) to add N elements when the command is called with multiple arguments."},
+	"LPUSHX":    {Summary: "Prepends one or more elements to a list only when the list exists.", Since: "2.2.0", Group: "list", Complexity: "O(1) for each element added, so O(N) to add N elements when the command is called with multiple arguments."},
+	"LRANGE":    {Summary: "Returns a range of elements from a list.", Since: "1.0.0", Group: "list", Complexity: "O(S+N) where S is the distance of start offset from HEAD for small lists, from nearest end (HEAD or TAIL) for large lists; and N is the number of elements in the specified range."},
+	"LREM":      {Summary: "Removes elements from a list.", Since: "1.0.0", Group: "list", Complexity: "O(N+M) where N is the length of the list and M is the number of elements removed."},
+	"LSET":      {Summary: "Sets the value of an element in a list by its index.", Since: "1.0.0", Group: "list", Complexity: "O(N) where N is the length of the list."},
+	"LTRIM":     {Summary: "Removes elements from both ends of a list.", Since: "1.0.0", Group: "list", Complexity: "O(N) where N is the number of elements to be removed."},
+	"RPOP":      {Summary: "Returns and removes the last element of a list.", Since: "1.0.0", Group: "list", Complexity: "O(1)"},
+	"RPOPLPUSH": {Summary: "Returns the last element of a list after removing and pushing it to another list.", Since: "1.2.0", Group: "list", Complexity: "O(1)"},
+	"RPUSH":     {Summary: "Appends one or more elements to a list. Creates the key if it doesn't exist.", Since: "1.0.0", Group: "list", Complexity: "O(1) for each element added, so O(N) to add N elements when the command is called with multiple arguments."},
+	"RPUSHX":    {Summary: "Appends an element to a list only when the list exists.", Since: "2.2.0", Group: "list", Complexity: "O(1) for each element added, so O(N) to add N elements when the command is called with multiple arguments."},
+	// Server Management
+	"ACL":      {Summary: "A container for Access List Control commands.", Since: "6.0.0", Group: "server", Complexity: "Depends on subcommand."},
+	"COMMAND":  {Summary: "Returns detailed information about all commands.", Since: "2.8.13", Group: "server", Complexity: "O(N) where N is the total number of commands."},
+	"DBSIZE":   {Summary: "Returns the number of keys in the database.", Since: "1.0.0", Group: "server", Complexity: "O(1)"},
+	"FLUSHALL": {Summary: "Removes all keys from all databases.", Since: "1.0.0", Group: "server", Complexity: "O(N) where N is the total number of keys in all databases."},
+	"FLUSHDB":  {Summary: "Removes all keys from the current database.", Since: "1.0.0", Group: "server", Complexity: "O(N) where N is the number of keys in the selected database."},
+	"INFO":     {Summary: "Returns information and statistics about the server.", Since: "1.0.0", Group: "server", Complexity: "O(1)"},
+	"LATENCY":  {Summary: "A container for latency diagnostics commands.", Since: "2.8.13", Group: "server", Complexity: "Depends on subcommand."},
+	"MEMORY":   {Summary: "A container for memory diagnostics commands.", Since: "4.0.0", Group: "server", Complexity: "Depends on subcommand."},
+	"MONITOR":  {Summary: "Listens for all requests received by the server in real-time.", Since: "1.0.0", Group: "server"},
+	"SLOWLOG":  {Summary: "A container for slow log commands.", Since: "2.2.12", Group: "server", Complexity: "Depends on subcommand."},
+	// Set
+	"SADD":        {Summary: "Adds one or more members to a set. Creates the key if it doesn't exist.", Since: "1.0.0", Group: "set", Complexity: "O(1) for each element added."},
+	"SCARD":       {Summary: "Returns the number of members in a set.", Since: "1.0.0", Group: "set", Complexity: "O(1)"},
+	"SDIFF":       {Summary: "Returns the difference of multiple sets.", Since: "1.0.0", Group: "set", Complexity: "O(N) where N is the total number of elements in all given sets."},
+	"SDIFFSTORE":  {Summary: "Stores the difference of multiple sets in a key.", Since: "1.0.0", Group: "set", Complexity: "O(N) where N is the total number of elements in all given sets."},
+	"SINTER":      {Summary: "Returns the intersect of multiple sets.", Since: "1.0.0", Group: "set", Complexity: "O(N*M) worst case where N is the cardinality of the smallest set and M is the number of sets."},
+	"SINTERSTORE": {Summary: "Stores the intersect of multiple sets in a key.", Since: "1.0.0", Group: "set", Complexity: "O(N*M) worst case where N is the cardinality of the smallest set and M is the number of sets."},
+	"SISMEMBER":   {Summary: "Determines whether a member belongs to a set.", Since: "1.0.0", Group: "set", Complexity: "O(1)"},
+	"SMEMBERS":    {Summary: "Returns all members of a set.", Since: "1.0.0", Group: "set", Complexity: "O(N) where N is the set cardinality."},
+	"SMOVE":       {Summary: "Moves a member from one set to another.", Since: "1.0.0", Group: "set", Complexity: "O(1)"},
+	"SPOP":        {Summary: "Returns a random member from a set after removing it.", Since: "1.0.0", Group: "set", Complexity: "O(1)"},
+	"SRANDMEMBER": {Summary: "Get one or multiple random members from a set.", Since: "1.0.0", Group: "set", Complexity: "Without the count argument O(1), otherwise O(N) where N is the absolute value of the passed count."},
+	"SREM":        {Summary: "Removes one or more members from a set.", Since: "1.0.0", Group: "set", Complexity: "O(N) where N is the number of members to be removed."},
+	"SSCAN":       {Summary: "Iterates over members of a set.", Since: "2.8.0", Group: "set", Complexity: "O(1) for every call. O(N) for a complete iteration."},
+	"SUNION":      {Summary: "Returns the union of multiple sets.", Since: "1.0.0", Group: "set", Complexity: "O(N) where N is the total number of elements in all given sets."},
+	"SUNIONSTORE": {Summary: "Stores the union of multiple sets in a key.", Since: "1.0.0", Group: "set", Complexity: "O(N) where N is the total number of elements in all given sets."},
+	// Stream
+	"XACK":       {Summary: "Returns the number of messages that were successfully acknowledged by the consumer group member of a stream.", Since: "5.0.0", Group: "stream", Complexity: "O(1) for each message ID processed."},
+	"XADD":       {Summary: "Appends a new message to a stream. Creates the key if it doesn't exist.", Since: "5.0.0", Group: "stream", Complexity: "O(1) when adding a new entry, O(N) when trimming where N being the number of entries evicted."},
+	"XAUTOCLAIM": {Summary: "Changes, or acquires, ownership of messages in a consumer group, as if the messages were delivered to as consumer group member.", Since: "6.2.0", Group: "stream", Complexity: "O(1) if COUNT is small."},
+	"XCLAIM":     {Summary: "Changes, or acquires, ownership of a message in a consumer group, as if the message was delivered a consumer group member.", Since: "5.0.0", Group: "stream", Complexity: "O(log N) with N being the number of messages in the PEL of the consumer group."},
+	"XDEL":       {Summary: "Returns the number of messages after removing them from a stream.", Since: "5.0.0", Group: "stream", Complexity: "O(1) for each single item to delete in the stream, regardless of the stream size."},
+	"XGROUP":     {Summary: "A container for consumer groups commands.", Since: "5.0.0", Group: "stream", Complexity: "Depends on subcommand."},
+	"XINFO":      {Summary: "A container for stream introspection commands.", Since: "5.0.0", Group: "stream", Complexity: "Depends on subcommand."},
+	"XLEN":       {Summary: "Return the number of messages in a stream.", Since: "5.0.0", Group: "stream", Complexity: "O(1)"},
+	"XPENDING":   {Summary: "Returns the information and entries from a stream consumer group's pending entries list.", Since: "5.0.0", Group: "stream", Complexity: "O(N) with N being the number of elements returned, so asking for a small fixed number of entries per call is O(1). O(M), where M is the total number of entries scanned when used with the IDLE filter. When the command returns just the summary and the list of consumers is small, it runs in O(1) time; otherwise, an additional O(N) time for iterating every consumer."},
+	"XRANGE":     {Summary: "Returns the messages from a stream within a range of IDs.", Since: "5.0.0", Group: "stream", Complexity: "O(N) with N being the number of elements being returned. If N is constant (e.g. always asking for the first 10 elements with COUNT), you can consider it O(1)."},
+	"XREAD":      {Summary: "Returns messages from multiple streams with IDs greater than the ones requested. Blocks until a message is available otherwise.", Since: "5.0.0", Group: "stream", Complexity: "For each stream mentioned: O(N) with N being the number of elements being returned, it means that XREAD-ing with a fixed COUNT is O(1). Note that when the BLOCK option is used, XADD will pay O(M) time in order to serve the M clients blocked on the stream getting new data."},
+	"XREADGROUP": {Summary: "Returns new or historical messages from a stream for a consumer in a group. Blocks until a message is available otherwise.", Since: "5.0.0", Group: "stream", Complexity: "For each stream mentioned: O(M) with M being the number of elements returned. If M is constant (e.g. always asking for the first 10 elements with COUNT), you can consider it O(1). On the other side when XREADGROUP blocks, XADD will pay the O(N) time in order to serve the N clients blocked on the stream getting new data."},
+	"XREVRANGE":  {Summary: "Returns the messages from a stream within a range of IDs in reverse order.", Since: "5.0.0", Group: "stream", Complexity: "O(N) with N being the number of elements returned. If N is constant (e.g. always asking for the first 10 elements with COUNT), you can consider it O(1)."},
+	"XTRIM":      {Summary: "Deletes messages from the beginning of a stream.", Since: "5.0.0", Group: "stream", Complexity: "O(N), with N being the number of evicted entries. Constant times are very small however, since entries are organized in macro nodes containing multiple entries that can be released with a single deallocation."},
+	// String
+	"APPEND":      {Summary: "Appends a string to the value of a key. Creates the key if it doesn't exist.", Since: "2.0.0", Group: "string", Complexity: "O(1). The amortized time complexity is O(1) assuming the appended value is small and the already present value is of any size, since the string is modified in place."},
+	"DECR":        {Summary: "Decrements the integer value of a key by one.", Since: "1.0.0", Group: "string", Complexity: "O(1)"},
+	"DECRBY":      {Summary: "Decrements a number from the integer value of a key.", Since: "1.0.0", Group: "string", Complexity: "O(1)"},
+	"GET":         {Summary: "Returns the string value of a key.", Since: "1.0.0", Group: "string", Complexity: "O(1)"},
+	"GETDEL":      {Summary: "Returns the string value of a key after deleting the key.", Since: "6.2.0", Group: "string", Complexity: "O(1)"},
+	"GETEX":       {Summary: "Returns the string value of a key after setting its expiration time.", Since: "6.2.0", Group: "string", Complexity: "O(1)"},
+	"GETRANGE":    {Summary: "Returns a substring of the string stored at a key.", Since: "2.4.0", Group: "string", Complexity: "O(N) where N is the length of the returned string."},
+	"GETSET":      {Summary: "Returns the previous string value of a key after setting it to a new value.", Since: "1.0.0", Group: "string", Complexity: "O(1)"},
+	"INCR":        {Summary: "Increments the integer value of a key by one.", Since: "1.0.0", Group: "string", Complexity: "O(1)"},
+	"INCRBY":      {Summary: "Increments the integer value of a key by a number.", Since: "1.0.0", Group: "string", Complexity: "O(1)"},
+	"INCRBYFLOAT": {Summary: "Increment the floating point value of a key by a number.", Since: "2.6.0", Group: "string", Complexity: "O(1)"},
+	"LCS":         {Summary: "Finds the longest common substring.", Since: "7.0.0", Group: "string", Complexity: "O(N*M) where N and M are the lengths of s1 and s2, respectively"},
+	"MGET":        {Summary: "Atomically returns the string values of one or more keys.", Since: "1.0.0", Group: "string", Complexity: "O(N) where N is the number of keys to retrieve."},
+	"MSET":        {Summary: "Atomically creates or modifies the string values of one or more keys.", Since: "1.0.1", Group: "string", Complexity: "O(N) where N is the number of keys to set."},
+	"MSETNX":      {Summary: "Atomically modifies the string values of one or more keys only when all keys don't exist.", Since: "1.0.1", Group: "string", Complexity: "O(N) where N is the number of keys to set."},
+	"PSETEX":      {Summary: "Sets both string value and expiration time in milliseconds of a key. The key is created if it doesn't exist.", Since: "2.6.0", Group: "string", Complexity: "O(1)"},
+	"SET":         {Summary: "Sets the string value of a key, ignoring its type. The key is created if it doesn't exist.", Since: "1.0.0", Group: "string", Complexity: "O(1)"},
+	"SETEX":       {Summary: "Sets the string value and expiration time of a key. Creates the key if it doesn't exist.", Since: "2.0.0", Group: "string", Complexity: "O(1)"},
+	"SETNX":       {Summary: "Set the string value of a key only when the key doesn't exist.", Since: "1.0.0", Group: "string", Complexity: "O(1)"},
+	"SETRANGE":    {Summary: "Overwrites a part of a string value with another by an offset. Creates the key if it doesn't exist.", Since: "2.2.0", Group: "string", Complexity: "O(1), not counting the time taken to copy the new string in place."},
+	"STRLEN":      {Summary: "Returns the length of a string value.", Since: "2.2.0", Group: "string", Complexity: "O(1)"},
+	"SUBSTR":      {Summary: "Returns a substring from a string value.", Since: "1.0.0", Group: "string", Complexity: "O(N) where N is the length of the returned string."},
+	// Transaction
+	"EXEC":  {Summary: "Executes all commands in a transaction.", Since: "1.2.0", Group: "transactions", Complexity: "Depends on commands in the transaction"},
+	"MULTI": {Summary: "Starts a transaction.", Since: "1.2.0", Group: "transactions", Complexity: "O(1)"},
+}
@@ -1,7 +1,9 @@
 package server
 
 import (
+	"math"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ghosind/antdb/client"
@@ -25,40 +27,15 @@ func (s *Server) existsCommand(cli *client.Client, args ...string) error {
 }
 
 func (s *Server) expireCommand(cli *client.Client, args ...string) error {
-	db := s.databases[cli.DB]
-
-	key := args[0]
-	expireSeconds, err := strconv.ParseInt(args[1], 10, 64)
-	if err != nil {
-		cli.ReplyError("ERR value is not an integer or out of range")
-		return nil
-	}
-	expireMillis := time.Now().UnixMilli() + expireSeconds*1000
-	ok := db.Expire(key, expireMillis)
-	if ok {
-		cli.ReplyInteger(1)
-	} else {
-		cli.ReplyInteger(0)
-	}
-	return nil
+	return s.genericExpireCommand(cli, "expire", time.Now().UnixMilli(), 1000, args...)
 }
 
 func (s *Server) expireAtCommand(cli *client.Client, args ...string) error {
-	db := s.databases[cli.DB]
+	return s.genericExpireCommand(cli, "expireat", 0, 1000, args...)
+}
 
-	key := args[0]
-	expireAt, err := strconv.ParseInt(args[1], 10, 64)
-	if err != nil {
-		cli.ReplyError("ERR value is not an integer or out of range")
-		return nil
-	}
-	ok := db.Expire(key, expireAt*1000)
-	if ok {
-		cli.ReplyInteger(1)
-	} else {
-		cli.ReplyInteger(0)
-	}
-	return nil
+func (s *Server) expireTimeCommand(cli *client.Client, args ...string) error {
+	return s.genericTTLCommand(cli, args[0], false, true)
 }
 
 func (s *Server) keysCommand(cli *client.Client, args ...string) error {
@@ -84,9 +61,17 @@ func (s *Server) moveCommand(cli *client.Client, args ...string) error {
 	newDB, err := strconv.Atoi(args[1])
 	if err != nil || newDB < 0 || newDB >= s.databaseNum {
 		return ErrInvalidDBIndex
+	} else if newDB == cli.DB {
+		return ErrSameObject
 	}
 
-	ok := db.Move(key, s.databases[newDB])
+	ok := db.Move(key, func(obj *core.Object) bool {
+		added := false
+		s.runInDatabase(cli.DB, newDB, func(dest *core.Database) {
+			added = dest.AddMovedKey(key, obj)
+		})
+		return added
+	})
 	if ok {
 		cli.ReplyInteger(1)
 	} else {
@@ -137,16 +122,80 @@ func (s *Server) renameNxCommand(cli *client.Client, args ...string) error {
 	return nil
 }
 
-func (s *Server) ttlCommand(cli *client.Client, args ...string) error {
+func (s *Server) scanCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	cursor, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+	opts, err := parseScanOptions(args[1:], true)
+	if err != nil {
+		return err
+	}
+
+	next, keys, err := db.Scan(cursor, opts.count, opts.match, opts.typ)
+	if err != nil {
+		return err
+	}
+
+	replyScan(cli, next, keys)
+	return nil
+}
+
+func (s *Server) persistCommand(cli *client.Client, args ...string) error {
 	db := s.databases[cli.DB]
 
 	key := args[0]
-	expires := db.TTL(key)
-	if expires <= 0 {
-		cli.ReplyInteger(expires)
+	if db.Persist(key) {
+		cli.ReplyInteger(1)
+	} else {
+		cli.ReplyInteger(0)
+	}
+	return nil
+}
+
+func (s *Server) pexpireCommand(cli *client.Client, args ...string) error {
+	return s.genericExpireCommand(cli, "pexpire", time.Now().UnixMilli(), 1, args...)
+}
+
+func (s *Server) pexpireAtCommand(cli *client.Client, args ...string) error {
+	return s.genericExpireCommand(cli, "pexpireat", 0, 1, args...)
+}
+
+func (s *Server) pexpireTimeCommand(cli *client.Client, args ...string) error {
+	return s.genericTTLCommand(cli, args[0], true, true)
+}
+
+func (s *Server) pttlCommand(cli *client.Client, args ...string) error {
+	return s.genericTTLCommand(cli, args[0], true, false)
+}
+
+func (s *Server) ttlCommand(cli *client.Client, args ...string) error {
+	return s.genericTTLCommand(cli, args[0], false, false)
+}
+
+func (s *Server) objectCommand(cli *client.Client, args ...string) error {
+	switch strings.ToUpper(args[0]) {
+	case "ENCODING":
+		return s.objectEncodingCommand(cli, args[1:]...)
+	default:
+		return newUnknownSubcommandError("OBJECT", args[0])
+	}
+}
+
+func (s *Server) objectEncodingCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	if len(args) != 1 {
+		return ErrSyntax
+	}
+
+	encoding, found := db.ObjectEncoding(args[0])
+	if !found {
+		cli.ReplyNilBulk()
 	} else {
-		ttl := (expires - time.Now().UnixMilli()) / 1000
-		cli.ReplyInteger(ttl)
+		cli.ReplyBulkString(encoding.String())
 	}
 	return nil
 }
@@ -159,3 +208,147 @@ func (s *Server) typeCommand(cli *client.Client, args ...string) error {
 	cli.ReplySimpleString(typ)
 	return nil
 }
+
+// genericExpireCommand implements EXPIRE, PEXPIRE, EXPIREAT and PEXPIREAT. The
+// time argument is multiplied by unit to get milliseconds, and is relative to
+// basetime in unix milliseconds, or absolute if basetime is 0.
+func (s *Server) genericExpireCommand(cli *client.Client, name string, basetime, unit int64, args ...string) error {
+	db := s.databases[cli.DB]
+
+	key := args[0]
+	when, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return core.ErrNotInteger
+	}
+
+	flag := core.ExpireFlag(0)
+	for _, arg := range args[2:] {
+		switch strings.ToUpper(arg) {
+		case "NX":
+			flag |= core.ExpireFlagNX
+		case "XX":
+			flag |= core.ExpireFlagXX
+		case "GT":
+			flag |= core.ExpireFlagGT
+		case "LT":
+			flag |= core.ExpireFlagLT
+		default:
+			return newUnsupportedOptionError(arg)
+		}
+	}
+	if flag&core.ExpireFlagNX != 0 && flag&(core.ExpireFlagXX|core.ExpireFlagGT|core.ExpireFlagLT) != 0 {
+		return ErrExpireNXIncompatible
+	}
+	if flag&core.ExpireFlagGT != 0 && flag&core.ExpireFlagLT != 0 {
+		return ErrExpireGTLTIncompatible
+	}
+
+	expires, ok := toExpireMillis(when, basetime, unit)
+	if !ok {
+		return newInvalidExpireTimeError(name)
+	}
+
+	if db.Expire(key, expires, flag) {
+		cli.ReplyInteger(1)
+	} else {
+		cli.ReplyInteger(0)
+	}
+	return nil
+}
+
+// genericTTLCommand implements TTL, PTTL, EXPIRETIME and PEXPIRETIME, replying
+// -2 if the key does not exist and -1 if it has no expiration.
+func (s *Server) genericTTLCommand(cli *client.Client, key string, outputMillis, absolute bool) error {
+	db := s.databases[cli.DB]
+
+	expires := db.TTL(key)
+	if expires < 0 {
+		cli.ReplyInteger(expires)
+		return nil
+	}
+
+	ttl := expires
+	if !absolute {
+		ttl -= time.Now().UnixMilli()
+		if ttl < 0 {
+			ttl = 0
+		}
+	}
+	if !outputMillis {
+		if absolute {
+			ttl /= 1000
+		} else {
+			ttl = (ttl + 500) / 1000
+		}
+	}
+
+	cli.ReplyInteger(ttl)
+	return nil
+}
+
+// toExpireMillis converts the time in units of unit milliseconds relative to
+// basetime into unix milliseconds. It returns false if the result overflows.
+func toExpireMillis(when, basetime, unit int64) (int64, bool) {
+	if when > math.MaxInt64/unit || when < math.MinInt64/unit {
+		return 0, false
+	}
+	when *= unit
+	if when > math.MaxInt64-basetime {
+		return 0, false
+	}
+	return when + basetime, true
+}
+
+const defaultScanCount = 10
+
+type scanOptions struct {
+	count int
+	match string
+	typ   core.ObjectType
+}
+
+func parseScanOptions(args []string, allowType bool) (*scanOptions, error) {
+	opts := &scanOptions{count: defaultScanCount}
+
+	for i := 0; i < len(args); i += 2 {
+		if i+1 >= len(args) {
+			return nil, ErrSyntax
+		}
+		value := args[i+1]
+
+		switch strings.ToUpper(args[i]) {
+		case "COUNT":
+			count, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, core.ErrNotInteger
+			} else if count < 1 {
+				return nil, ErrSyntax
+			}
+			opts.count = count
+		case "MATCH":
+			opts.match = value
+		case "TYPE":
+			if !allowType {
+				return nil, ErrSyntax
+			}
+			typ, ok := core.ParseObjectType(value)
+			if !ok {
+				return nil, newUnknownTypeError(value)
+			}
+			opts.typ = typ
+		default:
+			return nil, ErrSyntax
+		}
+	}
+
+	return opts, nil
+}
+
+func replyScan(cli *client.Client, cursor uint64, items []string) {
+	cli.ReplyArrayLength(2)
+	cli.ReplyBulkString(strconv.FormatUint(cursor, 10))
+	cli.ReplyArrayLength(int64(len(items)))
+	for _, item := range items {
+		cli.ReplyBulkString(item)
+	}
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestToExpireMillis(t *testing.T) {
+	tests := []struct {
+		name     string
+		when     int64
+		basetime int64
+		unit     int64
+		expected int64
+		ok       bool
+	}{
+		{"relative seconds", 10, 1000, 1000, 11000, true},
+		{"relative milliseconds", 10, 1000, 1, 1010, true},
+		{"absolute seconds", 1700000000, 0, 1000, 1700000000000, true},
+		{"negative", -10, 1000, 1000, -9000, true},
+		{"largest seconds", math.MaxInt64 / 1000, 0, 1000, math.MaxInt64 / 1000 * 1000, true},
+		{"largest milliseconds", math.MaxInt64 - 1000, 1000, 1, math.MaxInt64, true},
+		{"unit overflow", math.MaxInt64/1000 + 1, 0, 1000, 0, false},
+		{"negative unit overflow", math.MinInt64/1000 - 1, 0, 1000, 0, false},
+		{"basetime overflow", math.MaxInt64 - 999, 1000, 1, 0, false},
+		{"seconds and basetime overflow", math.MaxInt64 / 1000, 1000, 1000, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expires, ok := toExpireMillis(tt.when, tt.basetime, tt.unit)
+			if ok != tt.ok || (ok && expires != tt.expected) {
+				t.Errorf("toExpireMillis(%d, %d, %d) = (%d, %v), expected (%d, %v)",
+					tt.when, tt.basetime, tt.unit, expires, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
+
+func TestExpireConditions(t *testing.T) {
+	tests := []struct {
+		name string
+		// ttl is the TTL of the key in seconds before the command, where 0 is
+		// no expiration and -1 is no key.
+		ttl   int
+		args  []string
+		reply string
+		// expected is the TTL in seconds after the command, with the same
+		// meaning as ttl.
+		expected int
+	}{
+		{"set", 0, []string{"EXPIRE", "k", "100"}, ":1\r\n", 100},
+		{"overwrite", 50, []string{"EXPIRE", "k", "100"}, ":1\r\n", 100},
+		{"no key", -1, []string{"EXPIRE", "k", "100"}, ":0\r\n", -1},
+		{"past deletes", 50, []string{"EXPIRE", "k", "-1"}, ":1\r\n", -1},
+		{"NX without ttl", 0, []string{"EXPIRE", "k", "100", "NX"}, ":1\r\n", 100},
+		{"NX with ttl", 50, []string{"EXPIRE", "k", "100", "NX"}, ":0\r\n", 50},
+		{"XX without ttl", 0, []string{"EXPIRE", "k", "100", "XX"}, ":0\r\n", 0},
+		{"XX with ttl", 50, []string{"EXPIRE", "k", "100", "XX"}, ":1\r\n", 100},
+		{"GT without ttl", 0, []string{"EXPIRE", "k", "100", "GT"}, ":0\r\n", 0},
+		{"GT greater", 50, []string{"EXPIRE", "k", "100", "GT"}, ":1\r\n", 100},
+		{"GT less", 200, []string{"EXPIRE", "k", "100", "GT"}, ":0\r\n", 200},
+		{"LT without ttl", 0, []string{"EXPIRE", "k", "100", "LT"}, ":1\r\n", 100},
+		{"LT less", 200, []string{"EXPIRE", "k", "100", "LT"}, ":1\r\n", 100},
+		{"LT greater", 50, []string{"EXPIRE", "k", "100", "LT"}, ":0\r\n", 50},
+		{"XX and GT", 50, []string{"EXPIRE", "k", "100", "XX", "GT"}, ":1\r\n", 100},
+		{"XX and GT without ttl", 0, []string{"EXPIRE", "k", "100", "XX", "GT"}, ":0\r\n", 0},
+		{"lowercase", 50, []string{"EXPIRE", "k", "100", "gt"}, ":1\r\n", 100},
+		{"PEXPIRE LT", 200, []string{"PEXPIRE", "k", "100000", "LT"}, ":1\r\n", 100},
+		{"PEXPIRE GT", 200, []string{"PEXPIRE", "k", "100000", "GT"}, ":0\r\n", 200},
+		{"NX and XX", 50, []string{"EXPIRE", "k", "100", "NX", "XX"},
+			"-" + ErrExpireNXIncompatible.Error() + "\r\n", 50},
+		{"NX and GT", 50, []string{"PEXPIRE", "k", "100", "NX", "GT"},
+			"-" + ErrExpireNXIncompatible.Error() + "\r\n", 50},
+		{"GT and LT", 50, []string{"EXPIRE", "k", "100", "GT", "LT"},
+			"-" + ErrExpireGTLTIncompatible.Error() + "\r\n", 50},
+		{"unknown option", 50, []string{"EXPIRE", "k", "100", "FOO"},
+			"-" + newUnsupportedOptionError("FOO").Error() + "\r\n", 50},
+		{"not integer", 50, []string{"EXPIRE", "k", "abc"}, "-value is not an integer or out of range\r\n", 50},
+		{"overflow", 50, []string{"EXPIRE", "k", strconv.FormatInt(math.MaxInt64/1000+1, 10)},
+			"-" + newInvalidExpireTimeError("expire").Error() + "\r\n", 50},
+		{"PEXPIRE overflow", 50, []string{"PEXPIRE", "k", strconv.FormatInt(math.MaxInt64, 10)},
+			"-" + newInvalidExpireTimeError("pexpire").Error() + "\r\n", 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t)
+			if tt.ttl >= 0 {
+				c.mustDo("+OK\r\n", "SET", "k", "v")
+			}
+			if tt.ttl > 0 {
+				c.mustDo(":1\r\n", "EXPIRE", "k", strconv.Itoa(tt.ttl))
+			}
+
+			c.mustDo(tt.reply, tt.args...)
+			assertTTL(t, c, "k", tt.expected)
+		})
+	}
+}
+
+func TestExpireAt(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		args     []string
+		reply    string
+		expected int
+	}{
+		{"EXPIREAT", []string{"EXPIREAT", "k", strconv.FormatInt(now.Unix()+100, 10)}, ":1\r\n", 100},
+		{"PEXPIREAT", []string{"PEXPIREAT", "k", strconv.FormatInt(now.UnixMilli()+100000, 10)}, ":1\r\n", 100},
+		{"EXPIREAT past", []string{"EXPIREAT", "k", strconv.FormatInt(now.Unix()-1, 10)}, ":1\r\n", -1},
+		{"PEXPIREAT GT", []string{"PEXPIREAT", "k", strconv.FormatInt(now.UnixMilli()+100000, 10), "GT"}, ":0\r\n", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t)
+			c.mustDo("+OK\r\n", "SET", "k", "v")
+			c.mustDo(tt.reply, tt.args...)
+			assertTTL(t, c, "k", tt.expected)
+		})
+	}
+}
+
+// assertTTL checks that the key has the TTL in seconds with one second of
+// tolerance, where 0 is no expiration and -1 is no key.
+func assertTTL(t *testing.T, c *testClient, key string, expected int) {
+	t.Helper()
+
+	reply := c.do("PTTL", key)
+	pttl, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(reply, ":"), "\r\n"), 10, 64)
+	if err != nil {
+		t.Fatalf("PTTL %s: unexpected reply %q", key, reply)
+	}
+
+	switch expected {
+	case -1:
+		if pttl != -2 {
+			t.Errorf("expected %s to not exist, got PTTL %d", key, pttl)
+		}
+	case 0:
+		if pttl != -1 {
+			t.Errorf("expected %s to have no expiration, got PTTL %d", key, pttl)
+		}
+	default:
+		if pttl <= int64(expected-1)*1000 || pttl > int64(expected)*1000 {
+			t.Errorf("expected %s to have TTL %ds, got PTTL %d", key, expected, pttl)
+		}
+	}
+}
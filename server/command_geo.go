@@ -0,0 +1,353 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/ghosind/antdb/client"
+	"github.com/ghosind/antdb/core"
+)
+
+// geoUnits are the units of distances in meters.
+var geoUnits = map[string]float64{
+	"M":  1,
+	"KM": 1000,
+	"FT": 0.3048,
+	"MI": 1609.34,
+}
+
+// geoSearchOptions are the parsed options of GEOSEARCH and GEOSEARCHSTORE.
+type geoSearchOptions struct {
+	query     core.GeoSearchQuery
+	unit      float64
+	withCoord bool
+	withDist  bool
+	withHash  bool
+}
+
+func (s *Server) geoaddCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	nx, xx, ch := false, false, false
+	i := 1
+options:
+	for ; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "CH":
+			ch = true
+		default:
+			break options
+		}
+	}
+	if nx && xx {
+		return ErrNXAndXX
+	}
+
+	rest := args[i:]
+	if len(rest) == 0 || len(rest)%3 != 0 {
+		return ErrSyntax
+	}
+	members := make([]string, 0, len(rest)/3)
+	points := make([]core.GeoPoint, 0, len(rest)/3)
+	for j := 0; j < len(rest); j += 3 {
+		p, err := parseGeoPoint(rest[j], rest[j+1])
+		if err != nil {
+			return err
+		}
+		members = append(members, rest[j+2])
+		points = append(points, p)
+	}
+
+	cnt, err := db.GeoAdd(args[0], members, points, nx, xx, ch)
+	if err != nil {
+		return err
+	}
+
+	cli.ReplyInteger(cnt)
+	return nil
+}
+
+func (s *Server) geodistCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	unit := 1.0
+	switch len(args) {
+	case 3:
+	case 4:
+		var err error
+		unit, err = parseGeoUnit(args[3])
+		if err != nil {
+			return err
+		}
+	default:
+		return ErrSyntax
+	}
+
+	dist, ok, err := db.GeoDist(args[0], args[1], args[2])
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		cli.ReplyNilBulk()
+	} else {
+		cli.ReplyBulkString(formatGeoDistance(dist, unit))
+	}
+	return nil
+}
+
+func (s *Server) geohashCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	hashes, err := db.GeoHash(args[0], args[1:]...)
+	if err != nil {
+		return err
+	}
+
+	cli.ReplyArrayLength(int64(len(hashes)))
+	for _, hash := range hashes {
+		cli.ReplyBulkString(hash)
+	}
+	return nil
+}
+
+func (s *Server) geoposCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	points, err := db.GeoPos(args[0], args[1:]...)
+	if err != nil {
+		return err
+	}
+
+	cli.ReplyArrayLength(int64(len(points)))
+	for _, p := range points {
+		if p == nil {
+			cli.ReplyArrayLength(-1)
+		} else {
+			replyGeoPoint(cli, *p)
+		}
+	}
+	return nil
+}
+
+func (s *Server) geosearchCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	opts, err := parseGeoSearchOptions("geosearch", args[1:], false)
+	if err != nil {
+		return err
+	}
+
+	results, err := db.GeoSearch(args[0], &opts.query)
+	if err != nil {
+		return err
+	}
+
+	cli.ReplyArrayLength(int64(len(results)))
+	for _, result := range results {
+		if !opts.withCoord && !opts.withDist && !opts.withHash {
+			cli.ReplyBulkString(result.Member)
+			continue
+		}
+
+		fields := int64(1)
+		for _, with := range []bool{opts.withCoord, opts.withDist, opts.withHash} {
+			if with {
+				fields++
+			}
+		}
+		cli.ReplyArrayLength(fields)
+		cli.ReplyBulkString(result.Member)
+		if opts.withDist {
+			cli.ReplyBulkString(formatGeoDistance(result.Distance, opts.unit))
+		}
+		if opts.withHash {
+			cli.ReplyInteger(int64(result.Hash))
+		}
+		if opts.withCoord {
+			replyGeoPoint(cli, result.Position)
+		}
+	}
+	return nil
+}
+
+func (s *Server) geosearchStoreCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	opts, err := parseGeoSearchOptions("geosearchstore", args[2:], true)
+	if err != nil {
+		return err
+	}
+
+	cnt, err := db.GeoSearchStore(args[0], args[1], &opts.query)
+	if err != nil {
+		return err
+	}
+
+	cli.ReplyInteger(cnt)
+	return nil
+}
+
+// parseGeoSearchOptions parses the options of GEOSEARCH, or of GEOSEARCHSTORE
+// if store is true, which does not accept the WITH options.
+func parseGeoSearchOptions(name string, args []string, store bool) (*geoSearchOptions, error) {
+	opts := &geoSearchOptions{unit: 1}
+	query := &opts.query
+	hasFrom, hasBy := false, false
+	for i := 0; i < len(args); i++ {
+		var err error
+		switch option := strings.ToUpper(args[i]); option {
+		case "FROMMEMBER":
+			if i+1 >= len(args) {
+				return nil, ErrSyntax
+			} else if hasFrom {
+				return nil, newGeoSearchFromError(name)
+			}
+			query.FromMember, query.Member = true, args[i+1]
+			hasFrom = true
+			i++
+		case "FROMLONLAT":
+			if i+2 >= len(args) {
+				return nil, ErrSyntax
+			} else if hasFrom {
+				return nil, newGeoSearchFromError(name)
+			}
+			query.Center, err = parseGeoPoint(args[i+1], args[i+2])
+			if err != nil {
+				return nil, err
+			}
+			hasFrom = true
+			i += 2
+		case "BYRADIUS":
+			if i+2 >= len(args) {
+				return nil, ErrSyntax
+			} else if hasBy {
+				return nil, newGeoSearchByError(name)
+			}
+			query.Shape = core.GeoShapeRadius
+			query.Radius, err = strconv.ParseFloat(args[i+1], 64)
+			if err != nil {
+				return nil, core.ErrNotFloat
+			} else if query.Radius < 0 {
+				return nil, ErrGeoRadiusNegative
+			}
+			opts.unit, err = parseGeoUnit(args[i+2])
+			if err != nil {
+				return nil, err
+			}
+			query.Radius *= opts.unit
+			hasBy = true
+			i += 2
+		case "BYBOX":
+			if i+3 >= len(args) {
+				return nil, ErrSyntax
+			} else if hasBy {
+				return nil, newGeoSearchByError(name)
+			}
+			query.Shape = core.GeoShapeBox
+			query.Width, err = strconv.ParseFloat(args[i+1], 64)
+			if err != nil {
+				return nil, core.ErrNotFloat
+			}
+			query.Height, err = strconv.ParseFloat(args[i+2], 64)
+			if err != nil {
+				return nil, core.ErrNotFloat
+			}
+			if query.Width < 0 || query.Height < 0 {
+				return nil, ErrGeoBoxNegative
+			}
+			opts.unit, err = parseGeoUnit(args[i+3])
+			if err != nil {
+				return nil, err
+			}
+			query.Width *= opts.unit
+			query.Height *= opts.unit
+			hasBy = true
+			i += 3
+		case "ASC":
+			query.Sort = core.GeoSortAsc
+		case "DESC":
+			query.Sort = core.GeoSortDesc
+		case "COUNT":
+			if i+1 >= len(args) {
+				return nil, ErrSyntax
+			}
+			count, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil {
+				return nil, core.ErrNotInteger
+			} else if count <= 0 {
+				return nil, ErrCountNotPositive
+			}
+			query.Count = int(count)
+			i++
+			if i+1 < len(args) && strings.ToUpper(args[i+1]) == "ANY" {
+				query.Any = true
+				i++
+			}
+		case "WITHCOORD", "WITHDIST", "WITHHASH":
+			if store {
+				return nil, ErrSyntax
+			}
+			switch option {
+			case "WITHCOORD":
+				opts.withCoord = true
+			case "WITHDIST":
+				opts.withDist = true
+			case "WITHHASH":
+				opts.withHash = true
+			}
+		default:
+			return nil, ErrSyntax
+		}
+	}
+
+	if !hasFrom {
+		return nil, newGeoSearchFromError(name)
+	} else if !hasBy {
+		return nil, newGeoSearchByError(name)
+	}
+	// Without ANY, the nearest members are returned for COUNT.
+	if query.Count > 0 && !query.Any && query.Sort == core.GeoSortNone {
+		query.Sort = core.GeoSortAsc
+	}
+	return opts, nil
+}
+
+func parseGeoPoint(lonArg, latArg string) (core.GeoPoint, error) {
+	lon, err := strconv.ParseFloat(lonArg, 64)
+	if err != nil {
+		return core.GeoPoint{}, core.ErrNotFloat
+	}
+	lat, err := strconv.ParseFloat(latArg, 64)
+	if err != nil {
+		return core.GeoPoint{}, core.ErrNotFloat
+	}
+
+	p := core.GeoPoint{Longitude: lon, Latitude: lat}
+	if !p.Valid() {
+		return p, newInvalidGeoPointError(lon, lat)
+	}
+	return p, nil
+}
+
+func parseGeoUnit(arg string) (float64, error) {
+	unit, ok := geoUnits[strings.ToUpper(arg)]
+	if !ok {
+		return 0, ErrGeoUnit
+	}
+	return unit, nil
+}
+
+func formatGeoDistance(dist, unit float64) string {
+	return strconv.FormatFloat(dist/unit, 'f', 4, 64)
+}
+
+func replyGeoPoint(cli *client.Client, p core.GeoPoint) {
+	cli.ReplyArrayLength(2)
+	cli.ReplyBulkString(strconv.FormatFloat(p.Longitude, 'f', -1, 64))
+	cli.ReplyBulkString(strconv.FormatFloat(p.Latitude, 'f', -1, 64))
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/ghosind/antdb/client"
+	"github.com/ghosind/antdb/core"
+)
+
+func (s *Server) pfaddCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	updated, err := db.PFAdd(args[0], args[1:]...)
+	if err != nil {
+		return err
+	}
+
+	if updated {
+		cli.ReplyInteger(1)
+	} else {
+		cli.ReplyInteger(0)
+	}
+	return nil
+}
+
+func (s *Server) pfcountCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	card, err := db.PFCount(args...)
+	if err != nil {
+		return err
+	}
+
+	cli.ReplyInteger(card)
+	return nil
+}
+
+func (s *Server) pfdebugCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	var err error
+	switch strings.ToUpper(args[0]) {
+	case "GETREG":
+		var regs []uint8
+		regs, err = db.PFDebugGetReg(args[1])
+		if err == nil {
+			cli.ReplyArrayLength(int64(len(regs)))
+			for _, reg := range regs {
+				cli.ReplyInteger(int64(reg))
+			}
+		}
+	case "DECODE":
+		var decoded string
+		decoded, err = db.PFDebugDecode(args[1])
+		if err == nil {
+			cli.ReplySimpleString(decoded)
+		}
+	case "ENCODING":
+		var encoding string
+		encoding, err = db.PFDebugEncoding(args[1])
+		if err == nil {
+			cli.ReplySimpleString(encoding)
+		}
+	case "TODENSE":
+		var converted bool
+		converted, err = db.PFDebugToDense(args[1])
+		if err == nil {
+			if converted {
+				cli.ReplyInteger(1)
+			} else {
+				cli.ReplyInteger(0)
+			}
+		}
+	default:
+		return newUnknownSubcommandError("pfdebug", args[0])
+	}
+
+	if errors.Is(err, core.ErrNoSuchKey) {
+		return ErrKeyNotExist
+	}
+	return err
+}
+
+func (s *Server) pfmergeCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	if err := db.PFMerge(args[0], args[1:]...); err != nil {
+		return err
+	}
+
+	cli.ReplySimpleString("OK")
+	return nil
+}
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/ghosind/antdb/core"
+)
+
+func TestHyperLogLogCommands(t *testing.T) {
+	errNotHLL := "-" + core.ErrNotHLL.Error() + "\r\n"
+
+	c := newTestClient(t)
+	c.mustDo(":1\r\n", "PFADD", "hll", "a", "b", "c")
+	c.mustDo(":0\r\n", "PFADD", "hll", "a")
+	c.mustDo(":1\r\n", "PFADD", "empty")
+	c.mustDo(":0\r\n", "PFADD", "empty")
+	c.mustDo(":3\r\n", "PFCOUNT", "hll")
+	c.mustDo(":1\r\n", "PFADD", "other", "c", "d")
+	c.mustDo(":4\r\n", "PFCOUNT", "hll", "other", "none")
+	c.mustDo("+OK\r\n", "PFMERGE", "merged", "hll", "other")
+	c.mustDo(":4\r\n", "PFCOUNT", "merged")
+	c.mustDo("+sparse\r\n", "PFDEBUG", "ENCODING", "merged")
+	c.mustDo(":1\r\n", "PFDEBUG", "TODENSE", "merged")
+	c.mustDo("+dense\r\n", "PFDEBUG", "ENCODING", "merged")
+	c.mustDo(":4\r\n", "PFCOUNT", "merged")
+
+	c.mustDo("+OK\r\n", "SET", "str", "foo")
+	c.mustDo(errNotHLL, "PFADD", "str", "a")
+	c.mustDo(errNotHLL, "PFCOUNT", "str")
+	c.mustDo(errNotHLL, "PFCOUNT", "hll", "str")
+	c.mustDo(errNotHLL, "PFMERGE", "str", "hll")
+	c.mustDo(errNotHLL, "PFMERGE", "hll", "str")
+	c.mustDo(errNotHLL, "PFDEBUG", "ENCODING", "str")
+	c.mustDo("$3\r\nfoo\r\n", "GET", "str")
+
+	c.mustDo(":1\r\n", "RPUSH", "list", "a")
+	c.mustDo("-"+core.ErrWrongType.Error()+"\r\n", "PFADD", "list", "a")
+	c.mustDo("-"+core.ErrWrongType.Error()+"\r\n", "PFCOUNT", "list")
+}
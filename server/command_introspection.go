@@ -0,0 +1,254 @@
+package server
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/ghosind/antdb/client"
+	"github.com/ghosind/antdb/util"
+)
+
+// commandFlagNames are the names of the command flags in COMMAND INFO.
+var commandFlagNames = []struct {
+	Name string
+	Flag CommandFlags
+}{
+	{Name: "readonly", Flag: CommandFlagRead},
+	{Name: "write", Flag: CommandFlagWrite},
+	{Name: "denyoom", Flag: CommandFlagDenyOOM},
+	{Name: "admin", Flag: CommandFlagAdmin},
+	{Name: "skip_slowlog", Flag: CommandFlagSkipSlowlog},
+	{Name: "skip_monitor", Flag: CommandFlagSkipMonitor},
+	{Name: "blocking", Flag: CommandFlagBlocking},
+}
+
+func (s *Server) commandCommand(cli *client.Client, args ...string) error {
+	if len(args) == 0 {
+		names := sortedCommandNames()
+		cli.ReplyArrayLength(int64(len(names)))
+		for _, name := range names {
+			cmd := dbCommands[name]
+			replyCommandInfo(cli, name, &cmd)
+		}
+		return nil
+	}
+
+	switch strings.ToUpper(args[0]) {
+	case "COUNT":
+		return s.commandCountCommand(cli, args[1:]...)
+	case "INFO":
+		return s.commandInfoCommand(cli, args[1:]...)
+	case "DOCS":
+		return s.commandDocsCommand(cli, args[1:]...)
+	case "LIST":
+		return s.commandListCommand(cli, args[1:]...)
+	case "GETKEYS":
+		return s.commandGetKeysCommand(cli, args[1:]...)
+	default:
+		return newUnknownSubcommandError("COMMAND", args[0])
+	}
+}
+
+func (s *Server) commandCountCommand(cli *client.Client, args ...string) error {
+	if len(args) != 0 {
+		return newWrongArityError("command|count")
+	}
+
+	cli.ReplyInteger(int64(len(dbCommands)))
+	return nil
+}
+
+func (s *Server) commandInfoCommand(cli *client.Client, args ...string) error {
+	names := args
+	if len(names) == 0 {
+		names = sortedCommandNames()
+	}
+
+	cli.ReplyArrayLength(int64(len(names)))
+	for _, name := range names {
+		name = strings.ToUpper(name)
+		cmd, ok := dbCommands[name]
+		if !ok {
+			cli.ReplyNilBulk()
+			continue
+		}
+		replyCommandInfo(cli, name, &cmd)
+	}
+	return nil
+}
+
+func (s *Server) commandDocsCommand(cli *client.Client, args ...string) error {
+	names := make([]string, 0, len(args))
+	if len(args) == 0 {
+		names = sortedCommandNames()
+	} else {
+		for _, arg := range args {
+			name := strings.ToUpper(arg)
+			if _, ok := dbCommands[name]; ok {
+				names = append(names, name)
+			}
+		}
+	}
+
+	cli.ReplyArrayLength(int64(len(names) * 2))
+	for _, name := range names {
+		doc := commandDocs[name]
+		fields := make([]string, 0, 8)
+		if doc.Summary != "" {
+			fields = append(fields, "summary", doc.Summary)
+		}
+		if doc.Since != "" {
+			fields = append(fields, "since", doc.Since)
+		}
+		if doc.Group != "" {
+			fields = append(fields, "group", doc.Group)
+		}
+		if doc.Complexity != "" {
+			fields = append(fields, "complexity", doc.Complexity)
+		}
+
+		cli.ReplyBulkString(strings.ToLower(name))
+		cli.ReplyArrayLength(int64(len(fields)))
+		for _, field := range fields {
+			cli.ReplyBulkString(field)
+		}
+	}
+	return nil
+}
+
+// commandListCommand replies the names of the commands, filtered by ACL
+// category or by glob pattern.
+func (s *Server) commandListCommand(cli *client.Client, args ...string) error {
+	filter := func(name string, cmd *DBCommand) bool {
+		return true
+	}
+
+	switch len(args) {
+	case 0:
+	case 3:
+		if strings.ToUpper(args[0]) != "FILTERBY" {
+			return ErrSyntax
+		}
+		switch strings.ToUpper(args[1]) {
+		case "MODULE":
+			filter = func(string, *DBCommand) bool {
+				return false
+			}
+		case "ACLCAT":
+			category := strings.ToLower(args[2])
+			flag, ok := lookupACLCategory(category)
+			if !ok && category != "all" {
+				return newUnknownACLCategoryError(args[2])
+			}
+			if ok {
+				filter = func(name string, cmd *DBCommand) bool {
+					return cmd.Flags&flag != 0
+				}
+			}
+		case "PATTERN":
+			pattern, err := util.GlobToRegexp(args[2])
+			if err != nil {
+				return err
+			}
+			filter = func(name string, cmd *DBCommand) bool {
+				return pattern.MatchString(strings.ToLower(name))
+			}
+		default:
+			return ErrSyntax
+		}
+	default:
+		return ErrSyntax
+	}
+
+	names := make([]string, 0, len(dbCommands))
+	for _, name := range sortedCommandNames() {
+		cmd := dbCommands[name]
+		if filter(name, &cmd) {
+			names = append(names, strings.ToLower(name))
+		}
+	}
+
+	cli.ReplyArrayLength(int64(len(names)))
+	for _, name := range names {
+		cli.ReplyBulkString(name)
+	}
+	return nil
+}
+
+func (s *Server) commandGetKeysCommand(cli *client.Client, args ...string) error {
+	if len(args) == 0 {
+		return newWrongArityError("command|getkeys")
+	}
+
+	cmd, ok := dbCommands[strings.ToUpper(args[0])]
+	if !ok {
+		return ErrInvalidCommand
+	}
+	cmdArgs := args[1:]
+	if (cmd.Arity > 0 && cmd.Arity != len(cmdArgs)) || (cmd.Arity <= 0 && len(cmdArgs) < -cmd.Arity) {
+		return ErrInvalidCommandArgs
+	}
+
+	keys := cmd.keys(cmdArgs)
+	if len(keys) == 0 {
+		return ErrNoKeyArguments
+	}
+
+	cli.ReplyArrayLength(int64(len(keys)))
+	for _, key := range keys {
+		cli.ReplyBulkString(key)
+	}
+	return nil
+}
+
+// replyCommandInfo replies the command in the format of COMMAND INFO, where the
+// arity and the key positions include the command name.
+func replyCommandInfo(cli *client.Client, name string, cmd *DBCommand) {
+	arity := cmd.Arity + 1
+	if cmd.Arity <= 0 {
+		arity = cmd.Arity - 1
+	}
+
+	flags := make([]string, 0, len(commandFlagNames))
+	for _, flag := range commandFlagNames {
+		if cmd.Flags&flag.Flag != 0 {
+			flags = append(flags, flag.Name)
+		}
+	}
+	if cmd.GetKeys != nil {
+		flags = append(flags, "movablekeys")
+	}
+	categories := make([]string, 0, len(aclCategories))
+	for _, category := range aclCategories {
+		if cmd.Flags&category.Flag != 0 {
+			categories = append(categories, "@"+category.Name)
+		}
+	}
+
+	cli.ReplyArrayLength(10)
+	cli.ReplyBulkString(strings.ToLower(name))
+	cli.ReplyInteger(int64(arity))
+	cli.ReplyArrayLength(int64(len(flags)))
+	for _, flag := range flags {
+		cli.ReplySimpleString(flag)
+	}
+	cli.ReplyInteger(int64(cmd.Keys.First))
+	cli.ReplyInteger(int64(cmd.Keys.Last))
+	cli.ReplyInteger(int64(cmd.Keys.Step))
+	cli.ReplyArrayLength(int64(len(categories)))
+	for _, category := range categories {
+		cli.ReplySimpleString(category)
+	}
+	cli.ReplyArrayLength(0)
+	cli.ReplyArrayLength(0)
+	cli.ReplyArrayLength(0)
+}
+
+func sortedCommandNames() []string {
+	names := make([]string, 0, len(dbCommands))
+	for name := range dbCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
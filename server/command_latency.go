@@ -0,0 +1,173 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ghosind/antdb/client"
+)
+
+// latencyAdvices are the advices of LATENCY DOCTOR for the events.
+var latencyAdvices = map[string]string{
+	latencyEventCommand: "Check SLOWLOG GET for the slow commands, and avoid the O(N) commands like KEYS " +
+		"or SMEMBERS on big keys.",
+	latencyEventExpireCycle: "Many keys are expiring at the same time. Add a random jitter to the expire " +
+		"time of the keys set together.",
+	latencyEventFlush: "FLUSHALL and FLUSHDB block the database while it is cleared. Avoid flushing big " +
+		"databases while clients are served.",
+	latencyEventAccept: "Setting up new connections is slow, which usually means many connections are " +
+		"opened at the same time, or slow TLS handshakes. Use connection pools in the clients.",
+	latencyEventRequestHandoff: "Commands are waiting for their database to finish previous commands. A slow " +
+		"command blocks all the clients of the same database, check LATENCY HISTOGRAM and SLOWLOG GET.",
+}
+
+func (s *Server) latencyCommand(cli *client.Client, args ...string) error {
+	switch strings.ToUpper(args[0]) {
+	case "LATEST":
+		return s.latencyLatestCommand(cli, args[1:]...)
+	case "HISTORY":
+		return s.latencyHistoryCommand(cli, args[1:]...)
+	case "RESET":
+		return s.latencyResetCommand(cli, args[1:]...)
+	case "DOCTOR":
+		return s.latencyDoctorCommand(cli, args[1:]...)
+	case "HISTOGRAM":
+		return s.latencyHistogramCommand(cli, args[1:]...)
+	default:
+		return newUnknownSubcommandError("LATENCY", args[0])
+	}
+}
+
+func (s *Server) latencyLatestCommand(cli *client.Client, args ...string) error {
+	if len(args) != 0 {
+		return newWrongArityError("latency|latest")
+	}
+
+	events := s.latency.snapshot()
+	cli.ReplyArrayLength(int64(len(events)))
+	for _, event := range events {
+		cli.ReplyArrayLength(4)
+		cli.ReplyBulkString(event.name)
+		cli.ReplyInteger(event.latest.time)
+		cli.ReplyInteger(event.latest.latency)
+		cli.ReplyInteger(event.max)
+	}
+	return nil
+}
+
+func (s *Server) latencyHistoryCommand(cli *client.Client, args ...string) error {
+	if len(args) != 1 {
+		return newWrongArityError("latency|history")
+	}
+
+	samples := s.latency.history(args[0])
+	cli.ReplyArrayLength(int64(len(samples)))
+	for _, sample := range samples {
+		cli.ReplyArrayLength(2)
+		cli.ReplyInteger(sample.time)
+		cli.ReplyInteger(sample.latency)
+	}
+	return nil
+}
+
+func (s *Server) latencyResetCommand(cli *client.Client, args ...string) error {
+	cli.ReplyInteger(int64(s.latency.reset(args...)))
+	return nil
+}
+
+func (s *Server) latencyDoctorCommand(cli *client.Client, args ...string) error {
+	if len(args) != 0 {
+		return newWrongArityError("latency|doctor")
+	}
+
+	if s.latency.threshold <= 0 {
+		cli.ReplyBulkString("The latency monitor is disabled. Set latency-monitor-threshold to the number of " +
+			"milliseconds over which an event is considered a latency spike, and run LATENCY DOCTOR again.\n")
+		return nil
+	}
+
+	events := s.latency.snapshot()
+	if len(events) == 0 {
+		cli.ReplyBulkString(fmt.Sprintf("No latency spike over %d milliseconds was observed in this instance.\n",
+			s.latency.threshold.Milliseconds()))
+		return nil
+	}
+
+	var report strings.Builder
+	fmt.Fprintf(&report, "Latency spikes over %d milliseconds were observed for %d events:\n\n",
+		s.latency.threshold.Milliseconds(), len(events))
+	for i, event := range events {
+		sum := int64(0)
+		for _, sample := range event.samples {
+			sum += sample.latency
+		}
+		avg := float64(sum) / float64(len(event.samples))
+		deviation := 0.0
+		for _, sample := range event.samples {
+			d := float64(sample.latency) - avg
+			if d < 0 {
+				d = -d
+			}
+			deviation += d
+		}
+		deviation /= float64(len(event.samples))
+
+		fmt.Fprintf(&report, "%d. %s: %d latency spikes (average %.0fms, mean deviation %.0fms",
+			i+1, event.name, len(event.samples), avg, deviation)
+		if len(event.samples) > 1 {
+			first, last := event.samples[0].time, event.samples[len(event.samples)-1].time
+			fmt.Fprintf(&report, ", period %.2f sec", float64(last-first)/float64(len(event.samples)-1))
+		}
+		fmt.Fprintf(&report, "). Worst all time event %dms.\n", event.max)
+	}
+
+	report.WriteString("\nAdvices:\n")
+	for _, event := range events {
+		if advice, ok := latencyAdvices[event.name]; ok {
+			fmt.Fprintf(&report, "- %s: %s\n", event.name, advice)
+		}
+	}
+
+	cli.ReplyBulkString(report.String())
+	return nil
+}
+
+// latencyHistogramCommand replies the latency histogram of the commands, or of
+// all called commands if no command is specified.
+func (s *Server) latencyHistogramCommand(cli *client.Client, args ...string) error {
+	names := make([]string, 0, len(args))
+	if len(args) == 0 {
+		for name, stats := range s.commandStats {
+			if stats.calls.Load() > 0 {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+	} else {
+		for _, arg := range args {
+			name := strings.ToUpper(arg)
+			if stats, ok := s.commandStats[name]; ok && stats.calls.Load() > 0 {
+				names = append(names, name)
+			}
+		}
+	}
+
+	cli.ReplyArrayLength(int64(len(names) * 2))
+	for _, name := range names {
+		stats := s.commandStats[name]
+		buckets := stats.cumulativeHistogram()
+
+		cli.ReplyBulkString(strings.ToLower(name))
+		cli.ReplyArrayLength(4)
+		cli.ReplyBulkString("calls")
+		cli.ReplyInteger(stats.calls.Load())
+		cli.ReplyBulkString("histogram_usec")
+		cli.ReplyArrayLength(int64(len(buckets) * 2))
+		for _, bucket := range buckets {
+			cli.ReplyInteger(bucket.usec)
+			cli.ReplyInteger(bucket.count)
+		}
+	}
+	return nil
+}
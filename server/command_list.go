@@ -1,7 +1,9 @@
 package server
 
 import (
+	"math"
 	"strconv"
+	"strings"
 
 	"github.com/ghosind/antdb/client"
 	"github.com/ghosind/antdb/core"
@@ -13,7 +15,7 @@ func (s *Server) lindexCommand(cli *client.Client, args ...string) error {
 	key := args[0]
 	index, err := strconv.Atoi(args[1])
 	if err != nil {
-		return core.ErrWrongType
+		return core.ErrNotInteger
 	}
 	value, found, err := db.ListIndex(key, index)
 	if err != nil {
@@ -21,7 +23,7 @@ func (s *Server) lindexCommand(cli *client.Client, args ...string) error {
 	} else if !found {
 		cli.ReplyNilBulk()
 	} else {
-		cli.ReplyBulkString(value)
+		replyStringValue(cli, value)
 	}
 	return nil
 }
@@ -48,7 +50,151 @@ func (s *Server) lpopCommand(cli *client.Client, args ...string) error {
 	} else if !found {
 		cli.ReplyNilBulk()
 	} else {
-		cli.ReplyBulkString(value)
+		replyStringValue(cli, value)
+	}
+	return nil
+}
+
+func (s *Server) linsertCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	var before bool
+	switch strings.ToUpper(args[1]) {
+	case "BEFORE":
+		before = true
+	case "AFTER":
+	default:
+		return ErrSyntax
+	}
+
+	length, err := db.ListInsert(args[0], before, args[2], args[3])
+	if err != nil {
+		return err
+	}
+	cli.ReplyInteger(int64(length))
+	return nil
+}
+
+func (s *Server) lmoveCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	sourceLeft, err := parseListSide(args[2])
+	if err != nil {
+		return err
+	}
+	destLeft, err := parseListSide(args[3])
+	if err != nil {
+		return err
+	}
+
+	value, found, err := db.ListMove(args[0], args[1], sourceLeft, destLeft)
+	if err != nil {
+		return err
+	} else if !found {
+		cli.ReplyNilBulk()
+	} else {
+		replyStringValue(cli, value)
+	}
+	return nil
+}
+
+func (s *Server) lmpopCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	numKeys, err := strconv.Atoi(args[0])
+	if err != nil || numKeys <= 0 {
+		return ErrNumKeys
+	} else if numKeys > len(args)-2 {
+		return ErrSyntax
+	}
+	keys := args[1 : numKeys+1]
+	left, err := parseListSide(args[numKeys+1])
+	if err != nil {
+		return err
+	}
+
+	count := 1
+	switch rest := args[numKeys+2:]; {
+	case len(rest) == 0:
+	case len(rest) == 2 && strings.ToUpper(rest[0]) == "COUNT":
+		count, err = strconv.Atoi(rest[1])
+		if err != nil || count <= 0 {
+			return ErrListCount
+		}
+	default:
+		return ErrSyntax
+	}
+
+	key, values, err := db.ListMPop(keys, left, count)
+	if err != nil {
+		return err
+	} else if key == "" {
+		cli.ReplyArrayLength(-1)
+		return nil
+	}
+
+	cli.ReplyArrayLength(2)
+	cli.ReplyBulkString(key)
+	cli.ReplyArrayLength(int64(len(values)))
+	for _, v := range values {
+		replyStringValue(cli, v)
+	}
+	return nil
+}
+
+func (s *Server) lposCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	rank, count, maxLen := 1, 0, 0
+	hasCount := false
+	for i := 2; i < len(args); i += 2 {
+		if i+1 >= len(args) {
+			return ErrSyntax
+		}
+		n, err := strconv.Atoi(args[i+1])
+		if err != nil {
+			return core.ErrNotInteger
+		}
+		switch strings.ToUpper(args[i]) {
+		case "RANK":
+			if n == 0 || n == math.MinInt {
+				return ErrLPosRank
+			}
+			rank = n
+		case "COUNT":
+			if n < 0 {
+				return ErrLPosCount
+			}
+			count, hasCount = n, true
+		case "MAXLEN":
+			if n < 0 {
+				return ErrLPosMaxLen
+			}
+			maxLen = n
+		default:
+			return ErrSyntax
+		}
+	}
+	if !hasCount {
+		count = 1
+	}
+
+	positions, err := db.ListPos(args[0], args[1], rank, count, maxLen)
+	if err != nil {
+		return err
+	}
+
+	if !hasCount {
+		if len(positions) == 0 {
+			cli.ReplyNilBulk()
+		} else {
+			cli.ReplyInteger(int64(positions[0]))
+		}
+		return nil
+	}
+	cli.ReplyArrayLength(int64(len(positions)))
+	for _, pos := range positions {
+		cli.ReplyInteger(int64(pos))
 	}
 	return nil
 }
@@ -56,14 +202,22 @@ func (s *Server) lpopCommand(cli *client.Client, args ...string) error {
 func (s *Server) lpushCommand(cli *client.Client, args ...string) error {
 	db := s.databases[cli.DB]
 
-	key := args[0]
-	value := args[1]
+	length, err := db.ListPush(args[0], true, args[1:]...)
+	if err != nil {
+		return err
+	}
+	cli.ReplyInteger(int64(length))
+	return nil
+}
+
+func (s *Server) lpushxCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
 
-	len, err := db.ListPush(key, value, true)
+	length, err := db.ListPushX(args[0], true, args[1:]...)
 	if err != nil {
 		return err
 	}
-	cli.ReplyInteger(int64(len))
+	cli.ReplyInteger(int64(length))
 	return nil
 }
 
@@ -80,16 +234,13 @@ func (s *Server) lrangeCommand(cli *client.Client, args ...string) error {
 		return core.ErrNotInteger
 	}
 
-	values, ok, err := db.ListRange(key, start, end)
+	values, _, err := db.ListRange(key, start, end)
 	if err != nil {
 		return err
-	} else if !ok {
-		cli.ReplyNilBulk()
-	} else {
-		cli.ReplyArrayLength(int64(len(values)))
-		for _, v := range values {
-			cli.ReplyBulkString(v)
-		}
+	}
+	cli.ReplyArrayLength(int64(len(values)))
+	for _, v := range values {
+		replyStringValue(cli, v)
 	}
 	return nil
 }
@@ -162,7 +313,7 @@ func (s *Server) rpopCommand(cli *client.Client, args ...string) error {
 	} else if !found {
 		cli.ReplyNilBulk()
 	} else {
-		cli.ReplyBulkString(value)
+		replyStringValue(cli, value)
 	}
 	return nil
 }
@@ -173,13 +324,13 @@ func (s *Server) rpoplpushCommand(cli *client.Client, args ...string) error {
 	sourceKey := args[0]
 	destKey := args[1]
 
-	val, found, err := db.ListRPopLPush(sourceKey, destKey)
+	val, found, err := db.ListMove(sourceKey, destKey, false, true)
 	if err != nil {
 		return err
 	} else if !found {
 		cli.ReplyNilBulk()
 	} else {
-		cli.ReplyBulkString(val)
+		replyStringValue(cli, val)
 	}
 	return nil
 }
@@ -187,13 +338,42 @@ func (s *Server) rpoplpushCommand(cli *client.Client, args ...string) error {
 func (s *Server) rpushCommand(cli *client.Client, args ...string) error {
 	db := s.databases[cli.DB]
 
-	key := args[0]
-	value := args[1]
+	length, err := db.ListPush(args[0], false, args[1:]...)
+	if err != nil {
+		return err
+	}
+	cli.ReplyInteger(int64(length))
+	return nil
+}
 
-	len, err := db.ListPush(key, value, false)
+func (s *Server) rpushxCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	length, err := db.ListPushX(args[0], false, args[1:]...)
 	if err != nil {
 		return err
 	}
-	cli.ReplyInteger(int64(len))
+	cli.ReplyInteger(int64(length))
 	return nil
 }
+
+// parseListSide parses LEFT or RIGHT of LMOVE and LMPOP, and returns true for
+// LEFT.
+func parseListSide(arg string) (bool, error) {
+	switch strings.ToUpper(arg) {
+	case "LEFT":
+		return true, nil
+	case "RIGHT":
+		return false, nil
+	}
+	return false, ErrSyntax
+}
+
+// lmpopKeys returns the keys of LMPOP, which are preceded by their number.
+func lmpopKeys(args []string) []string {
+	numKeys, err := strconv.Atoi(args[0])
+	if err != nil || numKeys <= 0 || numKeys >= len(args) {
+		return nil
+	}
+	return args[1 : numKeys+1]
+}
@@ -0,0 +1,282 @@
+package server
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestListPush(t *testing.T) {
+	c := newTestClient(t)
+	c.mustDo(":0\r\n", "LPUSHX", "l", "a")
+	c.mustDo(":0\r\n", "RPUSHX", "l", "a")
+	c.mustDo(":0\r\n", "EXISTS", "l")
+
+	c.mustDo(":3\r\n", "LPUSH", "l", "a", "b", "c")
+	c.mustDo(":5\r\n", "RPUSH", "l", "d", "e")
+	c.mustDo(":7\r\n", "LPUSHX", "l", "f", "g")
+	c.mustDo(":9\r\n", "RPUSHX", "l", "h", "i")
+	c.mustDo(arrayReply("g", "f", "c", "b", "a", "d", "e", "h", "i"), "LRANGE", "l", "0", "-1")
+
+	c.mustDo("+OK\r\n", "SET", "s", "v")
+	c.mustDo("-wrong type\r\n", "LPUSH", "s", "a")
+	c.mustDo("-wrong type\r\n", "RPUSHX", "s", "a")
+}
+
+func TestLPos(t *testing.T) {
+	tests := []struct {
+		args  []string
+		reply string
+	}{
+		{[]string{"c"}, ":2\r\n"},
+		{[]string{"c", "RANK", "1"}, ":2\r\n"},
+		{[]string{"c", "RANK", "2"}, ":6\r\n"},
+		{[]string{"c", "RANK", "4"}, "$-1\r\n"},
+		{[]string{"c", "RANK", "-1"}, ":9\r\n"},
+		{[]string{"c", "RANK", "-2"}, ":6\r\n"},
+		{[]string{"c", "RANK", "-3"}, ":2\r\n"},
+		{[]string{"c", "RANK", "-4"}, "$-1\r\n"},
+		{[]string{"c", "COUNT", "0"}, "*3\r\n:2\r\n:6\r\n:9\r\n"},
+		{[]string{"c", "COUNT", "2"}, "*2\r\n:2\r\n:6\r\n"},
+		{[]string{"c", "COUNT", "10"}, "*3\r\n:2\r\n:6\r\n:9\r\n"},
+		{[]string{"c", "COUNT", "0", "RANK", "2"}, "*2\r\n:6\r\n:9\r\n"},
+		{[]string{"c", "COUNT", "2", "RANK", "-1"}, "*2\r\n:9\r\n:6\r\n"},
+		{[]string{"c", "RANK", "-2", "COUNT", "0"}, "*2\r\n:6\r\n:2\r\n"},
+		{[]string{"c", "MAXLEN", "2"}, "$-1\r\n"},
+		{[]string{"c", "MAXLEN", "3"}, ":2\r\n"},
+		{[]string{"c", "MAXLEN", "0"}, ":2\r\n"},
+		{[]string{"c", "COUNT", "0", "MAXLEN", "7"}, "*2\r\n:2\r\n:6\r\n"},
+		{[]string{"c", "RANK", "-1", "MAXLEN", "1"}, ":9\r\n"},
+		{[]string{"c", "RANK", "-2", "MAXLEN", "3"}, "$-1\r\n"},
+		{[]string{"c", "RANK", "-2", "MAXLEN", "4"}, ":6\r\n"},
+		{[]string{"c", "RANK", "2", "COUNT", "1", "MAXLEN", "6"}, "*0\r\n"},
+		{[]string{"x"}, "$-1\r\n"},
+		{[]string{"x", "COUNT", "0"}, "*0\r\n"},
+		{[]string{"c", "rank", "2", "count", "1"}, "*1\r\n:6\r\n"},
+		{[]string{"c", "RANK", "0"}, "-" + ErrLPosRank.Error() + "\r\n"},
+		{[]string{"c", "COUNT", "-1"}, "-" + ErrLPosCount.Error() + "\r\n"},
+		{[]string{"c", "MAXLEN", "-1"}, "-" + ErrLPosMaxLen.Error() + "\r\n"},
+		{[]string{"c", "RANK", "x"}, "-value is not an integer or out of range\r\n"},
+		{[]string{"c", "RANK"}, "-syntax error\r\n"},
+		{[]string{"c", "FOO", "1"}, "-syntax error\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			c := newTestClient(t)
+			c.mustDo(":10\r\n", "RPUSH", "l", "a", "b", "c", "1", "2", "3", "c", "b", "a", "c")
+			c.mustDo(tt.reply, append([]string{"LPOS", "l"}, tt.args...)...)
+		})
+	}
+
+	c := newTestClient(t)
+	c.mustDo("$-1\r\n", "LPOS", "none", "a")
+	c.mustDo("*0\r\n", "LPOS", "none", "a", "COUNT", "0")
+}
+
+func TestLMove(t *testing.T) {
+	tests := []struct {
+		source string
+		dest   string
+		from   string
+		to     string
+		reply  string
+		// list and other are the elements of the lists l and o after the move.
+		list  []string
+		other []string
+	}{
+		{"l", "o", "LEFT", "LEFT", "a", []string{"b", "c"}, []string{"a", "x", "y"}},
+		{"l", "o", "LEFT", "RIGHT", "a", []string{"b", "c"}, []string{"x", "y", "a"}},
+		{"l", "o", "RIGHT", "LEFT", "c", []string{"a", "b"}, []string{"c", "x", "y"}},
+		{"l", "o", "RIGHT", "RIGHT", "c", []string{"a", "b"}, []string{"x", "y", "c"}},
+		{"l", "l", "LEFT", "LEFT", "a", []string{"a", "b", "c"}, []string{"x", "y"}},
+		{"l", "l", "LEFT", "RIGHT", "a", []string{"b", "c", "a"}, []string{"x", "y"}},
+		{"l", "l", "RIGHT", "LEFT", "c", []string{"c", "a", "b"}, []string{"x", "y"}},
+		{"l", "l", "RIGHT", "RIGHT", "c", []string{"a", "b", "c"}, []string{"x", "y"}},
+		{"l", "n", "left", "right", "a", []string{"b", "c"}, []string{"x", "y"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join([]string{tt.source, tt.dest, tt.from, tt.to}, " "), func(t *testing.T) {
+			c := newTestClient(t)
+			c.mustDo(":3\r\n", "RPUSH", "l", "a", "b", "c")
+			c.mustDo(":2\r\n", "RPUSH", "o", "x", "y")
+			c.mustDo(bulkReply(tt.reply), "LMOVE", tt.source, tt.dest, tt.from, tt.to)
+			c.mustDo(arrayReply(tt.list...), "LRANGE", "l", "0", "-1")
+			c.mustDo(arrayReply(tt.other...), "LRANGE", "o", "0", "-1")
+		})
+	}
+
+	c := newTestClient(t)
+	c.mustDo(":1\r\n", "RPUSH", "l", "a")
+	c.mustDo("+OK\r\n", "SET", "s", "v")
+	c.mustDo("$-1\r\n", "LMOVE", "none", "d", "LEFT", "LEFT")
+	c.mustDo(":0\r\n", "EXISTS", "d")
+	c.mustDo("-syntax error\r\n", "LMOVE", "l", "d", "UP", "LEFT")
+	c.mustDo("-syntax error\r\n", "LMOVE", "l", "d", "LEFT", "DOWN")
+	c.mustDo("-wrong type\r\n", "LMOVE", "l", "s", "LEFT", "LEFT")
+	c.mustDo("-wrong type\r\n", "LMOVE", "s", "l", "LEFT", "LEFT")
+	c.mustDo(arrayReply("a"), "LRANGE", "l", "0", "-1")
+
+	// Moving the last element removes the source list.
+	c.mustDo(bulkReply("a"), "LMOVE", "l", "d", "RIGHT", "LEFT")
+	c.mustDo(":0\r\n", "EXISTS", "l")
+	c.mustDo(bulkReply("a"), "RPOPLPUSH", "d", "d")
+	c.mustDo(arrayReply("a"), "LRANGE", "d", "0", "-1")
+}
+
+func TestLMPop(t *testing.T) {
+	tests := []struct {
+		args  []string
+		reply string
+		// list is the elements of the list l after the pop.
+		list []string
+	}{
+		{[]string{"1", "l", "LEFT"}, "*2\r\n" + bulkReply("l") + arrayReply("a"), []string{"b", "c", "d"}},
+		{[]string{"1", "l", "RIGHT"}, "*2\r\n" + bulkReply("l") + arrayReply("d"), []string{"a", "b", "c"}},
+		{[]string{"1", "l", "LEFT", "COUNT", "2"}, "*2\r\n" + bulkReply("l") + arrayReply("a", "b"), []string{"c", "d"}},
+		{[]string{"1", "l", "RIGHT", "COUNT", "3"}, "*2\r\n" + bulkReply("l") + arrayReply("d", "c", "b"), []string{"a"}},
+		{[]string{"1", "l", "left", "count", "10"}, "*2\r\n" + bulkReply("l") + arrayReply("a", "b", "c", "d"), nil},
+		{[]string{"2", "none", "l", "RIGHT", "COUNT", "2"}, "*2\r\n" + bulkReply("l") + arrayReply("d", "c"), []string{"a", "b"}},
+		{[]string{"2", "o", "l", "LEFT"}, "*2\r\n" + bulkReply("o") + arrayReply("x"), []string{"a", "b", "c", "d"}},
+		{[]string{"1", "none", "LEFT"}, "*-1\r\n", []string{"a", "b", "c", "d"}},
+		{[]string{"0", "l", "LEFT"}, "-" + ErrNumKeys.Error() + "\r\n", []string{"a", "b", "c", "d"}},
+		{[]string{"x", "l", "LEFT"}, "-" + ErrNumKeys.Error() + "\r\n", []string{"a", "b", "c", "d"}},
+		{[]string{"3", "l", "LEFT"}, "-syntax error\r\n", []string{"a", "b", "c", "d"}},
+		{[]string{"1", "l", "UP"}, "-syntax error\r\n", []string{"a", "b", "c", "d"}},
+		{[]string{"1", "l", "LEFT", "COUNT"}, "-syntax error\r\n", []string{"a", "b", "c", "d"}},
+		{[]string{"1", "l", "LEFT", "COUNT", "0"}, "-" + ErrListCount.Error() + "\r\n", []string{"a", "b", "c", "d"}},
+		{[]string{"1", "l", "LEFT", "FOO", "1"}, "-syntax error\r\n", []string{"a", "b", "c", "d"}},
+		{[]string{"2", "s", "l", "LEFT"}, "-wrong type\r\n", []string{"a", "b", "c", "d"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			c := newTestClient(t)
+			c.mustDo(":4\r\n", "RPUSH", "l", "a", "b", "c", "d")
+			c.mustDo(":1\r\n", "RPUSH", "o", "x")
+			c.mustDo("+OK\r\n", "SET", "s", "v")
+			c.mustDo(tt.reply, append([]string{"LMPOP"}, tt.args...)...)
+			c.mustDo(arrayReply(tt.list...), "LRANGE", "l", "0", "-1")
+		})
+	}
+}
+
+func TestListInsert(t *testing.T) {
+	c := newTestClient(t)
+	c.mustDo(":0\r\n", "LINSERT", "l", "BEFORE", "a", "x")
+	c.mustDo(":0\r\n", "EXISTS", "l")
+
+	c.mustDo(":3\r\n", "RPUSH", "l", "a", "b", "a")
+	c.mustDo(":4\r\n", "LINSERT", "l", "BEFORE", "a", "x")
+	c.mustDo(":5\r\n", "LINSERT", "l", "after", "a", "y")
+	c.mustDo(":6\r\n", "LINSERT", "l", "AFTER", "b", "z")
+	c.mustDo(arrayReply("x", "a", "y", "b", "z", "a"), "LRANGE", "l", "0", "-1")
+	c.mustDo(":-1\r\n", "LINSERT", "l", "BEFORE", "none", "x")
+	c.mustDo("-syntax error\r\n", "LINSERT", "l", "MIDDLE", "a", "x")
+}
+
+func TestListEmptyElements(t *testing.T) {
+	c := newTestClient(t)
+	c.mustDo(":4\r\n", "RPUSH", "l", "", "", "", "")
+	c.mustDo(arrayReply("", "", "", ""), "LRANGE", "l", "0", "-1")
+	c.mustDo("$0\r\n\r\n", "LINDEX", "l", "0")
+	c.mustDo("$0\r\n\r\n", "LPOP", "l")
+	c.mustDo("$0\r\n\r\n", "RPOP", "l")
+	c.mustDo("$0\r\n\r\n", "LMOVE", "l", "l", "LEFT", "RIGHT")
+	c.mustDo("$0\r\n\r\n", "RPOPLPUSH", "l", "l")
+	c.mustDo("*2\r\n"+bulkReply("l")+arrayReply("", ""), "LMPOP", "1", "l", "LEFT", "COUNT", "2")
+	c.mustDo(":0\r\n", "EXISTS", "l")
+
+	c.mustDo("-value is not an integer or out of range\r\n", "LINDEX", "l", "x")
+}
+
+// TestLongList runs the list commands on a long list of many nodes, which is
+// compared with the elements of a slice after every command.
+func TestLongList(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []ServerOption
+	}{
+		{"default", nil},
+		{"small nodes", []ServerOption{WithListMaxListpackSize(4)}},
+		{"compressed nodes", []ServerOption{WithListMaxListpackSize(8), WithListCompressDepth(1)}},
+		{"deeply compressed nodes", []ServerOption{WithListMaxListpackSize(-1), WithListCompressDepth(3)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rnd := rand.New(rand.NewSource(1))
+			c := newTestClient(t, tt.options...)
+
+			expected := make([]string, 0)
+			for i := 0; i < 1000; i++ {
+				value := strings.Repeat(strconv.Itoa(i%10), 10+i%50) + strconv.Itoa(i)
+				expected = append(expected, value)
+			}
+			c.mustDo(":"+strconv.Itoa(len(expected))+"\r\n", append([]string{"RPUSH", "l"}, expected...)...)
+
+			for i := 0; i < 300; i++ {
+				index := rnd.Intn(len(expected))
+				switch rnd.Intn(4) {
+				case 0:
+					c.mustDo(bulkReply(expected[index]), "LINDEX", "l", strconv.Itoa(index))
+					c.mustDo(bulkReply(expected[index]), "LINDEX", "l", strconv.Itoa(index-len(expected)))
+				case 1:
+					value := "set" + strconv.Itoa(i)
+					c.mustDo("+OK\r\n", "LSET", "l", strconv.Itoa(index-len(expected)*rnd.Intn(2)), value)
+					expected[index] = value
+				case 2:
+					value := "insert" + strconv.Itoa(i)
+					if rnd.Intn(2) == 0 {
+						c.mustDo(":"+strconv.Itoa(len(expected)+1)+"\r\n", "LINSERT", "l", "BEFORE", expected[index], value)
+					} else {
+						c.mustDo(":"+strconv.Itoa(len(expected)+1)+"\r\n", "LINSERT", "l", "AFTER", expected[index], value)
+						index++
+					}
+					expected = append(expected[:index], append([]string{value}, expected[index:]...)...)
+				case 3:
+					value := expected[index]
+					c.mustDo(":1\r\n", "LREM", "l", "0", value)
+					expected = append(expected[:index], expected[index+1:]...)
+				}
+			}
+
+			c.mustDo(":"+strconv.Itoa(len(expected))+"\r\n", "LLEN", "l")
+			c.mustDo(arrayReply(expected...), "LRANGE", "l", "0", "-1")
+			c.mustDo(arrayReply(expected[100:200]...), "LRANGE", "l", "100", "199")
+			c.mustDo(arrayReply(expected[len(expected)-50:]...), "LRANGE", "l", "-50", "-1")
+			c.mustDo("$-1\r\n", "LINDEX", "l", strconv.Itoa(len(expected)))
+			c.mustDo("-index out of range\r\n", "LSET", "l", strconv.Itoa(-len(expected)-1), "x")
+
+			c.mustDo("+OK\r\n", "LTRIM", "l", "100", "-100")
+			expected = expected[100 : len(expected)-99]
+			c.mustDo(arrayReply(expected...), "LRANGE", "l", "0", "-1")
+			for i := 0; i < len(expected); i += 37 {
+				c.mustDo(bulkReply(expected[i]), "LINDEX", "l", strconv.Itoa(i))
+			}
+		})
+	}
+}
+
+func TestListEncoding(t *testing.T) {
+	c := newTestClient(t, WithListMaxListpackSize(4), WithListCompressDepth(1))
+	c.mustDo(":4\r\n", "RPUSH", "l", "a", "b", "c", "d")
+	c.mustDo(bulkReply("listpack"), "OBJECT", "ENCODING", "l")
+	c.mustDo(":5\r\n", "RPUSH", "l", "e")
+	c.mustDo(bulkReply("quicklist"), "OBJECT", "ENCODING", "l")
+	c.mustDo(bulkReply("e"), "RPOP", "l")
+	c.mustDo(bulkReply("listpack"), "OBJECT", "ENCODING", "l")
+
+	// The nodes left by removed elements are merged back into a single node.
+	c.mustDo(":12\r\n", "RPUSH", "l", "x", "e", "f", "x", "g", "h", "x", "i")
+	c.mustDo(bulkReply("quicklist"), "OBJECT", "ENCODING", "l")
+	c.mustDo(":3\r\n", "LREM", "l", "0", "x")
+	c.mustDo("+OK\r\n", "LTRIM", "l", "1", "4")
+	c.mustDo(arrayReply("b", "c", "d", "e"), "LRANGE", "l", "0", "-1")
+	c.mustDo(bulkReply("quicklist"), "OBJECT", "ENCODING", "l")
+	c.mustDo(":1\r\n", "LREM", "l", "0", "c")
+	c.mustDo(arrayReply("b", "d", "e"), "LRANGE", "l", "0", "-1")
+	c.mustDo(bulkReply("listpack"), "OBJECT", "ENCODING", "l")
+}
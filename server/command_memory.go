@@ -0,0 +1,259 @@
+package server
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"github.com/ghosind/antdb/client"
+	"github.com/ghosind/antdb/core"
+)
+
+const (
+	defaultMemoryUsageSamples = 5
+	defaultMemoryBigKeysCount = 10
+
+	// memoryDoctorMinAllocated is the heap size under which MEMORY DOCTOR does
+	// not analyze the instance, as the fixed overhead of the process dominates.
+	memoryDoctorMinAllocated = 5 * 1024 * 1024
+)
+
+// clientMemoryUsage is the estimated bytes used by a connected client, which is
+// dominated by the 4 KB buffer of its reader.
+var clientMemoryUsage = int64(unsafe.Sizeof(client.Client{})) + 4096
+
+type memoryStats struct {
+	allocated       int64
+	clients         int64
+	dbOverheads     [][2]int64
+	overhead        int64
+	keys            int64
+	used            int64
+	dataset         int64
+	datasetPercent  float64
+	bytesPerKey     int64
+	maxMemory       int64
+	maxMemoryPolicy core.EvictionPolicy
+}
+
+func (s *Server) memoryCommand(cli *client.Client, args ...string) error {
+	switch strings.ToUpper(args[0]) {
+	case "USAGE":
+		return s.memoryUsageCommand(cli, args[1:]...)
+	case "STATS":
+		return s.memoryStatsCommand(cli, args[1:]...)
+	case "DOCTOR":
+		return s.memoryDoctorCommand(cli, args[1:]...)
+	case "BIGKEYS":
+		return s.memoryBigKeysCommand(cli, args[1:]...)
+	default:
+		return newUnknownSubcommandError("MEMORY", args[0])
+	}
+}
+
+// memoryKeys returns the key of MEMORY USAGE, as the other subcommands take no
+// key.
+func memoryKeys(args []string) []string {
+	if len(args) >= 2 && strings.EqualFold(args[0], "USAGE") {
+		return args[1:2]
+	}
+	return nil
+}
+
+func (s *Server) memoryUsageCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	if len(args) != 1 && len(args) != 3 {
+		return ErrSyntax
+	}
+
+	key := args[0]
+	samples := defaultMemoryUsageSamples
+	if len(args) == 3 {
+		if strings.ToUpper(args[1]) != "SAMPLES" {
+			return ErrSyntax
+		}
+		n, err := strconv.Atoi(args[2])
+		if err != nil || n < 0 {
+			return core.ErrNotInteger
+		}
+		samples = n
+	}
+
+	usage, found := db.MemoryUsage(key, samples)
+	if !found {
+		cli.ReplyNilBulk()
+	} else {
+		cli.ReplyInteger(usage)
+	}
+	return nil
+}
+
+func (s *Server) memoryStatsCommand(cli *client.Client, args ...string) error {
+	if len(args) != 0 {
+		return ErrSyntax
+	}
+
+	stats := s.getMemoryStats()
+
+	dbCount := 0
+	for _, overhead := range stats.dbOverheads {
+		if overhead[0] > 0 {
+			dbCount++
+		}
+	}
+
+	cli.ReplyArrayLength(int64(2 * (8 + dbCount)))
+	cli.ReplyBulkString("total.allocated")
+	cli.ReplyInteger(stats.allocated)
+	cli.ReplyBulkString("maxmemory")
+	cli.ReplyInteger(stats.maxMemory)
+	cli.ReplyBulkString("clients.normal")
+	cli.ReplyInteger(stats.clients)
+	for i, overhead := range stats.dbOverheads {
+		if overhead[0] == 0 {
+			continue
+		}
+		cli.ReplyBulkString("db." + strconv.Itoa(i))
+		cli.ReplyArrayLength(4)
+		cli.ReplyBulkString("overhead.hashtable.main")
+		cli.ReplyInteger(overhead[0])
+		cli.ReplyBulkString("overhead.hashtable.expires")
+		cli.ReplyInteger(overhead[1])
+	}
+	cli.ReplyBulkString("overhead.total")
+	cli.ReplyInteger(stats.overhead)
+	cli.ReplyBulkString("keys.count")
+	cli.ReplyInteger(stats.keys)
+	cli.ReplyBulkString("keys.bytes-per-key")
+	cli.ReplyInteger(stats.bytesPerKey)
+	cli.ReplyBulkString("dataset.bytes")
+	cli.ReplyInteger(stats.dataset)
+	cli.ReplyBulkString("dataset.percentage")
+	cli.ReplyBulkString(strconv.FormatFloat(stats.datasetPercent, 'f', 2, 64))
+	return nil
+}
+
+func (s *Server) memoryDoctorCommand(cli *client.Client, args ...string) error {
+	if len(args) != 0 {
+		return ErrSyntax
+	}
+
+	stats := s.getMemoryStats()
+
+	if stats.keys == 0 || stats.allocated < memoryDoctorMinAllocated {
+		cli.ReplyBulkString("This instance is empty or is using very little memory, so there is nothing to analyze yet.\n")
+		return nil
+	}
+
+	var report strings.Builder
+	issues := 0
+	if stats.used > 0 && float64(stats.allocated)/float64(stats.used) > 1.5 {
+		issues++
+		fmt.Fprintf(&report, " * High heap overhead: the Go heap holds %d bytes while the dataset and its overhead are estimated at %d bytes. "+
+			"This is usually caused by many deleted or shrunk values that the garbage collector has not returned yet.\n",
+			stats.allocated, stats.used)
+	}
+	if stats.used > 0 && float64(stats.clients)/float64(stats.used+stats.clients) > 0.3 {
+		issues++
+		fmt.Fprintf(&report, " * Client buffers use %d bytes, which is a large part of the memory. "+
+			"Check for leaked or idle connections with many clients connected.\n", stats.clients)
+	}
+	if stats.maxMemory > 0 && float64(stats.used) > float64(stats.maxMemory)*0.9 {
+		issues++
+		if stats.maxMemoryPolicy == core.EvictionNoEviction {
+			fmt.Fprintf(&report, " * Used memory is over 90%% of maxmemory (%d bytes) and the policy is noeviction, "+
+				"so write commands will soon be refused with OOM errors.\n", stats.maxMemory)
+		} else {
+			fmt.Fprintf(&report, " * Used memory is over 90%% of maxmemory (%d bytes), so keys are being evicted with the %s policy.\n",
+				stats.maxMemory, stats.maxMemoryPolicy)
+		}
+	}
+	if stats.keys > 0 && stats.bytesPerKey > 1024*1024 {
+		issues++
+		fmt.Fprintf(&report, " * Keys use %d bytes each on average. Use MEMORY BIGKEYS to find the biggest ones.\n", stats.bytesPerKey)
+	}
+
+	if issues == 0 {
+		cli.ReplyBulkString("No memory issues were detected in this instance.\n")
+		return nil
+	}
+
+	cli.ReplyBulkString(fmt.Sprintf("%d memory issues were detected:\n\n%s", issues, report.String()))
+	return nil
+}
+
+func (s *Server) memoryBigKeysCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	if !s.acl.hasAllKeys(cli.User) {
+		return ErrNoKeyPermission
+	}
+
+	count := defaultMemoryBigKeysCount
+	samples := defaultMemoryUsageSamples
+	for i := 0; i < len(args); i += 2 {
+		if i+1 >= len(args) {
+			return ErrSyntax
+		}
+		n, err := strconv.Atoi(args[i+1])
+		if err != nil || n < 0 {
+			return core.ErrNotInteger
+		}
+		switch strings.ToUpper(args[i]) {
+		case "COUNT":
+			count = n
+		case "SAMPLES":
+			samples = n
+		default:
+			return ErrSyntax
+		}
+	}
+
+	keys := db.BigKeys(count, samples)
+	cli.ReplyArrayLength(int64(len(keys)))
+	for _, key := range keys {
+		cli.ReplyArrayLength(3)
+		cli.ReplyBulkString(key.Key)
+		cli.ReplyBulkString(key.Type.String())
+		cli.ReplyInteger(key.Bytes)
+	}
+	return nil
+}
+
+// getMemoryStats collects the memory statistics of the server. It only reads
+// the counters of the databases, which are safe to read from the loop goroutine
+// of any database.
+func (s *Server) getMemoryStats() *memoryStats {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	stats := &memoryStats{
+		allocated:       int64(ms.HeapAlloc),
+		clients:         int64(s.clientCount()) * clientMemoryUsage,
+		dbOverheads:     make([][2]int64, len(s.databases)),
+		maxMemory:       s.maxMemory,
+		maxMemoryPolicy: s.maxMemoryPolicy,
+	}
+	stats.overhead = stats.clients
+
+	for i, db := range s.databases {
+		main, expires := db.Overhead()
+		stats.dbOverheads[i] = [2]int64{main, expires}
+		stats.overhead += main + expires
+		stats.keys += db.Size()
+		stats.used += db.UsedMemory()
+	}
+
+	stats.dataset = stats.used + stats.clients - stats.overhead
+	if stats.used+stats.clients > 0 {
+		stats.datasetPercent = float64(stats.dataset) * 100 / float64(stats.used+stats.clients)
+	}
+	if stats.keys > 0 {
+		stats.bytesPerKey = stats.used / stats.keys
+	}
+
+	return stats
+}
@@ -1,6 +1,11 @@
 package server
 
-import "github.com/ghosind/antdb/client"
+import (
+	"time"
+
+	"github.com/ghosind/antdb/client"
+	"github.com/ghosind/antdb/core"
+)
 
 func (s *Server) dbSizeCommand(cli *client.Client, args ...string) error {
 	db := s.databases[cli.DB]
@@ -11,16 +16,34 @@ func (s *Server) dbSizeCommand(cli *client.Client, args ...string) error {
 }
 
 func (s *Server) flushAllCommand(cli *client.Client, args ...string) error {
-	for _, db := range s.databases {
-		db.Clear()
+	start := time.Now()
+	for i := range s.databases {
+		s.runInDatabase(cli.DB, i, func(db *core.Database) {
+			db.Clear()
+		})
 	}
+	s.latency.add(latencyEventFlush, time.Since(start))
 	cli.ReplySimpleString("OK")
 	return nil
 }
 
 func (s *Server) flushDBCommand(cli *client.Client, args ...string) error {
 	db := s.databases[cli.DB]
+	start := time.Now()
 	db.Clear()
+	s.latency.add(latencyEventFlush, time.Since(start))
+	cli.ReplySimpleString("OK")
+	return nil
+}
+
+func (s *Server) monitorCommand(cli *client.Client, args ...string) error {
+	cli.Flag |= client.CLIENT_MONITOR
 	cli.ReplySimpleString("OK")
+	s.addMonitor(cli)
+	return nil
+}
+
+func (s *Server) infoCommand(cli *client.Client, args ...string) error {
+	cli.ReplyBulkString(s.genInfo(args...))
 	return nil
 }
@@ -1,6 +1,10 @@
 package server
 
-import "github.com/ghosind/antdb/client"
+import (
+	"strconv"
+
+	"github.com/ghosind/antdb/client"
+)
 
 func (s *Server) saddCommand(cli *client.Client, args ...string) error {
 	db := s.databases[cli.DB]
@@ -185,6 +189,28 @@ func (s *Server) sremCommand(cli *client.Client, args ...string) error {
 	return nil
 }
 
+func (s *Server) sscanCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	key := args[0]
+	cursor, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+	opts, err := parseScanOptions(args[2:], false)
+	if err != nil {
+		return err
+	}
+
+	next, members, err := db.SetScan(key, cursor, opts.count, opts.match)
+	if err != nil {
+		return err
+	}
+
+	replyScan(cli, next, members)
+	return nil
+}
+
 func (s *Server) sunionCommand(cli *client.Client, args ...string) error {
 	db := s.databases[cli.DB]
 
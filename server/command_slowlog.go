@@ -0,0 +1,74 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/ghosind/antdb/client"
+	"github.com/ghosind/antdb/core"
+)
+
+const defaultSlowlogGetCount = 10
+
+func (s *Server) slowlogCommand(cli *client.Client, args ...string) error {
+	switch strings.ToUpper(args[0]) {
+	case "GET":
+		return s.slowlogGetCommand(cli, args[1:]...)
+	case "LEN":
+		return s.slowlogLenCommand(cli, args[1:]...)
+	case "RESET":
+		return s.slowlogResetCommand(cli, args[1:]...)
+	default:
+		return newUnknownSubcommandError("SLOWLOG", args[0])
+	}
+}
+
+func (s *Server) slowlogGetCommand(cli *client.Client, args ...string) error {
+	count := defaultSlowlogGetCount
+	switch len(args) {
+	case 0:
+	case 1:
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < -1 {
+			return core.ErrNotInteger
+		}
+		count = n
+	default:
+		return newWrongArityError("slowlog|get")
+	}
+
+	entries := s.slowlog.get(count)
+	cli.ReplyArrayLength(int64(len(entries)))
+	for _, entry := range entries {
+		cli.ReplyArrayLength(6)
+		cli.ReplyInteger(entry.id)
+		cli.ReplyInteger(entry.time.Unix())
+		cli.ReplyInteger(entry.duration.Microseconds())
+		cli.ReplyArrayLength(int64(len(entry.args)))
+		for _, arg := range entry.args {
+			replyStringValue(cli, arg)
+		}
+		cli.ReplyBulkString(entry.addr)
+		replyStringValue(cli, entry.name)
+	}
+	return nil
+}
+
+func (s *Server) slowlogLenCommand(cli *client.Client, args ...string) error {
+	if len(args) != 0 {
+		return newWrongArityError("slowlog|len")
+	}
+
+	cli.ReplyInteger(int64(s.slowlog.len()))
+	return nil
+}
+
+func (s *Server) slowlogResetCommand(cli *client.Client, args ...string) error {
+	if len(args) != 0 {
+		return newWrongArityError("slowlog|reset")
+	}
+
+	s.slowlog.reset()
+	cli.ReplySimpleString("OK")
+	return nil
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSlowlogGetEmptyStrings(t *testing.T) {
+	c := newTestClient(t, WithSlowlogLogSlowerThan(0))
+	c.mustDo("$-1\r\n", "GET", "")
+
+	reply := c.do("SLOWLOG", "GET", "1")
+	// The empty key argument and the name of the unnamed client are empty bulk
+	// strings instead of nil.
+	if !strings.Contains(reply, "*2\r\n$3\r\nGET\r\n$0\r\n\r\n") {
+		t.Errorf("expected an empty key argument, got %q", reply)
+	}
+	if !strings.HasSuffix(reply, "$0\r\n\r\n") || strings.Contains(reply, "$-1\r\n") {
+		t.Errorf("expected an empty client name, got %q", reply)
+	}
+}
+
+func TestSlowlogMaxLen(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  []ServerOption
+		commands int
+		expected string
+	}{
+		{"default", []ServerOption{WithSlowlogLogSlowerThan(0)}, 200, ":128\r\n"},
+		{"limited", []ServerOption{WithSlowlogLogSlowerThan(0), WithSlowlogMaxLen(5)}, 10, ":5\r\n"},
+		{"zero", []ServerOption{WithSlowlogLogSlowerThan(0), WithSlowlogMaxLen(0)}, 10, ":0\r\n"},
+		{"disabled", []ServerOption{WithSlowlogLogSlowerThan(-1)}, 10, ":0\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, tt.options...)
+			for i := 0; i < tt.commands; i++ {
+				c.do("PING")
+			}
+			c.mustDo(tt.expected, "SLOWLOG", "LEN")
+		})
+	}
+}
+
+func TestSlowlogMaxLenNegative(t *testing.T) {
+	s := NewServer(WithSlowlogMaxLen(-1))
+	if s.configErr == nil || !strings.Contains(s.configErr.Error(), "slowlog-max-len") {
+		t.Errorf("expected an invalid config error, got %v", s.configErr)
+	}
+}
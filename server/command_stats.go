@@ -0,0 +1,75 @@
+package server
+
+import (
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// commandHistogramBuckets is the number of buckets of the latency histogram of
+// a command, where the bucket i counts the calls that took up to 2^i
+// microseconds, and the last bucket counts all slower calls.
+const commandHistogramBuckets = 25
+
+type commandStats struct {
+	calls     atomic.Int64
+	usec      atomic.Int64
+	rejected  atomic.Int64
+	failed    atomic.Int64
+	histogram [commandHistogramBuckets]atomic.Int64
+}
+
+func newCommandStats() map[string]*commandStats {
+	stats := make(map[string]*commandStats, len(dbCommands))
+	for name := range dbCommands {
+		stats[name] = new(commandStats)
+	}
+	return stats
+}
+
+func (cs *commandStats) record(duration time.Duration) {
+	usec := duration.Microseconds()
+	cs.calls.Add(1)
+	cs.usec.Add(usec)
+
+	bucket := 0
+	if usec > 1 {
+		bucket = bits.Len64(uint64(usec - 1))
+	}
+	if bucket >= commandHistogramBuckets {
+		bucket = commandHistogramBuckets - 1
+	}
+	cs.histogram[bucket].Add(1)
+}
+
+type histogramBucket struct {
+	usec  int64
+	count int64
+}
+
+// cumulativeHistogram returns the buckets from the first to the last non-empty
+// one, with the number of calls that took up to the time of every bucket.
+func (cs *commandStats) cumulativeHistogram() []histogramBucket {
+	var counts [commandHistogramBuckets]int64
+	first, last := -1, -1
+	for i := range cs.histogram {
+		counts[i] = cs.histogram[i].Load()
+		if counts[i] > 0 {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+	if first < 0 {
+		return nil
+	}
+
+	buckets := make([]histogramBucket, 0, last-first+1)
+	total := int64(0)
+	for i := first; i <= last; i++ {
+		total += counts[i]
+		buckets = append(buckets, histogramBucket{usec: 1 << i, count: total})
+	}
+	return buckets
+}
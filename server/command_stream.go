@@ -0,0 +1,1029 @@
+package server
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ghosind/antdb/client"
+	"github.com/ghosind/antdb/core"
+)
+
+const (
+	defaultStreamAutoClaimCount = 100
+	defaultStreamInfoFullCount  = 10
+)
+
+// streamReadOptions are the parsed options of XREAD and XREADGROUP.
+type streamReadOptions struct {
+	group    string
+	consumer string
+	count    int
+	block    bool
+	timeout  time.Duration
+	noack    bool
+	streams  []core.StreamReadArg
+}
+
+func (s *Server) xackCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	ids, err := parseStreamIDs(args[2:])
+	if err != nil {
+		return err
+	}
+
+	cnt, err := db.XAck(args[0], args[1], ids...)
+	if err != nil {
+		return err
+	}
+
+	cli.ReplyInteger(cnt)
+	return nil
+}
+
+func (s *Server) xaddCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	addArgs := &core.StreamAddArgs{}
+	i := 1
+options:
+	for ; i < len(args); i++ {
+		var err error
+		switch strings.ToUpper(args[i]) {
+		case "NOMKSTREAM":
+			addArgs.NoMkStream = true
+		case "MAXLEN", "MINID":
+			if addArgs.Trim != nil {
+				return ErrStreamMaxLenAndMinID
+			}
+			addArgs.Trim, i, err = parseStreamTrimArgs(args, i)
+			if err != nil {
+				return err
+			}
+		default:
+			break options
+		}
+	}
+
+	rest := args[i:]
+	if len(rest) < 3 || len(rest)%2 != 1 {
+		return newWrongArityError("XADD")
+	}
+	if err := parseStreamAddID(rest[0], addArgs); err != nil {
+		return err
+	}
+	addArgs.Fields = rest[1:]
+
+	id, ok, err := db.XAdd(args[0], addArgs)
+	if err != nil {
+		return err
+	} else if !ok {
+		cli.ReplyNilBulk()
+		return nil
+	}
+
+	s.signalKeyAsReady(cli.DB, args[0])
+	cli.ReplyBulkString(id.String())
+	return nil
+}
+
+func (s *Server) xautoclaimCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	minIdle, err := strconv.ParseInt(args[3], 10, 64)
+	if err != nil {
+		return newStreamMinIdleError("XAUTOCLAIM")
+	}
+	start, err := parseStreamRangeID(args[4], true)
+	if err != nil {
+		return err
+	}
+
+	count, justID := defaultStreamAutoClaimCount, false
+	for i := 5; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "COUNT":
+			if i+1 >= len(args) {
+				return ErrSyntax
+			}
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil {
+				return core.ErrNotInteger
+			} else if n <= 0 || n > math.MaxInt32/10 {
+				return ErrCountNotPositive
+			}
+			count = int(n)
+			i++
+		case "JUSTID":
+			justID = true
+		default:
+			return ErrSyntax
+		}
+	}
+
+	next, claimed, deleted, err := db.XAutoClaim(args[0], args[1], args[2], max(minIdle, 0), start, count, justID)
+	if err != nil {
+		return err
+	}
+
+	cli.ReplyArrayLength(3)
+	cli.ReplyBulkString(next.String())
+	if justID {
+		replyStreamIDs(cli, claimed)
+	} else {
+		replyStreamEntries(cli, claimed)
+	}
+	cli.ReplyArrayLength(int64(len(deleted)))
+	for _, id := range deleted {
+		cli.ReplyBulkString(id.String())
+	}
+	return nil
+}
+
+func (s *Server) xclaimCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	minIdle, err := strconv.ParseInt(args[3], 10, 64)
+	if err != nil {
+		return newStreamMinIdleError("XCLAIM")
+	}
+
+	// The IDs are followed by the options.
+	i := 4
+	ids := make([]core.StreamID, 0, len(args)-i)
+	for ; i < len(args); i++ {
+		id, err := core.ParseStreamID(args[i], 0)
+		if err != nil {
+			break
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return core.ErrInvalidStreamID
+	}
+
+	claimArgs := &core.StreamClaimArgs{Idle: -1, Time: -1, RetryCount: -1}
+	for ; i < len(args); i++ {
+		switch option := strings.ToUpper(args[i]); option {
+		case "IDLE", "TIME", "RETRYCOUNT":
+			if i+1 >= len(args) {
+				return ErrSyntax
+			}
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil {
+				return newStreamClaimOptionError(option)
+			}
+			switch option {
+			case "IDLE":
+				claimArgs.Idle = max(n, 0)
+			case "TIME":
+				claimArgs.Time = max(n, 0)
+			case "RETRYCOUNT":
+				claimArgs.RetryCount = max(n, 0)
+			}
+			i++
+		case "FORCE":
+			claimArgs.Force = true
+		case "JUSTID":
+			claimArgs.JustID = true
+		case "LASTID":
+			if i+1 >= len(args) {
+				return ErrSyntax
+			}
+			id, err := core.ParseStreamID(args[i+1], 0)
+			if err != nil {
+				return err
+			}
+			claimArgs.LastID = &id
+			i++
+		default:
+			return ErrSyntax
+		}
+	}
+
+	claimed, err := db.XClaim(args[0], args[1], args[2], max(minIdle, 0), ids, claimArgs)
+	if err != nil {
+		return err
+	}
+
+	if claimArgs.JustID {
+		replyStreamIDs(cli, claimed)
+	} else {
+		replyStreamEntries(cli, claimed)
+	}
+	return nil
+}
+
+func (s *Server) xdelCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	ids, err := parseStreamIDs(args[1:])
+	if err != nil {
+		return err
+	}
+
+	cnt, err := db.XDel(args[0], ids...)
+	if err != nil {
+		return err
+	}
+
+	cli.ReplyInteger(cnt)
+	return nil
+}
+
+func (s *Server) xgroupCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	subcommand := strings.ToUpper(args[0])
+	var err error
+	switch {
+	case subcommand == "CREATE" && len(args) >= 4 && len(args) <= 7:
+		var id core.StreamID
+		var last bool
+		id, last, err = parseStreamGroupID(args[3])
+		if err != nil {
+			return err
+		}
+		entriesRead, mkstream := int64(-1), false
+		for i := 4; i < len(args); i++ {
+			switch strings.ToUpper(args[i]) {
+			case "MKSTREAM":
+				mkstream = true
+			case "ENTRIESREAD":
+				if i+1 >= len(args) {
+					return ErrSyntax
+				}
+				entriesRead, err = parseStreamEntriesRead(args[i+1])
+				if err != nil {
+					return err
+				}
+				i++
+			default:
+				return ErrSyntax
+			}
+		}
+		err = db.XGroupCreate(args[1], args[2], id, last, mkstream, entriesRead)
+		if err == nil {
+			cli.ReplySimpleString("OK")
+		}
+	case subcommand == "SETID" && (len(args) == 4 || len(args) == 6):
+		var id core.StreamID
+		var last bool
+		id, last, err = parseStreamGroupID(args[3])
+		if err != nil {
+			return err
+		}
+		entriesRead := int64(-1)
+		if len(args) == 6 {
+			if strings.ToUpper(args[4]) != "ENTRIESREAD" {
+				return ErrSyntax
+			}
+			entriesRead, err = parseStreamEntriesRead(args[5])
+			if err != nil {
+				return err
+			}
+		}
+		err = db.XGroupSetID(args[1], args[2], id, last, entriesRead)
+		if err == nil {
+			cli.ReplySimpleString("OK")
+		}
+	case subcommand == "DESTROY" && len(args) == 3:
+		var destroyed bool
+		destroyed, err = db.XGroupDestroy(args[1], args[2])
+		if err == nil {
+			if destroyed {
+				// Wake up the clients blocked on the group to fail.
+				s.signalKeyAsReady(cli.DB, args[1])
+				cli.ReplyInteger(1)
+			} else {
+				cli.ReplyInteger(0)
+			}
+		}
+	case subcommand == "CREATECONSUMER" && len(args) == 4:
+		var created bool
+		created, err = db.XGroupCreateConsumer(args[1], args[2], args[3])
+		if err == nil {
+			if created {
+				cli.ReplyInteger(1)
+			} else {
+				cli.ReplyInteger(0)
+			}
+		}
+	case subcommand == "DELCONSUMER" && len(args) == 4:
+		var cnt int64
+		cnt, err = db.XGroupDelConsumer(args[1], args[2], args[3])
+		if err == nil {
+			cli.ReplyInteger(cnt)
+		}
+	default:
+		return newUnknownSubcommandError("XGROUP", args[0])
+	}
+
+	var noGroup *core.NoGroupError
+	if errors.Is(err, core.ErrNoSuchKey) {
+		return ErrStreamKeyNotExist
+	} else if errors.As(err, &noGroup) {
+		return newStreamNoGroupError(noGroup.Key, noGroup.Group)
+	}
+	return err
+}
+
+func (s *Server) xinfoCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	switch subcommand := strings.ToUpper(args[0]); {
+	case subcommand == "STREAM" && len(args) >= 2:
+		full, count := false, defaultStreamInfoFullCount
+		if len(args) > 2 {
+			if strings.ToUpper(args[2]) != "FULL" {
+				return ErrSyntax
+			}
+			full = true
+			if len(args) == 5 && strings.ToUpper(args[3]) == "COUNT" {
+				n, err := strconv.ParseInt(args[4], 10, 64)
+				if err != nil {
+					return core.ErrNotInteger
+				}
+				count = int(max(n, 0))
+			} else if len(args) != 3 {
+				return ErrSyntax
+			}
+		}
+
+		info, err := db.XInfoStream(args[1], full, count)
+		if err != nil {
+			return err
+		}
+		replyStreamInfo(cli, info, full)
+	case subcommand == "GROUPS" && len(args) == 2:
+		groups, err := db.XInfoGroups(args[1])
+		if err != nil {
+			return err
+		}
+		cli.ReplyArrayLength(int64(len(groups)))
+		for _, g := range groups {
+			cli.ReplyArrayLength(12)
+			cli.ReplyBulkString("name")
+			cli.ReplyBulkString(g.Name)
+			cli.ReplyBulkString("consumers")
+			cli.ReplyInteger(int64(len(g.Consumers)))
+			cli.ReplyBulkString("pending")
+			cli.ReplyInteger(g.Pending)
+			cli.ReplyBulkString("last-delivered-id")
+			cli.ReplyBulkString(g.LastDeliveredID.String())
+			cli.ReplyBulkString("entries-read")
+			replyOptionalInteger(cli, g.EntriesRead)
+			cli.ReplyBulkString("lag")
+			replyOptionalInteger(cli, g.Lag)
+		}
+	case subcommand == "CONSUMERS" && len(args) == 3:
+		consumers, err := db.XInfoConsumers(args[1], args[2])
+		var noGroup *core.NoGroupError
+		if errors.As(err, &noGroup) {
+			return newStreamNoGroupError(noGroup.Key, noGroup.Group)
+		} else if err != nil {
+			return err
+		}
+		cli.ReplyArrayLength(int64(len(consumers)))
+		for _, c := range consumers {
+			cli.ReplyArrayLength(8)
+			cli.ReplyBulkString("name")
+			cli.ReplyBulkString(c.Name)
+			cli.ReplyBulkString("pending")
+			cli.ReplyInteger(c.Pending)
+			cli.ReplyBulkString("idle")
+			cli.ReplyInteger(c.Idle)
+			cli.ReplyBulkString("inactive")
+			cli.ReplyInteger(c.Inactive)
+		}
+	default:
+		return newUnknownSubcommandError("XINFO", args[0])
+	}
+	return nil
+}
+
+func (s *Server) xlenCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	length, err := db.XLen(args[0])
+	if err != nil {
+		return err
+	}
+
+	cli.ReplyInteger(length)
+	return nil
+}
+
+func (s *Server) xpendingCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	if len(args) == 2 {
+		summary, err := db.XPendingSummary(args[0], args[1])
+		if err != nil {
+			return err
+		}
+
+		cli.ReplyArrayLength(4)
+		cli.ReplyInteger(summary.Count)
+		if summary.Count == 0 {
+			cli.ReplyNilBulk()
+			cli.ReplyNilBulk()
+			cli.ReplyArrayLength(-1)
+			return nil
+		}
+		cli.ReplyBulkString(summary.First.String())
+		cli.ReplyBulkString(summary.Last.String())
+		cli.ReplyArrayLength(int64(len(summary.Consumers)))
+		for _, c := range summary.Consumers {
+			cli.ReplyArrayLength(2)
+			cli.ReplyBulkString(c.Name)
+			cli.ReplyBulkString(strconv.FormatInt(c.Count, 10))
+		}
+		return nil
+	}
+
+	rest := args[2:]
+	minIdle := int64(0)
+	if strings.ToUpper(rest[0]) == "IDLE" {
+		if len(rest) < 2 {
+			return ErrSyntax
+		}
+		n, err := strconv.ParseInt(rest[1], 10, 64)
+		if err != nil {
+			return core.ErrNotInteger
+		}
+		minIdle = max(n, 0)
+		rest = rest[2:]
+	}
+	if len(rest) != 3 && len(rest) != 4 {
+		return ErrSyntax
+	}
+	start, err := parseStreamRangeID(rest[0], true)
+	if err != nil {
+		return err
+	}
+	end, err := parseStreamRangeID(rest[1], false)
+	if err != nil {
+		return err
+	}
+	count, err := strconv.ParseInt(rest[2], 10, 64)
+	if err != nil {
+		return core.ErrNotInteger
+	}
+	consumer := ""
+	if len(rest) == 4 {
+		consumer = rest[3]
+	}
+
+	entries, err := db.XPending(args[0], args[1], start, end, int(min(max(count, 0), math.MaxInt32)), consumer, minIdle)
+	if err != nil {
+		return err
+	}
+
+	cli.ReplyArrayLength(int64(len(entries)))
+	for _, entry := range entries {
+		cli.ReplyArrayLength(4)
+		cli.ReplyBulkString(entry.ID.String())
+		cli.ReplyBulkString(entry.Consumer)
+		cli.ReplyInteger(entry.Idle)
+		cli.ReplyInteger(entry.DeliveryCount)
+	}
+	return nil
+}
+
+func (s *Server) xrangeCommand(cli *client.Client, args ...string) error {
+	return s.xrangeGeneric(cli, args[0], args[1], args[2], args[3:], false)
+}
+
+func (s *Server) xreadCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	opts, err := parseStreamReadOptions(args, false)
+	if err != nil {
+		return err
+	}
+
+	results, err := db.XRead(opts.streams, opts.count)
+	if err != nil {
+		return err
+	}
+
+	if len(results) == 0 && opts.block && cli.Flag&client.CLIENT_MULTI == 0 {
+		s.blockClient(cli, streamReadKeys(args), opts.timeout, newStreamReadCommand("XREAD", opts))
+		return nil
+	}
+	replyStreamReadResults(cli, results)
+	return nil
+}
+
+func (s *Server) xreadgroupCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	opts, err := parseStreamReadOptions(args, true)
+	if err != nil {
+		return err
+	}
+
+	results, err := db.XReadGroup(opts.group, opts.consumer, opts.streams, opts.count, opts.noack)
+	var noGroup *core.NoGroupError
+	if errors.As(err, &noGroup) {
+		return newStreamReadNoGroupError(noGroup)
+	} else if err != nil {
+		return err
+	}
+
+	if len(results) == 0 && opts.block && cli.Flag&client.CLIENT_MULTI == 0 {
+		s.blockClient(cli, streamReadKeys(args), opts.timeout, newStreamReadCommand("XREADGROUP", opts))
+		return nil
+	}
+	replyStreamReadResults(cli, results)
+	return nil
+}
+
+func (s *Server) xrevrangeCommand(cli *client.Client, args ...string) error {
+	return s.xrangeGeneric(cli, args[0], args[2], args[1], args[3:], true)
+}
+
+func (s *Server) xtrimCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	switch strings.ToUpper(args[1]) {
+	case "MAXLEN", "MINID":
+	default:
+		return ErrSyntax
+	}
+	trimArgs, i, err := parseStreamTrimArgs(args, 1)
+	if err != nil {
+		return err
+	} else if i != len(args)-1 {
+		return ErrSyntax
+	}
+
+	cnt, err := db.XTrim(args[0], trimArgs)
+	if err != nil {
+		return err
+	}
+
+	cli.ReplyInteger(cnt)
+	return nil
+}
+
+func (s *Server) xrangeGeneric(cli *client.Client, key, startArg, endArg string, options []string, rev bool) error {
+	db := s.databases[cli.DB]
+
+	start, err := parseStreamRangeID(startArg, true)
+	if err != nil {
+		return err
+	}
+	end, err := parseStreamRangeID(endArg, false)
+	if err != nil {
+		return err
+	}
+
+	count := int64(-1)
+	switch {
+	case len(options) == 0:
+	case len(options) == 2 && strings.ToUpper(options[0]) == "COUNT":
+		count, err = strconv.ParseInt(options[1], 10, 64)
+		if err != nil {
+			return core.ErrNotInteger
+		}
+		count = max(count, 0)
+	default:
+		return ErrSyntax
+	}
+	if count == 0 {
+		cli.ReplyArrayLength(-1)
+		return nil
+	}
+
+	entries, err := db.XRange(key, start, end, int(min(count, math.MaxInt32)), rev)
+	if err != nil {
+		return err
+	}
+
+	replyStreamEntries(cli, entries)
+	return nil
+}
+
+// parseStreamTrimArgs parses the trimming strategy at args[i] with its
+// arguments, and returns the index of the last parsed argument.
+func parseStreamTrimArgs(args []string, i int) (*core.StreamTrimArgs, int, error) {
+	trimArgs := &core.StreamTrimArgs{Strategy: core.StreamTrimMaxLen}
+	if strings.ToUpper(args[i]) == "MINID" {
+		trimArgs.Strategy = core.StreamTrimMinID
+	}
+	i++
+	if i < len(args) && (args[i] == "~" || args[i] == "=") {
+		trimArgs.Approx = args[i] == "~"
+		i++
+	}
+	if i >= len(args) {
+		return nil, i, ErrSyntax
+	}
+
+	if trimArgs.Strategy == core.StreamTrimMaxLen {
+		maxLen, err := strconv.ParseInt(args[i], 10, 64)
+		if err != nil {
+			return nil, i, core.ErrNotInteger
+		} else if maxLen < 0 {
+			return nil, i, ErrStreamMaxLen
+		}
+		trimArgs.MaxLen = maxLen
+	} else {
+		minID, err := core.ParseStreamID(args[i], 0)
+		if err != nil {
+			return nil, i, err
+		}
+		trimArgs.MinID = minID
+	}
+
+	if trimArgs.Approx {
+		trimArgs.Limit = core.DefaultStreamTrimLimit
+	}
+	if i+2 < len(args) && strings.ToUpper(args[i+1]) == "LIMIT" {
+		limit, err := strconv.ParseInt(args[i+2], 10, 64)
+		if err != nil {
+			return nil, i, core.ErrNotInteger
+		} else if limit < 0 {
+			return nil, i, ErrStreamLimit
+		} else if !trimArgs.Approx {
+			return nil, i, ErrStreamLimitWithoutApprox
+		}
+		trimArgs.Limit = limit
+		i += 2
+	}
+	return trimArgs, i, nil
+}
+
+// parseStreamAddID parses the ID of XADD, which is "*" for an auto-generated
+// ID, or "<ms>-*" for an auto-generated sequence number.
+func parseStreamAddID(arg string, addArgs *core.StreamAddArgs) error {
+	if arg == "*" {
+		addArgs.AutoID = true
+		return nil
+	}
+	if ms, ok := strings.CutSuffix(arg, "-*"); ok {
+		n, err := strconv.ParseUint(ms, 10, 64)
+		if err != nil {
+			return core.ErrInvalidStreamID
+		}
+		addArgs.ID, addArgs.AutoSeq = core.StreamID{Ms: n}, true
+		return nil
+	}
+
+	id, err := core.ParseStreamID(arg, 0)
+	if err != nil {
+		return err
+	}
+	addArgs.ID = id
+	return nil
+}
+
+// parseStreamRangeID parses an ID of a range, where "-" and "+" are the
+// smallest and the greatest IDs, and an ID prefixed by "(" is excluded.
+func parseStreamRangeID(arg string, start bool) (core.StreamID, error) {
+	switch arg {
+	case "-":
+		return core.StreamID{}, nil
+	case "+":
+		return core.MaxStreamID, nil
+	}
+
+	missingSeq := uint64(0)
+	if !start {
+		missingSeq = math.MaxUint64
+	}
+	exclusive := strings.HasPrefix(arg, "(")
+	id, err := core.ParseStreamID(strings.TrimPrefix(arg, "("), missingSeq)
+	if err != nil || !exclusive {
+		return id, err
+	}
+
+	if start {
+		next, ok := id.Next()
+		if !ok {
+			return id, ErrStreamInvalidStart
+		}
+		return next, nil
+	}
+	prev, ok := id.Prev()
+	if !ok {
+		return id, ErrStreamInvalidEnd
+	}
+	return prev, nil
+}
+
+// parseStreamGroupID parses the last delivered ID of a consumer group, which
+// is "$" for the last ID of the stream.
+func parseStreamGroupID(arg string) (core.StreamID, bool, error) {
+	if arg == "$" {
+		return core.StreamID{}, true, nil
+	}
+	id, err := core.ParseStreamID(arg, 0)
+	return id, false, err
+}
+
+func parseStreamEntriesRead(arg string) (int64, error) {
+	n, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return 0, core.ErrNotInteger
+	} else if n < -1 {
+		return 0, ErrStreamEntriesRead
+	}
+	return n, nil
+}
+
+func parseStreamIDs(args []string) ([]core.StreamID, error) {
+	ids := make([]core.StreamID, 0, len(args))
+	for _, arg := range args {
+		id, err := core.ParseStreamID(arg, 0)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// parseStreamReadOptions parses the arguments of XREAD, or of XREADGROUP if
+// group is true.
+func parseStreamReadOptions(args []string, group bool) (*streamReadOptions, error) {
+	opts := &streamReadOptions{}
+	hasGroup := false
+	i := 0
+	for ; i < len(args); i++ {
+		option := strings.ToUpper(args[i])
+		if option == "STREAMS" {
+			break
+		}
+
+		switch option {
+		case "COUNT":
+			if i+1 >= len(args) {
+				return nil, ErrSyntax
+			}
+			count, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil {
+				return nil, core.ErrNotInteger
+			}
+			opts.count = int(min(max(count, 0), math.MaxInt32))
+			i++
+		case "BLOCK":
+			if i+1 >= len(args) {
+				return nil, ErrSyntax
+			}
+			timeout, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil {
+				return nil, ErrStreamTimeout
+			} else if timeout < 0 {
+				return nil, ErrInvalidTimeout
+			}
+			opts.block, opts.timeout = true, time.Duration(timeout)*time.Millisecond
+			i++
+		case "GROUP":
+			if !group {
+				return nil, ErrStreamGroupOption
+			} else if i+2 >= len(args) {
+				return nil, ErrSyntax
+			}
+			opts.group, opts.consumer = args[i+1], args[i+2]
+			hasGroup = true
+			i += 2
+		case "NOACK":
+			if !group {
+				return nil, ErrSyntax
+			}
+			opts.noack = true
+		default:
+			return nil, ErrSyntax
+		}
+	}
+	if i >= len(args) {
+		return nil, ErrSyntax
+	}
+	if group && !hasGroup {
+		return nil, ErrStreamMissingGroup
+	}
+
+	rest := args[i+1:]
+	if len(rest) == 0 || len(rest)%2 != 0 {
+		if group {
+			return nil, newStreamUnbalancedError("xreadgroup", ">")
+		}
+		return nil, newStreamUnbalancedError("xread", "$")
+	}
+	n := len(rest) / 2
+	opts.streams = make([]core.StreamReadArg, n)
+	for j, key := range rest[:n] {
+		arg := &opts.streams[j]
+		arg.Key = key
+		switch id := rest[n+j]; {
+		case id == "$" && !group:
+			arg.Last = true
+		case id == "$":
+			return nil, ErrStreamLastIDWithGroup
+		case id == ">" && group:
+			arg.Last = true
+		case id == ">":
+			return nil, ErrStreamNewIDWithoutGroup
+		default:
+			var err error
+			arg.ID, err = core.ParseStreamID(id, 0)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return opts, nil
+}
+
+// streamReadKeys returns the keys of XREAD and XREADGROUP, which are the first
+// half of the arguments after STREAMS.
+func streamReadKeys(args []string) []string {
+	for i := 0; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "STREAMS":
+			rest := args[i+1:]
+			return rest[:len(rest)/2]
+		case "COUNT", "BLOCK":
+			i++
+		case "GROUP":
+			i += 2
+		}
+	}
+	return nil
+}
+
+// newStreamReadCommand returns the command to retry a blocked XREAD or
+// XREADGROUP, where the "$" IDs of XREAD are resolved by the first read.
+func newStreamReadCommand(name string, opts *streamReadOptions) *client.Command {
+	cmd := client.GetCommand()
+	cmd.Command = name
+	cmd.Args = cmd.Args[:0]
+	if opts.group != "" {
+		cmd.Args = append(cmd.Args, "GROUP", opts.group, opts.consumer)
+	}
+	if opts.count > 0 {
+		cmd.Args = append(cmd.Args, "COUNT", strconv.Itoa(opts.count))
+	}
+	cmd.Args = append(cmd.Args, "BLOCK", strconv.FormatInt(opts.timeout.Milliseconds(), 10))
+	if opts.noack {
+		cmd.Args = append(cmd.Args, "NOACK")
+	}
+	cmd.Args = append(cmd.Args, "STREAMS")
+	for _, arg := range opts.streams {
+		cmd.Args = append(cmd.Args, arg.Key)
+	}
+	for _, arg := range opts.streams {
+		if arg.Last {
+			cmd.Args = append(cmd.Args, ">")
+		} else {
+			cmd.Args = append(cmd.Args, arg.ID.String())
+		}
+	}
+	return cmd
+}
+
+func replyStreamReadResults(cli *client.Client, results []core.StreamReadResult) {
+	if len(results) == 0 {
+		cli.ReplyArrayLength(-1)
+		return
+	}
+
+	cli.ReplyArrayLength(int64(len(results)))
+	for _, result := range results {
+		cli.ReplyArrayLength(2)
+		cli.ReplyBulkString(result.Key)
+		replyStreamEntries(cli, result.Entries)
+	}
+}
+
+func replyStreamEntries(cli *client.Client, entries []core.StreamEntry) {
+	cli.ReplyArrayLength(int64(len(entries)))
+	for _, entry := range entries {
+		replyStreamEntry(cli, entry)
+	}
+}
+
+// replyStreamEntry replies the entry with its fields, or a nil array for the
+// fields of a deleted entry.
+func replyStreamEntry(cli *client.Client, entry core.StreamEntry) {
+	cli.ReplyArrayLength(2)
+	cli.ReplyBulkString(entry.ID.String())
+	if entry.Fields == nil {
+		cli.ReplyArrayLength(-1)
+		return
+	}
+	cli.ReplyArrayLength(int64(len(entry.Fields)))
+	for _, field := range entry.Fields {
+		cli.ReplyBulkString(field)
+	}
+}
+
+func replyOptionalStreamEntry(cli *client.Client, entry *core.StreamEntry) {
+	if entry == nil {
+		cli.ReplyArrayLength(-1)
+	} else {
+		replyStreamEntry(cli, *entry)
+	}
+}
+
+func replyStreamIDs(cli *client.Client, entries []core.StreamEntry) {
+	cli.ReplyArrayLength(int64(len(entries)))
+	for _, entry := range entries {
+		cli.ReplyBulkString(entry.ID.String())
+	}
+}
+
+// replyOptionalInteger replies the integer, or a nil bulk if it is negative.
+func replyOptionalInteger(cli *client.Client, n int64) {
+	if n < 0 {
+		cli.ReplyNilBulk()
+	} else {
+		cli.ReplyInteger(n)
+	}
+}
+
+func replyStreamInfo(cli *client.Client, info *core.StreamInfo, full bool) {
+	if full {
+		cli.ReplyArrayLength(18)
+	} else {
+		cli.ReplyArrayLength(20)
+	}
+	cli.ReplyBulkString("length")
+	cli.ReplyInteger(info.Length)
+	cli.ReplyBulkString("radix-tree-keys")
+	cli.ReplyInteger(info.Chunks)
+	cli.ReplyBulkString("radix-tree-nodes")
+	cli.ReplyInteger(info.Chunks)
+	cli.ReplyBulkString("last-generated-id")
+	cli.ReplyBulkString(info.LastID.String())
+	cli.ReplyBulkString("max-deleted-entry-id")
+	cli.ReplyBulkString(info.MaxDeletedID.String())
+	cli.ReplyBulkString("entries-added")
+	cli.ReplyInteger(info.EntriesAdded)
+	cli.ReplyBulkString("recorded-first-entry-id")
+	cli.ReplyBulkString(info.FirstID.String())
+
+	if !full {
+		cli.ReplyBulkString("groups")
+		cli.ReplyInteger(int64(len(info.Groups)))
+		cli.ReplyBulkString("first-entry")
+		replyOptionalStreamEntry(cli, info.FirstEntry)
+		cli.ReplyBulkString("last-entry")
+		replyOptionalStreamEntry(cli, info.LastEntry)
+		return
+	}
+
+	cli.ReplyBulkString("entries")
+	replyStreamEntries(cli, info.Entries)
+	cli.ReplyBulkString("groups")
+	cli.ReplyArrayLength(int64(len(info.Groups)))
+	for _, g := range info.Groups {
+		cli.ReplyArrayLength(14)
+		cli.ReplyBulkString("name")
+		cli.ReplyBulkString(g.Name)
+		cli.ReplyBulkString("last-delivered-id")
+		cli.ReplyBulkString(g.LastDeliveredID.String())
+		cli.ReplyBulkString("entries-read")
+		replyOptionalInteger(cli, g.EntriesRead)
+		cli.ReplyBulkString("lag")
+		replyOptionalInteger(cli, g.Lag)
+		cli.ReplyBulkString("pel-count")
+		cli.ReplyInteger(g.Pending)
+		cli.ReplyBulkString("pending")
+		cli.ReplyArrayLength(int64(len(g.PEL)))
+		for _, entry := range g.PEL {
+			cli.ReplyArrayLength(4)
+			cli.ReplyBulkString(entry.ID.String())
+			cli.ReplyBulkString(entry.Consumer)
+			cli.ReplyInteger(entry.DeliveryTime)
+			cli.ReplyInteger(entry.DeliveryCount)
+		}
+		cli.ReplyBulkString("consumers")
+		cli.ReplyArrayLength(int64(len(g.Consumers)))
+		for _, c := range g.Consumers {
+			cli.ReplyArrayLength(10)
+			cli.ReplyBulkString("name")
+			cli.ReplyBulkString(c.Name)
+			cli.ReplyBulkString("seen-time")
+			cli.ReplyInteger(c.SeenTime)
+			cli.ReplyBulkString("active-time")
+			cli.ReplyInteger(c.ActiveTime)
+			cli.ReplyBulkString("pel-count")
+			cli.ReplyInteger(c.Pending)
+			cli.ReplyBulkString("pending")
+			cli.ReplyArrayLength(int64(len(c.PEL)))
+			for _, entry := range c.PEL {
+				cli.ReplyArrayLength(3)
+				cli.ReplyBulkString(entry.ID.String())
+				cli.ReplyInteger(entry.DeliveryTime)
+				cli.ReplyInteger(entry.DeliveryCount)
+			}
+		}
+	}
+}
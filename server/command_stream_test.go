@@ -0,0 +1,184 @@
+package server
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ghosind/antdb/core"
+)
+
+func TestStreamCommands(t *testing.T) {
+	entry := func(id string) string {
+		return "*2\r\n$3\r\n" + id + "\r\n*2\r\n$1\r\nf\r\n$1\r\nv\r\n"
+	}
+	steps := []struct {
+		args  []string
+		reply string
+	}{
+		{[]string{"XADD", "s", "1-1", "f", "v"}, "$3\r\n1-1\r\n"},
+		{[]string{"XADD", "s", "1-2", "f", "v"}, "$3\r\n1-2\r\n"},
+		{[]string{"XADD", "s", "1-2", "f", "v"},
+			"-The ID specified in XADD is equal or smaller than the target stream top item\r\n"},
+		{[]string{"XADD", "s", "NOMKSTREAM", "2-1", "f", "v"}, "$3\r\n2-1\r\n"},
+		{[]string{"XADD", "none", "NOMKSTREAM", "*", "f", "v"}, "$-1\r\n"},
+		{[]string{"XLEN", "s"}, ":3\r\n"},
+		{[]string{"XRANGE", "s", "-", "+"}, "*3\r\n" + entry("1-1") + entry("1-2") + entry("2-1")},
+		{[]string{"XRANGE", "s", "(1-1", "+", "COUNT", "1"}, "*1\r\n" + entry("1-2")},
+		{[]string{"XREVRANGE", "s", "+", "-", "COUNT", "2"}, "*2\r\n" + entry("2-1") + entry("1-2")},
+		{[]string{"XDEL", "s", "1-2", "9-9"}, ":1\r\n"},
+		{[]string{"XLEN", "s"}, ":2\r\n"},
+		{[]string{"XREAD", "STREAMS", "s", "1-1"}, "*1\r\n*2\r\n$1\r\ns\r\n*1\r\n" + entry("2-1")},
+		{[]string{"XREAD", "STREAMS", "s", "$"}, "*-1\r\n"},
+		{[]string{"XGROUP", "CREATE", "s", "g", "0"}, "+OK\r\n"},
+		{[]string{"XGROUP", "CREATE", "s", "g", "0"}, "-BUSYGROUP Consumer Group name already exists\r\n"},
+		{[]string{"XREADGROUP", "GROUP", "g", "c", "COUNT", "1", "STREAMS", "s", ">"},
+			"*1\r\n*2\r\n$1\r\ns\r\n*1\r\n" + entry("1-1")},
+		{[]string{"XPENDING", "s", "g"}, "*4\r\n:1\r\n$3\r\n1-1\r\n$3\r\n1-1\r\n*1\r\n*2\r\n$1\r\nc\r\n$1\r\n1\r\n"},
+		{[]string{"XREADGROUP", "GROUP", "g", "c", "STREAMS", "s", ">"},
+			"*1\r\n*2\r\n$1\r\ns\r\n*1\r\n" + entry("2-1")},
+		{[]string{"XREADGROUP", "GROUP", "g", "c", "STREAMS", "s", ">"}, "*-1\r\n"},
+		{[]string{"XACK", "s", "g", "1-1", "2-1", "3-1"}, ":2\r\n"},
+		{[]string{"XPENDING", "s", "g"}, "*4\r\n:0\r\n$-1\r\n$-1\r\n*-1\r\n"},
+		{[]string{"XREADGROUP", "GROUP", "none", "c", "STREAMS", "s", ">"},
+			"-NOGROUP No such key 's' or consumer group 'none' in XREADGROUP with GROUP option\r\n"},
+		{[]string{"XGROUP", "DESTROY", "s", "g"}, ":1\r\n"},
+		{[]string{"XGROUP", "DESTROY", "s", "g"}, ":0\r\n"},
+	}
+
+	c := newTestClient(t)
+	for _, step := range steps {
+		c.mustDo(step.reply, step.args...)
+	}
+}
+
+func TestStreamBlocking(t *testing.T) {
+	entry := "*1\r\n*2\r\n$1\r\ns\r\n*1\r\n*2\r\n$3\r\n2-1\r\n*2\r\n$1\r\nf\r\n$1\r\nv\r\n"
+	noGroup := "-NOGROUP No such key 's' or consumer group 'g' in XREADGROUP with GROUP option\r\n"
+	tests := []struct {
+		name string
+		// block is the blocking command, and commands are run by another client
+		// while the command is blocked.
+		block    []string
+		commands [][]string
+		reply    string
+		// timeout is the minimal time the command is blocked for.
+		timeout time.Duration
+	}{
+		{"XREAD woken by XADD", []string{"XREAD", "BLOCK", "0", "STREAMS", "s", "$"},
+			[][]string{{"XADD", "s", "2-1", "f", "v"}}, entry, 0},
+		{"XREAD woken by XADD of a new stream", []string{"XREAD", "BLOCK", "0", "STREAMS", "new", "0"},
+			[][]string{{"XADD", "new", "2-1", "f", "v"}},
+			"*1\r\n*2\r\n$3\r\nnew\r\n*1\r\n*2\r\n$3\r\n2-1\r\n*2\r\n$1\r\nf\r\n$1\r\nv\r\n", 0},
+		{"XREAD not woken by an older entry", []string{"XREAD", "BLOCK", "0", "STREAMS", "s", "1-5"},
+			[][]string{{"XDEL", "s", "1-1"}, {"XADD", "s", "2-1", "f", "v"}}, entry, 0},
+		{"XREAD timeout", []string{"XREAD", "BLOCK", "200", "STREAMS", "s", "$"}, nil, "*-1\r\n", 200 * time.Millisecond},
+		{"XREAD timeout with other keys written", []string{"XREAD", "BLOCK", "200", "STREAMS", "s", "$"},
+			[][]string{{"XADD", "other", "2-1", "f", "v"}}, "*-1\r\n", 200 * time.Millisecond},
+		{"XREAD blocked after the stream is deleted", []string{"XREAD", "BLOCK", "0", "STREAMS", "s", "$"},
+			[][]string{{"DEL", "s"}, {"XADD", "s", "2-1", "f", "v"}}, entry, 0},
+		{"XREADGROUP woken by XADD", []string{"XREADGROUP", "GROUP", "g", "c", "BLOCK", "0", "STREAMS", "s", ">"},
+			[][]string{{"XADD", "s", "2-1", "f", "v"}}, entry, 0},
+		{"XREADGROUP timeout", []string{"XREADGROUP", "GROUP", "g", "c", "BLOCK", "200", "STREAMS", "s", ">"},
+			nil, "*-1\r\n", 200 * time.Millisecond},
+		{"XREADGROUP stream deleted", []string{"XREADGROUP", "GROUP", "g", "c", "BLOCK", "0", "STREAMS", "s", ">"},
+			[][]string{{"DEL", "s"}}, noGroup, 0},
+		{"XREADGROUP stream overwritten", []string{"XREADGROUP", "GROUP", "g", "c", "BLOCK", "0", "STREAMS", "s", ">"},
+			[][]string{{"SET", "s", "v"}}, "-" + core.ErrWrongType.Error() + "\r\n", 0},
+		{"XREADGROUP group destroyed", []string{"XREADGROUP", "GROUP", "g", "c", "BLOCK", "0", "STREAMS", "s", ">"},
+			[][]string{{"XGROUP", "DESTROY", "s", "g"}}, noGroup, 0},
+		{"XREADGROUP flushed", []string{"XREADGROUP", "GROUP", "g", "c", "BLOCK", "0", "STREAMS", "s", ">"},
+			[][]string{{"FLUSHALL"}}, noGroup, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, addr := startTestServer(t)
+			c := dialTestServer(t, addr)
+			blocked := dialTestServer(t, addr)
+			c.mustDo("$3\r\n1-1\r\n", "XADD", "s", "1-1", "f", "v")
+			c.mustDo("+OK\r\n", "XGROUP", "CREATE", "s", "g", "$")
+
+			start := time.Now()
+			blocked.send(tt.block...)
+			waitBlockedClients(t, s, 1)
+			for _, args := range tt.commands {
+				c.do(args...)
+			}
+
+			if reply := blocked.read(5 * time.Second); reply != tt.reply {
+				t.Errorf("expected reply %q, got %q", tt.reply, reply)
+			}
+			if elapsed := time.Since(start); elapsed < tt.timeout {
+				t.Errorf("expected blocked for %v, got %v", tt.timeout, elapsed)
+			}
+			waitBlockedClients(t, s, 0)
+			blocked.mustDo("+PONG\r\n", "PING")
+		})
+	}
+}
+
+func TestStreamBlockedDisconnect(t *testing.T) {
+	s, addr := startTestServer(t)
+	c := dialTestServer(t, addr)
+	blocked := dialTestServer(t, addr)
+
+	blocked.send("XREAD", "BLOCK", "0", "STREAMS", "s", "$")
+	waitBlockedClients(t, s, 1)
+	blocked.conn.Close()
+	waitBlockedClients(t, s, 0)
+
+	// The closed client is not served when the key is ready.
+	c.mustDo("$3\r\n1-1\r\n", "XADD", "s", "1-1", "f", "v")
+	if reply := c.do("CLIENT", "LIST"); strings.Count(reply, "id=") != 1 {
+		t.Errorf("expected only the current client, got %q", reply)
+	}
+}
+
+// TestStreamBlockedSideEffects checks that a woken up command is seen once by
+// the monitors, the command stats and the slowlog.
+func TestStreamBlockedSideEffects(t *testing.T) {
+	s, addr := startTestServer(t, WithSlowlogLogSlowerThan(0))
+	c := dialTestServer(t, addr)
+	blocked := dialTestServer(t, addr)
+	monitor := dialTestServer(t, addr)
+	monitor.mustDo("+OK\r\n", "MONITOR")
+
+	blocked.send("XREAD", "BLOCK", "0", "STREAMS", "s", "$")
+	waitBlockedClients(t, s, 1)
+	c.mustDo("$3\r\n1-1\r\n", "XADD", "s", "1-1", "f", "v")
+	blocked.read(5 * time.Second)
+
+	xreads := 0
+	for {
+		line, err := monitor.readReply(200 * time.Millisecond)
+		if err != nil {
+			break
+		}
+		if strings.Contains(line, `"XREAD"`) {
+			xreads++
+		}
+	}
+	if xreads != 1 {
+		t.Errorf("expected XREAD fed to the monitor once, got %d", xreads)
+	}
+
+	if reply := c.do("INFO", "commandstats"); !strings.Contains(reply, "cmdstat_xread:calls=1,") {
+		t.Errorf("expected XREAD counted once, got %q", reply)
+	}
+	if reply := c.do("SLOWLOG", "GET", "-1"); strings.Count(reply, "$5\r\nXREAD\r\n") != 1 {
+		t.Errorf("expected XREAD logged once, got %q", reply)
+	}
+}
+
+// waitBlockedClients waits until the number of the blocked clients is n.
+func waitBlockedClients(t *testing.T, s *Server, n int) {
+	t.Helper()
+
+	for deadline := time.Now().Add(5 * time.Second); s.blockedClientCount() != n; {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d blocked clients, got %d", n, s.blockedClientCount())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"math"
 	"strconv"
 	"strings"
 	"time"
@@ -9,6 +10,18 @@ import (
 	"github.com/ghosind/antdb/core"
 )
 
+func (s *Server) appendCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	size, err := db.Append(args[0], args[1])
+	if err != nil {
+		return err
+	}
+
+	cli.ReplyInteger(size)
+	return nil
+}
+
 func (s *Server) decrCommand(cli *client.Client, args ...string) error {
 	db := s.databases[cli.DB]
 
@@ -52,46 +65,84 @@ func (s *Server) getCommand(cli *client.Client, args ...string) error {
 	if !found {
 		cli.ReplyNilBulk()
 	} else {
-		cli.ReplyBulkString(value)
+		replyStringValue(cli, value)
 	}
 
 	return nil
 }
 
+func (s *Server) strlenCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	size, err := db.StrLen(args[0])
+	if err != nil {
+		return err
+	}
+
+	cli.ReplyInteger(size)
+	return nil
+}
+
+// substrCommand is the same as GETRANGE.
 func (s *Server) substrCommand(cli *client.Client, args ...string) error {
+	return s.getRangeCommand(cli, args...)
+}
+
+func (s *Server) getDelCommand(cli *client.Client, args ...string) error {
 	db := s.databases[cli.DB]
 
-	key := args[0]
-	start, err := strconv.Atoi(args[1])
+	value, found, err := db.GetDel(args[0])
 	if err != nil {
-		return core.ErrNotInteger
+		return err
+	}
+
+	if !found {
+		cli.ReplyNilBulk()
+	} else {
+		replyStringValue(cli, value)
 	}
-	end, err := strconv.Atoi(args[2])
+	return nil
+}
+
+func (s *Server) getExCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	expires, persist, err := parseGetExOptions(args[1:])
 	if err != nil {
-		return core.ErrNotInteger
+		return err
 	}
 
-	value, found, err := db.Get(key)
+	value, found, err := db.GetEx(args[0], expires, persist)
 	if err != nil {
 		return err
 	}
 
-	str := ""
-	if found {
-		if start < 0 {
-			start = len(value) + start
-		}
-		if end < 0 {
-			end = len(value) + end
-		}
+	if !found {
+		cli.ReplyNilBulk()
+	} else {
+		replyStringValue(cli, value)
+	}
+	return nil
+}
 
-		if start < len(value) && end < len(value) && start <= end {
-			str = value[start : end+1]
-		}
+func (s *Server) getRangeCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	start, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return core.ErrNotInteger
+	}
+	end, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil {
+		return core.ErrNotInteger
 	}
 
-	cli.ReplyBulkString(str)
+	value, err := db.GetRange(args[0], start, end)
+	if err != nil {
+		return err
+	}
 
+	replyStringValue(cli, value)
 	return nil
 }
 
@@ -99,7 +150,7 @@ func (s *Server) getSetCommand(cli *client.Client, args ...string) error {
 	key := args[0]
 	value := args[1]
 
-	return s.genericSetCommand(cli, key, value, 0, 0, true)
+	return s.genericSetCommand(cli, key, value, core.SetFlagGet, 0)
 }
 
 func (s *Server) incrCommand(cli *client.Client, args ...string) error {
@@ -133,6 +184,108 @@ func (s *Server) incrByCommand(cli *client.Client, args ...string) error {
 	return nil
 }
 
+func (s *Server) incrByFloatCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	incrBy, err := strconv.ParseFloat(args[1], 64)
+	if err != nil || math.IsNaN(incrBy) || math.IsInf(incrBy, 0) {
+		return core.ErrNotFloat
+	}
+
+	val, err := db.IncrByFloat(args[0], incrBy)
+	if err != nil {
+		return err
+	}
+
+	cli.ReplyBulkString(val)
+	return nil
+}
+
+func (s *Server) lcsCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	getLen, getIdx, withMatchLen := false, false, false
+	minMatchLen := int64(0)
+	for i := 2; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "LEN":
+			getLen = true
+		case "IDX":
+			getIdx = true
+		case "WITHMATCHLEN":
+			withMatchLen = true
+		case "MINMATCHLEN":
+			if i+1 >= len(args) {
+				return ErrSyntax
+			}
+			i++
+			val, err := strconv.ParseInt(args[i], 10, 64)
+			if err != nil {
+				return core.ErrNotInteger
+			}
+			if val > 0 {
+				minMatchLen = val
+			}
+		default:
+			return ErrSyntax
+		}
+	}
+	if getLen && getIdx {
+		return ErrLCSLenAndIdx
+	}
+
+	a, _, err := db.Get(args[0])
+	if err != nil {
+		return err
+	}
+	b, _, err := db.Get(args[1])
+	if err != nil {
+		return err
+	}
+	if (int64(len(a))+1)*(int64(len(b))+1)*4 > s.protoMaxBulkLen {
+		return ErrLCSTooLarge
+	}
+
+	str, matches := lcs(a, b)
+	if getLen {
+		cli.ReplyInteger(int64(len(str)))
+		return nil
+	} else if !getIdx {
+		replyStringValue(cli, str)
+		return nil
+	}
+
+	filtered := make([]lcsMatch, 0, len(matches))
+	for _, match := range matches {
+		if int64(match.len) >= minMatchLen {
+			filtered = append(filtered, match)
+		}
+	}
+
+	cli.ReplyArrayLength(4)
+	cli.ReplyBulkString("matches")
+	cli.ReplyArrayLength(int64(len(filtered)))
+	for _, match := range filtered {
+		if withMatchLen {
+			cli.ReplyArrayLength(3)
+		} else {
+			cli.ReplyArrayLength(2)
+		}
+		cli.ReplyArrayLength(2)
+		cli.ReplyInteger(int64(match.aStart))
+		cli.ReplyInteger(int64(match.aStart + match.len - 1))
+		cli.ReplyArrayLength(2)
+		cli.ReplyInteger(int64(match.bStart))
+		cli.ReplyInteger(int64(match.bStart + match.len - 1))
+		if withMatchLen {
+			cli.ReplyInteger(int64(match.len))
+		}
+	}
+	cli.ReplyBulkString("len")
+	cli.ReplyInteger(int64(len(str)))
+	return nil
+}
+
 func (s *Server) mgetCommand(cli *client.Client, args ...string) error {
 	db := s.databases[cli.DB]
 
@@ -142,7 +295,7 @@ func (s *Server) mgetCommand(cli *client.Client, args ...string) error {
 		if err != nil || !found {
 			cli.ReplyNilBulk()
 		} else {
-			cli.ReplyBulkString(value)
+			replyStringValue(cli, value)
 		}
 	}
 
@@ -183,54 +336,77 @@ func (s *Server) msetnxCommand(cli *client.Client, args ...string) error {
 	return nil
 }
 
-func (s *Server) setCommand(cli *client.Client, args ...string) (err error) {
+func (s *Server) setCommand(cli *client.Client, args ...string) error {
 	key := args[0]
 	value := args[1]
-	flag := core.SetFlag(0)
-	expires := int64(0)
-	skip := false
 
-	for i, v := range args {
-		if skip || i < 2 {
-			skip = false
-			continue
-		}
+	flag, expires, err := parseSetOptions(args[2:])
+	if err != nil {
+		return err
+	}
 
-		switch strings.ToUpper(v) {
-		case "NX":
-			flag |= core.SetFlagNX
-		case "XX":
-			flag |= core.SetFlagXX
-		case "EX":
-			if i+1 >= len(args) {
-				return ErrSyntax
-			}
-			expires, err = strconv.ParseInt(args[i+1], 10, 64)
-			if err != nil {
-				return err
-			}
-			expires = expires*1000 + time.Now().UnixMilli()
-			skip = true
-		case "PX":
-			if i+1 >= len(args) {
-				return ErrSyntax
-			}
-			expires, err = strconv.ParseInt(args[i+1], 10, 64)
-			if err != nil {
-				return err
-			}
-			skip = true
-		}
+	return s.genericSetCommand(cli, key, value, flag, expires)
+}
+
+func (s *Server) setexCommand(cli *client.Client, args ...string) error {
+	return s.genericSetexCommand(cli, "setex", args[0], args[2], args[1], 1000)
+}
+
+func (s *Server) psetexCommand(cli *client.Client, args ...string) error {
+	return s.genericSetexCommand(cli, "psetex", args[0], args[2], args[1], 1)
+}
+
+func (s *Server) genericSetexCommand(cli *client.Client, cmd, key, value, ttl string, unit int64) error {
+	when, err := strconv.ParseInt(ttl, 10, 64)
+	if err != nil {
+		return core.ErrNotInteger
+	} else if when <= 0 {
+		return newInvalidExpireTimeError(cmd)
+	}
+
+	expires, ok := toExpireMillis(when, time.Now().UnixMilli(), unit)
+	if !ok {
+		return newInvalidExpireTimeError(cmd)
+	}
+
+	return s.genericSetCommand(cli, key, value, 0, expires)
+}
+
+func (s *Server) setRangeCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
+	offset, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return core.ErrNotInteger
+	} else if offset < 0 {
+		return ErrOffsetOutOfRange
+	}
+
+	size, err := db.SetRange(args[0], offset, args[2])
+	if err != nil {
+		return err
 	}
 
-	return s.genericSetCommand(cli, key, value, flag, expires, false)
+	cli.ReplyInteger(size)
+	return nil
 }
 
 func (s *Server) setnxCommand(cli *client.Client, args ...string) error {
+	db := s.databases[cli.DB]
+
 	key := args[0]
 	value := args[1]
 
-	return s.genericSetCommand(cli, key, value, core.SetFlagNX, 9, false)
+	ok, _, _, err := db.Set(key, value, core.SetFlagNX, 0)
+	if err != nil {
+		return err
+	}
+	if ok {
+		cli.ReplyInteger(1)
+	} else {
+		cli.ReplyInteger(0)
+	}
+	return nil
 }
 
 func (s *Server) genericSetCommand(
@@ -238,21 +414,195 @@ func (s *Server) genericSetCommand(
 	key, value string,
 	flag core.SetFlag,
 	expires int64,
-	getOld bool,
 ) error {
 	db := s.databases[cli.DB]
 
-	ok, oldVal, err := db.Set(key, value, flag, expires)
+	ok, oldVal, oldFound, err := db.Set(key, value, flag, expires)
 	if err != nil {
 		return err
 	}
-	if !ok || (getOld && oldVal == "") {
+	if flag&core.SetFlagGet != 0 {
+		if oldFound {
+			replyStringValue(cli, oldVal)
+		} else {
+			cli.ReplyNilBulk()
+		}
+	} else if !ok {
 		cli.ReplyNilBulk()
-	} else if getOld {
-		cli.ReplyBulkString(oldVal)
 	} else {
 		cli.ReplySimpleString("OK")
 	}
 
 	return nil
 }
+
+// parseSetOptions parses the NX, XX, GET, KEEPTTL, EX, PX, EXAT and PXAT
+// options of SET, and returns the expiration in unix milliseconds.
+func parseSetOptions(args []string) (core.SetFlag, int64, error) {
+	flag := core.SetFlag(0)
+	expires := int64(0)
+	hasExpire := false
+
+	for i := 0; i < len(args); i++ {
+		opt := strings.ToUpper(args[i])
+		switch opt {
+		case "NX":
+			if flag&core.SetFlagXX != 0 {
+				return 0, 0, ErrSyntax
+			}
+			flag |= core.SetFlagNX
+		case "XX":
+			if flag&core.SetFlagNX != 0 {
+				return 0, 0, ErrSyntax
+			}
+			flag |= core.SetFlagXX
+		case "GET":
+			flag |= core.SetFlagGet
+		case "KEEPTTL":
+			if hasExpire {
+				return 0, 0, ErrSyntax
+			}
+			hasExpire = true
+			flag |= core.SetFlagKeepTTL
+		case "EX", "PX", "EXAT", "PXAT":
+			if hasExpire || i+1 >= len(args) {
+				return 0, 0, ErrSyntax
+			}
+			hasExpire = true
+			i++
+
+			when, err := strconv.ParseInt(args[i], 10, 64)
+			if err != nil {
+				return 0, 0, core.ErrNotInteger
+			} else if when <= 0 {
+				return 0, 0, newInvalidExpireTimeError("set")
+			}
+
+			basetime, unit := int64(0), int64(1)
+			if opt == "EX" || opt == "PX" {
+				basetime = time.Now().UnixMilli()
+			}
+			if opt == "EX" || opt == "EXAT" {
+				unit = 1000
+			}
+			var ok bool
+			expires, ok = toExpireMillis(when, basetime, unit)
+			if !ok {
+				return 0, 0, newInvalidExpireTimeError("set")
+			}
+		default:
+			return 0, 0, ErrSyntax
+		}
+	}
+
+	return flag, expires, nil
+}
+
+// lcsMatch is a range of the longest common subsequence that is contiguous in
+// both strings.
+type lcsMatch struct {
+	aStart int
+	bStart int
+	len    int
+}
+
+// lcs returns the longest common subsequence of the strings, and its contiguous
+// ranges from the end of the strings.
+func lcs(a, b string) (string, []lcsMatch) {
+	width := len(b) + 1
+	table := make([]uint32, (len(a)+1)*width)
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			if a[i-1] == b[j-1] {
+				table[i*width+j] = table[(i-1)*width+j-1] + 1
+			} else if table[(i-1)*width+j] > table[i*width+j-1] {
+				table[i*width+j] = table[(i-1)*width+j]
+			} else {
+				table[i*width+j] = table[i*width+j-1]
+			}
+		}
+	}
+
+	size := int(table[len(a)*width+len(b)])
+	result := make([]byte, size)
+	matches := make([]lcsMatch, 0)
+	var match *lcsMatch
+	for i, j, k := len(a), len(b), size; i > 0 && j > 0; {
+		if a[i-1] == b[j-1] {
+			k--
+			result[k] = a[i-1]
+			i--
+			j--
+			if match != nil && match.aStart == i+1 && match.bStart == j+1 {
+				match.aStart, match.bStart = i, j
+				match.len++
+			} else {
+				matches = append(matches, lcsMatch{aStart: i, bStart: j, len: 1})
+				match = &matches[len(matches)-1]
+			}
+			continue
+		}
+
+		match = nil
+		if table[(i-1)*width+j] > table[i*width+j-1] {
+			i--
+		} else {
+			j--
+		}
+	}
+
+	return string(result), matches
+}
+
+// parseGetExOptions parses the EX, PX, EXAT, PXAT and PERSIST options of GETEX,
+// and returns the expiration in unix milliseconds.
+func parseGetExOptions(args []string) (int64, bool, error) {
+	if len(args) == 0 {
+		return 0, false, nil
+	}
+
+	opt := strings.ToUpper(args[0])
+	switch opt {
+	case "PERSIST":
+		if len(args) != 1 {
+			return 0, false, ErrSyntax
+		}
+		return 0, true, nil
+	case "EX", "PX", "EXAT", "PXAT":
+		if len(args) != 2 {
+			return 0, false, ErrSyntax
+		}
+
+		when, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return 0, false, core.ErrNotInteger
+		} else if when <= 0 {
+			return 0, false, newInvalidExpireTimeError("getex")
+		}
+
+		basetime, unit := int64(0), int64(1)
+		if opt == "EX" || opt == "PX" {
+			basetime = time.Now().UnixMilli()
+		}
+		if opt == "EX" || opt == "EXAT" {
+			unit = 1000
+		}
+		expires, ok := toExpireMillis(when, basetime, unit)
+		if !ok {
+			return 0, false, newInvalidExpireTimeError("getex")
+		}
+		return expires, false, nil
+	default:
+		return 0, false, ErrSyntax
+	}
+}
+
+// replyStringValue replies the string value as a bulk string, which is empty
+// rather than nil if the value is empty.
+func replyStringValue(cli *client.Client, value string) {
+	if value == "" {
+		cli.ReplyEmptyBulk()
+	} else {
+		cli.ReplyBulkString(value)
+	}
+}
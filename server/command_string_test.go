@@ -0,0 +1,268 @@
+package server
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ghosind/antdb/core"
+)
+
+func TestParseSetOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		flag core.SetFlag
+		// expires is the expected expiration in milliseconds, which is relative to
+		// the time of the call if relative is set.
+		expires  int64
+		relative bool
+		err      error
+	}{
+		{"none", nil, 0, 0, false, nil},
+		{"NX", []string{"NX"}, core.SetFlagNX, 0, false, nil},
+		{"XX", []string{"xx"}, core.SetFlagXX, 0, false, nil},
+		{"GET", []string{"GET"}, core.SetFlagGet, 0, false, nil},
+		{"NX GET", []string{"NX", "GET"}, core.SetFlagNX | core.SetFlagGet, 0, false, nil},
+		{"KEEPTTL", []string{"KEEPTTL"}, core.SetFlagKeepTTL, 0, false, nil},
+		{"EX", []string{"EX", "100"}, 0, 100000, true, nil},
+		{"PX", []string{"PX", "100000"}, 0, 100000, true, nil},
+		{"EXAT", []string{"EXAT", "1700000000"}, 0, 1700000000000, false, nil},
+		{"PXAT", []string{"PXAT", "1700000000000"}, 0, 1700000000000, false, nil},
+		{"XX PX GET", []string{"XX", "px", "100", "GET"}, core.SetFlagXX | core.SetFlagGet, 100, true, nil},
+		{"NX and XX", []string{"NX", "XX"}, 0, 0, false, ErrSyntax},
+		{"XX and NX", []string{"XX", "NX"}, 0, 0, false, ErrSyntax},
+		{"EX and PX", []string{"EX", "10", "PX", "10000"}, 0, 0, false, ErrSyntax},
+		{"EX and KEEPTTL", []string{"EX", "10", "KEEPTTL"}, 0, 0, false, ErrSyntax},
+		{"KEEPTTL and PXAT", []string{"KEEPTTL", "PXAT", "1700000000000"}, 0, 0, false, ErrSyntax},
+		{"missing value", []string{"EX"}, 0, 0, false, ErrSyntax},
+		{"unknown option", []string{"FOO"}, 0, 0, false, ErrSyntax},
+		{"not integer", []string{"EX", "abc"}, 0, 0, false, core.ErrNotInteger},
+		{"zero", []string{"PX", "0"}, 0, 0, false, newInvalidExpireTimeError("set")},
+		{"negative", []string{"EXAT", "-1"}, 0, 0, false, newInvalidExpireTimeError("set")},
+		{"EX overflow", []string{"EX", strconv.FormatInt(math.MaxInt64/1000+1, 10)},
+			0, 0, false, newInvalidExpireTimeError("set")},
+		{"PX overflow", []string{"PX", strconv.FormatInt(math.MaxInt64, 10)},
+			0, 0, false, newInvalidExpireTimeError("set")},
+		{"PXAT largest", []string{"PXAT", strconv.FormatInt(math.MaxInt64, 10)}, 0, math.MaxInt64, false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := time.Now().UnixMilli()
+			flag, expires, err := parseSetOptions(tt.args)
+			after := time.Now().UnixMilli()
+
+			if tt.err != nil {
+				if err == nil || err.Error() != tt.err.Error() {
+					t.Fatalf("parseSetOptions(%v): expected error %v, got %v", tt.args, tt.err, err)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("parseSetOptions(%v): unexpected error %v", tt.args, err)
+			}
+
+			if flag != tt.flag {
+				t.Errorf("parseSetOptions(%v): expected flag %d, got %d", tt.args, tt.flag, flag)
+			}
+			if tt.relative {
+				if expires < before+tt.expires || expires > after+tt.expires {
+					t.Errorf("parseSetOptions(%v): expected expires %d after now, got %d",
+						tt.args, tt.expires, expires-after)
+				}
+			} else if expires != tt.expires {
+				t.Errorf("parseSetOptions(%v): expected expires %d, got %d", tt.args, tt.expires, expires)
+			}
+		})
+	}
+}
+
+func TestSetExpiration(t *testing.T) {
+	tests := []struct {
+		name string
+		// ttl is the TTL of the key in seconds before the command, where 0 is
+		// no expiration and -1 is no key.
+		ttl   int
+		args  []string
+		reply string
+		// expected is the TTL in seconds after the command, with the same
+		// meaning as ttl.
+		expected int
+		value    string
+	}{
+		{"SET", 50, []string{"SET", "k", "v2"}, "+OK\r\n", 0, "v2"},
+		{"SET EX", 0, []string{"SET", "k", "v2", "EX", "100"}, "+OK\r\n", 100, "v2"},
+		{"SET PX", 0, []string{"SET", "k", "v2", "PX", "100000"}, "+OK\r\n", 100, "v2"},
+		{"SET EXAT", 0, []string{"SET", "k", "v2", "EXAT",
+			strconv.FormatInt(time.Now().Unix()+100, 10)}, "+OK\r\n", 100, "v2"},
+		{"SET PXAT", 0, []string{"SET", "k", "v2", "PXAT",
+			strconv.FormatInt(time.Now().UnixMilli()+100000, 10)}, "+OK\r\n", 100, "v2"},
+		{"SET KEEPTTL", 50, []string{"SET", "k", "v2", "KEEPTTL"}, "+OK\r\n", 50, "v2"},
+		{"SET KEEPTTL without ttl", 0, []string{"SET", "k", "v2", "KEEPTTL"}, "+OK\r\n", 0, "v2"},
+		{"SET GET", 50, []string{"SET", "k", "v2", "GET"}, "$1\r\nv\r\n", 0, "v2"},
+		{"SET GET no key", -1, []string{"SET", "k", "v2", "GET"}, "$-1\r\n", 0, "v2"},
+		{"SET GET KEEPTTL", 50, []string{"SET", "k", "v2", "GET", "KEEPTTL"}, "$1\r\nv\r\n", 50, "v2"},
+		{"SET NX", 50, []string{"SET", "k", "v2", "NX", "EX", "100"}, "$-1\r\n", 50, "v"},
+		{"SET NX no key", -1, []string{"SET", "k", "v2", "NX", "PX", "100000"}, "+OK\r\n", 100, "v2"},
+		{"SET XX", 50, []string{"SET", "k", "v2", "XX", "PX", "100000"}, "+OK\r\n", 100, "v2"},
+		{"SET XX no key", -1, []string{"SET", "k", "v2", "XX", "EX", "100"}, "$-1\r\n", -1, ""},
+		{"SET invalid", 50, []string{"SET", "k", "v2", "EX", "100", "KEEPTTL"},
+			"-" + ErrSyntax.Error() + "\r\n", 50, "v"},
+		{"SETNX", -1, []string{"SETNX", "k", "v2"}, ":1\r\n", 0, "v2"},
+		{"SETNX exists", 50, []string{"SETNX", "k", "v2"}, ":0\r\n", 50, "v"},
+		{"SETEX", 50, []string{"SETEX", "k", "100", "v2"}, "+OK\r\n", 100, "v2"},
+		{"PSETEX", 0, []string{"PSETEX", "k", "100000", "v2"}, "+OK\r\n", 100, "v2"},
+		{"SETEX zero", 50, []string{"SETEX", "k", "0", "v2"},
+			"-" + newInvalidExpireTimeError("setex").Error() + "\r\n", 50, "v"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t)
+			if tt.ttl >= 0 {
+				c.mustDo("+OK\r\n", "SET", "k", "v")
+			}
+			if tt.ttl > 0 {
+				c.mustDo(":1\r\n", "EXPIRE", "k", strconv.Itoa(tt.ttl))
+			}
+
+			c.mustDo(tt.reply, tt.args...)
+			assertTTL(t, c, "k", tt.expected)
+			if tt.expected >= 0 {
+				c.mustDo("$"+strconv.Itoa(len(tt.value))+"\r\n"+tt.value+"\r\n", "GET", "k")
+			}
+		})
+	}
+}
+
+// TestSetExpirationNotExpired checks that a key set with a relative expiration
+// is not expired by the next command, where a relative time stored as absolute
+// is in the past.
+func TestSetExpirationNotExpired(t *testing.T) {
+	for _, args := range [][]string{
+		{"SET", "k", "v", "PX", "100"},
+		{"SET", "k", "v", "EX", "1"},
+		{"PSETEX", "k", "100", "v"},
+		{"SETEX", "k", "1", "v"},
+	} {
+		t.Run(strings.Join(args, " "), func(t *testing.T) {
+			c := newTestClient(t)
+			c.mustDo("+OK\r\n", args...)
+			c.mustDo(":1\r\n", "EXISTS", "k")
+		})
+	}
+}
+
+func TestGetRange(t *testing.T) {
+	tests := []struct {
+		start string
+		end   string
+		reply string
+	}{
+		{"0", "4", "$5\r\nHello\r\n"},
+		{"0", "-1", "$11\r\nHello World\r\n"},
+		{"-5", "-1", "$5\r\nWorld\r\n"},
+		{"-100", "4", "$5\r\nHello\r\n"},
+		{"6", "100", "$5\r\nWorld\r\n"},
+		{"-100", "100", "$11\r\nHello World\r\n"},
+		{"10", "10", "$1\r\nd\r\n"},
+		{"5", "3", "$0\r\n\r\n"},
+		{"-1", "-5", "$0\r\n\r\n"},
+		{"11", "20", "$0\r\n\r\n"},
+		{"20", "30", "$0\r\n\r\n"},
+		{"-100", "-50", "$1\r\nH\r\n"},
+		{"a", "1", "-value is not an integer or out of range\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.start+" "+tt.end, func(t *testing.T) {
+			c := newTestClient(t)
+			c.mustDo("+OK\r\n", "SET", "k", "Hello World")
+			c.mustDo(tt.reply, "GETRANGE", "k", tt.start, tt.end)
+		})
+	}
+}
+
+func TestEmptyBulkReplies(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup [][]string
+		args  []string
+		reply string
+	}{
+		{"GETRANGE of an empty range", nil, []string{"GETRANGE", "k", "5", "3"}, "$0\r\n\r\n"},
+		{"GETRANGE of a missing key", nil, []string{"GETRANGE", "none", "0", "-1"}, "$0\r\n\r\n"},
+		{"GETRANGE of an empty string", nil, []string{"GETRANGE", "e", "0", "-1"}, "$0\r\n\r\n"},
+		{"GET of an empty string", nil, []string{"GET", "e"}, "$0\r\n\r\n"},
+		{"GET of a missing key", nil, []string{"GET", "none"}, "$-1\r\n"},
+		{"MGET of an empty string", nil, []string{"MGET", "e", "none", "k"}, "*3\r\n$0\r\n\r\n$-1\r\n$3\r\nabc\r\n"},
+		{"SET GET of an empty string", nil, []string{"SET", "e", "v", "GET"}, "$0\r\n\r\n"},
+		{"GETDEL of an empty string", nil, []string{"GETDEL", "e"}, "$0\r\n\r\n"},
+		{"GETDEL of a missing key", nil, []string{"GETDEL", "none"}, "$-1\r\n"},
+		{"GETEX of an empty string", nil, []string{"GETEX", "e", "EX", "100"}, "$0\r\n\r\n"},
+		{"GETEX PERSIST of an empty string", nil, []string{"GETEX", "e", "PERSIST"}, "$0\r\n\r\n"},
+		{"GETEX of a missing key", nil, []string{"GETEX", "none"}, "$-1\r\n"},
+		{"LCS without a common subsequence", [][]string{{"SET", "x", "xyz"}},
+			[]string{"LCS", "k", "x"}, "$0\r\n\r\n"},
+		{"LCS of a missing key", nil, []string{"LCS", "k", "none"}, "$0\r\n\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t)
+			c.mustDo("+OK\r\n", "SET", "k", "abc")
+			c.mustDo("+OK\r\n", "SET", "e", "")
+			for _, args := range tt.setup {
+				c.do(args...)
+			}
+			c.mustDo(tt.reply, tt.args...)
+		})
+	}
+}
+
+func TestLCS(t *testing.T) {
+	// The matches of "ohmytext" and "mynewtext" are "text" at 4-7 and 5-8, and
+	// "my" at 2-3 and 0-1.
+	textMatch := "*2\r\n:4\r\n:7\r\n*2\r\n:5\r\n:8\r\n"
+	myMatch := "*2\r\n:2\r\n:3\r\n*2\r\n:0\r\n:1\r\n"
+	tests := []struct {
+		name  string
+		args  []string
+		reply string
+	}{
+		{"string", []string{}, "$6\r\nmytext\r\n"},
+		{"LEN", []string{"LEN"}, ":6\r\n"},
+		{"IDX", []string{"IDX"},
+			"*4\r\n$7\r\nmatches\r\n*2\r\n*2\r\n" + textMatch + "*2\r\n" + myMatch + "$3\r\nlen\r\n:6\r\n"},
+		{"IDX MINMATCHLEN", []string{"IDX", "MINMATCHLEN", "4"},
+			"*4\r\n$7\r\nmatches\r\n*1\r\n*2\r\n" + textMatch + "$3\r\nlen\r\n:6\r\n"},
+		{"IDX MINMATCHLEN over all matches", []string{"IDX", "MINMATCHLEN", "5"},
+			"*4\r\n$7\r\nmatches\r\n*0\r\n$3\r\nlen\r\n:6\r\n"},
+		{"IDX WITHMATCHLEN", []string{"IDX", "WITHMATCHLEN"},
+			"*4\r\n$7\r\nmatches\r\n*2\r\n*3\r\n" + textMatch + ":4\r\n*3\r\n" + myMatch + ":2\r\n$3\r\nlen\r\n:6\r\n"},
+		{"IDX MINMATCHLEN WITHMATCHLEN", []string{"idx", "minmatchlen", "4", "withmatchlen"},
+			"*4\r\n$7\r\nmatches\r\n*1\r\n*3\r\n" + textMatch + ":4\r\n$3\r\nlen\r\n:6\r\n"},
+		{"LEN and IDX", []string{"LEN", "IDX"}, "-If you want both the length and indexes, please just use IDX.\r\n"},
+		{"MINMATCHLEN without value", []string{"IDX", "MINMATCHLEN"}, "-" + ErrSyntax.Error() + "\r\n"},
+		{"MINMATCHLEN not integer", []string{"IDX", "MINMATCHLEN", "a"}, "-value is not an integer or out of range\r\n"},
+		{"unknown option", []string{"FOO"}, "-" + ErrSyntax.Error() + "\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t)
+			c.mustDo("+OK\r\n", "MSET", "key1", "ohmytext", "key2", "mynewtext")
+			c.mustDo(tt.reply, append([]string{"LCS", "key1", "key2"}, tt.args...)...)
+		})
+	}
+}
+
+func TestLCSWrongType(t *testing.T) {
+	c := newTestClient(t)
+	c.mustDo("+OK\r\n", "SET", "a", "abc")
+	c.mustDo(":1\r\n", "RPUSH", "l", "abc")
+	c.mustDo("-"+core.ErrWrongType.Error()+"\r\n", "LCS", "a", "l")
+	c.mustDo("$3\r\nabc\r\n", "LCS", "a", "a")
+}
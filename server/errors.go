@@ -1,18 +1,160 @@
 package server
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ghosind/antdb/core"
+)
 
 var (
 	ErrSyntax          = errors.New("syntax error")
 	ErrInvalidDBIndex  = errors.New("value is not an integer or out of range")
-	ErrInvalidPassword = errors.New("invalid password")
+	ErrInvalidPassword = errors.New("WRONGPASS invalid username-password pair or user is disabled.")
 	ErrNotPermitted    = errors.New("operation not permitted")
+	ErrOOM             = errors.New("OOM command not allowed when used memory > 'maxmemory'")
+	ErrInvalidCursor   = errors.New("invalid cursor")
+	ErrSameObject      = errors.New("source and destination objects are the same")
+
+	ErrOffsetOutOfRange = errors.New("offset is out of range")
+	ErrLCSLenAndIdx     = errors.New("If you want both the length and indexes, please just use IDX.")
+	ErrLCSTooLarge      = errors.New("Insufficient memory, transient memory for LCS exceeds proto-max-bulk-len")
+
+	ErrBitOffset   = errors.New("bit offset is not an integer or out of range")
+	ErrBitValue    = errors.New("bit is not an integer or out of range")
+	ErrBitArgument = errors.New("The bit argument must be 1 or 0.")
+	ErrBitOpNot    = errors.New("BITOP NOT must be called with a single source key.")
+
+	ErrBitFieldType     = errors.New("Invalid bitfield type. Use something like i16 u8. Note that u64 is not supported but i64 is.")
+	ErrBitFieldOverflow = errors.New("Invalid OVERFLOW type specified")
+	ErrBitFieldReadOnly = errors.New("BITFIELD_RO only supports the GET subcommand")
+
+	ErrKeyNotExist = errors.New("The specified key does not exist")
+
+	ErrNumKeys    = errors.New("numkeys should be greater than 0")
+	ErrListCount  = errors.New("count should be greater than 0")
+	ErrLPosRank   = errors.New("RANK can't be zero: use 1 to start from the first match, 2 from the second ... or use negative to start from the end of the list")
+	ErrLPosCount  = errors.New("COUNT can't be negative")
+	ErrLPosMaxLen = errors.New("MAXLEN can't be negative")
+
+	ErrNXAndXX           = errors.New("XX and NX options at the same time are not compatible")
+	ErrGeoUnit           = errors.New("unsupported unit provided. please use M, KM, FT, MI")
+	ErrGeoRadiusNegative = errors.New("radius cannot be negative")
+	ErrGeoBoxNegative    = errors.New("height or width cannot be negative")
+	ErrCountNotPositive  = errors.New("COUNT must be > 0")
+
+	ErrStreamMaxLen             = errors.New("The MAXLEN argument must be >= 0.")
+	ErrStreamLimit              = errors.New("The LIMIT argument must be >= 0.")
+	ErrStreamLimitWithoutApprox = errors.New("syntax error, LIMIT cannot be used without the special ~ option")
+	ErrStreamMaxLenAndMinID     = errors.New("syntax error, MAXLEN and MINID options at the same time are not compatible")
+	ErrStreamInvalidStart       = errors.New("invalid start ID for the interval")
+	ErrStreamInvalidEnd         = errors.New("invalid end ID for the interval")
+	ErrStreamTimeout            = errors.New("timeout is not an integer or out of range")
+	ErrStreamGroupOption        = errors.New("The GROUP option is only supported by XREADGROUP. You called XREAD instead.")
+	ErrStreamMissingGroup       = errors.New("Missing GROUP option for XREADGROUP")
+	ErrStreamNewIDWithoutGroup  = errors.New("The > ID can be specified only when calling XREADGROUP using the GROUP <group> <consumer> option.")
+	ErrStreamLastIDWithGroup    = errors.New("The $ ID is meaningless in the context of XREADGROUP: you want to read the history " +
+		"of this consumer by specifying a proper ID, or use the > ID to get new messages. The $ ID would just return an empty result set.")
+	ErrStreamEntriesRead = errors.New("value for ENTRIESREAD must be positive or -1")
+	ErrStreamKeyNotExist = errors.New("The XGROUP subcommand requires the key to exist. " +
+		"Note that for CREATE you may want to use the MKSTREAM option to create an empty stream automatically.")
+
+	ErrExpireNXIncompatible   = errors.New("NX and XX, GT or LT options at the same time are not compatible")
+	ErrExpireGTLTIncompatible = errors.New("GT and LT options at the same time are not compatible")
+
+	ErrACLNoFile            = errors.New("This instance is not configured to use an ACL file")
+	ErrACLDeleteDefaultUser = errors.New("The 'default' user cannot be removed")
+	ErrNoKeyPermission      = errors.New("NOPERM No permissions to access a key")
+
+	ErrMaxClients        = errors.New("ERR max number of clients reached")
+	ErrInvalidClientID   = errors.New("client-id should be greater than 0")
+	ErrInvalidClientName = errors.New("Client names cannot contain spaces, newlines or special characters.")
+	ErrNoSuchClient      = errors.New("No such client")
+	ErrInvalidTimeout    = errors.New("timeout is negative")
+
+	ErrInvalidCommand     = errors.New("Invalid command specified")
+	ErrInvalidCommandArgs = errors.New("Invalid number of arguments specified for command")
+	ErrNoKeyArguments     = errors.New("The command has no key arguments")
+
+	ErrProtectedMode = errors.New("DENIED AntDB is running in protected mode because protected mode is enabled " +
+		"and no password is set for the default user. In this mode connections are only accepted from the " +
+		"loopback interface. If you want to connect from external computers, you may either set a password " +
+		"with requirepass or an ACL user, or disable protected mode with 'protected-mode no' in the " +
+		"configuration file and restart the server, after making sure the instance is not reachable from the internet.")
 )
 
 func newUnknownCommandError(cmd string) error {
 	return errors.New("unknown command '" + cmd + "'")
 }
 
+func newInvalidConfigError(name, value string) error {
+	return errors.New("invalid value '" + value + "' for '" + name + "'")
+}
+
+func newUnknownSubcommandError(cmd, subcommand string) error {
+	return errors.New("unknown subcommand '" + subcommand + "' for '" + cmd + "' command")
+}
+
+func newInvalidExpireTimeError(cmd string) error {
+	return errors.New("invalid expire time in '" + cmd + "' command")
+}
+
+func newUnsupportedOptionError(option string) error {
+	return errors.New("Unsupported option " + option)
+}
+
+func newUnknownTypeError(typ string) error {
+	return errors.New("unknown type name '" + typ + "'")
+}
+
+func newNoCommandPermissionError(user, cmd string) error {
+	return errors.New("NOPERM User " + user + " has no permissions to run the '" + cmd + "' command")
+}
+
+func newUnknownACLCategoryError(category string) error {
+	return errors.New("Unknown category '" + category + "'")
+}
+
+func newUnknownClientTypeError(typ string) error {
+	return errors.New("Unknown client type '" + typ + "'")
+}
+
+func newBitOpSourceKeysError(op string) error {
+	return errors.New("BITOP " + op + " must be called with at least two source keys.")
+}
+
+func newInvalidGeoPointError(lon, lat float64) error {
+	return fmt.Errorf("invalid longitude,latitude pair %f,%f", lon, lat)
+}
+
+func newGeoSearchFromError(cmd string) error {
+	return errors.New("exactly one of FROMMEMBER or FROMLONLAT can be specified for " + cmd)
+}
+
+func newGeoSearchByError(cmd string) error {
+	return errors.New("exactly one of BYRADIUS and BYBOX can be specified for " + cmd)
+}
+
+func newStreamUnbalancedError(cmd, id string) error {
+	return errors.New("Unbalanced '" + cmd + "' list of streams: for each stream key an ID or '" + id + "' must be specified.")
+}
+
+func newStreamNoGroupError(key, group string) error {
+	return errors.New("NOGROUP No such consumer group '" + group + "' for key name '" + key + "'")
+}
+
+func newStreamReadNoGroupError(err *core.NoGroupError) error {
+	return errors.New(err.Error() + " in XREADGROUP with GROUP option")
+}
+
+func newStreamMinIdleError(cmd string) error {
+	return errors.New("Invalid min-idle-time argument for " + cmd)
+}
+
+func newStreamClaimOptionError(option string) error {
+	return errors.New("Invalid " + option + " option argument for XCLAIM")
+}
+
 func newWrongArityError(cmd string) error {
 	return errors.New("wrong number of arguments for '" + cmd + "' command")
 }
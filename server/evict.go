@@ -0,0 +1,52 @@
+package server
+
+import "github.com/ghosind/antdb/core"
+
+// usedMemory returns the estimated memory used by the keys of all databases.
+func (s *Server) usedMemory() int64 {
+	used := int64(0)
+	for _, db := range s.databases {
+		used += db.UsedMemory()
+	}
+	return used
+}
+
+// performEvictions frees memory until the used memory is under the maxmemory
+// limit. It runs in the loop goroutine of the database, and evicts the keys of
+// that database first, then the keys of the other databases in their own loop
+// goroutines, so ErrOOM is returned only if no database can free enough memory.
+func (s *Server) performEvictions(dbIndex int) error {
+	if s.usedMemory() <= s.maxMemory {
+		return nil
+	}
+	if s.maxMemoryPolicy == core.EvictionNoEviction {
+		return ErrOOM
+	}
+
+	s.evictDatabase(dbIndex)
+	for i := range s.databases {
+		if s.usedMemory() <= s.maxMemory {
+			return nil
+		}
+		if i != dbIndex {
+			s.runInDatabase(dbIndex, i, func(*core.Database) {
+				s.evictDatabase(i)
+			})
+		}
+	}
+
+	if s.usedMemory() <= s.maxMemory {
+		return nil
+	}
+	return ErrOOM
+}
+
+func (s *Server) evictDatabase(dbIndex int) {
+	db := s.databases[dbIndex]
+	for s.usedMemory() > s.maxMemory {
+		if _, ok := db.Evict(s.maxMemoryPolicy, s.maxMemorySamples); !ok {
+			return
+		}
+		s.stats.evictedKeys.Add(1)
+	}
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestEviction(t *testing.T) {
+	oom := "-" + ErrOOM.Error() + "\r\n"
+	value := strings.Repeat("x", 10000)
+	tests := []struct {
+		name   string
+		policy string
+		// volatile is whether the keys are set with an expiration.
+		volatile bool
+		// reply is the reply of the SET after the limit is reached.
+		reply string
+	}{
+		{"noeviction", "noeviction", true, oom},
+		{"allkeys-lru", "allkeys-lru", false, "+OK\r\n"},
+		{"allkeys-lfu", "allkeys-lfu", false, "+OK\r\n"},
+		{"allkeys-random", "allkeys-random", false, "+OK\r\n"},
+		{"volatile-lru", "volatile-lru", true, "+OK\r\n"},
+		{"volatile-lfu", "volatile-lfu", true, "+OK\r\n"},
+		{"volatile-random", "volatile-random", true, "+OK\r\n"},
+		{"volatile-ttl", "volatile-ttl", true, "+OK\r\n"},
+		{"volatile-lru without volatile keys", "volatile-lru", false, oom},
+		{"volatile-ttl without volatile keys", "volatile-ttl", false, oom},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, addr := startTestServer(t, WithMaxMemory(25000), WithMaxMemoryPolicy(tt.policy))
+			c := dialTestServer(t, addr)
+
+			args := []string{"SET", "", value}
+			if tt.volatile {
+				args = append(args, "EX", "1000")
+			}
+			// The limit is checked before the write, so the third key exceeds it.
+			for i := 0; i < 3; i++ {
+				args[1] = "k" + strconv.Itoa(i)
+				c.mustDo("+OK\r\n", args...)
+			}
+
+			c.mustDo(tt.reply, "SET", "k3", "v")
+			if tt.reply == oom {
+				c.mustDo(":3\r\n", "DBSIZE")
+				// The commands that don't use more memory are still allowed.
+				c.mustDo("$1\r\nx\r\n", "GETRANGE", "k0", "0", "0")
+				c.mustDo(":1\r\n", "DEL", "k0")
+				c.mustDo("+OK\r\n", "SET", "k3", "v")
+				return
+			}
+
+			c.mustDo(":3\r\n", "DBSIZE")
+			c.mustDo("$1\r\nv\r\n", "GET", "k3")
+			info := c.do("INFO")
+			if evicted := infoField(info, "evicted_keys"); evicted != "1" {
+				t.Errorf("expected 1 evicted key, got %s", evicted)
+			}
+			if used, _ := strconv.Atoi(infoField(info, "used_memory")); used > 25000 {
+				t.Errorf("expected used memory under the limit, got %d", used)
+			}
+		})
+	}
+}
+
+func TestEvictionAcrossDatabases(t *testing.T) {
+	value := strings.Repeat("x", 10000)
+	tests := []struct {
+		name   string
+		policy string
+		args   []string
+		reply  string
+	}{
+		{"allkeys-lru", "allkeys-lru", nil, "+OK\r\n"},
+		{"volatile-ttl", "volatile-ttl", []string{"EX", "1000"}, "+OK\r\n"},
+		{"volatile-lru without volatile keys", "volatile-lru", nil, "-" + ErrOOM.Error() + "\r\n"},
+		{"noeviction", "noeviction", []string{"EX", "1000"}, "-" + ErrOOM.Error() + "\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, addr := startTestServer(t, WithMaxMemory(15000), WithMaxMemoryPolicy(tt.policy))
+			c := dialTestServer(t, addr)
+
+			c.mustDo("+OK\r\n", "SELECT", "1")
+			c.mustDo("+OK\r\n", append([]string{"SET", "a", value}, tt.args...)...)
+			c.mustDo("+OK\r\n", append([]string{"SET", "b", value}, tt.args...)...)
+
+			// The current database has no key to evict, so the keys of the
+			// other database are evicted.
+			c.mustDo("+OK\r\n", "SELECT", "0")
+			c.mustDo(tt.reply, "SET", "k", "v")
+
+			c.mustDo("+OK\r\n", "SELECT", "1")
+			if tt.reply == "+OK\r\n" {
+				c.mustDo(":1\r\n", "DBSIZE")
+			} else {
+				c.mustDo(":2\r\n", "DBSIZE")
+			}
+		})
+	}
+}
+
+// infoField returns the value of the field in the reply of INFO.
+func infoField(info, name string) string {
+	m := regexp.MustCompile(`(?m)^` + regexp.QuoteMeta(name) + `:(.*)\r$`).FindStringSubmatch(info)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+func TestMaxMemoryPolicyConfig(t *testing.T) {
+	for _, policy := range []string{"allkeys-lru", "VOLATILE-TTL", "noeviction"} {
+		if s := NewServer(WithMaxMemoryPolicy(policy)); s.configErr != nil {
+			t.Errorf("%s: unexpected config error %v", policy, s.configErr)
+		}
+	}
+
+	s := NewServer(WithMaxMemoryPolicy("allkeys"))
+	if s.configErr == nil || !strings.Contains(s.configErr.Error(), "maxmemory-policy") {
+		t.Errorf("expected an invalid config error, got %v", s.configErr)
+	}
+}
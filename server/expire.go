@@ -0,0 +1,46 @@
+package server
+
+import "time"
+
+const (
+	// activeExpireCycleSlowPercent is the percentage of every cron tick that a
+	// database may spend on removing expired keys in the slow cycle.
+	activeExpireCycleSlowPercent = 25
+	// activeExpireCycleFastDuration is the time limit of the fast cycle, that
+	// runs between commands while the slow cycle could not remove all expired
+	// keys. A fast cycle never starts within twice its duration of the last one.
+	activeExpireCycleFastDuration = time.Millisecond
+)
+
+type expireCycleState struct {
+	timeLimited bool
+	lastFast    time.Time
+}
+
+// activeExpireCycle removes the expired keys of the database. It runs in the
+// loop goroutine of the database, either as a slow cycle on every cron tick, or
+// as a fast cycle after commands when the last cycle reached its time limit.
+func (s *Server) activeExpireCycle(dbIndex int, fast bool) {
+	db := s.databases[dbIndex]
+	state := &s.expireCycles[dbIndex]
+
+	start := time.Now()
+	var duration time.Duration
+	if fast {
+		if !state.timeLimited || start.Sub(state.lastFast) < 2*activeExpireCycleFastDuration {
+			return
+		}
+		state.lastFast = start
+		duration = activeExpireCycleFastDuration
+	} else {
+		duration = time.Second / time.Duration(s.hz) * activeExpireCycleSlowPercent / 100
+	}
+
+	_, state.timeLimited = db.ExpireKeys(start.Add(duration), s.activeExpireSamples)
+	if state.timeLimited {
+		s.stats.expiredTimeCapReached.Add(1)
+	}
+	elapsed := time.Since(start)
+	s.stats.expireCycleTime.Add(int64(elapsed))
+	s.latency.add(latencyEventExpireCycle, elapsed)
+}
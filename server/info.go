@@ -0,0 +1,122 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+type infoSection struct {
+	Name      string
+	Generator func(*Server, *strings.Builder)
+	Default   bool
+}
+
+var infoSections []infoSection
+
+func init() {
+	infoSections = []infoSection{
+		{Name: "server", Generator: (*Server).genServerInfo, Default: true},
+		{Name: "clients", Generator: (*Server).genClientsInfo, Default: true},
+		{Name: "memory", Generator: (*Server).genMemoryInfo, Default: true},
+		{Name: "stats", Generator: (*Server).genStatsInfo, Default: true},
+		{Name: "commandstats", Generator: (*Server).genCommandStatsInfo, Default: false},
+		{Name: "keyspace", Generator: (*Server).genKeyspaceInfo, Default: true},
+	}
+}
+
+// genInfo generates the INFO report of the sections. No section means the
+// default sections, and "all" or "everything" means all sections.
+func (s *Server) genInfo(sections ...string) string {
+	requested := make(map[string]bool, len(sections))
+	for _, section := range sections {
+		requested[strings.ToLower(section)] = true
+	}
+	all := requested["all"] || requested["everything"]
+	defaults := len(sections) == 0 || requested["default"]
+
+	var info strings.Builder
+	for _, section := range infoSections {
+		if !all && !requested[section.Name] && !(defaults && section.Default) {
+			continue
+		}
+		if info.Len() > 0 {
+			info.WriteString("\r\n")
+		}
+		info.WriteString("# " + strings.ToUpper(section.Name[:1]) + section.Name[1:] + "\r\n")
+		section.Generator(s, &info)
+	}
+
+	return info.String()
+}
+
+func (s *Server) genServerInfo(info *strings.Builder) {
+	uptime := time.Since(s.stats.startTime)
+
+	fmt.Fprintf(info, "go_version:%s\r\n", runtime.Version())
+	fmt.Fprintf(info, "process_id:%d\r\n", os.Getpid())
+	fmt.Fprintf(info, "tcp_port:%d\r\n", s.port)
+	fmt.Fprintf(info, "uptime_in_seconds:%d\r\n", int64(uptime.Seconds()))
+	fmt.Fprintf(info, "uptime_in_days:%d\r\n", int64(uptime.Hours()/24))
+	fmt.Fprintf(info, "hz:%d\r\n", s.hz)
+}
+
+func (s *Server) genClientsInfo(info *strings.Builder) {
+	fmt.Fprintf(info, "connected_clients:%d\r\n", s.clientCount())
+	fmt.Fprintf(info, "maxclients:%d\r\n", s.maxClients)
+	fmt.Fprintf(info, "blocked_clients:%d\r\n", s.blockedClientCount())
+}
+
+func (s *Server) genMemoryInfo(info *strings.Builder) {
+	fmt.Fprintf(info, "used_memory:%d\r\n", s.usedMemory())
+	fmt.Fprintf(info, "maxmemory:%d\r\n", s.maxMemory)
+	fmt.Fprintf(info, "maxmemory_policy:%s\r\n", s.maxMemoryPolicy)
+}
+
+func (s *Server) genStatsInfo(info *strings.Builder) {
+	s.stats.metricsMu.Lock()
+	expiredPerSecond := s.stats.expiredPerSecond.value()
+	s.stats.metricsMu.Unlock()
+
+	fmt.Fprintf(info, "total_connections_received:%d\r\n", s.counter.Load())
+	fmt.Fprintf(info, "rejected_connections:%d\r\n", s.stats.rejectedConnections.Load())
+	fmt.Fprintf(info, "expired_keys:%d\r\n", s.expiredKeys())
+	fmt.Fprintf(info, "instantaneous_expired_per_sec:%.2f\r\n", expiredPerSecond)
+	fmt.Fprintf(info, "expired_time_cap_reached_count:%d\r\n", s.stats.expiredTimeCapReached.Load())
+	fmt.Fprintf(info, "expire_cycle_cpu_milliseconds:%d\r\n", time.Duration(s.stats.expireCycleTime.Load()).Milliseconds())
+	fmt.Fprintf(info, "evicted_keys:%d\r\n", s.stats.evictedKeys.Load())
+}
+
+func (s *Server) genCommandStatsInfo(info *strings.Builder) {
+	names := make([]string, 0, len(s.commandStats))
+	for name, stats := range s.commandStats {
+		if stats.calls.Load() > 0 || stats.rejected.Load() > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		stats := s.commandStats[name]
+		calls, usec := stats.calls.Load(), stats.usec.Load()
+		usecPerCall := 0.0
+		if calls > 0 {
+			usecPerCall = float64(usec) / float64(calls)
+		}
+		fmt.Fprintf(info, "cmdstat_%s:calls=%d,usec=%d,usec_per_call=%.2f,rejected_calls=%d,failed_calls=%d\r\n",
+			strings.ToLower(name), calls, usec, usecPerCall, stats.rejected.Load(), stats.failed.Load())
+	}
+}
+
+func (s *Server) genKeyspaceInfo(info *strings.Builder) {
+	for i, db := range s.databases {
+		keys := db.Size()
+		if keys == 0 {
+			continue
+		}
+		fmt.Fprintf(info, "db%d:keys=%d,expires=%d\r\n", i, keys, db.ExpiresSize())
+	}
+}
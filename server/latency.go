@@ -0,0 +1,156 @@
+package server
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const latencyTimeSeriesLen = 160
+
+const (
+	latencyEventCommand        = "command"
+	latencyEventExpireCycle    = "expire-cycle"
+	latencyEventFlush          = "flush"
+	latencyEventAccept         = "accept"
+	latencyEventRequestHandoff = "request-handoff"
+)
+
+type latencySample struct {
+	time    int64
+	latency int64
+}
+
+// latencyTimeSeries keeps the latest samples of an event in a ring buffer, with
+// at most one sample per second.
+type latencyTimeSeries struct {
+	samples [latencyTimeSeriesLen]latencySample
+	next    int
+	size    int
+	max     int64
+}
+
+// history returns the samples from the oldest one.
+func (ts *latencyTimeSeries) history() []latencySample {
+	samples := make([]latencySample, 0, ts.size)
+	for i := ts.size; i > 0; i-- {
+		samples = append(samples, ts.samples[(ts.next-i+latencyTimeSeriesLen)%latencyTimeSeriesLen])
+	}
+	return samples
+}
+
+func (ts *latencyTimeSeries) latest() latencySample {
+	return ts.samples[(ts.next-1+latencyTimeSeriesLen)%latencyTimeSeriesLen]
+}
+
+// latencyMonitor records the internal events that took longer than the
+// threshold, in milliseconds. A threshold of zero disables the monitor.
+type latencyMonitor struct {
+	mu        sync.Mutex
+	threshold time.Duration
+	events    map[string]*latencyTimeSeries
+}
+
+func newLatencyMonitor(threshold time.Duration) *latencyMonitor {
+	return &latencyMonitor{
+		threshold: threshold,
+		events:    make(map[string]*latencyTimeSeries),
+	}
+}
+
+func (m *latencyMonitor) add(event string, duration time.Duration) {
+	if m.threshold <= 0 || duration < m.threshold {
+		return
+	}
+
+	now := time.Now().Unix()
+	latency := duration.Milliseconds()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ts, ok := m.events[event]
+	if !ok {
+		ts = new(latencyTimeSeries)
+		m.events[event] = ts
+	}
+	if latency > ts.max {
+		ts.max = latency
+	}
+
+	if ts.size > 0 {
+		last := &ts.samples[(ts.next-1+latencyTimeSeriesLen)%latencyTimeSeriesLen]
+		if last.time == now {
+			if latency > last.latency {
+				last.latency = latency
+			}
+			return
+		}
+	}
+
+	ts.samples[ts.next] = latencySample{time: now, latency: latency}
+	ts.next = (ts.next + 1) % latencyTimeSeriesLen
+	if ts.size < latencyTimeSeriesLen {
+		ts.size++
+	}
+}
+
+type latencyEventStats struct {
+	name    string
+	latest  latencySample
+	max     int64
+	samples []latencySample
+}
+
+// snapshot returns the recorded events ordered by their names.
+func (m *latencyMonitor) snapshot() []latencyEventStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	events := make([]latencyEventStats, 0, len(m.events))
+	for name, ts := range m.events {
+		events = append(events, latencyEventStats{
+			name:    name,
+			latest:  ts.latest(),
+			max:     ts.max,
+			samples: ts.history(),
+		})
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].name < events[j].name
+	})
+	return events
+}
+
+func (m *latencyMonitor) history(event string) []latencySample {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ts, ok := m.events[event]
+	if !ok {
+		return nil
+	}
+	return ts.history()
+}
+
+// reset removes the events, or all events if no event is specified, and
+// returns the number of removed events.
+func (m *latencyMonitor) reset(events ...string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(events) == 0 {
+		cnt := len(m.events)
+		m.events = make(map[string]*latencyTimeSeries)
+		return cnt
+	}
+
+	cnt := 0
+	for _, event := range events {
+		if _, ok := m.events[event]; ok {
+			delete(m.events, event)
+			cnt++
+		}
+	}
+	return cnt
+}
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// listen opens the plaintext port, the TLS port and the unix socket, where a
+// port of 0 is disabled.
+func (s *Server) listen() ([]net.Listener, error) {
+	listeners := make([]net.Listener, 0, 2*len(s.bind)+1)
+	closeAll := func() {
+		for _, listener := range listeners {
+			listener.Close()
+		}
+	}
+
+	if s.port > 0 {
+		tcpListeners, err := s.listenTCP(s.port, nil)
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		listeners = append(listeners, tcpListeners...)
+	}
+
+	if s.tlsPort > 0 {
+		cfg, err := s.tls.config()
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		tlsListeners, err := s.listenTCP(s.tlsPort, cfg)
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		listeners = append(listeners, tlsListeners...)
+	}
+
+	if s.unixSocket != "" {
+		listener, err := s.listenUnix()
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		listeners = append(listeners, listener)
+		s.log(LevelNotice, "AntDB listening on unix socket", "path", s.unixSocket)
+	}
+
+	if len(listeners) == 0 {
+		return nil, errors.New("no listener configured, port and tls-port are disabled without unixsocket")
+	}
+
+	return listeners, nil
+}
+
+// listenTCP listens on the port of every bind address. An address prefixed by
+// "-" is optional, and is skipped if it is not available on the host.
+func (s *Server) listenTCP(port int, cfg *tls.Config) ([]net.Listener, error) {
+	listeners := make([]net.Listener, 0, len(s.bind))
+	for _, bind := range s.bind {
+		optional := strings.HasPrefix(bind, "-")
+		host := strings.TrimPrefix(bind, "-")
+		switch host {
+		case "*":
+			host = "0.0.0.0"
+		case "::*":
+			host = "::"
+		}
+
+		// IPv6 addresses are listened with IPV6_V6ONLY, so "::" doesn't
+		// conflict with "0.0.0.0" on the same port.
+		network := "tcp"
+		if ip := net.ParseIP(host); ip != nil {
+			if ip.To4() != nil {
+				network = "tcp4"
+			} else {
+				network = "tcp6"
+			}
+		}
+
+		address := net.JoinHostPort(host, strconv.Itoa(port))
+		lc := net.ListenConfig{KeepAlive: s.tcpKeepAlive}
+		if s.tcpKeepAlive <= 0 {
+			lc.KeepAlive = -1
+		}
+		listener, err := lc.Listen(context.Background(), network, address)
+		if err != nil {
+			if optional && isAddressNotAvailable(err) {
+				s.log(LevelWarning, "Skipping optional bind address", "address", address, "error", err)
+				continue
+			}
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, err
+		}
+
+		if cfg != nil {
+			listeners = append(listeners, tls.NewListener(listener, cfg))
+			s.log(LevelNotice, "AntDB listening", "address", address, "tls", true)
+		} else {
+			listeners = append(listeners, listener)
+			s.log(LevelNotice, "AntDB listening", "address", address)
+		}
+	}
+
+	return listeners, nil
+}
+
+// listenUnix listens on the unix socket, replacing the socket file left by a
+// previous process. The file is removed when the listener is closed.
+func (s *Server) listenUnix() (net.Listener, error) {
+	if err := os.Remove(s.unixSocket); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	listener, err := net.Listen("unix", s.unixSocket)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.unixSocketPerm != 0 {
+		if err := os.Chmod(s.unixSocket, os.FileMode(s.unixSocketPerm)); err != nil {
+			listener.Close()
+			return nil, err
+		}
+	}
+
+	return listener, nil
+}
+
+func isAddressNotAvailable(err error) bool {
+	return errors.Is(err, syscall.EADDRNOTAVAIL) || errors.Is(err, syscall.EAFNOSUPPORT) ||
+		errors.Is(err, syscall.EPROTONOSUPPORT)
+}
+
+// isLoopbackAddr returns true if the connection comes from the loopback
+// interface or from the unix socket.
+func isLoopbackAddr(addr net.Addr) bool {
+	switch addr := addr.(type) {
+	case *net.TCPAddr:
+		return addr.IP.IsLoopback()
+	case *net.UnixAddr:
+		return true
+	default:
+		return false
+	}
+}
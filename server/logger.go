@@ -0,0 +1,122 @@
+package server
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+// The log levels of the loglevel directive.
+const (
+	LevelDebug   = slog.LevelDebug
+	LevelVerbose = slog.LevelInfo
+	LevelNotice  = slog.LevelInfo + 2
+	LevelWarning = slog.LevelWarn
+)
+
+const defaultServerLogLevel = LevelNotice
+
+var logLevelNames = map[slog.Level]string{
+	LevelDebug:   "debug",
+	LevelVerbose: "verbose",
+	LevelNotice:  "notice",
+	LevelWarning: "warning",
+}
+
+func parseLogLevel(name string) (slog.Level, bool) {
+	for level, levelName := range logLevelNames {
+		if strings.EqualFold(name, levelName) {
+			return level, true
+		}
+	}
+	return 0, false
+}
+
+// isLogFormat returns true if the format is empty, text or json.
+func isLogFormat(format string) bool {
+	return format == "" || strings.EqualFold(format, "text") || strings.EqualFold(format, "json")
+}
+
+// logFile writes the logs into the file of the path, or into the standard
+// output if the path is empty or the file is not opened yet. The file can be
+// reopened after it has been moved by logrotate.
+type logFile struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+func (f *logFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return os.Stdout.Write(p)
+	}
+	return f.file.Write(p)
+}
+
+// open opens the file of the path, and closes the previously opened one.
+func (f *logFile) open() error {
+	if f.path == "" {
+		return nil
+	}
+
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	old := f.file
+	f.file = file
+	f.mu.Unlock()
+
+	if old != nil {
+		old.Close()
+	}
+	return nil
+}
+
+func newLogger(w io.Writer, level slog.Level, format string) *slog.Logger {
+	opts := &slog.HandlerOptions{
+		Level: level,
+		ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+			if attr.Key == slog.LevelKey && len(groups) == 0 {
+				if level, ok := attr.Value.Any().(slog.Level); ok {
+					if name, ok := logLevelNames[level]; ok {
+						attr.Value = slog.StringValue(name)
+					}
+				}
+			}
+			return attr
+		},
+	}
+
+	if strings.EqualFold(format, "json") {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
+
+// Logger returns the logger of the server.
+func (s *Server) Logger() *slog.Logger {
+	return s.logger
+}
+
+// ReopenLogFile reopens the log file, to continue logging into the new file
+// after the old one has been moved. It does nothing if the server is logging
+// into the standard output or into a logger provided by WithLogger.
+func (s *Server) ReopenLogFile() error {
+	if s.logFile == nil {
+		return nil
+	}
+	return s.logFile.open()
+}
+
+func (s *Server) log(level slog.Level, msg string, args ...any) {
+	s.logger.Log(context.Background(), level, msg, args...)
+}
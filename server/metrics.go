@@ -0,0 +1,139 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// listenMetrics serves the Prometheus metrics at /metrics and the health check
+// at /healthz on the metrics port.
+func (s *Server) listenMetrics() error {
+	listener, err := net.Listen("tcp", net.JoinHostPort(s.metricsBind, strconv.Itoa(s.metricsPort)))
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", s.handleMetrics)
+	mux.HandleFunc("/healthz", s.handleHealthz)
+	s.metricsServer = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		err := s.metricsServer.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.log(LevelWarning, "Failed to serve metrics", "error", err)
+		}
+	}()
+	return nil
+}
+
+// handleHealthz replies 200 while the server is accepting connections, and 503
+// before the listeners are opened. The metrics server is shut down by Close
+// with the listeners, so the endpoint refuses connections after that.
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	s.listenersMu.Lock()
+	accepting := len(s.listeners) > 0
+	s.listenersMu.Unlock()
+
+	if !accepting {
+		http.Error(w, "not accepting connections", http.StatusServiceUnavailable)
+		return
+	}
+	io.WriteString(w, "OK\n")
+}
+
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	var metrics strings.Builder
+	s.genMetrics(&metrics)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	io.WriteString(w, metrics.String())
+}
+
+// genMetrics generates the metrics in the Prometheus text exposition format.
+// It runs in the goroutine of the HTTP handler, so it only reads the counters
+// that are safe to read from any goroutine.
+func (s *Server) genMetrics(metrics *strings.Builder) {
+	writeMetricHeader(metrics, "antdb_uptime_seconds", "gauge", "Number of seconds since the server started.")
+	fmt.Fprintf(metrics, "antdb_uptime_seconds %d\n", int64(time.Since(s.stats.startTime).Seconds()))
+
+	writeMetricHeader(metrics, "antdb_connected_clients", "gauge", "Number of connected clients.")
+	fmt.Fprintf(metrics, "antdb_connected_clients %d\n", s.clientCount())
+	writeMetricHeader(metrics, "antdb_connections_received_total", "counter", "Total number of accepted connections.")
+	fmt.Fprintf(metrics, "antdb_connections_received_total %d\n", s.counter.Load())
+	writeMetricHeader(metrics, "antdb_rejected_connections_total", "counter",
+		"Total number of connections rejected because of maxclients.")
+	fmt.Fprintf(metrics, "antdb_rejected_connections_total %d\n", s.stats.rejectedConnections.Load())
+
+	writeMetricHeader(metrics, "antdb_memory_used_bytes", "gauge", "Estimated memory used by the keys.")
+	fmt.Fprintf(metrics, "antdb_memory_used_bytes %d\n", s.usedMemory())
+	writeMetricHeader(metrics, "antdb_memory_max_bytes", "gauge", "Value of maxmemory, 0 if unlimited.")
+	fmt.Fprintf(metrics, "antdb_memory_max_bytes %d\n", s.maxMemory)
+
+	writeMetricHeader(metrics, "antdb_db_keys", "gauge", "Number of keys in the database.")
+	for i, db := range s.databases {
+		fmt.Fprintf(metrics, "antdb_db_keys{db=\"%d\"} %d\n", i, db.Size())
+	}
+	writeMetricHeader(metrics, "antdb_db_expiring_keys", "gauge", "Number of keys with an expiration in the database.")
+	for i, db := range s.databases {
+		fmt.Fprintf(metrics, "antdb_db_expiring_keys{db=\"%d\"} %d\n", i, db.ExpiresSize())
+	}
+	writeMetricHeader(metrics, "antdb_expired_keys_total", "counter", "Total number of expired keys.")
+	fmt.Fprintf(metrics, "antdb_expired_keys_total %d\n", s.expiredKeys())
+	writeMetricHeader(metrics, "antdb_evicted_keys_total", "counter", "Total number of keys evicted by maxmemory.")
+	fmt.Fprintf(metrics, "antdb_evicted_keys_total %d\n", s.stats.evictedKeys.Load())
+
+	names := make([]string, 0, len(s.commandStats))
+	for name, stats := range s.commandStats {
+		if stats.calls.Load() > 0 || stats.rejected.Load() > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	writeMetricHeader(metrics, "antdb_commands_total", "counter", "Total number of calls of the command.")
+	for _, name := range names {
+		fmt.Fprintf(metrics, "antdb_commands_total{cmd=\"%s\"} %d\n", strings.ToLower(name), s.commandStats[name].calls.Load())
+	}
+	writeMetricHeader(metrics, "antdb_commands_rejected_total", "counter",
+		"Total number of calls of the command rejected before execution.")
+	for _, name := range names {
+		fmt.Fprintf(metrics, "antdb_commands_rejected_total{cmd=\"%s\"} %d\n", strings.ToLower(name),
+			s.commandStats[name].rejected.Load())
+	}
+	writeMetricHeader(metrics, "antdb_commands_failed_total", "counter", "Total number of calls of the command that failed.")
+	for _, name := range names {
+		fmt.Fprintf(metrics, "antdb_commands_failed_total{cmd=\"%s\"} %d\n", strings.ToLower(name),
+			s.commandStats[name].failed.Load())
+	}
+
+	writeMetricHeader(metrics, "antdb_commands_duration_seconds", "histogram", "Execution time of the command.")
+	for _, name := range names {
+		stats := s.commandStats[name]
+		cmd := strings.ToLower(name)
+		total := int64(0)
+		for i := 0; i < commandHistogramBuckets-1; i++ {
+			total += stats.histogram[i].Load()
+			fmt.Fprintf(metrics, "antdb_commands_duration_seconds_bucket{cmd=\"%s\",le=\"%g\"} %d\n",
+				cmd, float64(int64(1)<<i)/1e6, total)
+		}
+		total += stats.histogram[commandHistogramBuckets-1].Load()
+		fmt.Fprintf(metrics, "antdb_commands_duration_seconds_bucket{cmd=\"%s\",le=\"+Inf\"} %d\n", cmd, total)
+		fmt.Fprintf(metrics, "antdb_commands_duration_seconds_sum{cmd=\"%s\"} %g\n", cmd, float64(stats.usec.Load())/1e6)
+		fmt.Fprintf(metrics, "antdb_commands_duration_seconds_count{cmd=\"%s\"} %d\n", cmd, total)
+	}
+}
+
+func writeMetricHeader(metrics *strings.Builder, name, typ, help string) {
+	fmt.Fprintf(metrics, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
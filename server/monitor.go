@@ -0,0 +1,150 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ghosind/antdb/client"
+)
+
+// monitorBufferSize is the number of commands buffered for a monitor. The
+// commands are dropped while the buffer of a slow monitor is full, so the
+// database loops never wait for the monitors.
+const monitorBufferSize = 1024
+
+type monitor struct {
+	cli     *client.Client
+	lines   chan string
+	dropped atomic.Int64
+	done    chan struct{}
+}
+
+type monitors struct {
+	mu       sync.RWMutex
+	monitors map[uint64]*monitor
+	count    atomic.Int32
+}
+
+func (s *Server) addMonitor(cli *client.Client) {
+	m := &monitor{
+		cli:   cli,
+		lines: make(chan string, monitorBufferSize),
+		done:  make(chan struct{}),
+	}
+
+	s.monitors.mu.Lock()
+	s.monitors.monitors[cli.ID] = m
+	s.monitors.count.Store(int32(len(s.monitors.monitors)))
+	s.monitors.mu.Unlock()
+
+	go m.write()
+}
+
+// removeMonitor stops feeding the client, and returns the monitor to wait for
+// its writer, or nil if the client is not a monitor.
+func (s *Server) removeMonitor(cli *client.Client) *monitor {
+	s.monitors.mu.Lock()
+	defer s.monitors.mu.Unlock()
+
+	m, ok := s.monitors.monitors[cli.ID]
+	if !ok {
+		return nil
+	}
+	delete(s.monitors.monitors, cli.ID)
+	s.monitors.count.Store(int32(len(s.monitors.monitors)))
+	close(m.lines)
+	return m
+}
+
+// feedMonitors sends the command to all monitors without blocking.
+func (s *Server) feedMonitors(cli *client.Client, cmd *client.Command) {
+	if s.monitors.count.Load() == 0 {
+		return
+	}
+
+	line := formatMonitorLine(cli, cmd)
+
+	s.monitors.mu.RLock()
+	defer s.monitors.mu.RUnlock()
+
+	for _, m := range s.monitors.monitors {
+		select {
+		case m.lines <- line:
+		default:
+			m.dropped.Add(1)
+		}
+	}
+}
+
+func (m *monitor) write() {
+	defer close(m.done)
+
+	for line := range m.lines {
+		if _, err := m.cli.ReplySimpleString(line); err != nil {
+			continue
+		}
+		if len(m.lines) == 0 {
+			if dropped := m.dropped.Swap(0); dropped > 0 {
+				m.cli.ReplySimpleString(monitorTimestamp(time.Now()) + " [dropped " +
+					strconv.FormatInt(dropped, 10) + " commands]")
+			}
+		}
+	}
+}
+
+func formatMonitorLine(cli *client.Client, cmd *client.Command) string {
+	var buf strings.Builder
+	buf.WriteString(monitorTimestamp(time.Now()))
+	buf.WriteString(" [")
+	buf.WriteString(strconv.Itoa(cli.DB))
+	buf.WriteString(" ")
+	buf.WriteString(cli.Info().Addr)
+	buf.WriteString("] ")
+	buf.WriteString(quoteMonitorArg(cmd.Command))
+	for _, arg := range cmd.Args {
+		buf.WriteString(" ")
+		buf.WriteString(quoteMonitorArg(arg))
+	}
+	return buf.String()
+}
+
+func monitorTimestamp(t time.Time) string {
+	return fmt.Sprintf("%d.%06d", t.Unix(), t.Nanosecond()/1000)
+}
+
+// quoteMonitorArg quotes the argument, escaping the quotes, the backslashes and
+// the non-printable characters.
+func quoteMonitorArg(arg string) string {
+	var buf strings.Builder
+	buf.WriteByte('"')
+	for i := 0; i < len(arg); i++ {
+		c := arg[i]
+		switch c {
+		case '\\', '"':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		case '\n':
+			buf.WriteString("\\n")
+		case '\r':
+			buf.WriteString("\\r")
+		case '\t':
+			buf.WriteString("\\t")
+		case '\a':
+			buf.WriteString("\\a")
+		case '\b':
+			buf.WriteString("\\b")
+		default:
+			if c < ' ' || c > '~' {
+				fmt.Fprintf(&buf, "\\x%02x", c)
+			} else {
+				buf.WriteByte(c)
+			}
+		}
+	}
+	buf.WriteByte('"')
+	return buf.String()
+}
@@ -1,26 +1,211 @@
 package server
 
+import "log/slog"
+
 type serverBuilder struct {
-	bind      string
+	bind      []string
 	port      int
+	portSet   bool
 	databases int
 
+	unixSocket     string
+	unixSocketPerm int
+
+	metricsPort int
+	metricsBind string
+
+	logger    *slog.Logger
+	logLevel  string
+	logFile   string
+	logFormat string
+
+	timeout         int
+	tcpKeepAlive    int
+	tcpKeepAliveSet bool
+	maxClients      int
+
+	protoMaxBulkLen int64
+
+	listMaxListpackSize    int
+	listMaxListpackSizeSet bool
+	listCompressDepth      int
+
+	tlsPort        int
+	tlsCertFile    string
+	tlsKeyFile     string
+	tlsCACertFile  string
+	tlsAuthClients string
+	tlsProtocols   string
+	tlsCiphers     string
+
 	hz                  int
 	activeExpireSamples int
 	requirePass         string
+	protectedMode       bool
+	protectedModeSet    bool
+	aclFile             string
+
+	slowlogLogSlowerThan    int
+	slowlogLogSlowerThanSet bool
+	slowlogMaxLen           int
+	slowlogMaxLenSet        bool
+
+	latencyMonitorThreshold int
+
+	maxMemory        int64
+	maxMemoryPolicy  string
+	maxMemorySamples int
 }
 
 type ServerOption func(*serverBuilder)
 
-func WithBind(bind string) ServerOption {
+func WithBind(addresses ...string) ServerOption {
 	return func(sb *serverBuilder) {
-		sb.bind = bind
+		sb.bind = addresses
 	}
 }
 
 func WithPort(port int) ServerOption {
 	return func(sb *serverBuilder) {
 		sb.port = port
+		sb.portSet = true
+	}
+}
+
+func WithUnixSocket(path string) ServerOption {
+	return func(sb *serverBuilder) {
+		sb.unixSocket = path
+	}
+}
+
+func WithUnixSocketPerm(perm int) ServerOption {
+	return func(sb *serverBuilder) {
+		sb.unixSocketPerm = perm
+	}
+}
+
+func WithMetricsPort(port int) ServerOption {
+	return func(sb *serverBuilder) {
+		sb.metricsPort = port
+	}
+}
+
+func WithMetricsBind(address string) ServerOption {
+	return func(sb *serverBuilder) {
+		sb.metricsBind = address
+	}
+}
+
+// WithLogger sets the logger of the server, which overrides the loglevel,
+// logfile and log-format options.
+func WithLogger(logger *slog.Logger) ServerOption {
+	return func(sb *serverBuilder) {
+		sb.logger = logger
+	}
+}
+
+func WithLogLevel(level string) ServerOption {
+	return func(sb *serverBuilder) {
+		sb.logLevel = level
+	}
+}
+
+func WithLogFile(path string) ServerOption {
+	return func(sb *serverBuilder) {
+		sb.logFile = path
+	}
+}
+
+func WithLogFormat(format string) ServerOption {
+	return func(sb *serverBuilder) {
+		sb.logFormat = format
+	}
+}
+
+func WithTimeout(seconds int) ServerOption {
+	return func(sb *serverBuilder) {
+		sb.timeout = seconds
+	}
+}
+
+func WithTCPKeepAlive(seconds int) ServerOption {
+	return func(sb *serverBuilder) {
+		sb.tcpKeepAlive = seconds
+		sb.tcpKeepAliveSet = true
+	}
+}
+
+func WithMaxClients(num int) ServerOption {
+	return func(sb *serverBuilder) {
+		sb.maxClients = num
+	}
+}
+
+// WithProtoMaxBulkLen sets the maximum length in bytes of the string values.
+func WithProtoMaxBulkLen(bytes int64) ServerOption {
+	return func(sb *serverBuilder) {
+		sb.protoMaxBulkLen = bytes
+	}
+}
+
+// WithListMaxListpackSize sets the maximum number of elements of a list node if
+// it is positive, or the maximum size of a node from -1 (4 KB) to -5 (64 KB).
+func WithListMaxListpackSize(size int) ServerOption {
+	return func(sb *serverBuilder) {
+		sb.listMaxListpackSize = size
+		sb.listMaxListpackSizeSet = true
+	}
+}
+
+// WithListCompressDepth sets the number of nodes at both ends of a list that
+// are never compressed, or disables the compression if it is 0.
+func WithListCompressDepth(depth int) ServerOption {
+	return func(sb *serverBuilder) {
+		sb.listCompressDepth = depth
+	}
+}
+
+func WithTLSPort(port int) ServerOption {
+	return func(sb *serverBuilder) {
+		sb.tlsPort = port
+	}
+}
+
+func WithTLSCertFile(file string) ServerOption {
+	return func(sb *serverBuilder) {
+		sb.tlsCertFile = file
+	}
+}
+
+func WithTLSKeyFile(file string) ServerOption {
+	return func(sb *serverBuilder) {
+		sb.tlsKeyFile = file
+	}
+}
+
+func WithTLSCACertFile(file string) ServerOption {
+	return func(sb *serverBuilder) {
+		sb.tlsCACertFile = file
+	}
+}
+
+func WithTLSAuthClients(mode string) ServerOption {
+	return func(sb *serverBuilder) {
+		sb.tlsAuthClients = mode
+	}
+}
+
+// WithTLSProtocols sets the space separated TLS versions that can be used,
+// like "TLSv1.2 TLSv1.3".
+func WithTLSProtocols(protocols string) ServerOption {
+	return func(sb *serverBuilder) {
+		sb.tlsProtocols = protocols
+	}
+}
+
+func WithTLSCiphers(ciphers string) ServerOption {
+	return func(sb *serverBuilder) {
+		sb.tlsCiphers = ciphers
 	}
 }
 
@@ -47,3 +232,54 @@ func WithRequirePass(password string) ServerOption {
 		sb.requirePass = password
 	}
 }
+
+func WithProtectedMode(enabled bool) ServerOption {
+	return func(sb *serverBuilder) {
+		sb.protectedMode = enabled
+		sb.protectedModeSet = true
+	}
+}
+
+func WithACLFile(file string) ServerOption {
+	return func(sb *serverBuilder) {
+		sb.aclFile = file
+	}
+}
+
+func WithSlowlogLogSlowerThan(micros int) ServerOption {
+	return func(sb *serverBuilder) {
+		sb.slowlogLogSlowerThan = micros
+		sb.slowlogLogSlowerThanSet = true
+	}
+}
+
+func WithSlowlogMaxLen(num int) ServerOption {
+	return func(sb *serverBuilder) {
+		sb.slowlogMaxLen = num
+		sb.slowlogMaxLenSet = true
+	}
+}
+
+func WithLatencyMonitorThreshold(millis int) ServerOption {
+	return func(sb *serverBuilder) {
+		sb.latencyMonitorThreshold = millis
+	}
+}
+
+func WithMaxMemory(bytes int64) ServerOption {
+	return func(sb *serverBuilder) {
+		sb.maxMemory = bytes
+	}
+}
+
+func WithMaxMemoryPolicy(policy string) ServerOption {
+	return func(sb *serverBuilder) {
+		sb.maxMemoryPolicy = policy
+	}
+}
+
+func WithMaxMemorySamples(samples int) ServerOption {
+	return func(sb *serverBuilder) {
+		sb.maxMemorySamples = samples
+	}
+}
@@ -1,13 +1,16 @@
 package server
 
 import (
-	"context"
+	"crypto/tls"
 	"errors"
-	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net"
+	"net/http"
+	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"syscall"
 	"time"
@@ -23,21 +26,68 @@ const (
 
 	defaultServerHz                  = 10
 	defaultServerActiveExpireSamples = 20
+
+	defaultServerMaxMemorySamples = 5
+
+	defaultServerTCPKeepAlive = 300
+	defaultServerMaxClients   = 10000
 )
 
 type Server struct {
 	databaseNum int
-	bind        string
+	bind        []string
 	port        int
-	listener    net.Listener
+	tlsPort     int
+	tls         tlsOptions
+	listeners   []net.Listener
+	listenersMu sync.Mutex
 	databases   []*core.Database
-	connections atomic.Int64
 	counter     atomic.Uint64
 	requests    []chan *client.Client
+	// tasks are the functions to run in the loop goroutines of the databases,
+	// for the commands that touch other databases.
+	tasks []chan func()
+
+	clients   map[uint64]*client.Client
+	clientsMu sync.RWMutex
+	pause     clientPause
+	monitors  monitors
+	blocked   blockedClients
+
+	unixSocket     string
+	unixSocketPerm int
+
+	metricsPort   int
+	metricsBind   string
+	metricsServer *http.Server
+
+	logger  *slog.Logger
+	logFile *logFile
+
+	timeout      time.Duration
+	tcpKeepAlive time.Duration
+	maxClients   int
+
+	protoMaxBulkLen int64
 
 	hz                  int
 	activeExpireSamples int
-	requirePass         string
+	acl                 *acl
+	protectedMode       bool
+
+	maxMemory        int64
+	maxMemoryPolicy  core.EvictionPolicy
+	maxMemorySamples int
+
+	expireCycles []expireCycleState
+	slowlog      *slowlog
+	latency      *latencyMonitor
+	commandStats map[string]*commandStats
+	stats        serverStats
+
+	// configErr is the error of an invalid directive, which is returned by
+	// Listen before the server starts.
+	configErr error
 }
 
 func NewServer(options ...ServerOption) *Server {
@@ -49,19 +99,113 @@ func NewServer(options ...ServerOption) *Server {
 	}
 
 	s.databaseNum = s.withIntOption(builder.databases, defaultServerDatabases)
-	s.bind = s.withStringOption(builder.bind, defaultServerBind)
+	s.bind = builder.bind
+	if len(s.bind) == 0 {
+		s.bind = []string{defaultServerBind}
+	}
 	s.port = s.withIntOption(builder.port, defaultServerPort)
+	if builder.portSet && builder.port == 0 {
+		s.port = 0
+	}
+	s.unixSocket = builder.unixSocket
+	s.unixSocketPerm = builder.unixSocketPerm
+	s.metricsPort = builder.metricsPort
+	s.metricsBind = s.withStringOption(builder.metricsBind, defaultServerBind)
+	s.logger = builder.logger
+	if s.logger == nil {
+		level := defaultServerLogLevel
+		if builder.logLevel != "" {
+			var ok bool
+			if level, ok = parseLogLevel(builder.logLevel); !ok {
+				level = defaultServerLogLevel
+				s.configErr = newInvalidConfigError("loglevel", builder.logLevel)
+			}
+		}
+		if !isLogFormat(builder.logFormat) {
+			s.configErr = newInvalidConfigError("log-format", builder.logFormat)
+		}
+		s.logFile = &logFile{path: builder.logFile}
+		s.logger = newLogger(s.logFile, level, builder.logFormat)
+	}
+	s.timeout = time.Duration(builder.timeout) * time.Second
+	s.tcpKeepAlive = defaultServerTCPKeepAlive * time.Second
+	if builder.tcpKeepAliveSet {
+		s.tcpKeepAlive = time.Duration(builder.tcpKeepAlive) * time.Second
+	}
+	s.maxClients = s.withIntOption(builder.maxClients, defaultServerMaxClients)
+	s.protoMaxBulkLen = builder.protoMaxBulkLen
+	if s.protoMaxBulkLen <= 0 {
+		s.protoMaxBulkLen = core.DefaultMaxStringLength
+	}
+	s.tlsPort = builder.tlsPort
+	s.tls = tlsOptions{
+		certFile:    builder.tlsCertFile,
+		keyFile:     builder.tlsKeyFile,
+		caCertFile:  builder.tlsCACertFile,
+		authClients: builder.tlsAuthClients,
+		protocols:   builder.tlsProtocols,
+		ciphers:     builder.tlsCiphers,
+	}
+
+	s.clients = make(map[uint64]*client.Client)
+	s.pause.resume = make(chan struct{})
+	s.monitors.monitors = make(map[uint64]*monitor)
+	s.blocked.clients = make(map[uint64]*blockedClient)
+	s.blocked.keys = make([]map[string]map[uint64]*blockedClient, s.databaseNum)
 
 	s.databases = make([]*core.Database, s.databaseNum)
 	s.requests = make([]chan *client.Client, s.databaseNum)
+	s.tasks = make([]chan func(), s.databaseNum)
+	s.expireCycles = make([]expireCycleState, s.databaseNum)
 	for i := 0; i < s.databaseNum; i++ {
 		s.databases[i] = core.NewDatabase()
+		s.databases[i].SetMaxStringLength(s.protoMaxBulkLen)
+		if builder.listMaxListpackSizeSet {
+			s.databases[i].SetListMaxListpackSize(builder.listMaxListpackSize)
+		}
+		s.databases[i].SetListCompressDepth(builder.listCompressDepth)
+		dbIndex := i
+		s.databases[i].SetStreamRemovedHook(func(key string) {
+			s.signalKeyAsReady(dbIndex, key)
+		})
 		s.requests[i] = make(chan *client.Client)
+		s.tasks[i] = make(chan func())
+		s.blocked.keys[i] = make(map[string]map[uint64]*blockedClient)
 	}
 
 	s.hz = s.withIntOption(builder.hz, defaultServerHz)
 	s.activeExpireSamples = s.withIntOption(builder.activeExpireSamples, defaultServerActiveExpireSamples)
-	s.requirePass = builder.requirePass
+	s.acl = newACL(builder.aclFile, builder.requirePass)
+	s.protectedMode = !builder.protectedModeSet || builder.protectedMode
+
+	s.maxMemory = builder.maxMemory
+	if builder.maxMemoryPolicy != "" {
+		policy, ok := core.ParseEvictionPolicy(builder.maxMemoryPolicy)
+		if !ok {
+			s.configErr = newInvalidConfigError("maxmemory-policy", builder.maxMemoryPolicy)
+		}
+		s.maxMemoryPolicy = policy
+	}
+	s.maxMemorySamples = s.withIntOption(builder.maxMemorySamples, defaultServerMaxMemorySamples)
+
+	slowerThan := defaultSlowlogLogSlowerThan
+	if builder.slowlogLogSlowerThanSet {
+		slowerThan = builder.slowlogLogSlowerThan
+	}
+	slowlogMaxLen := defaultSlowlogMaxLen
+	if builder.slowlogMaxLenSet {
+		if builder.slowlogMaxLen < 0 {
+			s.configErr = newInvalidConfigError("slowlog-max-len", strconv.Itoa(builder.slowlogMaxLen))
+		} else {
+			slowlogMaxLen = builder.slowlogMaxLen
+		}
+	}
+	s.slowlog = newSlowlog(time.Duration(slowerThan)*time.Microsecond, slowlogMaxLen)
+
+	s.latency = newLatencyMonitor(time.Duration(builder.latencyMonitorThreshold) * time.Millisecond)
+	s.commandStats = newCommandStats()
+
+	s.stats.startTime = time.Now()
 
 	go s.serverCron()
 
@@ -69,55 +213,192 @@ func NewServer(options ...ServerOption) *Server {
 }
 
 func (s *Server) Listen() error {
-	address := fmt.Sprintf("%s:%d", s.bind, s.port)
+	if s.configErr != nil {
+		return s.configErr
+	}
+
+	if s.logFile != nil {
+		if err := s.logFile.open(); err != nil {
+			return err
+		}
+	}
 
-	listener, err := net.Listen("tcp", address)
+	if s.acl.file != "" {
+		if err := s.acl.load(); err != nil {
+			return err
+		}
+	}
+
+	if s.metricsPort > 0 {
+		if err := s.listenMetrics(); err != nil {
+			return err
+		}
+	}
+
+	listeners, err := s.listen()
 	if err != nil {
+		if s.metricsServer != nil {
+			s.metricsServer.Close()
+		}
 		return err
 	}
-	s.listener = listener
-
-	log.Printf("AntDB listening on %s", address)
+	s.listenersMu.Lock()
+	s.listeners = listeners
+	s.listenersMu.Unlock()
 
 	for i := 0; i < s.databaseNum; i++ {
 		go s.loop(i)
 	}
 
+	errs := make(chan error, len(listeners))
+	for _, listener := range listeners {
+		go func(listener net.Listener) {
+			errs <- s.serve(listener)
+		}(listener)
+	}
+	return <-errs
+}
+
+// Close stops accepting new connections and closes the listeners and the
+// metrics server, which makes Listen return.
+func (s *Server) Close() error {
+	s.listenersMu.Lock()
+	defer s.listenersMu.Unlock()
+
+	var err error
+	for _, listener := range s.listeners {
+		if e := listener.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	s.listeners = nil
+	if s.metricsServer != nil {
+		if e := s.metricsServer.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+func (s *Server) serve(listener net.Listener) error {
 	for {
-		conn, err := s.listener.Accept()
-		if err != nil {
-			log.Printf("Failed to accept connection: %v", err)
+		conn, err := listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		} else if err != nil {
+			s.log(LevelWarning, "Failed to accept connection", "error", err)
 			return err
 		}
 		id := s.counter.Add(1)
-		s.connections.Add(1)
 		client := client.NewClient(conn, id)
 		go s.handleConnection(client)
 	}
 }
 
 func (s *Server) loop(dbIndex int) {
+	ticker := time.NewTicker(time.Second / time.Duration(s.hz))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case cli := <-s.requests[dbIndex]:
+			s.handleCommand(cli, cli.LastCommand)
+			cli.Done <- struct{}{}
+			if !s.pause.isWritePaused() {
+				s.activeExpireCycle(dbIndex, true)
+			}
+		case task := <-s.tasks[dbIndex]:
+			task()
+		case <-ticker.C:
+			if !s.pause.isWritePaused() {
+				s.activeExpireCycle(dbIndex, false)
+			}
+		}
+	}
+}
+
+// runInDatabase runs fn with the database of the index in its loop goroutine,
+// and waits for it. The caller runs in the loop goroutine of the database of
+// the current index, or in no loop if current is negative, and keeps serving the
+// tasks sent to its database while waiting, so that two databases waiting for
+// each other never deadlock.
+func (s *Server) runInDatabase(current, index int, fn func(db *core.Database)) {
+	if current == index {
+		fn(s.databases[index])
+		return
+	}
+
+	var tasks chan func()
+	if current >= 0 {
+		tasks = s.tasks[current]
+	}
+	done := make(chan struct{})
+	task := func() {
+		fn(s.databases[index])
+		close(done)
+	}
+
+	pending := s.tasks[index]
 	for {
-		cli := <-s.requests[dbIndex]
-		s.handleCommand(cli, cli.LastCommand)
+		select {
+		case pending <- task:
+			pending = nil
+		case <-done:
+			return
+		case t := <-tasks:
+			t()
+		}
 	}
 }
 
 func (s *Server) handleConnection(cli *client.Client) {
+	start := time.Now()
 	defer func() {
-		s.connections.Add(-1)
+		s.removeClient(cli)
+		m := s.removeMonitor(cli)
 		cli.Conn.Close()
+		if m != nil {
+			<-m.done
+		}
 		client.PutClient(cli)
 	}()
 
+	if !s.addClient(cli) {
+		s.stats.rejectedConnections.Add(1)
+		cli.ReplyError(ErrMaxClients.Error())
+		return
+	}
+
+	if s.protectedMode && !isLoopbackAddr(cli.Conn.RemoteAddr()) && s.acl.isDefaultUserNoPass() {
+		cli.ReplyError(ErrProtectedMode.Error())
+		return
+	}
+
+	if conn, ok := cli.Conn.(*tls.Conn); ok {
+		if err := s.tlsHandshake(cli, conn); err != nil {
+			s.log(LevelVerbose, "TLS handshake failed", "client", cli.ID, "error", err)
+			return
+		}
+	}
+
+	cli.User = aclDefaultUser
+	cli.Authenticated = s.acl.isDefaultUserNoPass()
+	cli.UpdateInfo()
+	s.latency.add(latencyEventAccept, time.Since(start))
+
 	for {
+		s.setReadDeadline(cli)
 		err := cli.ReadCommand()
 		if errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) || errors.Is(err, syscall.ECONNRESET) {
 			break
+		} else if errors.Is(err, os.ErrDeadlineExceeded) {
+			s.log(LevelVerbose, "Closing idle client", "client", cli.ID)
+			break
 		} else if err != nil {
-			log.Printf("Error reading command from client %d: %v", cli.ID, err)
+			s.log(LevelVerbose, "Failed to read command", "client", cli.ID, "error", err)
 			continue
 		}
+		cli.UpdateInfo()
 
 		if cli.LastCommand != nil {
 			switch cli.LastCommand.Command {
@@ -127,37 +408,68 @@ func (s *Server) handleConnection(cli *client.Client) {
 			}
 		}
 
-		if err := s.checkAuthentication(cli); err != nil {
-			cli.ReplyError(err.Error())
+		// The replies of a monitor are written by its writer, so it can only
+		// quit.
+		if cli.Flag&client.CLIENT_MONITOR != 0 {
 			continue
 		}
 
-		if cli.Flag&client.CLIENT_MULTI != 0 && cli.LastCommand.Command != "EXEC" {
-			cli.State = append(cli.State, cli.LastCommand)
-			cli.ReplySimpleString("QUEUED")
+		if err := s.checkAuthentication(cli); err != nil {
+			cli.ReplyError(err.Error())
 			continue
 		}
 
-		isNoWait := false
 		cmd, ok := dbCommands[strings.ToUpper(cli.LastCommand.Command)]
 		if !ok {
 			cli.ReplyError(newUnknownCommandError(cli.LastCommand.Command).Error())
 			continue
 		}
-		isNoWait = cmd.NoWait
+		isNoWait := cmd.NoWait
+
+		if err := s.checkPermission(cli, &cmd); err != nil {
+			cli.ReplyError(err.Error())
+			continue
+		}
+
+		if cli.Flag&client.CLIENT_MULTI != 0 && cli.LastCommand.Command != "EXEC" {
+			cli.State = append(cli.State, cli.LastCommand)
+			cli.ReplySimpleString("QUEUED")
+			continue
+		}
+
+		s.pause.wait(&cmd)
 
 		if isNoWait {
 			s.handleCommand(cli, cli.LastCommand)
 		} else {
+			start := time.Now()
 			s.requests[cli.DB] <- cli
+			s.latency.add(latencyEventRequestHandoff, time.Since(start))
+			<-cli.Done
+			s.serveBlocked(cli)
+		}
+
+		cli.UpdateInfo()
+		if cli.Flag&client.CLIENT_CLOSE_AFTER_REPLY != 0 {
+			return
 		}
 	}
 }
 
-func (s *Server) checkAuthentication(cli *client.Client) error {
-	if s.requirePass == "" {
-		return nil
+// setReadDeadline closes the client if it is idle for the timeout, unless it is
+// in a transaction or a monitor.
+func (s *Server) setReadDeadline(cli *client.Client) {
+	if s.timeout <= 0 {
+		return
+	}
+	if cli.Flag&(client.CLIENT_MULTI|client.CLIENT_MONITOR) != 0 {
+		cli.Conn.SetReadDeadline(time.Time{})
+	} else {
+		cli.Conn.SetReadDeadline(time.Now().Add(s.timeout))
 	}
+}
+
+func (s *Server) checkAuthentication(cli *client.Client) error {
 	if cli.LastCommand != nil {
 		if cli.LastCommand.Command == "AUTH" {
 			return nil
@@ -169,6 +481,27 @@ func (s *Server) checkAuthentication(cli *client.Client) error {
 	return nil
 }
 
+// checkPermission checks the ACL rules of the client's user for the command
+// and its keys, and logs the denials into the ACL log.
+func (s *Server) checkPermission(cli *client.Client, cmd *DBCommand) error {
+	name := cli.LastCommand.Command
+	if name == "AUTH" {
+		return nil
+	}
+
+	reason, object, ok := s.acl.checkPermission(cli.User, name, cmd, cli.LastCommand.Args)
+	if ok {
+		return nil
+	}
+
+	s.acl.addLogEntry(cli, reason, object, cli.User)
+	s.commandStats[strings.ToUpper(name)].rejected.Add(1)
+	if reason == aclDeniedKey {
+		return ErrNoKeyPermission
+	}
+	return newNoCommandPermissionError(cli.User, strings.ToLower(name))
+}
+
 func (s *Server) handleCommand(cli *client.Client, nextCmd *client.Command) {
 	defer func() {
 		client.PutCommand(nextCmd)
@@ -182,12 +515,34 @@ func (s *Server) handleCommand(cli *client.Client, nextCmd *client.Command) {
 
 	if (cmd.Arity > 0 && cmd.Arity != len(nextCmd.Args)) ||
 		(cmd.Arity <= 0 && len(nextCmd.Args) < -cmd.Arity) {
+		s.commandStats[nextCmd.Command].rejected.Add(1)
 		cli.ReplyError(newWrongArityError(nextCmd.Command).Error())
 		return
 	}
 
+	if cmd.Flags&CommandFlagSkipMonitor == 0 {
+		s.feedMonitors(cli, nextCmd)
+	}
+
+	if s.maxMemory > 0 && cmd.Flags&CommandFlagWrite != 0 && !cmd.NoWait {
+		err := s.performEvictions(cli.DB)
+		if err != nil && cmd.Flags&CommandFlagDenyOOM != 0 {
+			s.commandStats[nextCmd.Command].rejected.Add(1)
+			cli.ReplyError(err.Error())
+			return
+		}
+	}
+
+	start := time.Now()
 	err := cmd.Handler(s, cli, nextCmd.Args...)
+	duration := time.Since(start)
+	s.commandStats[nextCmd.Command].record(duration)
+	s.latency.add(latencyEventCommand, duration)
+	if cmd.Flags&CommandFlagSkipSlowlog == 0 {
+		s.slowlog.add(cli, nextCmd, duration)
+	}
 	if err != nil {
+		s.commandStats[nextCmd.Command].failed.Add(1)
 		cli.ReplyError(err.Error())
 	}
 
@@ -197,30 +552,11 @@ func (s *Server) handleCommand(cli *client.Client, nextCmd *client.Command) {
 }
 
 func (s *Server) serverCron() {
-	duration := 1000 / s.hz
-	ticker := time.NewTicker(time.Duration(duration) * time.Millisecond)
+	ticker := time.NewTicker(time.Second / time.Duration(s.hz))
 	defer ticker.Stop()
 
 	for range ticker.C {
-		timeout := int(float64(duration) * 0.25)
-		ctx, canFunc := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Millisecond)
-
-	dbLoop:
-		for _, db := range s.databases {
-			select {
-			case <-ctx.Done():
-				break dbLoop
-			default:
-			again:
-				cnt := db.CheckExpire(ctx, s.activeExpireSamples)
-				ratio := float64(cnt) / float64(s.activeExpireSamples)
-				if ratio > 0.25 {
-					goto again
-				}
-			}
-		}
-
-		canFunc()
+		s.trackMetrics()
 	}
 }
 
@@ -0,0 +1,268 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ghosind/antdb/client"
+)
+
+// testConn is a connection that records the replies written to it.
+type testConn struct {
+	net.Conn
+	replies bytes.Buffer
+}
+
+func (c *testConn) Write(p []byte) (int, error) {
+	return c.replies.Write(p)
+}
+
+func (c *testConn) LocalAddr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 6379}
+}
+
+func (c *testConn) RemoteAddr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 50000}
+}
+
+// testClient runs commands against a server without a listener, in the test
+// goroutine as the loop goroutine of its database.
+type testClient struct {
+	t    *testing.T
+	s    *Server
+	cli  *client.Client
+	conn *testConn
+}
+
+func newTestClient(t *testing.T, options ...ServerOption) *testClient {
+	conn := new(testConn)
+	return &testClient{
+		t:    t,
+		s:    NewServer(append([]ServerOption{WithDatabases(1)}, options...)...),
+		cli:  client.NewClient(conn, 1),
+		conn: conn,
+	}
+}
+
+// do runs the command, and returns its raw RESP reply.
+func (c *testClient) do(args ...string) string {
+	c.t.Helper()
+
+	cmd := client.GetCommand()
+	cmd.Command = strings.ToUpper(args[0])
+	cmd.Args = append(cmd.Args, args[1:]...)
+	c.cli.LastCommand = cmd
+
+	c.conn.replies.Reset()
+	c.s.handleCommand(c.cli, cmd)
+	return c.conn.replies.String()
+}
+
+// mustDo runs the command, and fails the test if the reply is not expected.
+func (c *testClient) mustDo(expected string, args ...string) {
+	c.t.Helper()
+
+	if reply := c.do(args...); reply != expected {
+		c.t.Fatalf("%s: expected reply %q, got %q", strings.Join(args, " "), expected, reply)
+	}
+}
+
+// bulkReply returns the bulk string reply of the value.
+func bulkReply(value string) string {
+	return "$" + strconv.Itoa(len(value)) + "\r\n" + value + "\r\n"
+}
+
+// arrayReply returns the array reply of the bulk strings of the values.
+func arrayReply(values ...string) string {
+	reply := "*" + strconv.Itoa(len(values)) + "\r\n"
+	for _, value := range values {
+		reply += bulkReply(value)
+	}
+	return reply
+}
+
+// startTestServer starts a server listening on a free port of the loopback
+// address, and returns the server and its address. The server is closed at the
+// end of the test.
+func startTestServer(t *testing.T, options ...ServerOption) (*Server, string) {
+	t.Helper()
+
+	port := freeTestPort(t)
+	s := NewServer(append([]ServerOption{
+		WithBind("127.0.0.1"),
+		WithPort(port),
+		WithDatabases(2),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	}, options...)...)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- s.Listen()
+	}()
+	t.Cleanup(func() {
+		s.Close()
+	})
+
+	addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+		select {
+		case err := <-errs:
+			t.Fatalf("failed to start server: %v", err)
+		default:
+		}
+		if conn, err := net.Dial("tcp", addr); err == nil {
+			conn.Close()
+			return s, addr
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("server is not listening on %s", addr)
+	return nil, ""
+}
+
+// freeTestPort returns a free port of the loopback address.
+func freeTestPort(t *testing.T) int {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to find a free port: %v", err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+// netClient is a client connected to a server started by startTestServer.
+type netClient struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func dialTestServer(t *testing.T, addr string) *netClient {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("failed to connect to %s: %v", addr, err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+	})
+	return newNetClient(t, conn)
+}
+
+func newNetClient(t *testing.T, conn net.Conn) *netClient {
+	return &netClient{t: t, conn: conn, r: bufio.NewReader(conn)}
+}
+
+// send writes the command without waiting for its reply.
+func (c *netClient) send(args ...string) {
+	c.t.Helper()
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&buf, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := c.conn.Write(buf.Bytes()); err != nil {
+		c.t.Fatalf("%s: failed to send: %v", strings.Join(args, " "), err)
+	}
+}
+
+// read returns the raw RESP reply, or fails the test if no reply is received in
+// the timeout.
+func (c *netClient) read(timeout time.Duration) string {
+	c.t.Helper()
+
+	reply, err := c.readReply(timeout)
+	if err != nil {
+		c.t.Fatalf("failed to read reply: %v", err)
+	}
+	return reply
+}
+
+// readReply returns the raw RESP reply, or the error of the connection.
+func (c *netClient) readReply(timeout time.Duration) (string, error) {
+	c.conn.SetReadDeadline(time.Now().Add(timeout))
+	defer c.conn.SetReadDeadline(time.Time{})
+
+	var buf strings.Builder
+	err := readRESP(c.r, &buf)
+	return buf.String(), err
+}
+
+// do sends the command, and returns its raw RESP reply.
+func (c *netClient) do(args ...string) string {
+	c.t.Helper()
+
+	c.send(args...)
+	return c.read(5 * time.Second)
+}
+
+// mustDo runs the command, and fails the test if the reply is not expected.
+func (c *netClient) mustDo(expected string, args ...string) {
+	c.t.Helper()
+
+	if reply := c.do(args...); reply != expected {
+		c.t.Fatalf("%s: expected reply %q, got %q", strings.Join(args, " "), expected, reply)
+	}
+}
+
+// expectClosed fails the test if the connection is not closed by the server in
+// the timeout.
+func (c *netClient) expectClosed(timeout time.Duration) {
+	c.t.Helper()
+
+	reply, err := c.readReply(timeout)
+	if err == nil {
+		c.t.Fatalf("expected connection closed, got reply %q", reply)
+	} else if errors.Is(err, os.ErrDeadlineExceeded) {
+		c.t.Fatalf("expected connection closed, got no reply")
+	}
+}
+
+// readRESP copies a RESP value from the reader into the buffer.
+func readRESP(r *bufio.Reader, buf *strings.Builder) error {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	buf.WriteString(line)
+	if len(line) < 3 {
+		return fmt.Errorf("invalid RESP line %q", line)
+	}
+
+	switch line[0] {
+	case '$':
+		n, err := strconv.Atoi(strings.TrimSuffix(line[1:], "\r\n"))
+		if err != nil || n < 0 {
+			return err
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return err
+		}
+		buf.Write(data)
+	case '*':
+		n, err := strconv.Atoi(strings.TrimSuffix(line[1:], "\r\n"))
+		if err != nil {
+			return err
+		}
+		for i := 0; i < n; i++ {
+			if err := readRESP(r, buf); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ghosind/antdb/client"
+)
+
+const (
+	defaultSlowlogLogSlowerThan = 10000
+	defaultSlowlogMaxLen        = 128
+
+	slowlogEntryMaxArgc   = 32
+	slowlogEntryMaxString = 128
+)
+
+type slowlogEntry struct {
+	id       int64
+	time     time.Time
+	duration time.Duration
+	args     []string
+	addr     string
+	name     string
+}
+
+// slowlog keeps the latest commands that ran longer than slowerThan in a ring
+// buffer. A negative slowerThan disables the log, and zero logs every command.
+type slowlog struct {
+	mu         sync.Mutex
+	entries    []slowlogEntry
+	next       int
+	size       int
+	nextID     int64
+	slowerThan time.Duration
+}
+
+func newSlowlog(slowerThan time.Duration, maxLen int) *slowlog {
+	return &slowlog{
+		entries:    make([]slowlogEntry, maxLen),
+		slowerThan: slowerThan,
+	}
+}
+
+func (l *slowlog) add(cli *client.Client, cmd *client.Command, duration time.Duration) {
+	if l.slowerThan < 0 || duration < l.slowerThan || len(l.entries) == 0 {
+		return
+	}
+
+	info := cli.Info()
+	entry := slowlogEntry{
+		time:     time.Now(),
+		duration: duration,
+		args:     slowlogArgs(cmd),
+		addr:     info.Addr,
+		name:     info.Name,
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry.id = l.nextID
+	l.nextID++
+	l.entries[l.next] = entry
+	l.next = (l.next + 1) % len(l.entries)
+	if l.size < len(l.entries) {
+		l.size++
+	}
+}
+
+// get returns up to count entries from the newest one, or all entries if count
+// is negative.
+func (l *slowlog) get(count int) []slowlogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if count < 0 || count > l.size {
+		count = l.size
+	}
+	entries := make([]slowlogEntry, 0, count)
+	for i := 1; i <= count; i++ {
+		idx := (l.next - i + len(l.entries)) % len(l.entries)
+		entries = append(entries, l.entries[idx])
+	}
+	return entries
+}
+
+func (l *slowlog) len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.size
+}
+
+func (l *slowlog) reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i := range l.entries {
+		l.entries[i] = slowlogEntry{}
+	}
+	l.next = 0
+	l.size = 0
+}
+
+// slowlogArgs returns the arguments of the command for the slow log, keeping up
+// to slowlogEntryMaxArgc arguments of up to slowlogEntryMaxString bytes.
+func slowlogArgs(cmd *client.Command) []string {
+	argc := len(cmd.Args) + 1
+	if argc > slowlogEntryMaxArgc {
+		argc = slowlogEntryMaxArgc
+	}
+
+	args := make([]string, 0, argc)
+	args = append(args, cmd.Command)
+	for i, arg := range cmd.Args {
+		if len(args) == slowlogEntryMaxArgc-1 && i < len(cmd.Args)-1 {
+			more := len(cmd.Args) - i
+			args = append(args, "... ("+strconv.Itoa(more)+" more arguments)")
+			break
+		}
+		if len(arg) > slowlogEntryMaxString {
+			more := len(arg) - slowlogEntryMaxString
+			arg = arg[:slowlogEntryMaxString] + "... (" + strconv.Itoa(more) + " more bytes)"
+		}
+		args = append(args, arg)
+	}
+	return args
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// statsMetricSamples is the number of samples of an instantaneous metric, which
+// are taken every cron tick and averaged.
+const statsMetricSamples = 16
+
+type serverStats struct {
+	startTime time.Time
+
+	rejectedConnections   atomic.Int64
+	evictedKeys           atomic.Int64
+	expiredTimeCapReached atomic.Int64
+	expireCycleTime       atomic.Int64
+
+	metricsMu        sync.Mutex
+	expiredPerSecond instantaneousMetric
+}
+
+// instantaneousMetric computes the rate per second of a counter, averaged over
+// its last samples.
+type instantaneousMetric struct {
+	samples    [statsMetricSamples]float64
+	index      int
+	lastTime   time.Time
+	lastReport int64
+}
+
+func (m *instantaneousMetric) track(current int64, now time.Time) {
+	if !m.lastTime.IsZero() {
+		elapsed := now.Sub(m.lastTime).Seconds()
+		if elapsed > 0 {
+			m.samples[m.index] = float64(current-m.lastReport) / elapsed
+			m.index = (m.index + 1) % statsMetricSamples
+		}
+	}
+	m.lastTime = now
+	m.lastReport = current
+}
+
+func (m *instantaneousMetric) value() float64 {
+	sum := 0.0
+	for _, sample := range m.samples {
+		sum += sample
+	}
+	return sum / statsMetricSamples
+}
+
+func (s *Server) expiredKeys() int64 {
+	expired := int64(0)
+	for _, db := range s.databases {
+		expired += db.ExpiredKeys()
+	}
+	return expired
+}
+
+func (s *Server) trackMetrics() {
+	now := time.Now()
+
+	s.stats.metricsMu.Lock()
+	defer s.stats.metricsMu.Unlock()
+
+	s.stats.expiredPerSecond.track(s.expiredKeys(), now)
+}
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ghosind/antdb/client"
+)
+
+const tlsHandshakeTimeout = 10 * time.Second
+
+var tlsVersions = map[string]uint16{
+	"tlsv1":   tls.VersionTLS10,
+	"tlsv1.0": tls.VersionTLS10,
+	"tlsv1.1": tls.VersionTLS11,
+	"tlsv1.2": tls.VersionTLS12,
+	"tlsv1.3": tls.VersionTLS13,
+}
+
+type tlsOptions struct {
+	certFile    string
+	keyFile     string
+	caCertFile  string
+	authClients string
+	protocols   string
+	ciphers     string
+}
+
+// config builds the TLS configuration of the TLS port. Clients must present a
+// certificate signed by the CA unless tls-auth-clients is no or optional.
+func (o *tlsOptions) config() (*tls.Config, error) {
+	if o.certFile == "" || o.keyFile == "" {
+		return nil, errors.New("tls-cert-file and tls-key-file are required to enable tls-port")
+	}
+
+	cert, err := tls.LoadX509KeyPair(o.certFile, o.keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if o.protocols != "" {
+		cfg.MinVersion, cfg.MaxVersion, err = parseTLSProtocols(o.protocols)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if o.ciphers != "" {
+		cfg.CipherSuites, err = parseTLSCiphers(o.ciphers)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch strings.ToLower(o.authClients) {
+	case "", "yes":
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	case "optional":
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	case "no":
+		cfg.ClientAuth = tls.NoClientCert
+	default:
+		return nil, fmt.Errorf("invalid tls-auth-clients value '%s'", o.authClients)
+	}
+
+	if cfg.ClientAuth != tls.NoClientCert {
+		if o.caCertFile == "" {
+			return nil, errors.New("tls-ca-cert-file is required to authenticate TLS clients")
+		}
+		data, err := os.ReadFile(o.caCertFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS CA certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificate found in %s", o.caCertFile)
+		}
+		cfg.ClientCAs = pool
+	}
+
+	return cfg, nil
+}
+
+// parseTLSProtocols parses a space separated list of the TLS versions, like
+// "TLSv1.2 TLSv1.3", and returns the lowest and the highest versions. All the
+// versions between them are enabled.
+func parseTLSProtocols(protocols string) (uint16, uint16, error) {
+	var minVersion, maxVersion uint16
+	names := strings.Fields(protocols)
+	if len(names) == 0 {
+		return 0, 0, fmt.Errorf("invalid tls-protocols value '%s'", protocols)
+	}
+	for _, name := range names {
+		version, ok := tlsVersions[strings.ToLower(name)]
+		if !ok {
+			return 0, 0, fmt.Errorf("unknown TLS protocol '%s'", name)
+		}
+		if minVersion == 0 || version < minVersion {
+			minVersion = version
+		}
+		if version > maxVersion {
+			maxVersion = version
+		}
+	}
+	return minVersion, maxVersion, nil
+}
+
+// parseTLSCiphers parses a colon separated list of the TLS 1.2 cipher suites,
+// like "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256:TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384".
+// The TLS 1.3 cipher suites are not configurable.
+func parseTLSCiphers(ciphers string) ([]uint16, error) {
+	suites := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		suites[suite.Name] = suite.ID
+	}
+
+	ids := make([]uint16, 0)
+	for _, name := range strings.Split(ciphers, ":") {
+		if name == "" {
+			continue
+		}
+		id, ok := suites[strings.ToUpper(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown or insecure TLS cipher suite '%s'", name)
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("invalid tls-ciphers value '%s'", ciphers)
+	}
+	return ids, nil
+}
+
+// tlsHandshake completes the handshake of a TLS connection, and saves the
+// subject of the verified client certificate if the client presented one.
+func (s *Server) tlsHandshake(cli *client.Client, conn *tls.Conn) error {
+	conn.SetDeadline(time.Now().Add(tlsHandshakeTimeout))
+	if err := conn.Handshake(); err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Time{})
+
+	state := conn.ConnectionState()
+	if len(state.VerifiedChains) > 0 && len(state.PeerCertificates) > 0 {
+		cli.CertSubject = state.PeerCertificates[0].Subject.String()
+	}
+	return nil
+}
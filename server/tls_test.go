@@ -0,0 +1,468 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ghosind/antdb/client"
+)
+
+// testCertificates are the certificates generated for the TLS tests. The
+// server and the client certificates are signed by the CA, and the untrusted
+// certificate is signed by another CA.
+type testCertificates struct {
+	caFile    string
+	certFile  string
+	keyFile   string
+	roots     *x509.CertPool
+	client    tls.Certificate
+	untrusted tls.Certificate
+}
+
+func newTestCertificates(t *testing.T) *testCertificates {
+	t.Helper()
+
+	ca, caKey := newTestCertificate(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "AntDB Test CA"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil, nil)
+	server, serverKey := newTestCertificate(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "localhost"},
+		DNSNames:    []string{"localhost"},
+		IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1)},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca, caKey)
+	cli, cliKey := newTestCertificate(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "client", Organization: []string{"AntDB"}},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca, caKey)
+	otherCA, otherCAKey := newTestCertificate(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "Other CA"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil, nil)
+	untrusted, untrustedKey := newTestCertificate(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "untrusted"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, otherCA, otherCAKey)
+
+	dir := t.TempDir()
+	certs := &testCertificates{
+		caFile:    filepath.Join(dir, "ca.crt"),
+		certFile:  filepath.Join(dir, "server.crt"),
+		keyFile:   filepath.Join(dir, "server.key"),
+		roots:     x509.NewCertPool(),
+		client:    tls.Certificate{Certificate: [][]byte{cli.Raw}, PrivateKey: cliKey, Leaf: cli},
+		untrusted: tls.Certificate{Certificate: [][]byte{untrusted.Raw}, PrivateKey: untrustedKey, Leaf: untrusted},
+	}
+	certs.roots.AddCert(ca)
+
+	keyDER, err := x509.MarshalECPrivateKey(serverKey)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	writeTestPEM(t, certs.caFile, "CERTIFICATE", ca.Raw)
+	writeTestPEM(t, certs.certFile, "CERTIFICATE", server.Raw)
+	writeTestPEM(t, certs.keyFile, "EC PRIVATE KEY", keyDER)
+
+	return certs
+}
+
+// newTestCertificate creates a certificate of the template signed by the
+// parent, or a self-signed certificate if the parent is nil.
+func newTestCertificate(t *testing.T, template, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 62))
+	if err != nil {
+		t.Fatalf("failed to generate serial number: %v", err)
+	}
+	template.SerialNumber = serial
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	template.KeyUsage |= x509.KeyUsageDigitalSignature
+
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	return cert, key
+}
+
+func writeTestPEM(t *testing.T, file, blockType string, der []byte) {
+	t.Helper()
+
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(file, data, 0600); err != nil {
+		t.Fatalf("failed to write %s: %v", file, err)
+	}
+}
+
+func TestTLSConfig(t *testing.T) {
+	certs := newTestCertificates(t)
+	missing := filepath.Join(t.TempDir(), "missing.crt")
+
+	tests := []struct {
+		name       string
+		options    tlsOptions
+		err        string
+		clientAuth tls.ClientAuthType
+		minVersion uint16
+		maxVersion uint16
+		ciphers    []uint16
+	}{
+		{
+			name:    "no certificate",
+			options: tlsOptions{caCertFile: certs.caFile},
+			err:     "tls-cert-file and tls-key-file are required to enable tls-port",
+		},
+		{
+			name:    "no key",
+			options: tlsOptions{certFile: certs.certFile, caCertFile: certs.caFile},
+			err:     "tls-cert-file and tls-key-file are required to enable tls-port",
+		},
+		{
+			name:    "missing certificate",
+			options: tlsOptions{certFile: missing, keyFile: certs.keyFile, caCertFile: certs.caFile},
+			err:     "failed to load TLS certificate: open " + missing + ": no such file or directory",
+		},
+		{
+			name:    "key of other certificate",
+			options: tlsOptions{certFile: certs.caFile, keyFile: certs.keyFile, caCertFile: certs.caFile},
+			err:     "failed to load TLS certificate: tls: private key does not match public key",
+		},
+		{
+			name:       "auth clients default",
+			options:    tlsOptions{certFile: certs.certFile, keyFile: certs.keyFile, caCertFile: certs.caFile},
+			clientAuth: tls.RequireAndVerifyClientCert,
+			minVersion: tls.VersionTLS12,
+		},
+		{
+			name:       "auth clients yes",
+			options:    tlsOptions{certFile: certs.certFile, keyFile: certs.keyFile, caCertFile: certs.caFile, authClients: "YES"},
+			clientAuth: tls.RequireAndVerifyClientCert,
+			minVersion: tls.VersionTLS12,
+		},
+		{
+			name:       "auth clients optional",
+			options:    tlsOptions{certFile: certs.certFile, keyFile: certs.keyFile, caCertFile: certs.caFile, authClients: "optional"},
+			clientAuth: tls.VerifyClientCertIfGiven,
+			minVersion: tls.VersionTLS12,
+		},
+		{
+			name:       "auth clients no",
+			options:    tlsOptions{certFile: certs.certFile, keyFile: certs.keyFile, authClients: "no"},
+			clientAuth: tls.NoClientCert,
+			minVersion: tls.VersionTLS12,
+		},
+		{
+			name:    "invalid auth clients",
+			options: tlsOptions{certFile: certs.certFile, keyFile: certs.keyFile, caCertFile: certs.caFile, authClients: "maybe"},
+			err:     "invalid tls-auth-clients value 'maybe'",
+		},
+		{
+			name:    "no CA",
+			options: tlsOptions{certFile: certs.certFile, keyFile: certs.keyFile, authClients: "optional"},
+			err:     "tls-ca-cert-file is required to authenticate TLS clients",
+		},
+		{
+			name:    "missing CA",
+			options: tlsOptions{certFile: certs.certFile, keyFile: certs.keyFile, caCertFile: missing},
+			err:     "failed to load TLS CA certificate: open " + missing + ": no such file or directory",
+		},
+		{
+			name:    "invalid CA",
+			options: tlsOptions{certFile: certs.certFile, keyFile: certs.keyFile, caCertFile: certs.keyFile},
+			err:     "no certificate found in " + certs.keyFile,
+		},
+		{
+			name:       "protocol",
+			options:    tlsOptions{certFile: certs.certFile, keyFile: certs.keyFile, authClients: "no", protocols: "tlsv1.3"},
+			clientAuth: tls.NoClientCert,
+			minVersion: tls.VersionTLS13,
+			maxVersion: tls.VersionTLS13,
+		},
+		{
+			name:       "protocols",
+			options:    tlsOptions{certFile: certs.certFile, keyFile: certs.keyFile, authClients: "no", protocols: " TLSv1.3  TLSv1.1 "},
+			clientAuth: tls.NoClientCert,
+			minVersion: tls.VersionTLS11,
+			maxVersion: tls.VersionTLS13,
+		},
+		{
+			name:    "unknown protocol",
+			options: tlsOptions{certFile: certs.certFile, keyFile: certs.keyFile, authClients: "no", protocols: "TLSv1.2 SSLv3"},
+			err:     "unknown TLS protocol 'SSLv3'",
+		},
+		{
+			name:    "no protocols",
+			options: tlsOptions{certFile: certs.certFile, keyFile: certs.keyFile, authClients: "no", protocols: "  "},
+			err:     "invalid tls-protocols value '  '",
+		},
+		{
+			name: "ciphers",
+			options: tlsOptions{certFile: certs.certFile, keyFile: certs.keyFile, authClients: "no",
+				ciphers: "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256::tls_ecdhe_ecdsa_with_aes_256_gcm_sha384"},
+			clientAuth: tls.NoClientCert,
+			minVersion: tls.VersionTLS12,
+			ciphers:    []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384},
+		},
+		{
+			name:    "unknown cipher",
+			options: tlsOptions{certFile: certs.certFile, keyFile: certs.keyFile, authClients: "no", ciphers: "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256:TLS_UNKNOWN"},
+			err:     "unknown or insecure TLS cipher suite 'TLS_UNKNOWN'",
+		},
+		{
+			name:    "insecure cipher",
+			options: tlsOptions{certFile: certs.certFile, keyFile: certs.keyFile, authClients: "no", ciphers: "TLS_RSA_WITH_RC4_128_SHA"},
+			err:     "unknown or insecure TLS cipher suite 'TLS_RSA_WITH_RC4_128_SHA'",
+		},
+		{
+			name:    "no ciphers",
+			options: tlsOptions{certFile: certs.certFile, keyFile: certs.keyFile, authClients: "no", ciphers: ":"},
+			err:     "invalid tls-ciphers value ':'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := tt.options.config()
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("expected error %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to build TLS config: %v", err)
+			}
+
+			if cfg.ClientAuth != tt.clientAuth {
+				t.Errorf("expected client auth %v, got %v", tt.clientAuth, cfg.ClientAuth)
+			}
+			if (cfg.ClientCAs != nil) != (tt.clientAuth != tls.NoClientCert) {
+				t.Errorf("expected client CAs only if clients are authenticated")
+			}
+			if cfg.MinVersion != tt.minVersion || cfg.MaxVersion != tt.maxVersion {
+				t.Errorf("expected versions %x-%x, got %x-%x", tt.minVersion, tt.maxVersion, cfg.MinVersion, cfg.MaxVersion)
+			}
+			if !reflect.DeepEqual(cfg.CipherSuites, tt.ciphers) {
+				t.Errorf("expected cipher suites %v, got %v", tt.ciphers, cfg.CipherSuites)
+			}
+		})
+	}
+}
+
+func TestTLSHandshake(t *testing.T) {
+	certs := newTestCertificates(t)
+	s := NewServer(WithDatabases(1))
+
+	tests := []struct {
+		name        string
+		authClients string
+		cert        *tls.Certificate
+		ok          bool
+		subject     string
+	}{
+		{name: "required", authClients: "yes", cert: &certs.client, ok: true, subject: "CN=client,O=AntDB"},
+		{name: "required without certificate", authClients: "yes", ok: false},
+		{name: "required untrusted", authClients: "yes", cert: &certs.untrusted, ok: false},
+		{name: "optional", authClients: "optional", cert: &certs.client, ok: true, subject: "CN=client,O=AntDB"},
+		{name: "optional without certificate", authClients: "optional", ok: true},
+		{name: "optional untrusted", authClients: "optional", cert: &certs.untrusted, ok: false},
+		{name: "not requested", authClients: "no", cert: &certs.client, ok: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := (&tlsOptions{
+				certFile:    certs.certFile,
+				keyFile:     certs.keyFile,
+				caCertFile:  certs.caFile,
+				authClients: tt.authClients,
+			}).config()
+			if err != nil {
+				t.Fatalf("failed to build TLS config: %v", err)
+			}
+
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("failed to listen: %v", err)
+			}
+			defer l.Close()
+
+			clientCfg := clientTLSConfig(certs.roots, tt.cert)
+			go func() {
+				conn, err := tls.Dial("tcp", l.Addr().String(), clientCfg)
+				if err == nil {
+					defer conn.Close()
+					conn.Read(make([]byte, 1))
+				}
+			}()
+
+			conn, err := l.Accept()
+			if err != nil {
+				t.Fatalf("failed to accept: %v", err)
+			}
+			defer conn.Close()
+			cli := client.NewClient(tls.Server(conn, cfg), 1)
+
+			err = s.tlsHandshake(cli, cli.Conn.(*tls.Conn))
+			if tt.ok && err != nil {
+				t.Fatalf("expected handshake completed, got %v", err)
+			} else if !tt.ok && err == nil {
+				t.Fatalf("expected handshake failed")
+			}
+			if cli.CertSubject != tt.subject {
+				t.Errorf("expected certificate subject %q, got %q", tt.subject, cli.CertSubject)
+			}
+		})
+	}
+}
+
+func TestTLSAuthClients(t *testing.T) {
+	certs := newTestCertificates(t)
+
+	tests := []struct {
+		authClients string
+		client      bool
+		none        bool
+		untrusted   bool
+	}{
+		{authClients: "yes", client: true, none: false, untrusted: false},
+		{authClients: "optional", client: true, none: true, untrusted: false},
+		{authClients: "no", client: true, none: true, untrusted: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.authClients, func(t *testing.T) {
+			tlsPort := freeTestPort(t)
+			startTestServer(t,
+				WithTLSPort(tlsPort),
+				WithTLSCertFile(certs.certFile),
+				WithTLSKeyFile(certs.keyFile),
+				WithTLSCACertFile(certs.caFile),
+				WithTLSAuthClients(tt.authClients),
+			)
+			addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(tlsPort))
+
+			for name, cert := range map[string]*tls.Certificate{
+				"client":    &certs.client,
+				"none":      nil,
+				"untrusted": &certs.untrusted,
+			} {
+				expected := map[string]bool{"client": tt.client, "none": tt.none, "untrusted": tt.untrusted}[name]
+				reply, err := pingTLS(t, addr, clientTLSConfig(certs.roots, cert))
+				if expected && (err != nil || reply != "+PONG\r\n") {
+					t.Errorf("%s: expected PONG, got %q, %v", name, reply, err)
+				} else if !expected && err == nil {
+					t.Errorf("%s: expected connection rejected, got %q", name, reply)
+				}
+			}
+		})
+	}
+}
+
+func TestTLSProtocols(t *testing.T) {
+	certs := newTestCertificates(t)
+
+	tlsPort := freeTestPort(t)
+	startTestServer(t,
+		WithTLSPort(tlsPort),
+		WithTLSCertFile(certs.certFile),
+		WithTLSKeyFile(certs.keyFile),
+		WithTLSAuthClients("no"),
+		WithTLSProtocols("TLSv1.2"),
+		WithTLSCiphers("TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384"),
+	)
+	addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(tlsPort))
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{RootCAs: certs.roots})
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer conn.Close()
+	state := conn.ConnectionState()
+	if state.Version != tls.VersionTLS12 {
+		t.Errorf("expected TLS 1.2, got %x", state.Version)
+	}
+	if state.CipherSuite != tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384 {
+		t.Errorf("expected cipher suite %s, got %s", tls.CipherSuiteName(tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384), tls.CipherSuiteName(state.CipherSuite))
+	}
+
+	if _, err := pingTLS(t, addr, &tls.Config{RootCAs: certs.roots, MinVersion: tls.VersionTLS13}); err == nil {
+		t.Errorf("expected TLS 1.3 client rejected")
+	}
+	if _, err := pingTLS(t, addr, &tls.Config{
+		RootCAs:      certs.roots,
+		MaxVersion:   tls.VersionTLS12,
+		CipherSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+	}); err == nil {
+		t.Errorf("expected client without the cipher suite rejected")
+	}
+}
+
+// clientTLSConfig returns the TLS configuration of a client that sends the
+// certificate whenever the server requests one, even if it is not signed by the
+// CAs accepted by the server.
+func clientTLSConfig(roots *x509.CertPool, cert *tls.Certificate) *tls.Config {
+	cfg := &tls.Config{RootCAs: roots}
+	if cert != nil {
+		cfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return cert, nil
+		}
+	}
+	return cfg
+}
+
+// pingTLS connects to the TLS address, and returns the reply of PING or the
+// error of the connection. With TLS 1.3, the server rejects the certificate of
+// a client after the client completes its handshake, so the error may only be
+// returned by the read.
+func pingTLS(t *testing.T, addr string, cfg *tls.Config) (string, error) {
+	t.Helper()
+
+	conn, err := tls.Dial("tcp", addr, cfg)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	c := newNetClient(t, conn)
+	if _, err := conn.Write([]byte("*1\r\n$4\r\nPING\r\n")); err != nil {
+		return "", err
+	}
+	reply, err := c.readReply(5 * time.Second)
+	if err == nil && strings.HasPrefix(reply, "-") {
+		return reply, os.ErrPermission
+	}
+	return reply, err
+}